@@ -0,0 +1,99 @@
+package book
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ztrue/tracerr"
+)
+
+// Annotation is a note or sticky the viewer shows on a page. Its position is relative to the page, as fractions of
+// the page width and height.
+type Annotation struct {
+	Text   string  `json:"text"`
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+// annotationSelectors match the notes layer of the viewer. They are broad, so only elements with text and on the
+// captured page are kept.
+const annotationSelectors = `[class*="annotation"], [class*="sticky"], [class*="note"], [data-type="note"]`
+
+// annotationsScript returns the annotations on the captured side of the spread
+const annotationsScript = `(() => {
+	const side = %q;
+	const pages = (` + visiblePagesScript + `)();
+	const page = side === "right" && pages.length >= 2 ? pages[1] : pages[0];
+	if (!page) {
+		return [];
+	}
+	const bounds = page.getBoundingClientRect();
+
+	const onPage = el => {
+		const rect = el.getBoundingClientRect();
+		const x = rect.left + rect.width / 2;
+		const y = rect.top + rect.height / 2;
+		return x >= bounds.left && x <= bounds.right && y >= bounds.top && y <= bounds.bottom;
+	};
+
+	const notes = Array.from(document.querySelectorAll('` + annotationSelectors + `'))
+		.filter(el => (el.innerText || '').trim() !== '' && onPage(el));
+
+	// nested matches would repeat the text of the note they are part of
+	return notes
+		.filter(el => !notes.some(other => other !== el && other.contains(el)))
+		.map(el => {
+			const rect = el.getBoundingClientRect();
+			return {
+				text: el.innerText.trim(),
+				x: (rect.left - bounds.left) / bounds.width,
+				y: (rect.top - bounds.top) / bounds.height,
+				width: rect.width / bounds.width,
+				height: rect.height / bounds.height,
+			};
+		});
+})()`
+
+// AnnotationsPath returns the path of the file holding the annotations of a capture
+func AnnotationsPath(capturePath string) string {
+	return strings.TrimSuffix(capturePath, filepath.Ext(capturePath)) + ".annotations.json"
+}
+
+// ReadAnnotations reads the annotations of a capture, returning none if the capture has no annotations file
+func ReadAnnotations(capturePath string) ([]Annotation, error) {
+	data, err := os.ReadFile(AnnotationsPath(capturePath))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+
+	var annotations []Annotation
+	if err := json.Unmarshal(data, &annotations); err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+
+	return annotations, nil
+}
+
+// writeAnnotations saves the annotations of a capture next to it, or removes a stale file if there are none
+func writeAnnotations(capturePath string, annotations []Annotation) error {
+	path := AnnotationsPath(capturePath)
+	if len(annotations) == 0 {
+		os.Remove(path)
+		return nil
+	}
+
+	data, err := json.MarshalIndent(annotations, "", "  ")
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	return tracerr.Wrap(os.WriteFile(path, data, 0644))
+}
@@ -0,0 +1,628 @@
+package book
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ztrue/tracerr"
+	"golang.org/x/text/language"
+)
+
+var idRegex = regexp.MustCompile(`^(\w+\/\w+)\/?`)
+
+type Book struct {
+	Url   string
+	Id    string
+	Title string
+	Pages []Page
+
+	// Outline is the table of contents of the book, if it has one
+	Outline []OutlineEntry
+
+	// Language is the BCP 47 tag of the language of the book, such as "en" or "pt-BR", or empty if unknown
+	Language string
+
+	// PageWidth and PageHeight are the size of the pages in the viewer in pixels, or zero if unknown
+	PageWidth  int
+	PageHeight int
+
+	Security Security
+}
+
+type Page struct {
+	Number       int
+	ThumbnailUrl string
+	ImageUrls    []string
+
+	// Links and Annotations are the layers the publisher put on top of the page image
+	Links       []Link
+	Annotations []Annotation
+}
+
+type PageImage struct {
+	PageNumber   int
+	ImageNumber  int
+	OverallOrder int
+	Url          string
+}
+
+type DownloadedImage struct {
+	PageNumber   int
+	ImageNumber  int
+	OverallOrder int
+	Url          string
+	FullPath     string
+	Attempts     int           // number of requests it took to download, 0 if the file was already on disk
+	Bytes        int64         // bytes transferred over the network, 0 if the file was already on disk
+	Duration     time.Duration // time it took to download, including retries
+	Revalidated  bool          // the file on disk was checked against the server with DownloadOptions.Revalidate
+}
+
+type htmlConfig struct {
+	Pages     []page         `json:"fliphtml5_pages"`
+	Meta      meta           `json:"meta"`
+	Outline   []outlineItem  `json:"outline"`
+	Bookmarks []outlineItem  `json:"bookmarks"`
+	Toc       []outlineItem  `json:"toc"`
+	Security  securityConfig `json:"security"`
+}
+
+type meta struct {
+	Title      string         `json:"title"`
+	Language   string         `json:"language"`
+	PageWidth  flexibleNumber `json:"pageWidth"`
+	PageHeight flexibleNumber `json:"pageHeight"`
+}
+
+type page struct {
+	Images      interface{}      `json:"n"`
+	ThumbUrl    string           `json:"t"`
+	Links       []linkItem       `json:"links"`
+	Annotations []annotationItem `json:"annotations"`
+}
+
+// outline returns the table of contents from whichever of the fields the config uses for it
+func (c *htmlConfig) outline() []outlineItem {
+	for _, items := range [][]outlineItem{c.Outline, c.Bookmarks, c.Toc} {
+		if len(items) > 0 {
+			return items
+		}
+	}
+
+	return nil
+}
+
+// interactivePageImage represents a screenshot of a page with all interactive elements visible
+type InteractivePageImage struct {
+	PageNumber   int
+	OverallOrder int
+	Url          string
+	FullPath     string
+	Popups       []string // captures of the popups opened on the page, in the order of their triggers
+}
+
+// revealInteractiveElementsScript is the javascript code to reveal all hidden texts and click all interactive elements
+const RevealInteractiveElementsScript = `
+(() => {
+  // use requestAnimationFrame for better performance
+  return new Promise(resolve => {
+    requestAnimationFrame(() => {
+      let revealedTextElementsCount = 0;
+      let clickedRectangleCount = 0;
+      let foundRectangleTriggers = 0;
+
+      // --- part 1: selectively reveal TEXT elements hidden by opacity: 0 ---
+      // optimize selector to be more specific
+      const potentialTextElements = document.querySelectorAll('[id^="E+_Text_"], .leo-comp--txt');
+      
+      // use for-loop instead of forEach for better performance
+      for (let i = 0; i < potentialTextElements.length; i++) {
+        const el = potentialTextElements[i];
+        const style = window.getComputedStyle(el);
+        if (style.opacity === '0') {
+          el.style.opacity = '1';
+          // make robustly visible
+          if (style.visibility === 'hidden') {
+            el.style.visibility = 'visible';
+          }
+          if (style.display === 'none') {
+             el.style.display = ''; // or 'block', 'inline', etc.
+          }
+          revealedTextElementsCount++;
+        }
+      }
+
+      // --- part 2: find and click RECTANGLE triggers ---
+      const rectangleTriggers = document.querySelectorAll('[id^="E+_Rectangle_"], .leo-comp--shape-rect.leo-action-trigger');
+      foundRectangleTriggers = rectangleTriggers.length;
+
+      // use for-loop instead of forEach for better performance
+      for (let i = 0; i < rectangleTriggers.length; i++) {
+        const rect = rectangleTriggers[i];
+        let originalOpacity = rect.style.opacity;
+        let originalVisibility = rect.style.visibility;
+        let originalDisplay = rect.style.display;
+
+        let computedStyle = window.getComputedStyle(rect);
+
+        // make it just interactable enough for a click
+        let madeTemporarilyInteractable = false;
+        if (computedStyle.opacity === '0') {
+            rect.style.opacity = '0.01';
+            madeTemporarilyInteractable = true;
+        }
+        if (computedStyle.visibility === 'hidden') {
+            rect.style.visibility = 'visible';
+            madeTemporarilyInteractable = true;
+        }
+        if (computedStyle.display === 'none') {
+            rect.style.display = 'block';
+            madeTemporarilyInteractable = true;
+        }
+
+        if (typeof rect.click === 'function') {
+          try {
+            rect.click();
+            clickedRectangleCount++;
+          } catch (e) {
+            console.error("Error clicking rectangle trigger:", rect.id || rect.className, e);
+          }
+        }
+
+        // if we temporarily made it interactable, revert those changes
+        if (madeTemporarilyInteractable) {
+            rect.style.opacity = originalOpacity;
+            rect.style.visibility = originalVisibility;
+            rect.style.display = originalDisplay;
+        }
+      }
+      
+      resolve(true);
+    });
+  });
+})()
+`
+
+func ParseId(idOrUrl string) (string, error) {
+	// First, check if the given string already looks like an ID (e.g. "abcde/fg123")
+	if matches := idRegex.FindStringSubmatch(idOrUrl); matches != nil && len(matches) >= 2 {
+		return matches[1], nil
+	}
+
+	// Try to parse it as a URL and extract the path components
+	if u, err := url.Parse(idOrUrl); err == nil && u.Host != "" {
+		// Trim leading and trailing slashes from the path
+		trimmedPath := strings.Trim(u.Path, "/")
+		// The ID in a FlipHTML5 URL is always the first two path segments: <account>/<book>
+		matches := idRegex.FindStringSubmatch(trimmedPath)
+		if matches != nil && len(matches) >= 2 {
+			return matches[1], nil
+		}
+	}
+
+	return "", fmt.Errorf("invalid ID or URL: %s", idOrUrl)
+}
+
+// Get downloads the information of a book. The request is stopped when ctx is cancelled.
+func Get(ctx context.Context, idOrUrl string) (*Book, error) {
+	id, err := ParseId(idOrUrl)
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+
+	htmlConfig, err := downloadHtmlConfig(ctx, id)
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+
+	pageWidth := float64(htmlConfig.Meta.PageWidth)
+	pageHeight := float64(htmlConfig.Meta.PageHeight)
+
+	pages := make([]Page, 0)
+	for i, pageInfo := range htmlConfig.Pages {
+		images := make([]string, 0)
+
+		// Handle different types of Images field
+		switch v := pageInfo.Images.(type) {
+		case []interface{}:
+			for _, img := range v {
+				if imgStr, ok := img.(string); ok {
+					// Clean leading "./" which appears in some configs
+					trimmed := strings.TrimPrefix(imgStr, "./")
+					// If the path already starts with "files/" it is a full relative path, otherwise assume it's just the filename.
+					if strings.HasPrefix(trimmed, "files/") {
+						images = append(images, fmt.Sprintf("https://online.fliphtml5.com/%s/%s", id, trimmed))
+					} else {
+						images = append(images, fmt.Sprintf("https://online.fliphtml5.com/%s/files/large/%s", id, trimmed))
+					}
+				}
+			}
+		case string:
+			// Clean leading "./" which appears in some configs
+			trimmed := strings.TrimPrefix(v, "./")
+			// If the path already starts with "files/" it is a full relative path, otherwise assume it's just the filename.
+			if strings.HasPrefix(trimmed, "files/") {
+				images = append(images, fmt.Sprintf("https://online.fliphtml5.com/%s/%s", id, trimmed))
+			} else {
+				images = append(images, fmt.Sprintf("https://online.fliphtml5.com/%s/files/large/%s", id, trimmed))
+			}
+		}
+
+		pages = append(pages, Page{
+			Number:       i + 1,
+			ThumbnailUrl: thumbnailUrl(id, pageInfo.ThumbUrl),
+			ImageUrls:    images,
+			Links:        parseLinks(pageInfo.Links, pageWidth, pageHeight),
+			Annotations:  parseAnnotations(pageInfo.Annotations, pageWidth, pageHeight),
+		})
+	}
+
+	if len(pages) > 0 && !hasImages(pages) {
+		return nil, fmt.Errorf("%w: no page of %s lists its images in a known format", ErrLayoutChanged, id)
+	}
+
+	return &Book{
+		Url:        fmt.Sprintf("https://online.fliphtml5.com/%s/", id),
+		Id:         id,
+		Title:      html.UnescapeString(htmlConfig.Meta.Title),
+		Pages:      pages,
+		Outline:    parseOutline(htmlConfig.outline()),
+		Language:   ParseLanguage(htmlConfig.Meta.Language),
+		PageWidth:  int(pageWidth),
+		PageHeight: int(pageHeight),
+		Security:   htmlConfig.Security.security(),
+	}, nil
+}
+
+// ParseLanguage normalizes a language code such as "en_US" or "tr" into a BCP 47 tag, returning an empty string
+// for values that aren't a known language
+func ParseLanguage(value string) string {
+	value = strings.ReplaceAll(strings.TrimSpace(value), "_", "-")
+	if value == "" {
+		return ""
+	}
+
+	tag, err := language.Parse(value)
+	if err != nil || tag == language.Und {
+		return ""
+	}
+
+	return tag.String()
+}
+
+// FindAllImages returns the images of every page, see FindImages
+func (b *Book) FindAllImages() []PageImage {
+	return b.FindImages(ImageOptions{})
+}
+
+// imageExtensions are the extensions images are saved with, by the type of their content
+var imageExtensions = map[string]string{
+	"image/jpeg": "jpg",
+	"image/png":  "png",
+	"image/webp": "webp",
+	"image/gif":  "gif",
+}
+
+// imageExtension tells the extension of an image by its first bytes, then by the Content-Type the server sent.
+// Anything else is assumed to be a JPEG, which most pages are.
+func imageExtension(head []byte, contentType string) string {
+	if extension, ok := imageExtensions[http.DetectContentType(head)]; ok {
+		return extension
+	}
+
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	if extension, ok := imageExtensions[strings.ToLower(strings.TrimSpace(mediaType))]; ok {
+		return extension
+	}
+
+	return "jpg"
+}
+
+// FileName is the name the image is saved under, zero padded so the files of a book sort in page order
+func (i *PageImage) FileName(extension string) string {
+	return fmt.Sprintf("%04d-%02d.%s", i.PageNumber, i.ImageNumber, extension)
+}
+
+// legacyFileName is the unpadded name images were saved under before
+func (i *PageImage) legacyFileName(extension string) string {
+	return fmt.Sprintf("%d-%d.%s", i.PageNumber, i.ImageNumber, extension)
+}
+
+// CachedPath returns the path of the image in the folder if it was downloaded before, whatever its type. Images
+// saved under the unpadded names of earlier versions are renamed on the way.
+func (i *PageImage) CachedPath(outputFolder string) (string, bool) {
+	for _, extension := range []string{"jpg", "png", "webp", "gif"} {
+		path := filepath.Join(outputFolder, i.FileName(extension))
+		if _, err := os.Stat(path); err == nil {
+			return path, true
+		}
+
+		legacyPath := filepath.Join(outputFolder, i.legacyFileName(extension))
+		if _, err := os.Stat(legacyPath); err == nil && os.Rename(legacyPath, path) == nil {
+			return path, true
+		}
+	}
+
+	return "", false
+}
+
+// DownloadOptions configures the requests of image downloads. The zero value uses the defaults.
+type DownloadOptions struct {
+	Timeout  time.Duration // for each request, defaults to 30 seconds
+	Attempts int           // how many times an image is tried, defaults to 3
+	Backoff  time.Duration // wait before the first retry, doubled for every further one, defaults to 2 seconds
+
+	// Revalidate asks the server whether images already on disk changed, and downloads them again if they did.
+	// Only images saved with an ETag or Last-Modified header can be revalidated, others are trusted as before.
+	Revalidate bool
+}
+
+func (o DownloadOptions) withDefaults() DownloadOptions {
+	if o.Timeout <= 0 {
+		o.Timeout = 30 * time.Second
+	}
+	if o.Attempts <= 0 {
+		o.Attempts = 3
+	}
+	if o.Backoff <= 0 {
+		o.Backoff = 2 * time.Second
+	}
+
+	return o
+}
+
+// retryDelay is how long to wait before the given attempt
+func (o DownloadOptions) retryDelay(attempt int) time.Duration {
+	return time.Duration(math.Pow(2, float64(attempt-1))) * o.Backoff
+}
+
+// Download saves the image into the folder as <page>-<image> with the extension of its type, unless it is there already
+func (i *PageImage) Download(ctx context.Context, outputFolder string, options DownloadOptions) (*DownloadedImage, error) {
+	// Check if file already exists first to avoid unnecessary downloads
+	cachedPath, cached := i.CachedPath(outputFolder)
+	cachedImage := &DownloadedImage{
+		PageNumber:   i.PageNumber,
+		ImageNumber:  i.ImageNumber,
+		OverallOrder: i.OverallOrder,
+		Url:          i.Url,
+		FullPath:     cachedPath,
+	}
+
+	var conditional *validators
+	if cached {
+		v, ok := i.readValidators(outputFolder)
+		if !options.Revalidate || !ok {
+			// File already exists, return it directly
+			return cachedImage, nil
+		}
+
+		conditional = &v
+		cachedImage.Revalidated = true
+	}
+
+	startTime := time.Now()
+
+	options = options.withDefaults()
+	client := newClient(options.Timeout)
+
+	var lastErr error
+
+	// Retry loop for resilience
+	for attempt := 0; attempt < options.Attempts; attempt++ {
+		if attempt > 0 {
+			// Exponential backoff for retries
+			select {
+			case <-time.After(options.retryDelay(attempt)):
+			case <-ctx.Done():
+				return nil, tracerr.Wrap(ctx.Err())
+			}
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, i.Url, nil)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		setBrowserHeaders(req)
+		if conditional != nil {
+			conditional.setConditional(req)
+		}
+
+		res, err := client.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		// Ensure the response body is always closed
+		if res.Body != nil {
+			defer res.Body.Close()
+		}
+
+		if conditional != nil && res.StatusCode == http.StatusNotModified {
+			return cachedImage, nil
+		}
+
+		if res.StatusCode != http.StatusOK {
+			// Try alternative URL forms
+			candidates := []string{}
+			if strings.Contains(i.Url, "/files/large/") {
+				candidates = append(candidates, strings.Replace(i.Url, "/files/large/", "/files/", 1))
+			}
+			if strings.HasSuffix(i.Url, ".webp") {
+				base := strings.TrimSuffix(i.Url, ".webp")
+				candidates = append(candidates, base+".jpg", base+".png")
+			}
+			for _, alt := range candidates {
+				reqAlt, errAlt := http.NewRequestWithContext(ctx, http.MethodGet, alt, nil)
+				if errAlt != nil {
+					continue
+				}
+				resAlt, errAlt := client.Do(reqAlt)
+				if errAlt == nil && resAlt.StatusCode == http.StatusOK {
+					i.Url = alt
+					res = resAlt
+					goto OK
+				}
+			}
+			altUrl := strings.Replace(i.Url, "/files/large/", "/files/", 1)
+			// quick retry with alternate URL (no recursion, single attempt)
+			reqAlt, errAlt := http.NewRequestWithContext(ctx, http.MethodGet, altUrl, nil)
+			if errAlt == nil {
+				resAlt, errAlt := client.Do(reqAlt)
+				if errAlt == nil && resAlt.StatusCode == http.StatusOK {
+					// swap URL and response for normal processing
+					i.Url = altUrl
+					res = resAlt
+				} else {
+					// fall through to continue retries
+					lastErr = imageStatusError(res)
+					continue
+				}
+			}
+			lastErr = imageStatusError(res)
+			continue
+		}
+
+	OK:
+		// Name the file by what the server actually sent, which isn't always a JPEG
+		body := bufio.NewReader(res.Body)
+		head, _ := body.Peek(512)
+		fullPath := filepath.Join(outputFolder, i.FileName(imageExtension(head, res.Header.Get("Content-Type"))))
+
+		// Create the output file
+		file, err := os.Create(fullPath)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		// Use a buffered copy for better performance
+		bufWriter := bufio.NewWriter(file)
+		written, err := io.Copy(bufWriter, body)
+
+		// Make sure to flush and close even if copy fails
+		flushErr := bufWriter.Flush()
+		closeErr := file.Close()
+
+		if err != nil {
+			// If the copy failed, handle it
+			lastErr = err
+			// Try to remove the potentially corrupted file
+			os.Remove(fullPath)
+			continue
+		}
+
+		if flushErr != nil {
+			lastErr = flushErr
+			os.Remove(fullPath)
+			continue
+		}
+
+		if closeErr != nil {
+			lastErr = closeErr
+			os.Remove(fullPath)
+			continue
+		}
+
+		// a changed image may come in another format than the one it replaces
+		if cached && cachedPath != fullPath {
+			os.Remove(cachedPath)
+		}
+
+		if err := i.writeValidators(outputFolder, res.Header); err != nil {
+			return nil, tracerr.Wrap(err)
+		}
+
+		// If we got here, download was successful
+		return &DownloadedImage{
+			PageNumber:   i.PageNumber,
+			ImageNumber:  i.ImageNumber,
+			OverallOrder: i.OverallOrder,
+			Url:          i.Url,
+			FullPath:     fullPath,
+			Attempts:     attempt + 1,
+			Bytes:        written,
+			Duration:     time.Since(startTime),
+			Revalidated:  cached,
+		}, nil
+	}
+
+	// An image that couldn't be revalidated is still better than none
+	if cached && ctx.Err() == nil && !errors.Is(lastErr, ErrRateLimited) {
+		cachedImage.Revalidated = false
+		return cachedImage, nil
+	}
+
+	// If we exhausted all retries, return the last error
+	return nil, tracerr.Wrap(fmt.Errorf("failed to download image after %d attempts: %w", options.Attempts, lastErr))
+}
+
+// imageStatusError describes a failed image response
+func imageStatusError(res *http.Response) error {
+	return &ImageStatusError{StatusCode: res.StatusCode, Status: res.Status}
+}
+
+// setBrowserHeaders adds headers to make an image request look like it comes from a browser
+func setBrowserHeaders(req *http.Request) {
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36")
+	req.Header.Set("Accept", "image/avif,image/webp,image/apng,image/svg+xml,image/*,*/*;q=0.8")
+	req.Header.Set("Accept-Encoding", "gzip, deflate")
+	req.Header.Set("Connection", "keep-alive")
+}
+
+// ImageProbe is the result of probing an image without saving it
+type ImageProbe struct {
+	Bytes    int64         // size of the image, -1 if the server didn't report one
+	Latency  time.Duration // time until the response headers arrived
+	Duration time.Duration // time until the whole response was read
+}
+
+// Probe requests the image with the given method (HEAD or GET) and measures its size and response times.
+// The body of GET responses is read and discarded, which also measures the available bandwidth.
+func (i *PageImage) Probe(ctx context.Context, method string) (*ImageProbe, error) {
+	req, err := http.NewRequestWithContext(ctx, method, i.Url, nil)
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+	setBrowserHeaders(req)
+
+	client := newClient(30 * time.Second)
+
+	startTime := time.Now()
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+	defer res.Body.Close()
+
+	probe := &ImageProbe{Bytes: res.ContentLength, Latency: time.Since(startTime)}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to probe image %s (status: %s)", i.Url, res.Status)
+	}
+
+	if method == http.MethodGet {
+		read, err := io.Copy(io.Discard, res.Body)
+		if err != nil {
+			return nil, tracerr.Wrap(err)
+		}
+		probe.Bytes = read
+	}
+	probe.Duration = time.Since(startTime)
+
+	return probe, nil
+}
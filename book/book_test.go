@@ -0,0 +1,119 @@
+package book
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestParseId(testing *testing.T) {
+	expected := "foo/bar"
+
+	url := fmt.Sprintf("https://online.fliphtml5.com/%s", expected)
+
+	actual, err := ParseId(url)
+	if err != nil {
+		testing.Fatalf("unexpected error: %v", err)
+	}
+
+	if actual != expected {
+		testing.Fatalf("expected %s, got %s", expected, actual)
+	}
+}
+
+func TestViewportForPage(testing *testing.T) {
+	cases := []struct {
+		width, height int
+		expected      float64
+	}{
+		{0, 0, 1},
+		{800, 1000, 1},     // smaller than the window
+		{2160, 3000, 2.78}, // portrait pages are limited by the window height
+		{3840, 2160, 2},    // landscape pages are limited by the window width
+		{20000, 30000, 4},  // capped
+	}
+
+	for _, c := range cases {
+		actual := ViewportForPage(c.width, c.height)
+		if actual.Width != DefaultViewport.Width || actual.Height != DefaultViewport.Height {
+			testing.Fatalf("expected the default window size for %dx%d, got %dx%d", c.width, c.height, actual.Width, actual.Height)
+		}
+
+		if actual.Scale != c.expected {
+			testing.Fatalf("expected scale %v for %dx%d, got %v", c.expected, c.width, c.height, actual.Scale)
+		}
+	}
+}
+
+func TestSinglePageViewportForPage(testing *testing.T) {
+	// portrait pages fill the width of the portrait window
+	actual := SinglePageViewport.ForPage(2160, 3000)
+	if actual.Width != 1080 || actual.Height != 1920 || actual.Scale != 2 {
+		testing.Fatalf("expected a 1080x1920 window at 2x, got %dx%d at %vx", actual.Width, actual.Height, actual.Scale)
+	}
+}
+
+func TestMobileViewportForPage(testing *testing.T) {
+	// the phone screen is small, so pages need a larger scale, but never more than MaxCaptureScale
+	actual := MobileViewport.ForPage(1170, 1600)
+	if !actual.Mobile || actual.Width != 390 || actual.Scale != 3 {
+		testing.Errorf("expected a mobile 390 wide window at 3x, got %+v", actual)
+	}
+
+	if actual := MobileViewport.ForPage(2160, 3000); actual.Scale != MaxCaptureScale {
+		testing.Errorf("expected the scale to be capped, got %+v", actual)
+	}
+}
+
+func TestParseLanguage(testing *testing.T) {
+	cases := map[string]string{
+		"":        "",
+		"en":      "en",
+		"pt_BR":   "pt-BR",
+		" TR ":    "tr",
+		"unknown": "",
+	}
+
+	for value, expected := range cases {
+		if actual := ParseLanguage(value); actual != expected {
+			testing.Errorf("expected %q for %q, got %q", expected, value, actual)
+		}
+	}
+}
+
+func TestGetCancelled(testing *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := Get(ctx, "abcde/fghij"); !errors.Is(err, context.Canceled) {
+		testing.Fatalf("expected the cancellation to stop the request, got %v", err)
+	}
+}
+
+func TestRevision(testing *testing.T) {
+	b := &Book{Pages: []Page{{Number: 1, ImageUrls: []string{"a.jpg"}}, {Number: 2, ImageUrls: []string{"b.jpg"}}}}
+	revision := b.Revision()
+	if revision == "" || revision != b.Revision() {
+		testing.Fatalf("expected a stable revision, got %q", revision)
+	}
+
+	b.Pages[1].ImageUrls = []string{"b.jpg?2"}
+	if b.Revision() == revision {
+		testing.Errorf("expected the revision to change with the page images")
+	}
+}
+
+func TestHtmlTitle(testing *testing.T) {
+	cases := map[string]string{
+		`<meta property="og:title" content="Catalog &amp; Guide"><title>Site</title>`: "Catalog & Guide",
+		"<title>\n  Spring Catalog\n</title>":                                         "Spring Catalog",
+		"<html></html>":                                                               "",
+	}
+
+	for body, expected := range cases {
+		if actual := HtmlTitle(body); actual != expected {
+			testing.Errorf("expected %q, got %q", expected, actual)
+		}
+	}
+}
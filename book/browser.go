@@ -0,0 +1,145 @@
+package book
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+
+	cdpbrowser "github.com/chromedp/cdproto/browser"
+	"github.com/chromedp/chromedp"
+	"github.com/ztrue/tracerr"
+)
+
+// Browser is a Chrome process shared by captures, so each page opens in a tab instead of starting a browser of its own
+type Browser struct {
+	ctx         context.Context
+	cancel      context.CancelFunc
+	userDataDir string
+}
+
+// NewBrowser starts Chrome with the browser settings of the options, which are the work dir, viewport and debug mode
+func NewBrowser(ctx context.Context, options CaptureOptions) (*Browser, error) {
+	options = options.withDefaults()
+
+	// Keep the browser profile inside the work dir instead of the system temp dir
+	userDataDir, err := newUserDataDir(options.WorkDir)
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+
+	opts := append(allocatorOptions(options), chromedp.UserDataDir(userDataDir))
+	allocCtx, allocCancel := chromedp.NewExecAllocator(ctx, opts...)
+	browserCtx, browserCancel := chromedp.NewContext(
+		allocCtx,
+		chromedp.WithLogf(func(format string, args ...interface{}) {
+			// chromedp logs are only useful when debugging
+			if options.Debug {
+				options.logf("[ChromeDP] "+format, args...)
+			}
+		}),
+	)
+
+	browser := &Browser{
+		ctx: browserCtx,
+		cancel: func() {
+			browserCancel()
+			allocCancel()
+		},
+		userDataDir: userDataDir,
+	}
+
+	// Running without actions launches the browser
+	if err := chromedp.Run(browserCtx); err != nil {
+		browser.Close()
+		if errors.Is(err, exec.ErrNotFound) {
+			return nil, tracerr.Wrap(fmt.Errorf("%w: %v", ErrChromeUnavailable, err))
+		}
+		return nil, tracerr.Wrap(err)
+	}
+
+	return browser, nil
+}
+
+// Version returns the product name and version of the browser, such as HeadlessChrome/126.0.6478.126
+func (b *Browser) Version() (string, error) {
+	var product string
+	err := chromedp.Run(b.ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		var err error
+		_, product, _, _, _, err = cdpbrowser.GetVersion().Do(ctx)
+		return err
+	}))
+
+	return product, tracerr.Wrap(err)
+}
+
+// Close stops the browser and removes its profile
+func (b *Browser) Close() error {
+	b.cancel()
+	return tracerr.Wrap(os.RemoveAll(b.userDataDir))
+}
+
+// sharedMemoryPath is where Chrome keeps its shared memory on Linux
+const sharedMemoryPath = "/dev/shm"
+
+// MinSharedMemory is how large /dev/shm has to be for Chrome to use it. Docker gives containers 64MB by default, in
+// which Chrome crashes on large pages.
+const MinSharedMemory = 512 << 20
+
+// allocatorOptions returns the command line options of Chrome
+func allocatorOptions(options CaptureOptions) []chromedp.ExecAllocatorOption {
+	opts := append(chromedp.DefaultExecAllocatorOptions[:],
+		chromedp.Flag("headless", !options.Debug),
+		chromedp.Flag("auto-open-devtools-for-tabs", options.Debug),
+		chromedp.Flag("disable-gpu", true),
+		chromedp.Flag("no-sandbox", true),
+		// /dev/shm is only used when it is known to be large enough, the temp dir is slower but always is
+		chromedp.Flag("disable-dev-shm-usage", !options.SharedMemory),
+		chromedp.Flag("disable-setuid-sandbox", true),
+		chromedp.Flag("no-first-run", true),
+		chromedp.Flag("no-default-browser-check", true),
+		// Add performance flags
+		chromedp.Flag("disable-extensions", true),
+		chromedp.Flag("disable-background-networking", true),
+		chromedp.Flag("disable-background-timer-throttling", true),
+		chromedp.Flag("disable-backgrounding-occluded-windows", true),
+		chromedp.Flag("disable-breakpad", true),
+		chromedp.Flag("disable-component-extensions-with-background-pages", true),
+		chromedp.Flag("disable-features", "TranslateUI,BlinkGenPropertyTrees"),
+		chromedp.Flag("disable-ipc-flooding-protection", true),
+		chromedp.Flag("disable-sync", true),
+		chromedp.Flag("ignore-certificate-errors", true),
+		chromedp.Flag("enable-automation", true),
+		chromedp.Flag("password-store", "basic"),
+		chromedp.Flag("use-mock-keychain", true),
+		chromedp.Flag("disable-web-security", true),
+		chromedp.Flag("blink-settings", "imagesEnabled=true"),
+		chromedp.Flag("disable-notifications", true),
+		chromedp.Flag("disable-popup-blocking", true),
+		chromedp.Flag("js-flags", "--max_old_space_size=512"),
+		chromedp.WindowSize(options.Viewport.Width, options.Viewport.Height),
+	)
+
+	// without a sandbox there is no need for the zygote process, which fails to start in some containers
+	if options.Container {
+		opts = append(opts,
+			chromedp.Flag("no-zygote", true),
+			chromedp.Flag("disable-software-rasterizer", true),
+			chromedp.Flag("mute-audio", true),
+		)
+	}
+
+	return opts
+}
+
+// newUserDataDir creates a fresh Chrome profile folder inside workDir, or the system temp dir if workDir is empty
+func newUserDataDir(workDir string) (string, error) {
+	if workDir != "" {
+		if err := os.MkdirAll(workDir, os.ModePerm); err != nil {
+			return "", err
+		}
+	}
+
+	return os.MkdirTemp(workDir, "fh5dl-chrome-")
+}
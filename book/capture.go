@@ -0,0 +1,407 @@
+package book
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/chromedp/cdproto/emulation"
+	cdppage "github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+	"github.com/ztrue/tracerr"
+)
+
+// CaptureOptions configures how interactive pages are captured. The zero value captures quietly with the defaults.
+type CaptureOptions struct {
+	WorkDir      string        // folder for the browser profile, the system temp dir if empty
+	Viewport     Viewport      // DefaultViewport if not set
+	RevealScript string        // javascript to reveal hidden content, DefaultRevealScript if empty
+	Timeout      time.Duration // for all attempts of a page together, defaults to a minute
+	Attempts     int           // how many times a page is tried, defaults to 2
+	Layout       SpreadLayout  // how the viewer pairs pages, see DetectLayout
+	Annotations  bool          // also save the notes layer of the page, see ReadAnnotations
+	Popups       bool          // also capture the popups and lightboxes opened by triggers, see InteractivePageImage
+	Browser      *Browser      // shared browser to open the page in, a new one is started for the page if nil
+	Debug        bool          // show the browser with DevTools
+	DebugDir     string        // where the diagnostics of failed pages go, defaults to the output folder
+	Container    bool          // Chrome runs in a container, see allocatorOptions
+	SharedMemory bool          // let Chrome use /dev/shm, only if it has at least MinSharedMemory
+
+	// Logf receives progress messages of the capture, nil to print nothing and leave reporting to the caller
+	Logf func(format string, args ...interface{})
+}
+
+func (o CaptureOptions) withDefaults() CaptureOptions {
+	if o.Viewport == (Viewport{}) {
+		o.Viewport = DefaultViewport
+	}
+	if o.RevealScript == "" {
+		o.RevealScript = DefaultRevealScript
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = 60 * time.Second
+	}
+	if o.Attempts <= 0 {
+		o.Attempts = 2
+	}
+
+	return o
+}
+
+// openTab opens a tab in the shared browser, or starts a browser just for the tab if there is none
+func (o CaptureOptions) openTab(ctx context.Context) (context.Context, func(), error) {
+	browser := o.Browser
+	closeBrowser := func() {}
+	if browser == nil {
+		var err error
+		browser, err = NewBrowser(ctx, o)
+		if err != nil {
+			return nil, nil, err
+		}
+		closeBrowser = func() { browser.Close() }
+	}
+
+	tabCtx, tabCancel := chromedp.NewContext(browser.ctx)
+
+	// The tab belongs to the browser, so it has to be closed when ctx is cancelled
+	stop := context.AfterFunc(ctx, tabCancel)
+	return tabCtx, func() {
+		stop()
+		tabCancel()
+		closeBrowser()
+	}, nil
+}
+
+func (o CaptureOptions) logf(format string, args ...interface{}) {
+	if o.Logf != nil {
+		o.Logf(format, args...)
+	}
+}
+
+// CaptureInteractivePage captures a screenshot of a page with all interactive elements revealed
+func CaptureInteractivePage(ctx context.Context, pageUrl string, outputFolder string, pageNumber int, overallOrder int, options CaptureOptions) (*InteractivePageImage, error) {
+	options = options.withDefaults()
+	options.logf("Starting to capture page %d from URL: %s", pageNumber, pageUrl)
+
+	// Full path for the screenshot
+	fullPath := filepath.Join(outputFolder, fmt.Sprintf("interactive-%d.png", pageNumber))
+
+	// First check if the file already exists to avoid duplicate work
+	if _, err := os.Stat(fullPath); err == nil {
+		options.logf("Screenshot for page %d already exists, skipping...", pageNumber)
+		existing := &InteractivePageImage{
+			PageNumber:   pageNumber,
+			OverallOrder: overallOrder,
+			Url:          pageUrl,
+			FullPath:     fullPath,
+		}
+		if options.Popups {
+			existing.Popups = PopupFiles(outputFolder, pageNumber)
+		}
+		return existing, nil
+	}
+
+	chromeCtx, closeTab, err := options.openTab(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer closeTab()
+
+	// console messages are kept for the diagnostics of the page, in case it fails
+	debugger := &captureDebugger{}
+	chromedp.ListenTarget(chromeCtx, debugger.listen)
+
+	// The timeout covers all attempts of the page
+	timeoutCtx, timeoutCancel := context.WithTimeout(chromeCtx, options.Timeout)
+	defer timeoutCancel()
+
+	var buf []byte
+	var popups [][]byte
+	var annotations []Annotation
+
+	// Retry loop
+	for attempt := 0; attempt < options.Attempts; attempt++ {
+		if attempt > 0 {
+			options.logf("Retry attempt %d for page %d", attempt, pageNumber)
+			time.Sleep(time.Second * 2)
+		}
+
+		// Use a single Run call for the entire process to reduce race conditions
+		err = chromedp.Run(timeoutCtx,
+			// Render the page at the resolution of the page images
+			options.Viewport.emulate(),
+
+			// First navigate to the page
+			chromedp.Navigate(pageUrl),
+
+			// Wait for the page to load
+			chromedp.Sleep(3*time.Second),
+
+			// Popups are captured before the reveal script clicks everything at once
+			chromedp.ActionFunc(func(ctx context.Context) error {
+				if !options.Popups {
+					return nil
+				}
+				return capturePopups(options.Layout.Side(pageNumber), &popups).Do(ctx)
+			}),
+
+			// Execute our reveal script to show hidden elements
+			chromedp.EvaluateAsDevTools(options.RevealScript, nil),
+
+			// Wait for triggers to take effect
+			chromedp.Sleep(1*time.Second),
+
+			// Read the notes before the page is isolated, which hides anything that looks like a control
+			chromedp.ActionFunc(func(ctx context.Context) error {
+				annotations = nil
+				if !options.Annotations {
+					return nil
+				}
+				return chromedp.EvaluateAsDevTools(fmt.Sprintf(annotationsScript, options.Layout.Side(pageNumber)), &annotations).Do(ctx)
+			}),
+
+			// Execute JavaScript to focus and isolate just the target page from the spread
+			chromedp.EvaluateAsDevTools(fmt.Sprintf(`
+			(() => {
+				// Use a single style element instead of modifying each element individually
+				// Create the style element first
+				const style = document.createElement('style');
+				document.head.appendChild(style);
+				
+				// UI element selectors to hide
+				const uiElementSelectors = [
+					// Specific IDs for FlipHTML5 UI
+					'#fbTopBar', '#fbToolBar',
+					
+					// Classes from the FlipHTML5 UI structure
+					'.fbTopBar', '.logoBar', '.topRightBar', '.searchBar', '.fbToolBar', '.buttonBar', '.pageBar',
+					
+					// General UI selectors
+					'.toolbar', '.navbar', '.nav', 'header', '.header', '.flipbook-bar', 
+					'.menu', '.button', '.btn', '.control', '.navigation', '.flipbook-menu',
+					'.flipbook-nav', '.flipbook-ui', '.ui-element', '[class*="menu"]', 
+					'[class*="toolbar"]', '[class*="button"]', '[class*="control"]',
+					'[class*="nav"]', '.app-header', '.app-footer', '.footer',
+					'#toolbar', '#menu', '#header', '#footer', '.zoom-panel',
+					'#appFooter', '#loadingFooter', '.hint', '.loading', '.bookLoading',
+					'.top-menu', '.bottom-menu', '.controls', '.thumbnails', '#toolbar', '#header',
+					'.fixed-top', '.fixed-bottom',
+					'.ms-control', '.ms-toolbar', '.btn-toolbar',
+					'.flip-book-toolbar', '.flipbook-container .toolbar'
+				];
+				
+				// Build CSS rules in a single string for better performance
+				let styleContent = '';
+				for (let i = 0; i < uiElementSelectors.length; i++) {
+					styleContent += uiElementSelectors[i] + ' { display: none !important; visibility: hidden !important; opacity: 0 !important; pointer-events: none !important; height: 0 !important; width: 0 !important; overflow: hidden !important; position: absolute !important; z-index: -1000 !important; }\n';
+				}
+				
+				// Apply all CSS at once
+				style.textContent = styleContent;
+				
+				// Get the visible pages, sorted from left to right
+				const currentPages = (`+visiblePagesScript+`)();
+				
+				// Pin the page to the top left corner, scaled to fit the viewport without
+				// stretching it, and mark it so the screenshot can be cropped to it
+				const fitPage = (page) => {
+					const rect = page.getBoundingClientRect();
+					const scale = Math.min(window.innerWidth / rect.width, window.innerHeight / rect.height);
+					page.style.cssText = "position:fixed;top:0;left:0;width:" + (rect.width * scale) + "px;height:" + (rect.height * scale) + "px;z-index:9999;";
+					page.setAttribute('data-fh5dl-page', '');
+				};
+				
+				// Which page of a spread to capture, worked out from the layout of the viewer
+				const side = %q;
+				
+				if (currentPages.length === 0) {
+					return "No page found, capturing the whole viewport";
+				}
+				
+				// A single visible page is the one we want, whatever the layout
+				const targetPage = side === "right" && currentPages.length >= 2 ? currentPages[1] : currentPages[0];
+				fitPage(targetPage);
+				document.body.style.background = 'white';
+				document.documentElement.style.background = 'white';
+				return "Page prepared for screenshot (" + currentPages.length + " visible)";
+			})()
+			`, options.Layout.Side(pageNumber)), nil),
+
+			// Wait for isolation to apply
+			chromedp.Sleep(1*time.Second),
+
+			// Take a screenshot of just the page
+			screenshotPage(&buf),
+		)
+
+		// If successful, break the retry loop
+		if err == nil && len(buf) > 0 {
+			break
+		}
+
+		// Log error but continue retrying
+		if err != nil {
+			options.logf("Error during capture for page %d (attempt %d): %v", pageNumber, attempt+1, err)
+		}
+	}
+
+	// Save what the tab shows, its DOM and console so the failure can be looked into
+	diagnostics := ""
+	if err != nil || len(buf) == 0 {
+		debugDir := options.DebugDir
+		if debugDir == "" {
+			debugDir = outputFolder
+		}
+
+		if saveErr := debugger.save(chromeCtx, debugDir, pageNumber); saveErr != nil {
+			options.logf("Couldn't save all diagnostics of page %d: %v", pageNumber, saveErr)
+		}
+		diagnostics = fmt.Sprintf(" (diagnostics in %s)", filepath.Join(debugDir, fmt.Sprintf("page-%d.*", pageNumber)))
+	}
+
+	// If we still have an error after all retries
+	if err != nil {
+		return nil, tracerr.Wrap(fmt.Errorf("error capturing page %d after %d attempts%s: %w", pageNumber, options.Attempts, diagnostics, err))
+	}
+
+	// If buf is empty, we never successfully took a screenshot
+	if len(buf) == 0 {
+		return nil, tracerr.Wrap(fmt.Errorf("failed to capture page %d after %d attempts%s", pageNumber, options.Attempts, diagnostics))
+	}
+
+	options.logf("Screenshot for page %d captured successfully", pageNumber)
+
+	// Save the screenshot to disk
+	err = os.WriteFile(fullPath, buf, 0644)
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+
+	popupPaths, err := writePopups(outputFolder, pageNumber, popups)
+	if err != nil {
+		return nil, err
+	}
+
+	if options.Annotations {
+		if err := writeAnnotations(fullPath, annotations); err != nil {
+			return nil, err
+		}
+	}
+
+	return &InteractivePageImage{
+		PageNumber:   pageNumber,
+		OverallOrder: overallOrder,
+		Url:          pageUrl,
+		FullPath:     fullPath,
+		Popups:       popupPaths,
+	}, nil
+}
+
+// Viewport is the browser window used for interactive captures
+type Viewport struct {
+	Width  int
+	Height int
+	Scale  float64 // device scale factor, how many screenshot pixels a CSS pixel takes up
+	Mobile bool    // emulate a phone, with touch events and a mobile user agent, see MobileViewport
+}
+
+// DefaultViewport is used when the size of the page images isn't known
+var DefaultViewport = Viewport{Width: 1920, Height: 1080, Scale: 1}
+
+// MaxCaptureScale keeps screenshots of huge page images from exhausting Chrome's memory
+const MaxCaptureScale = 4
+
+// SinglePageViewport is a portrait window, in which the viewer shows one page at a time instead of spreads
+var SinglePageViewport = Viewport{Width: 1080, Height: 1920, Scale: 1}
+
+// MobileViewport is the screen of a phone. Some viewers switch to a simplified layout with one page at a time on
+// phones, which is easier to capture cleanly than their desktop spreads.
+var MobileViewport = Viewport{Width: 390, Height: 844, Scale: 3, Mobile: true}
+
+// MobileUserAgent is the user agent of the browser in a MobileViewport
+const MobileUserAgent = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Mobile/15E148 Safari/604.1"
+
+// emulate sets the tab up to render in the viewport, as a phone for mobile viewports
+func (v Viewport) emulate() chromedp.Tasks {
+	if !v.Mobile {
+		return chromedp.Tasks{chromedp.EmulateViewport(int64(v.Width), int64(v.Height), chromedp.EmulateScale(v.Scale))}
+	}
+
+	return chromedp.Tasks{
+		emulation.SetUserAgentOverride(MobileUserAgent).WithPlatform("iPhone"),
+		chromedp.EmulateViewport(int64(v.Width), int64(v.Height), chromedp.EmulateScale(v.Scale), chromedp.EmulateMobile, chromedp.EmulateTouch),
+	}
+}
+
+// ViewportForPage returns DefaultViewport.ForPage(width, height)
+func ViewportForPage(width int, height int) Viewport {
+	return DefaultViewport.ForPage(width, height)
+}
+
+// ForPage returns the viewport with a scale factor that renders a page image of the given size at its native
+// resolution once the page is fitted into the window. Pages are never rendered below a scale of 1.
+func (v Viewport) ForPage(width int, height int) Viewport {
+	if width <= 0 || height <= 0 {
+		return v
+	}
+
+	fittedWidth := math.Min(float64(v.Width), float64(v.Height)*float64(width)/float64(height))
+	scale := math.Ceil(float64(width)/fittedWidth*100) / 100
+	v.Scale = math.Max(1, math.Min(MaxCaptureScale, scale))
+	return v
+}
+
+// boundsScript returns the bounding box of the element matching a selector, or null if there is none
+const boundsScript = `(() => {
+	const el = document.querySelector(%q);
+	if (!el) {
+		return null;
+	}
+	const rect = el.getBoundingClientRect();
+	return {x: rect.left, y: rect.top, width: rect.width, height: rect.height};
+})()`
+
+type pageBounds struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+// screenshotPage takes a PNG screenshot cropped to the page marked by the isolation script, so captures keep the
+// aspect ratio of the normal page images. The whole viewport is captured if the page couldn't be found.
+func screenshotPage(buf *[]byte) chromedp.Action {
+	return screenshotElement("[data-fh5dl-page]", buf)
+}
+
+// screenshotElement takes a PNG screenshot cropped to the element matching the selector, or of the whole viewport
+// if there is none
+func screenshotElement(selector string, buf *[]byte) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		var bounds *pageBounds
+		if err := chromedp.EvaluateAsDevTools(fmt.Sprintf(boundsScript, selector), &bounds).Do(ctx); err != nil {
+			return err
+		}
+
+		if bounds == nil || bounds.Width < 1 || bounds.Height < 1 {
+			return chromedp.FullScreenshot(buf, 100).Do(ctx)
+		}
+
+		var err error
+		*buf, err = cdppage.CaptureScreenshot().
+			WithFormat(cdppage.CaptureScreenshotFormatPng).
+			WithCaptureBeyondViewport(true).
+			WithClip(&cdppage.Viewport{
+				X:      math.Round(bounds.X),
+				Y:      math.Round(bounds.Y),
+				Width:  math.Round(bounds.Width),
+				Height: math.Round(bounds.Height),
+				Scale:  1,
+			}).
+			Do(ctx)
+		return err
+	})
+}
@@ -0,0 +1,18 @@
+package book
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCaptureOptionsDefaults(testing *testing.T) {
+	options := CaptureOptions{}.withDefaults()
+	if options.Viewport != DefaultViewport || options.RevealScript != DefaultRevealScript || options.Timeout != time.Minute || options.Attempts != 2 {
+		testing.Fatalf("unexpected defaults %+v", options)
+	}
+
+	custom := CaptureOptions{Viewport: Viewport{Width: 800, Height: 600, Scale: 2}, RevealScript: "1", Timeout: time.Second, Attempts: 5}
+	if options := custom.withDefaults(); options.Viewport != custom.Viewport || options.RevealScript != "1" || options.Timeout != time.Second || options.Attempts != 5 {
+		testing.Fatalf("expected the options to be kept, got %+v", options)
+	}
+}
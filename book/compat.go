@@ -0,0 +1,94 @@
+package book
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ztrue/tracerr"
+)
+
+// hasImages tells whether any of the pages has an image
+func hasImages(pages []Page) bool {
+	for _, page := range pages {
+		if len(page.ImageUrls) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// LayoutCheck is what CheckLayout found out about a book
+type LayoutCheck struct {
+	Book        *Book
+	ImageUrl    string // the image that was requested
+	ContentType string
+}
+
+// CheckLayout checks that a book can still be read the way fh5dl expects FlipHTML5 books to be laid out: its book
+// information parses and lists the page images, and the first image is served as an image. Mismatches wrap
+// ErrLayoutChanged, while network failures and books that are missing or private are returned as they are.
+func CheckLayout(ctx context.Context, idOrUrl string) (*LayoutCheck, error) {
+	b, err := Get(ctx, idOrUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	images := b.FindAllImages()
+	if len(images) == 0 {
+		return nil, fmt.Errorf("%w: %s has no pages", ErrLayoutChanged, b.Id)
+	}
+
+	check := &LayoutCheck{Book: b, ImageUrl: images[0].Url}
+	if !strings.Contains(check.ImageUrl, "/files/") {
+		return check, fmt.Errorf("%w: image %s isn't under files/", ErrLayoutChanged, check.ImageUrl)
+	}
+
+	// downloads fall back to the image outside files/large, so the probe does too
+	candidates := []string{check.ImageUrl}
+	if strings.Contains(check.ImageUrl, "/files/large/") {
+		candidates = append(candidates, strings.Replace(check.ImageUrl, "/files/large/", "/files/", 1))
+	}
+
+	var res *http.Response
+	for _, url := range candidates {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return check, tracerr.Wrap(err)
+		}
+		setBrowserHeaders(req)
+
+		res, err = newClient(30 * time.Second).Do(req)
+		if err != nil {
+			return check, tracerr.Wrap(err)
+		}
+		defer res.Body.Close()
+
+		check.ImageUrl = url
+		if res.StatusCode == http.StatusOK {
+			break
+		}
+	}
+
+	if res.StatusCode != http.StatusOK {
+		statusErr := imageStatusError(res)
+		if errors.Is(statusErr, ErrRateLimited) {
+			return check, statusErr
+		}
+		// the book information was read, so its images should be there
+		return check, fmt.Errorf("%w: image %s: %v", ErrLayoutChanged, check.ImageUrl, statusErr)
+	}
+
+	head, _ := bufio.NewReader(res.Body).Peek(512)
+	check.ContentType = http.DetectContentType(head)
+	if _, ok := imageExtensions[check.ContentType]; !ok {
+		return check, fmt.Errorf("%w: image %s is %s rather than an image", ErrLayoutChanged, check.ImageUrl, check.ContentType)
+	}
+
+	return check, nil
+}
@@ -0,0 +1,54 @@
+package book
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestCheckLayout(testing *testing.T) {
+	defer func(original http.RoundTripper) { Transport = original }(Transport)
+
+	config := `var htmlConfig = {"meta": {"title": "Layout"}, "fliphtml5_pages": [{"n": ["1.jpg"]}]};`
+	png := "\x89PNG\r\n\x1a\n\x00\x00\x00\x0dIHDR"
+
+	Transport = fixtureTransport{
+		"https://online.fliphtml5.com/abcde/fghij/javascript/config.js": config,
+		"https://online.fliphtml5.com/abcde/fghij/files/1.jpg":          png,
+	}
+	check, err := CheckLayout(context.Background(), "abcde/fghij")
+	if err != nil {
+		testing.Fatalf("unexpected error: %v", err)
+	}
+	if check.ContentType != "image/png" || check.ImageUrl != "https://online.fliphtml5.com/abcde/fghij/files/1.jpg" {
+		testing.Errorf("unexpected check %+v", check)
+	}
+
+	cases := map[string]fixtureTransport{
+		"unknown config": {
+			"https://online.fliphtml5.com/abcde/fghij/javascript/config.js": `window.bookData = "something else";`,
+		},
+		"unknown images": {
+			"https://online.fliphtml5.com/abcde/fghij/javascript/config.js": `var htmlConfig = {"fliphtml5_pages": [{"n": {"large": "1.jpg"}}]};`,
+		},
+		"missing image": {
+			"https://online.fliphtml5.com/abcde/fghij/javascript/config.js": config,
+		},
+		"not an image": {
+			"https://online.fliphtml5.com/abcde/fghij/javascript/config.js": config,
+			"https://online.fliphtml5.com/abcde/fghij/files/large/1.jpg":    "<html>Sign in</html>",
+		},
+	}
+	for name, transport := range cases {
+		Transport = transport
+		if _, err := CheckLayout(context.Background(), "abcde/fghij"); !errors.Is(err, ErrLayoutChanged) {
+			testing.Errorf("%s: expected the layout to have changed, got %v", name, err)
+		}
+	}
+
+	Transport = fixtureTransport{}
+	if _, err := CheckLayout(context.Background(), "abcde/fghij"); !errors.Is(err, ErrBookNotFound) || errors.Is(err, ErrLayoutChanged) {
+		testing.Errorf("expected the book not to be found, got %v", err)
+	}
+}
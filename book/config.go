@@ -0,0 +1,411 @@
+package book
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/ztrue/tracerr"
+)
+
+// configSource is a place the book information can be found, relative to the book URL
+type configSource struct {
+	path  string
+	parse func(body string) (*htmlConfig, error)
+}
+
+// configSources are tried in order. Most books have javascript/config.js, some only have the one of the
+// mobile viewer, and a few embed the config into the viewer page itself.
+var configSources = []configSource{
+	{"javascript/config.js", parseConfigScript},
+	{"mobile/javascript/config.js", parseConfigScript},
+	{"", parseConfigHtml},
+}
+
+func downloadHtmlConfig(ctx context.Context, id string) (*htmlConfig, error) {
+	errs := make([]error, 0, len(configSources))
+	for _, source := range configSources {
+		config, err := downloadConfigSource(ctx, fmt.Sprintf("https://online.fliphtml5.com/%s/%s", id, source.path), source.parse)
+		if err == nil {
+			return config, nil
+		}
+
+		// other sources won't be any more accessible
+		if errors.Is(err, ErrPrivateBook) || errors.Is(err, ErrRateLimited) || ctx.Err() != nil {
+			return nil, err
+		}
+
+		errs = append(errs, err)
+	}
+
+	// the book only counts as missing if no source was there at all
+	found := make([]error, 0, len(errs))
+	for _, err := range errs {
+		if !errors.Is(err, ErrBookNotFound) {
+			found = append(found, err)
+		}
+	}
+	if len(found) == 0 {
+		return nil, fmt.Errorf("failed to download book information: %w", ErrBookNotFound)
+	}
+
+	// none of the sources that were there could be read, which usually means FlipHTML5 changed its format
+	unreadable := true
+	for _, err := range found {
+		unreadable = unreadable && errors.Is(err, ErrConfigParse)
+	}
+	if unreadable {
+		return nil, fmt.Errorf("%w: %w", ErrLayoutChanged, errors.Join(found...))
+	}
+
+	return nil, errors.Join(found...)
+}
+
+// downloadConfigSource downloads and parses a single source of the book information
+func downloadConfigSource(ctx context.Context, url string, parse func(body string) (*htmlConfig, error)) (*htmlConfig, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+
+	response, err := newClient(0).Do(req)
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		if statusErr := errorForStatus(response.StatusCode); statusErr != nil {
+			return nil, fmt.Errorf("failed to download book information from %s: %s: %w", url, response.Status, statusErr)
+		}
+		return nil, fmt.Errorf("failed to download book information from %s: %s", url, response.Status)
+	}
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+
+	config, err := parse(string(body))
+	if err != nil {
+		return nil, tracerr.Wrap(fmt.Errorf("%w in %s: %v", ErrConfigParse, url, err))
+	}
+
+	if len(config.Pages) == 0 {
+		return nil, fmt.Errorf("%w in %s: no pages", ErrConfigParse, url)
+	}
+
+	return config, nil
+}
+
+// parseConfigScript reads the config object out of config.js. Usually the whole file is `var htmlConfig = {...};`,
+// but some books wrap the object into a JSONP callback or have other objects and code around it, so the object
+// assigned to htmlConfig is used when there is one, and otherwise the first object that lists pages.
+func parseConfigScript(body string) (*htmlConfig, error) {
+	if object, ok := assignedObject(body, "htmlConfig"); ok {
+		return decodeConfig(object)
+	}
+
+	var lastErr error = fmt.Errorf("no config object")
+	for _, object := range topLevelObjects(body) {
+		config, err := decodeConfig(object)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if len(config.Pages) > 0 {
+			return config, nil
+		}
+	}
+
+	return nil, lastErr
+}
+
+// parseConfigHtml reads the config object assigned to htmlConfig in an inline script of the viewer page
+func parseConfigHtml(body string) (*htmlConfig, error) {
+	object, ok := assignedObject(body, "htmlConfig")
+	if !ok {
+		return nil, fmt.Errorf("no inline config")
+	}
+
+	return decodeConfig(object)
+}
+
+// decodeConfig parses a config object, which is usually JSON but may also be written as a javascript literal
+func decodeConfig(object string) (*htmlConfig, error) {
+	var config htmlConfig
+	err := json.Unmarshal([]byte(object), &config)
+	if err == nil {
+		return &config, nil
+	}
+
+	if jsonErr := json.Unmarshal([]byte(literalToJson(object)), &config); jsonErr != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
+
+// assignedObject returns the object literal assigned to the variable, such as `var name = {...};`
+func assignedObject(text string, name string) (string, bool) {
+	for offset := 0; ; {
+		index := strings.Index(text[offset:], name)
+		if index < 0 {
+			return "", false
+		}
+		offset += index + len(name)
+
+		if object, ok := objectAfterAssignment(text[offset:]); ok {
+			return object, true
+		}
+	}
+}
+
+// objectAfterAssignment returns the object literal of an assignment such as ` = {...};`, with text being
+// everything after the variable name
+func objectAfterAssignment(text string) (string, bool) {
+	rest := strings.TrimLeft(text, " \t\r\n")
+	if !strings.HasPrefix(rest, "=") || strings.HasPrefix(rest, "==") {
+		return "", false
+	}
+
+	rest = strings.TrimLeft(rest[1:], " \t\r\n")
+	end := objectEnd(rest)
+	if end < 0 {
+		return "", false
+	}
+
+	return rest[:end], true
+}
+
+// topLevelObjects returns the outermost object literals of a script in order, such as the argument of a JSONP
+// callback. Braces in strings and comments are skipped.
+func topLevelObjects(text string) []string {
+	objects := make([]string, 0)
+	for i := 0; i < len(text); {
+		if skip := skipLiteral(text, i); skip > i {
+			i = skip
+			continue
+		}
+
+		if text[i] == '{' {
+			if end := objectEnd(text[i:]); end > 0 {
+				objects = append(objects, text[i:i+end])
+				i += end
+				continue
+			}
+		}
+
+		i++
+	}
+
+	return objects
+}
+
+// objectEnd returns the length of the object literal text starts with, skipping braces in strings and comments,
+// or -1 if text doesn't start with a complete object
+func objectEnd(text string) int {
+	if !strings.HasPrefix(text, "{") {
+		return -1
+	}
+
+	depth := 0
+	for i := 0; i < len(text); {
+		if skip := skipLiteral(text, i); skip > i {
+			i = skip
+			continue
+		}
+
+		switch text[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+		i++
+	}
+
+	return -1
+}
+
+// skipLiteral returns where the string or comment starting at i ends, or i if there is none there.
+// An unterminated one runs to the end of the text.
+func skipLiteral(text string, i int) int {
+	switch {
+	case text[i] == '"' || text[i] == '\'' || text[i] == '`':
+		quote := text[i]
+		for j := i + 1; j < len(text); j++ {
+			switch text[j] {
+			case '\\':
+				j++
+			case quote:
+				return j + 1
+			}
+		}
+		return len(text)
+	case strings.HasPrefix(text[i:], "//"):
+		if end := strings.IndexByte(text[i:], '\n'); end >= 0 {
+			return i + end + 1
+		}
+		return len(text)
+	case strings.HasPrefix(text[i:], "/*"):
+		if end := strings.Index(text[i+2:], "*/"); end >= 0 {
+			return i + 2 + end + 2
+		}
+		return len(text)
+	}
+
+	return i
+}
+
+// literalToJson turns a javascript object literal into JSON: single quoted strings are double quoted, so are
+// unquoted keys, and comments and trailing commas are dropped
+func literalToJson(object string) string {
+	var sb strings.Builder
+	for i := 0; i < len(object); {
+		c := object[i]
+
+		switch {
+		case c == '"':
+			end := skipLiteral(object, i)
+			sb.WriteString(object[i:end])
+			i = end
+		case c == '\'':
+			end := skipLiteral(object, i)
+			sb.WriteString(singleToDoubleQuoted(object[i:end]))
+			i = end
+		case strings.HasPrefix(object[i:], "//") || strings.HasPrefix(object[i:], "/*"):
+			i = skipLiteral(object, i)
+		case c == ',':
+			// drop the comma if only whitespace is left before the closing bracket
+			next := strings.TrimLeft(object[i+1:], " \t\r\n")
+			if !strings.HasPrefix(next, "}") && !strings.HasPrefix(next, "]") {
+				sb.WriteByte(c)
+			}
+			i++
+		case isIdentifierStart(c):
+			end := i
+			for end < len(object) && (isIdentifierStart(object[end]) || (object[end] >= '0' && object[end] <= '9')) {
+				end++
+			}
+			identifier := object[i:end]
+			if strings.HasPrefix(strings.TrimLeft(object[end:], " \t\r\n"), ":") {
+				identifier = `"` + identifier + `"`
+			}
+			sb.WriteString(identifier)
+			i = end
+		default:
+			sb.WriteByte(c)
+			i++
+		}
+	}
+
+	return sb.String()
+}
+
+func isIdentifierStart(c byte) bool {
+	return c == '_' || c == '$' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// singleToDoubleQuoted converts a single quoted javascript string, including its quotes, into a JSON string
+func singleToDoubleQuoted(literal string) string {
+	inner := strings.TrimSuffix(strings.TrimPrefix(literal, "'"), "'")
+
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for i := 0; i < len(inner); i++ {
+		switch {
+		case inner[i] == '\\' && i+1 < len(inner) && inner[i+1] == '\'':
+			sb.WriteByte('\'')
+			i++
+		case inner[i] == '\\' && i+1 < len(inner):
+			sb.WriteString(inner[i : i+2])
+			i++
+		case inner[i] == '"':
+			sb.WriteString(`\"`)
+		default:
+			sb.WriteByte(inner[i])
+		}
+	}
+	sb.WriteByte('"')
+
+	return sb.String()
+}
+
+// AssignedObjects returns the object literals assigned to variables whose name starts with the prefix, such as
+// `var option_df_12 = {...};` for the prefix option_df_, for providers that read book information out of scripts
+func AssignedObjects(text string, prefix string) []string {
+	objects := make([]string, 0)
+	for offset := 0; ; {
+		index := strings.Index(text[offset:], prefix)
+		if index < 0 {
+			return objects
+		}
+		offset += index + len(prefix)
+
+		// skip the rest of the variable name, usually the id of the embed
+		end := offset
+		for end < len(text) && (isIdentifierStart(text[end]) || text[end] >= '0' && text[end] <= '9') {
+			end++
+		}
+		if object, ok := objectAfterAssignment(text[end:]); ok {
+			objects = append(objects, object)
+		}
+	}
+}
+
+// DecodeObject decodes an object literal into v, also when it is written as javascript rather than JSON
+func DecodeObject(object string, v interface{}) error {
+	err := json.Unmarshal([]byte(object), v)
+	if err == nil {
+		return nil
+	}
+
+	if jsonErr := json.Unmarshal([]byte(literalToJson(object)), v); jsonErr != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ConfigSnapshot downloads the book information of a book as it is served, from the first source that is there,
+// for bug reports about books whose information can't be read
+func ConfigSnapshot(ctx context.Context, idOrUrl string) (string, []byte, error) {
+	id, err := ParseId(idOrUrl)
+	if err != nil {
+		return "", nil, tracerr.Wrap(err)
+	}
+
+	for _, source := range configSources {
+		url := fmt.Sprintf("https://online.fliphtml5.com/%s/%s", id, source.path)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return "", nil, tracerr.Wrap(err)
+		}
+
+		response, err := newClient(0).Do(req)
+		if err != nil {
+			return "", nil, tracerr.Wrap(err)
+		}
+
+		body, err := io.ReadAll(response.Body)
+		response.Body.Close()
+		if err != nil {
+			return "", nil, tracerr.Wrap(err)
+		}
+
+		if response.StatusCode == http.StatusOK {
+			return url, body, nil
+		}
+	}
+
+	return "", nil, fmt.Errorf("failed to download book information of %s: %w", id, ErrBookNotFound)
+}
@@ -0,0 +1,100 @@
+package book
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestConfigFallbacks(testing *testing.T) {
+	defer func(original http.RoundTripper) { Transport = original }(Transport)
+
+	cases := map[string]fixtureTransport{
+		"mobile": {
+			"https://online.fliphtml5.com/abcde/fghij/mobile/javascript/config.js": `var htmlConfig = {"meta": {"title": "Mobile"}, "fliphtml5_pages": [{"n": ["1.jpg"]}]};`,
+		},
+		"inline": {
+			"https://online.fliphtml5.com/abcde/fghij/": `<html><script>if (htmlConfig == null) {}</script>
+				<script>var htmlConfig = {"meta": {"title": "Inline {braces}"}, "fliphtml5_pages": [{"n": ["1.jpg"]}]}; start();</script></html>`,
+		},
+	}
+
+	for name, transport := range cases {
+		Transport = transport
+
+		b, err := Get(context.Background(), "abcde/fghij")
+		if err != nil {
+			testing.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		if len(b.Pages) != 1 {
+			testing.Errorf("%s: unexpected book %+v", name, b)
+		}
+	}
+
+	Transport = fixtureTransport{}
+	if _, err := Get(context.Background(), "abcde/fghij"); !errors.Is(err, ErrBookNotFound) {
+		testing.Errorf("expected the book not to be found, got %v", err)
+	}
+
+	Transport = fixtureTransport{
+		"https://online.fliphtml5.com/abcde/fghij/javascript/config.js": `var htmlConfig = {broken`,
+	}
+	if _, err := Get(context.Background(), "abcde/fghij"); !errors.Is(err, ErrConfigParse) || errors.Is(err, ErrBookNotFound) {
+		testing.Errorf("expected a parse error, got %v", err)
+	} else if !errors.Is(err, ErrLayoutChanged) {
+		testing.Errorf("expected a book no source can be read from to point to a layout change, got %v", err)
+	}
+}
+
+func TestParseConfigScript(testing *testing.T) {
+	cases := map[string]string{
+		"plain":    `var htmlConfig = {"meta": {"title": "A {b}"}, "fliphtml5_pages": [{"n": ["1.jpg"]}]};`,
+		"trailing": `var htmlConfig = {"meta": {"title": "A {b}"}, "fliphtml5_pages": [{"n": ["1.jpg"]}]}; var other = {"x": 1}; init();`,
+		"jsonp":    `/* config {v2} */ jsonpCallback({"meta": {"title": "A {b}"}, "fliphtml5_pages": [{"n": ["1.jpg"]}]});`,
+		"multiple": `var bookConfig = {"language": "en"}; var pages = {"meta": {"title": "A {b}"}, "fliphtml5_pages": [{"n": ["1.jpg"]}]};`,
+		"literal": `window.htmlConfig = {
+			// generated
+			meta: {title: 'A {b}',},
+			'fliphtml5_pages': [{n: ['1.jpg'], t: "it's"},],
+		};`,
+	}
+
+	for name, script := range cases {
+		config, err := parseConfigScript(script)
+		if err != nil {
+			testing.Errorf("%s: unexpected error: %v", name, err)
+			continue
+		}
+
+		if config.Meta.Title != "A {b}" || len(config.Pages) != 1 {
+			testing.Errorf("%s: unexpected config %+v", name, config)
+		}
+	}
+
+	if _, err := parseConfigScript(`var htmlConfig = {"fliphtml5_pages": [`); err == nil {
+		testing.Error("expected an error for a truncated config")
+	}
+}
+
+func TestAssignedObjects(testing *testing.T) {
+	script := `var option_df_12 = {"source": ["1.jpg"]}; var option_df_ = {source: 'a.pdf',}; option_df_34 == null; var other = {"x": 1};`
+
+	objects := AssignedObjects(script, "option_df_")
+	if len(objects) != 2 {
+		testing.Fatalf("expected 2 objects, got %v", objects)
+	}
+
+	var option struct {
+		Source interface{} `json:"source"`
+	}
+	for _, object := range objects {
+		if err := DecodeObject(object, &option); err != nil || option.Source == nil {
+			testing.Errorf("unexpected result %+v, %v for %s", option, err, object)
+		}
+	}
+
+	if err := DecodeObject(`{"source": [`, &option); err == nil {
+		testing.Error("expected an error for a truncated object")
+	}
+}
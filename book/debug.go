@@ -0,0 +1,95 @@
+package book
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+	"github.com/ztrue/tracerr"
+)
+
+// captureDebugger collects the console messages and uncaught errors of a page, and saves them along with the DOM
+// and a screenshot when the capture fails so the problem can be reported
+type captureDebugger struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+// listen is a chromedp target listener
+func (d *captureDebugger) listen(ev interface{}) {
+	switch ev := ev.(type) {
+	case *runtime.EventConsoleAPICalled:
+		args := make([]string, 0, len(ev.Args))
+		for _, arg := range ev.Args {
+			args = append(args, remoteObjectString(arg))
+		}
+		d.add(fmt.Sprintf("console.%s: %s", ev.Type, strings.Join(args, " ")))
+	case *runtime.EventExceptionThrown:
+		message := ev.ExceptionDetails.Text
+		if ev.ExceptionDetails.Exception != nil {
+			message += " " + remoteObjectString(ev.ExceptionDetails.Exception)
+		}
+		d.add(fmt.Sprintf("uncaught error at %d:%d: %s", ev.ExceptionDetails.LineNumber, ev.ExceptionDetails.ColumnNumber, message))
+	}
+}
+
+func (d *captureDebugger) add(message string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.messages = append(d.messages, message)
+}
+
+// save writes the diagnostics of a failed page into dir: page-<n>.console.log with the collected messages,
+// page-<n>.png with what the tab shows and page-<n>.html with its DOM. The tab may be what failed, so every file is
+// attempted on its own.
+func (d *captureDebugger) save(ctx context.Context, dir string, pageNumber int) error {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	d.mu.Lock()
+	messages := strings.Join(d.messages, "\n")
+	d.mu.Unlock()
+
+	consolePath := filepath.Join(dir, fmt.Sprintf("page-%d.console.log", pageNumber))
+	if err := os.WriteFile(consolePath, []byte(messages+"\n"), 0644); err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	// the capture may have failed because of a timeout, so the tab gets a few seconds of its own
+	tabCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	var errs []error
+
+	var screenshot []byte
+	if err := chromedp.Run(tabCtx, chromedp.CaptureScreenshot(&screenshot)); err != nil {
+		errs = append(errs, fmt.Errorf("failed to take a screenshot: %w", err))
+	} else if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("page-%d.png", pageNumber)), screenshot, 0644); err != nil {
+		errs = append(errs, tracerr.Wrap(err))
+	}
+
+	var html string
+	if err := chromedp.Run(tabCtx, chromedp.OuterHTML("html", &html, chromedp.ByQuery)); err != nil {
+		errs = append(errs, fmt.Errorf("failed to read the DOM: %w", err))
+	} else if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("page-%d.html", pageNumber)), []byte(html), 0644); err != nil {
+		errs = append(errs, tracerr.Wrap(err))
+	}
+
+	return errors.Join(errs...)
+}
+
+func remoteObjectString(object *runtime.RemoteObject) string {
+	if len(object.Value) > 0 {
+		return string(object.Value)
+	}
+
+	return object.Description
+}
@@ -0,0 +1,31 @@
+package book
+
+import (
+	"testing"
+
+	"github.com/chromedp/cdproto/runtime"
+)
+
+func TestCaptureDebuggerMessages(testing *testing.T) {
+	debugger := &captureDebugger{}
+	debugger.listen(&runtime.EventConsoleAPICalled{
+		Type: runtime.APITypeError,
+		Args: []*runtime.RemoteObject{{Value: []byte(`"Error clicking element:"`)}, {Description: "TypeError: x is undefined"}},
+	})
+	debugger.listen(&runtime.EventExceptionThrown{ExceptionDetails: &runtime.ExceptionDetails{Text: "Uncaught", LineNumber: 3, ColumnNumber: 7}})
+	debugger.listen("some other event")
+
+	expected := []string{
+		`console.error: "Error clicking element:" TypeError: x is undefined`,
+		"uncaught error at 3:7: Uncaught",
+	}
+	if len(debugger.messages) != len(expected) {
+		testing.Fatalf("expected %d messages, got %v", len(expected), debugger.messages)
+	}
+
+	for i, message := range expected {
+		if debugger.messages[i] != message {
+			testing.Fatalf("expected %s, got %s", message, debugger.messages[i])
+		}
+	}
+}
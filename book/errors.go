@@ -0,0 +1,54 @@
+package book
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Common failures, wrapped into the errors returned by this package so callers can tell them apart with errors.Is
+var (
+	ErrBookNotFound      = errors.New("book not found")
+	ErrPrivateBook       = errors.New("book is private or needs a login")
+	ErrRateLimited       = errors.New("too many requests")
+	ErrConfigParse       = errors.New("unexpected book information format")
+	ErrLayoutChanged     = errors.New("the site layout may have changed, please update fh5dl")
+	ErrChromeUnavailable = errors.New("chrome is not available")
+)
+
+// errorForStatus returns the error a response status stands for, or nil if it has no specific meaning
+func errorForStatus(status int) error {
+	switch status {
+	case http.StatusNotFound, http.StatusGone:
+		return ErrBookNotFound
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return ErrPrivateBook
+	case http.StatusTooManyRequests:
+		return ErrRateLimited
+	}
+
+	return nil
+}
+
+// ImageStatusError is returned when an image request is answered with an error status
+type ImageStatusError struct {
+	StatusCode int
+	Status     string
+}
+
+func (e *ImageStatusError) Error() string {
+	if e.StatusCode == http.StatusTooManyRequests {
+		return fmt.Sprintf("failed to download image (status: %s): %v", e.Status, ErrRateLimited)
+	}
+
+	return fmt.Sprintf("failed to download image (status: %s)", e.Status)
+}
+
+// Unwrap keeps rate limiting recognizable with errors.Is
+func (e *ImageStatusError) Unwrap() error {
+	if e.StatusCode == http.StatusTooManyRequests {
+		return ErrRateLimited
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package book
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ztrue/tracerr"
+)
+
+var (
+	ogTitleRegex = regexp.MustCompile(`(?i)<meta[^>]+property=["']og:title["'][^>]+content=["']([^"']*)["']`)
+	titleRegex   = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+)
+
+// FetchText downloads a page of a flipbook platform, such as the viewer of a book, for providers that read the book
+// information out of it. Error statuses are returned as ErrBookNotFound and the other errors of this package.
+func FetchText(ctx context.Context, url string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", tracerr.Wrap(err)
+	}
+	setBrowserHeaders(req)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/json,*/*;q=0.8")
+	// left to the transport, which only decompresses responses to encodings it asked for itself
+	req.Header.Del("Accept-Encoding")
+
+	res, err := newClient(30 * time.Second).Do(req)
+	if err != nil {
+		return "", tracerr.Wrap(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		if statusErr := errorForStatus(res.StatusCode); statusErr != nil {
+			return "", fmt.Errorf("failed to download %s: %s: %w", url, res.Status, statusErr)
+		}
+		return "", fmt.Errorf("failed to download %s: %s", url, res.Status)
+	}
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return "", tracerr.Wrap(err)
+	}
+
+	return string(body), nil
+}
+
+// Exists reports whether the server has the URL, for providers that find the pages of a book by probing their
+// images. Only rate limiting and failed requests are errors, other statuses mean the URL doesn't exist.
+func Exists(ctx context.Context, url string) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return false, tracerr.Wrap(err)
+	}
+	setBrowserHeaders(req)
+
+	res, err := newClient(30 * time.Second).Do(req)
+	if err != nil {
+		return false, tracerr.Wrap(err)
+	}
+	io.Copy(io.Discard, res.Body)
+	res.Body.Close()
+
+	if res.StatusCode == http.StatusTooManyRequests {
+		return false, fmt.Errorf("failed to check %s: %s: %w", url, res.Status, ErrRateLimited)
+	}
+
+	return res.StatusCode == http.StatusOK, nil
+}
+
+// HtmlTitle returns the og:title of an HTML page, or its <title> if it has none
+func HtmlTitle(body string) string {
+	for _, regex := range []*regexp.Regexp{ogTitleRegex, titleRegex} {
+		if match := regex.FindStringSubmatch(body); match != nil {
+			if title := strings.TrimSpace(html.UnescapeString(match[1])); title != "" {
+				return title
+			}
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,66 @@
+package book
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Transport makes every request of this package: the book information, image downloads and probes. Replace it
+// before any download starts to serve responses from fixtures or to add caching or recording.
+var Transport http.RoundTripper = newDefaultTransport()
+
+// newDefaultTransport keeps enough idle connections around for concurrent image downloads from the same CDN. Like the
+// default transport it is cloned from, it uses HTTP/2 where the CDN supports it.
+func newDefaultTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = 100
+	transport.MaxIdleConnsPerHost = 20
+	transport.IdleConnTimeout = 90 * time.Second
+
+	return transport
+}
+
+// newClient returns a client using Transport. A zero timeout leaves stopping the request to the context.
+func newClient(timeout time.Duration) *http.Client {
+	return &http.Client{Timeout: timeout, Transport: Transport}
+}
+
+// WarmUp sends the given number of concurrent requests to the host of the image before the downloads start, so they
+// don't all wait on connection setup at once. Over HTTP/1.1 every request opens a connection of its own, over HTTP/2
+// they share a single one. It is a no-op when Transport was replaced, and failures are left for the downloads to
+// report.
+func WarmUp(ctx context.Context, imageUrl string, connections int) {
+	if _, ok := Transport.(*http.Transport); !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	client := newClient(0)
+	wg := sync.WaitGroup{}
+	for range connections {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			req, err := http.NewRequestWithContext(ctx, http.MethodHead, imageUrl, nil)
+			if err != nil {
+				return
+			}
+			setBrowserHeaders(req)
+
+			res, err := client.Do(req)
+			if err != nil {
+				return
+			}
+			// the connection only goes back to the pool once the body is read
+			io.Copy(io.Discard, res.Body)
+			res.Body.Close()
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,246 @@
+package book
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fixtureTransport answers requests with canned bodies by URL, and 404 for anything else
+type fixtureTransport map[string]string
+
+func (f fixtureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, ok := f[req.URL.String()]
+	status := http.StatusOK
+	if !ok {
+		status = http.StatusNotFound
+	}
+
+	return &http.Response{
+		StatusCode: status,
+		Status:     http.StatusText(status),
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Header:     make(http.Header),
+		Request:    req,
+	}, nil
+}
+
+func TestTransport(testing *testing.T) {
+	defer func(original http.RoundTripper) { Transport = original }(Transport)
+	Transport = fixtureTransport{
+		"https://online.fliphtml5.com/abcde/fghij/javascript/config.js":  `var htmlConfig = {"meta": {"title": "Fixture"}, "fliphtml5_pages": [{"n": ["page1.jpg"], "t": "thumb1.jpg"}]};`,
+		"https://online.fliphtml5.com/abcde/fghij/files/large/page1.jpg": "image",
+	}
+
+	b, err := Get(context.Background(), "abcde/fghij")
+	if err != nil {
+		testing.Fatalf("unexpected error: %v", err)
+	}
+	if b.Title != "Fixture" || len(b.Pages) != 1 {
+		testing.Fatalf("unexpected book %+v", b)
+	}
+
+	images := b.FindAllImages()
+	downloaded, err := images[0].Download(context.Background(), testing.TempDir(), DownloadOptions{})
+	if err != nil {
+		testing.Fatalf("unexpected error: %v", err)
+	}
+
+	content, _ := os.ReadFile(downloaded.FullPath)
+	if string(content) != "image" {
+		testing.Errorf("expected the image from the transport, got %q", content)
+	}
+}
+
+func TestDownloadNamesByContent(testing *testing.T) {
+	defer func(original http.RoundTripper) { Transport = original }(Transport)
+	Transport = fixtureTransport{
+		"https://online.fliphtml5.com/abcde/fghij/files/large/page1.jpg": "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR",
+	}
+
+	dir := testing.TempDir()
+	img := PageImage{PageNumber: 3, ImageNumber: 1, Url: "https://online.fliphtml5.com/abcde/fghij/files/large/page1.jpg"}
+	downloaded, err := img.Download(context.Background(), dir, DownloadOptions{})
+	if err != nil {
+		testing.Fatalf("unexpected error: %v", err)
+	}
+	if filepath.Base(downloaded.FullPath) != "0003-01.png" {
+		testing.Errorf("expected the PNG to be saved as 0003-01.png, got %s", downloaded.FullPath)
+	}
+
+	cached, err := img.Download(context.Background(), dir, DownloadOptions{})
+	if err != nil {
+		testing.Fatalf("unexpected error: %v", err)
+	}
+	if cached.FullPath != downloaded.FullPath || cached.Attempts != 0 {
+		testing.Errorf("expected the PNG to be reused, got %+v", cached)
+	}
+}
+
+func TestImageExtension(testing *testing.T) {
+	cases := []struct {
+		head        string
+		contentType string
+		expected    string
+	}{
+		{"\xff\xd8\xff\xe0", "", "jpg"},
+		{"RIFF\x00\x00\x00\x00WEBPVP8 ", "image/jpeg", "webp"},
+		{"", "image/png; charset=binary", "png"},
+		{"<html>", "text/html", "jpg"},
+	}
+
+	for _, c := range cases {
+		if actual := imageExtension([]byte(c.head), c.contentType); actual != c.expected {
+			testing.Errorf("expected %s for %q (%s), got %s", c.expected, c.head, c.contentType, actual)
+		}
+	}
+}
+
+func TestCachedPathMigratesLegacyNames(testing *testing.T) {
+	dir := testing.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "12-2.webp"), []byte("image"), 0644); err != nil {
+		testing.Fatalf("unexpected error: %v", err)
+	}
+
+	img := PageImage{PageNumber: 12, ImageNumber: 2}
+	path, ok := img.CachedPath(dir)
+	if !ok || filepath.Base(path) != "0012-02.webp" {
+		testing.Fatalf("expected the image to be renamed to 0012-02.webp, got %s", path)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "12-2.webp")); !os.IsNotExist(err) {
+		testing.Errorf("expected the unpadded file to be gone")
+	}
+}
+
+// flakyTransport answers with 503 until the given number of requests failed
+type flakyTransport struct {
+	failures int
+	requests int
+}
+
+func (f *flakyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	f.requests++
+	status := http.StatusOK
+	if f.requests <= f.failures {
+		status = http.StatusServiceUnavailable
+	}
+
+	return &http.Response{
+		StatusCode: status,
+		Status:     http.StatusText(status),
+		Body:       io.NopCloser(strings.NewReader("image")),
+		Header:     make(http.Header),
+		Request:    req,
+	}, nil
+}
+
+func TestDownloadRetryPolicy(testing *testing.T) {
+	defer func(original http.RoundTripper) { Transport = original }(Transport)
+
+	// every failed attempt makes three requests, as it also tries the alternate URLs of the image
+	transport := &flakyTransport{failures: 6}
+	Transport = transport
+
+	img := PageImage{PageNumber: 1, ImageNumber: 1, Url: "https://online.fliphtml5.com/abcde/fghij/files/large/page1.jpg"}
+	options := DownloadOptions{Attempts: 3, Backoff: time.Millisecond}
+	downloaded, err := img.Download(context.Background(), testing.TempDir(), options)
+	if err != nil {
+		testing.Fatalf("unexpected error: %v", err)
+	}
+	if downloaded.Attempts != 3 {
+		testing.Errorf("expected the image to be downloaded on the third attempt, got %d", downloaded.Attempts)
+	}
+
+	transport.requests = 0
+	_, err = img.Download(context.Background(), testing.TempDir(), DownloadOptions{Attempts: 2, Backoff: time.Millisecond})
+	if err == nil || !strings.Contains(err.Error(), "after 2 attempts") {
+		testing.Errorf("expected the download to give up after 2 attempts, got %v", err)
+	}
+}
+
+func TestRetryDelay(testing *testing.T) {
+	options := DownloadOptions{Backoff: 500 * time.Millisecond}.withDefaults()
+	if options.retryDelay(1) != 500*time.Millisecond || options.retryDelay(3) != 2*time.Second {
+		testing.Errorf("expected the backoff to double, got %v and %v", options.retryDelay(1), options.retryDelay(3))
+	}
+}
+
+func TestWarmUp(testing *testing.T) {
+	defer func(original http.RoundTripper) { Transport = original }(Transport)
+	Transport = newDefaultTransport()
+
+	heads := make(chan string, 8)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		heads <- r.Method
+	}))
+	defer server.Close()
+
+	WarmUp(context.Background(), server.URL+"/files/large/page1.jpg", 3)
+	close(heads)
+
+	count := 0
+	for method := range heads {
+		if method != http.MethodHead {
+			testing.Errorf("expected HEAD requests, got %s", method)
+		}
+		count++
+	}
+	if count != 3 {
+		testing.Errorf("expected 3 warm-up requests, got %d", count)
+	}
+}
+
+func TestDownloadRevalidate(testing *testing.T) {
+	defer func(original http.RoundTripper) { Transport = original }(Transport)
+	Transport = newDefaultTransport()
+
+	etag := `"v1"`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Write([]byte("image " + etag))
+	}))
+	defer server.Close()
+
+	dir := testing.TempDir()
+	img := PageImage{PageNumber: 1, ImageNumber: 1, Url: server.URL + "/files/large/page1.jpg"}
+	options := DownloadOptions{Revalidate: true}
+	if _, err := img.Download(context.Background(), dir, options); err != nil {
+		testing.Fatalf("unexpected error: %v", err)
+	}
+
+	unchanged, err := img.Download(context.Background(), dir, options)
+	if err != nil {
+		testing.Fatalf("unexpected error: %v", err)
+	}
+	if !unchanged.Revalidated || unchanged.Attempts != 0 {
+		testing.Errorf("expected the unchanged image to be kept, got %+v", unchanged)
+	}
+
+	etag = `"v2"`
+	updated, err := img.Download(context.Background(), dir, options)
+	if err != nil {
+		testing.Fatalf("unexpected error: %v", err)
+	}
+	content, _ := os.ReadFile(updated.FullPath)
+	if !updated.Revalidated || updated.Attempts != 1 || string(content) != `image "v2"` {
+		testing.Errorf("expected the changed image to be downloaded again, got %+v with %q", updated, content)
+	}
+
+	trusted, err := img.Download(context.Background(), dir, DownloadOptions{})
+	if err != nil {
+		testing.Fatalf("unexpected error: %v", err)
+	}
+	if trusted.Revalidated {
+		testing.Errorf("expected the image on disk to be trusted without --revalidate")
+	}
+}
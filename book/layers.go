@@ -0,0 +1,147 @@
+package book
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// Link is a clickable area of a page, pointing either to a web address or to another page of the book.
+// Its position is relative to the page, as fractions of the page width and height.
+type Link struct {
+	Url    string
+	Page   int
+	X      float64
+	Y      float64
+	Width  float64
+	Height float64
+}
+
+// Security holds the restrictions the publisher set on the book
+type Security struct {
+	PasswordProtected bool
+	PrintDisabled     bool
+	DownloadDisabled  bool
+}
+
+// flexibleNumber is a number in the book config, which some configs write as a string
+type flexibleNumber float64
+
+func (n *flexibleNumber) UnmarshalJSON(data []byte) error {
+	value, err := strconv.ParseFloat(strings.Trim(string(data), `"`), 64)
+	if err != nil {
+		// leave values that aren't numbers at zero, like missing ones
+		*n = 0
+		return nil
+	}
+
+	*n = flexibleNumber(value)
+	return nil
+}
+
+// flexibleBool is a flag in the book config, written as a boolean, a number or a string
+type flexibleBool bool
+
+func (b *flexibleBool) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+
+	switch v := value.(type) {
+	case bool:
+		*b = flexibleBool(v)
+	case float64:
+		*b = v != 0
+	case string:
+		parsed, _ := strconv.ParseBool(strings.TrimSpace(v))
+		*b = flexibleBool(parsed || strings.TrimSpace(v) == "1")
+	default:
+		*b = false
+	}
+
+	return nil
+}
+
+// area is the position of a link or annotation in the book config, either in pixels of the page or as fractions
+type area struct {
+	X      flexibleNumber `json:"x"`
+	Y      flexibleNumber `json:"y"`
+	Width  flexibleNumber `json:"w"`
+	Height flexibleNumber `json:"h"`
+}
+
+type linkItem struct {
+	area
+	Url  string         `json:"url"`
+	Page flexibleNumber `json:"page"`
+}
+
+type annotationItem struct {
+	area
+	Text string `json:"text"`
+}
+
+type securityConfig struct {
+	Password        flexibleBool `json:"password"`
+	DisablePrint    flexibleBool `json:"disablePrint"`
+	DisableDownload flexibleBool `json:"disableDownload"`
+}
+
+// fractions converts the area to fractions of the page. Areas with any coordinate above 1 are taken to be in
+// pixels of the page size, and are dropped when the page size isn't known.
+func (a area) fractions(pageWidth float64, pageHeight float64) (x, y, width, height float64, ok bool) {
+	x, y, width, height = float64(a.X), float64(a.Y), float64(a.Width), float64(a.Height)
+	if width <= 0 || height <= 0 {
+		return 0, 0, 0, 0, false
+	}
+
+	if x > 1 || y > 1 || width > 1 || height > 1 {
+		if pageWidth <= 0 || pageHeight <= 0 {
+			return 0, 0, 0, 0, false
+		}
+		x, y, width, height = x/pageWidth, y/pageHeight, width/pageWidth, height/pageHeight
+	}
+
+	return x, y, width, height, true
+}
+
+// parseLinks converts the links of a page in the book config, dropping the ones without a target or position
+func parseLinks(items []linkItem, pageWidth float64, pageHeight float64) []Link {
+	links := make([]Link, 0, len(items))
+	for _, item := range items {
+		x, y, width, height, ok := item.fractions(pageWidth, pageHeight)
+		url := strings.TrimSpace(item.Url)
+		if !ok || (url == "" && item.Page < 1) {
+			continue
+		}
+
+		links = append(links, Link{Url: url, Page: int(item.Page), X: x, Y: y, Width: width, Height: height})
+	}
+
+	return links
+}
+
+// parseAnnotations converts the annotations of a page in the book config
+func parseAnnotations(items []annotationItem, pageWidth float64, pageHeight float64) []Annotation {
+	annotations := make([]Annotation, 0, len(items))
+	for _, item := range items {
+		x, y, width, height, ok := item.fractions(pageWidth, pageHeight)
+		text := strings.TrimSpace(item.Text)
+		if !ok || text == "" {
+			continue
+		}
+
+		annotations = append(annotations, Annotation{Text: text, X: x, Y: y, Width: width, Height: height})
+	}
+
+	return annotations
+}
+
+func (s securityConfig) security() Security {
+	return Security{
+		PasswordProtected: bool(s.Password),
+		PrintDisabled:     bool(s.DisablePrint),
+		DownloadDisabled:  bool(s.DisableDownload),
+	}
+}
@@ -0,0 +1,56 @@
+package book
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestConfigLayers(testing *testing.T) {
+	defer func(original http.RoundTripper) { Transport = original }(Transport)
+	Transport = fixtureTransport{
+		"https://online.fliphtml5.com/abcde/fghij/javascript/config.js": `var htmlConfig = {
+			"meta": {"title": "Layers", "pageWidth": "800", "pageHeight": 1000},
+			"bookmarks": [{"title": "Start", "page": "2"}],
+			"security": {"password": "1", "disablePrint": true},
+			"fliphtml5_pages": [
+				{"n": ["1.jpg"], "links": [
+					{"x": 400, "y": 500, "w": 200, "h": 100, "url": "https://example.com"},
+					{"x": 0.1, "y": 0.1, "w": 0.2, "h": 0.05, "page": 2},
+					{"x": 0.1, "y": 0.1, "w": 0.2, "h": 0.05}
+				]},
+				{"n": ["2.jpg"], "annotations": [{"x": 0.5, "y": 0.5, "w": 0.1, "h": 0.1, "text": "Check this"}]}
+			]
+		};`,
+	}
+
+	b, err := Get(context.Background(), "abcde/fghij")
+	if err != nil {
+		testing.Fatalf("unexpected error: %v", err)
+	}
+
+	if b.PageWidth != 800 || b.PageHeight != 1000 {
+		testing.Errorf("unexpected page size %dx%d", b.PageWidth, b.PageHeight)
+	}
+	if len(b.Outline) != 1 || b.Outline[0].Page != 2 {
+		testing.Errorf("expected the bookmarks as the outline, got %+v", b.Outline)
+	}
+	if !b.Security.PasswordProtected || !b.Security.PrintDisabled || b.Security.DownloadDisabled {
+		testing.Errorf("unexpected security %+v", b.Security)
+	}
+
+	links := b.Pages[0].Links
+	if len(links) != 2 {
+		testing.Fatalf("expected 2 links, got %+v", links)
+	}
+	if links[0].Url != "https://example.com" || links[0].X != 0.5 || links[0].Width != 0.25 || links[0].Height != 0.1 {
+		testing.Errorf("expected the pixel position to be converted to fractions, got %+v", links[0])
+	}
+	if links[1].Page != 2 {
+		testing.Errorf("expected a link to page 2, got %+v", links[1])
+	}
+
+	if annotations := b.Pages[1].Annotations; len(annotations) != 1 || annotations[0].Text != "Check this" {
+		testing.Errorf("unexpected annotations %+v", annotations)
+	}
+}
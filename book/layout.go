@@ -0,0 +1,123 @@
+package book
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/chromedp/chromedp"
+	"github.com/ztrue/tracerr"
+)
+
+// SpreadLayout is how the viewer of a book pairs pages into spreads
+type SpreadLayout int
+
+const (
+	LayoutUnknown     SpreadLayout = iota // not detected, handled like LayoutCoverSingle which most books use
+	LayoutCoverSingle                     // page 1 alone, then 2-3, 4-5 and so on
+	LayoutPaired                          // 1-2, 3-4 and so on
+	LayoutSingle                          // one page at a time
+)
+
+func (l SpreadLayout) String() string {
+	switch l {
+	case LayoutCoverSingle:
+		return "single cover"
+	case LayoutPaired:
+		return "paired"
+	case LayoutSingle:
+		return "single page"
+	}
+
+	return "unknown"
+}
+
+// Side returns which of the visible pages shows the given page: left, right or single.
+// A book ending on a page without a partner shows it alone, which the capture script handles on its own.
+func (l SpreadLayout) Side(pageNumber int) string {
+	switch l {
+	case LayoutSingle:
+		return "single"
+	case LayoutPaired:
+		if pageNumber%2 == 1 {
+			return "left"
+		}
+		return "right"
+	}
+
+	if pageNumber == 1 {
+		return "single"
+	}
+	if pageNumber%2 == 0 {
+		return "left"
+	}
+	return "right"
+}
+
+// visiblePagesScript is a javascript function returning the pages shown by the viewer, sorted from left to right
+const visiblePagesScript = `() => Array.from(document.querySelectorAll('.leo-page, .flipbook-page, .page-elem, .flipbook-page3d, [class*="page"]'))
+	.filter(page => {
+		const style = window.getComputedStyle(page);
+		const rect = page.getBoundingClientRect();
+
+		return style.display !== 'none' &&
+			style.visibility !== 'hidden' &&
+			style.opacity !== '0' &&
+			parseInt(style.zIndex || 0) > 0 &&
+			rect.width > 100 &&
+			rect.height > 100;
+	})
+	.sort((a, b) => a.getBoundingClientRect().left - b.getBoundingClientRect().left)`
+
+// DetectLayout opens the first two pages of a book and counts the pages the viewer shows for each. secondPageUrl is
+// empty for books with a single page.
+func DetectLayout(ctx context.Context, firstPageUrl string, secondPageUrl string, options CaptureOptions) (SpreadLayout, error) {
+	options = options.withDefaults()
+
+	tabCtx, closeTab, err := options.openTab(ctx)
+	if err != nil {
+		return LayoutUnknown, err
+	}
+	defer closeTab()
+
+	timeoutCtx, cancel := context.WithTimeout(tabCtx, options.Timeout)
+	defer cancel()
+
+	first, err := countVisiblePages(timeoutCtx, firstPageUrl, options.Viewport)
+	if err != nil {
+		return LayoutUnknown, err
+	}
+
+	if first >= 2 {
+		return LayoutPaired, nil
+	}
+	if first == 0 {
+		return LayoutUnknown, fmt.Errorf("no pages found in the viewer")
+	}
+	if secondPageUrl == "" {
+		return LayoutSingle, nil
+	}
+
+	second, err := countVisiblePages(timeoutCtx, secondPageUrl, options.Viewport)
+	if err != nil {
+		return LayoutUnknown, err
+	}
+
+	if second >= 2 {
+		return LayoutCoverSingle, nil
+	}
+	return LayoutSingle, nil
+}
+
+func countVisiblePages(ctx context.Context, pageUrl string, viewport Viewport) (int, error) {
+	var count int
+	err := chromedp.Run(ctx,
+		viewport.emulate(),
+		chromedp.Navigate(pageUrl),
+		// same wait as the capture, so the viewer has laid out the pages
+		chromedp.Sleep(3*time.Second),
+		chromedp.EvaluateAsDevTools("("+visiblePagesScript+")().length", &count),
+	)
+
+	return count, tracerr.Wrap(err)
+}
@@ -0,0 +1,23 @@
+package book
+
+import "testing"
+
+func TestSpreadLayoutSide(testing *testing.T) {
+	cases := []struct {
+		layout   SpreadLayout
+		expected []string // sides of pages 1 to 4
+	}{
+		{LayoutUnknown, []string{"single", "left", "right", "left"}},
+		{LayoutCoverSingle, []string{"single", "left", "right", "left"}},
+		{LayoutPaired, []string{"left", "right", "left", "right"}},
+		{LayoutSingle, []string{"single", "single", "single", "single"}},
+	}
+
+	for _, c := range cases {
+		for i, expected := range c.expected {
+			if actual := c.layout.Side(i + 1); actual != expected {
+				testing.Fatalf("expected page %d of a %s layout to be %s, got %s", i+1, c.layout, expected, actual)
+			}
+		}
+	}
+}
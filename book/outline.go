@@ -0,0 +1,60 @@
+package book
+
+import (
+	"html"
+	"strings"
+)
+
+// OutlineEntry is a chapter from the table of contents of a book
+type OutlineEntry struct {
+	Title    string
+	Page     int
+	Children []OutlineEntry
+}
+
+// FlatOutlineEntry is an outline entry along with how deeply it is nested
+type FlatOutlineEntry struct {
+	OutlineEntry
+	Depth int
+}
+
+// outlineItem is an entry of the outline in the book config
+type outlineItem struct {
+	Title    string         `json:"title"`
+	Page     flexibleNumber `json:"page"`
+	Children []outlineItem  `json:"children"`
+}
+
+// parseOutline converts the outline in the book config, dropping the entries that don't point to a page
+func parseOutline(items []outlineItem) []OutlineEntry {
+	entries := make([]OutlineEntry, 0, len(items))
+	for _, item := range items {
+		page := int(item.Page)
+		title := strings.TrimSpace(html.UnescapeString(item.Title))
+		if page < 1 || title == "" {
+			continue
+		}
+
+		entries = append(entries, OutlineEntry{
+			Title:    title,
+			Page:     page,
+			Children: parseOutline(item.Children),
+		})
+	}
+
+	return entries
+}
+
+// FlatOutline returns the outline entries in reading order, children right after their parent
+func (b *Book) FlatOutline() []FlatOutlineEntry {
+	return flattenOutline(b.Outline, 0, nil)
+}
+
+func flattenOutline(entries []OutlineEntry, depth int, flat []FlatOutlineEntry) []FlatOutlineEntry {
+	for _, entry := range entries {
+		flat = append(flat, FlatOutlineEntry{OutlineEntry: entry, Depth: depth})
+		flat = flattenOutline(entry.Children, depth+1, flat)
+	}
+
+	return flat
+}
@@ -0,0 +1,32 @@
+package book
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseOutline(testing *testing.T) {
+	config := `{"outline": [
+		{"title": "Cover", "page": 1},
+		{"title": "Chapter &amp; 1", "page": "3", "children": [{"title": "Exercises", "page": 5}]},
+		{"title": "Broken", "page": "x"}
+	]}`
+
+	var parsed htmlConfig
+	if err := json.Unmarshal([]byte(config), &parsed); err != nil {
+		testing.Fatalf("unexpected error: %v", err)
+	}
+
+	b := Book{Outline: parseOutline(parsed.Outline)}
+	flat := b.FlatOutline()
+	if len(flat) != 3 {
+		testing.Fatalf("expected 3 entries, got %+v", flat)
+	}
+
+	if flat[1].Title != "Chapter & 1" || flat[1].Page != 3 || flat[1].Depth != 0 {
+		testing.Errorf("unexpected chapter entry %+v", flat[1])
+	}
+	if flat[2].Title != "Exercises" || flat[2].Page != 5 || flat[2].Depth != 1 {
+		testing.Errorf("unexpected nested entry %+v", flat[2])
+	}
+}
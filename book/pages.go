@@ -0,0 +1,150 @@
+package book
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PageRange is an inclusive range of page numbers. A Last of zero means up to the last page.
+type PageRange struct {
+	First int
+	Last  int
+}
+
+// PageSet is a selection of pages. An empty set selects every page.
+type PageSet []PageRange
+
+// ParsePageSet parses a comma separated list of pages and ranges, such as "1-10,15,20-"
+func ParsePageSet(spec string) (PageSet, error) {
+	set := make(PageSet, 0)
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		first, last, isRange := strings.Cut(part, "-")
+		start, err := strconv.Atoi(strings.TrimSpace(first))
+		if err != nil || start < 1 {
+			return nil, fmt.Errorf("invalid page %q in %q", part, spec)
+		}
+
+		pageRange := PageRange{First: start, Last: start}
+		if isRange {
+			pageRange.Last = 0
+			if last = strings.TrimSpace(last); last != "" {
+				end, err := strconv.Atoi(last)
+				if err != nil || end < start {
+					return nil, fmt.Errorf("invalid page range %q in %q", part, spec)
+				}
+				pageRange.Last = end
+			}
+		}
+
+		set = append(set, pageRange)
+	}
+
+	if len(set) == 0 {
+		return nil, fmt.Errorf("no pages in %q", spec)
+	}
+
+	return set, nil
+}
+
+// Contains tells whether the page is selected
+func (s PageSet) Contains(pageNumber int) bool {
+	if len(s) == 0 {
+		return true
+	}
+
+	for _, pageRange := range s {
+		if pageNumber >= pageRange.First && (pageRange.Last == 0 || pageNumber <= pageRange.Last) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ImageOptions selects the images FindImages returns
+type ImageOptions struct {
+	Pages PageSet
+}
+
+// isThumbnail tells whether an image url points to the small preview of a page rather than the page itself
+func isThumbnail(imageUrl string) bool {
+	return strings.Contains(imageUrl, "files/thumb/")
+}
+
+// FindImages returns the images of the selected pages in reading order. Pages that only list thumbnails are
+// skipped, and an image that appears in several layers of a page is only returned once. Images keep the
+// numbering they have in the whole book, so the files of a page are named the same whatever the selection.
+func (b *Book) FindImages(options ImageOptions) []PageImage {
+	images := make([]PageImage, 0)
+
+	order := 1
+	for i, page := range b.Pages {
+		pageNumber := i + 1
+
+		fullSize := make([]string, 0, len(page.ImageUrls))
+		for _, imageUrl := range page.ImageUrls {
+			if !isThumbnail(imageUrl) {
+				fullSize = append(fullSize, imageUrl)
+			}
+		}
+		selected := options.Pages.Contains(pageNumber) && len(fullSize) > 0
+
+		seen := make(map[string]bool)
+		for j, imageUrl := range page.ImageUrls {
+			if selected && !isThumbnail(imageUrl) && !seen[imageUrl] {
+				images = append(images, PageImage{
+					PageNumber:   pageNumber,
+					ImageNumber:  j + 1,
+					OverallOrder: order,
+					Url:          imageUrl,
+				})
+			}
+
+			seen[imageUrl] = true
+			order++
+		}
+	}
+
+	return images
+}
+
+// thumbnailUrl resolves the thumbnail of a page in the book config, which is relative to the javascript folder
+func thumbnailUrl(id string, thumbnail string) string {
+	trimmed := strings.TrimPrefix(strings.TrimPrefix(thumbnail, "../"), "./")
+	switch {
+	case trimmed == "":
+		return ""
+	case strings.HasPrefix(trimmed, "http://") || strings.HasPrefix(trimmed, "https://"):
+		return trimmed
+	case strings.HasPrefix(trimmed, "files/"):
+		return fmt.Sprintf("https://online.fliphtml5.com/%s/%s", id, trimmed)
+	}
+
+	return fmt.Sprintf("https://online.fliphtml5.com/%s/files/thumb/%s", id, trimmed)
+}
+
+// ThumbnailImage returns the thumbnail of a page as an image numbered 0, so it doesn't overwrite the page images
+// when downloaded next to them
+func (b *Book) ThumbnailImage(pageNumber int) (PageImage, bool) {
+	if pageNumber < 1 || pageNumber > len(b.Pages) || b.Pages[pageNumber-1].ThumbnailUrl == "" {
+		return PageImage{}, false
+	}
+
+	order := 1
+	for _, page := range b.Pages[:pageNumber-1] {
+		order += len(page.ImageUrls)
+	}
+
+	return PageImage{
+		PageNumber:   pageNumber,
+		ImageNumber:  0,
+		OverallOrder: order,
+		Url:          b.Pages[pageNumber-1].ThumbnailUrl,
+	}, true
+}
@@ -0,0 +1,64 @@
+package book
+
+import (
+	"testing"
+)
+
+func TestParsePageSet(testing *testing.T) {
+	pages, err := ParsePageSet("1-3, 7,10-")
+	if err != nil {
+		testing.Fatalf("unexpected error: %v", err)
+	}
+
+	for pageNumber, expected := range map[int]bool{1: true, 3: true, 4: false, 7: true, 9: false, 10: true, 500: true} {
+		if pages.Contains(pageNumber) != expected {
+			testing.Errorf("expected page %d to be selected: %v", pageNumber, expected)
+		}
+	}
+
+	for _, spec := range []string{"", "0", "a-3", "5-2", ","} {
+		if _, err := ParsePageSet(spec); err == nil {
+			testing.Errorf("expected an error for %q", spec)
+		}
+	}
+}
+
+func TestFindImages(testing *testing.T) {
+	b := Book{Pages: []Page{
+		{ImageUrls: []string{"files/large/1.jpg", "files/large/bg.jpg", "files/large/bg.jpg"}},
+		{ImageUrls: []string{"files/thumb/2.jpg"}},
+		{ImageUrls: []string{"files/large/3.jpg"}},
+	}}
+
+	all := b.FindAllImages()
+	if len(all) != 3 {
+		testing.Fatalf("expected 3 images, got %+v", all)
+	}
+	if all[1].Url != "files/large/bg.jpg" || all[2].PageNumber != 3 || all[2].OverallOrder != 5 {
+		testing.Errorf("unexpected images %+v", all)
+	}
+
+	selected := b.FindImages(ImageOptions{Pages: PageSet{{First: 3, Last: 3}}})
+	if len(selected) != 1 || selected[0].Url != "files/large/3.jpg" || selected[0].OverallOrder != 5 {
+		testing.Errorf("unexpected selection %+v", selected)
+	}
+}
+
+func TestThumbnailImage(testing *testing.T) {
+	b := Book{Pages: []Page{
+		{ImageUrls: []string{"1.jpg", "1b.jpg"}, ThumbnailUrl: thumbnailUrl("abcde/fghij", "../files/thumb/1.jpg")},
+		{ThumbnailUrl: thumbnailUrl("abcde/fghij", "2.jpg")},
+		{ImageUrls: []string{"3.jpg"}},
+	}}
+
+	thumbnail, ok := b.ThumbnailImage(2)
+	if !ok || thumbnail.Url != "https://online.fliphtml5.com/abcde/fghij/files/thumb/2.jpg" || thumbnail.OverallOrder != 3 {
+		testing.Errorf("unexpected thumbnail %+v", thumbnail)
+	}
+	if b.Pages[0].ThumbnailUrl != "https://online.fliphtml5.com/abcde/fghij/files/thumb/1.jpg" {
+		testing.Errorf("unexpected thumbnail url %s", b.Pages[0].ThumbnailUrl)
+	}
+	if _, ok := b.ThumbnailImage(3); ok {
+		testing.Errorf("expected no thumbnail for page 3")
+	}
+}
@@ -0,0 +1,163 @@
+package book
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/chromedp/chromedp"
+	"github.com/ztrue/tracerr"
+)
+
+// maxPopups limits how many triggers are tried on a page, each one takes a couple of seconds
+const maxPopups = 20
+
+// popupSelectors match the containers of popups and lightboxes
+const popupSelectors = `[class*="popup"], [class*="lightbox"], [class*="modal"], [class*="dialog"], [role="dialog"]`
+
+// popupHelpersScript defines the functions shared by the popup scripts
+const popupHelpersScript = `
+	const popupSelectors = '` + popupSelectors + `';
+	const isVisible = el => {
+		const style = window.getComputedStyle(el);
+		const rect = el.getBoundingClientRect();
+		return style.display !== 'none' && style.visibility !== 'hidden' && style.opacity !== '0' && rect.width > 50 && rect.height > 50;
+	};
+	const visiblePopups = () => Array.from(document.querySelectorAll(popupSelectors)).filter(isVisible);
+`
+
+// markPopupTriggersScript marks the triggers on the captured side of the spread and returns how many there are
+const markPopupTriggersScript = `(() => {
+	const side = %q;
+	const pages = (` + visiblePagesScript + `)();
+	const root = side === "right" && pages.length >= 2 ? pages[1] : (pages[0] || document.body);
+	const triggers = Array.from(root.querySelectorAll('.leo-action-trigger, [id^="E+_Rectangle_"]'));
+	triggers.forEach((trigger, i) => trigger.setAttribute('data-fh5dl-trigger', i));
+	return triggers.length;
+})()`
+
+// clickTriggerScript remembers the popups that are already open and clicks a trigger
+const clickTriggerScript = `(() => {` + popupHelpersScript + `
+	window.fh5dlOpenPopups = new Set(visiblePopups());
+	const trigger = document.querySelector('[data-fh5dl-trigger="%d"]');
+	if (!trigger) {
+		return false;
+	}
+	trigger.click();
+	return true;
+})()`
+
+// markPopupScript marks the largest popup opened by the click, as its parts may match the selectors as well
+const markPopupScript = `(() => {` + popupHelpersScript + `
+	const area = el => el.getBoundingClientRect().width * el.getBoundingClientRect().height;
+	const opened = visiblePopups().filter(el => !window.fh5dlOpenPopups.has(el)).sort((a, b) => area(b) - area(a));
+	if (opened.length === 0) {
+		return false;
+	}
+	opened[0].setAttribute('data-fh5dl-popup', '');
+	return true;
+})()`
+
+// closePopupScript closes the marked popup with its close button or the escape key
+const closePopupScript = `(() => {
+	const popup = document.querySelector('[data-fh5dl-popup]');
+	if (!popup) {
+		return false;
+	}
+	const close = popup.querySelector('[class*="close"], [aria-label*="lose"]');
+	if (close) {
+		close.click();
+	}
+	document.dispatchEvent(new KeyboardEvent('keydown', {key: 'Escape', keyCode: 27, bubbles: true}));
+	return true;
+})()`
+
+// hidePopupScript hides the marked popup if closing it didn't work, so it doesn't cover the page
+const hidePopupScript = `(() => {` + popupHelpersScript + `
+	const popup = document.querySelector('[data-fh5dl-popup]');
+	if (!popup) {
+		return false;
+	}
+	if (isVisible(popup)) {
+		popup.style.display = 'none';
+	}
+	popup.removeAttribute('data-fh5dl-popup');
+	return true;
+})()`
+
+// capturePopups clicks the triggers of the page one by one and takes a screenshot of every popup they open
+func capturePopups(side string, popups *[][]byte) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		*popups = nil
+
+		var count int
+		if err := chromedp.EvaluateAsDevTools(fmt.Sprintf(markPopupTriggersScript, side), &count).Do(ctx); err != nil {
+			return err
+		}
+
+		for i := 0; i < count && len(*popups) < maxPopups; i++ {
+			var clicked, opened bool
+			err := chromedp.Tasks{
+				chromedp.EvaluateAsDevTools(fmt.Sprintf(clickTriggerScript, i), &clicked),
+				chromedp.Sleep(time.Second),
+				chromedp.EvaluateAsDevTools(markPopupScript, &opened),
+			}.Do(ctx)
+			if err != nil {
+				return err
+			}
+
+			if !opened {
+				continue
+			}
+
+			var buf []byte
+			var closed bool
+			err = chromedp.Tasks{
+				screenshotElement("[data-fh5dl-popup]", &buf),
+				chromedp.EvaluateAsDevTools(closePopupScript, &closed),
+				chromedp.Sleep(500 * time.Millisecond),
+				chromedp.EvaluateAsDevTools(hidePopupScript, &closed),
+			}.Do(ctx)
+			if err != nil {
+				return err
+			}
+
+			*popups = append(*popups, buf)
+		}
+
+		return nil
+	})
+}
+
+// popupPath returns the path of the nth popup of a page, zero padded so the files sort in order
+func popupPath(outputFolder string, pageNumber int, n int) string {
+	return filepath.Join(outputFolder, fmt.Sprintf("interactive-%d-popup-%02d.png", pageNumber, n))
+}
+
+// PopupFiles returns the popup captures of a page that are already on disk
+func PopupFiles(outputFolder string, pageNumber int) []string {
+	files, _ := filepath.Glob(filepath.Join(outputFolder, fmt.Sprintf("interactive-%d-popup-*.png", pageNumber)))
+	sort.Strings(files)
+	return files
+}
+
+// writePopups saves the popup captures of a page, replacing the ones of an earlier capture
+func writePopups(outputFolder string, pageNumber int, popups [][]byte) ([]string, error) {
+	for _, stale := range PopupFiles(outputFolder, pageNumber) {
+		os.Remove(stale)
+	}
+
+	paths := make([]string, 0, len(popups))
+	for i, popup := range popups {
+		path := popupPath(outputFolder, pageNumber, i+1)
+		if err := os.WriteFile(path, popup, 0644); err != nil {
+			return nil, tracerr.Wrap(err)
+		}
+		paths = append(paths, path)
+	}
+
+	return paths, nil
+}
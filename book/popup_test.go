@@ -0,0 +1,39 @@
+package book
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWritePopups(testing *testing.T) {
+	dir := testing.TempDir()
+
+	// a stale popup of an earlier capture and a popup of page 11, which must be left alone
+	os.WriteFile(popupPath(dir, 1, 3), []byte("stale"), 0644)
+	os.WriteFile(popupPath(dir, 11, 1), []byte("other"), 0644)
+
+	popups := make([][]byte, 0)
+	for i := 0; i < 10; i++ {
+		popups = append(popups, []byte("popup"))
+	}
+
+	paths, err := writePopups(dir, 1, popups)
+	if err != nil {
+		testing.Fatalf("unexpected error: %v", err)
+	}
+
+	existing := PopupFiles(dir, 1)
+	if strings.Join(existing, ",") != strings.Join(paths, ",") {
+		testing.Fatalf("expected %v, got %v", paths, existing)
+	}
+
+	if filepath.Base(existing[9]) != "interactive-1-popup-10.png" {
+		testing.Fatalf("expected the popups in order, got %v", existing)
+	}
+
+	if len(PopupFiles(dir, 11)) != 1 {
+		testing.Fatalf("expected the popup of page 11 to be kept")
+	}
+}
@@ -0,0 +1,171 @@
+package book
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	cdppage "github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+	"github.com/ztrue/tracerr"
+)
+
+// Frame is a screencast frame saved to disk, shown for Duration in the recording
+type Frame struct {
+	Path     string
+	Duration time.Duration
+}
+
+// frameRecorder saves the screencast frames of a tab as they arrive
+type frameRecorder struct {
+	ctx       context.Context
+	framesDir string
+
+	mu     sync.Mutex
+	paths  []string
+	times  []time.Time
+	err    error
+	stored sync.WaitGroup
+}
+
+// listen is a chromedp target listener. Frames have to be acknowledged, which is a CDP call, so they are handled
+// outside the listener.
+func (r *frameRecorder) listen(ev interface{}) {
+	frame, ok := ev.(*cdppage.EventScreencastFrame)
+	if !ok {
+		return
+	}
+
+	receivedAt := time.Now()
+	r.stored.Add(1)
+	go func() {
+		defer r.stored.Done()
+		r.store(frame, receivedAt)
+		cdppage.ScreencastFrameAck(frame.SessionID).Do(cdpExecutor(r.ctx))
+	}()
+}
+
+func (r *frameRecorder) store(frame *cdppage.EventScreencastFrame, receivedAt time.Time) {
+	data, err := base64.StdEncoding.DecodeString(frame.Data)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	path := filepath.Join(r.framesDir, fmt.Sprintf("frame-%06d.jpg", len(r.paths)+1))
+	if err == nil {
+		err = os.WriteFile(path, data, 0644)
+	}
+	if err != nil {
+		if r.err == nil {
+			r.err = tracerr.Wrap(err)
+		}
+		return
+	}
+
+	r.paths = append(r.paths, path)
+	r.times = append(r.times, receivedAt)
+}
+
+// frames waits for the pending frames and returns them with the time each one was on screen until end
+func (r *frameRecorder) frames(end time.Time) ([]Frame, error) {
+	r.stored.Wait()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	return frameDurations(r.paths, r.times, end), nil
+}
+
+// frameDurations turns the receive times of the frames into display durations, as Chrome only sends a frame when
+// something on the screen changes. Frames that arrive out of order are shown for no time at all.
+func frameDurations(paths []string, times []time.Time, end time.Time) []Frame {
+	frames := make([]Frame, len(paths))
+	for i, path := range paths {
+		next := end
+		if i+1 < len(times) {
+			next = times[i+1]
+		}
+
+		duration := next.Sub(times[i])
+		if duration < 0 {
+			duration = 0
+		}
+		frames[i] = Frame{Path: path, Duration: duration}
+	}
+
+	return frames
+}
+
+// cdpExecutor returns a context for running CDP commands on the tab of ctx directly
+func cdpExecutor(ctx context.Context) context.Context {
+	c := chromedp.FromContext(ctx)
+	if c == nil || c.Target == nil {
+		return ctx
+	}
+
+	return cdp.WithExecutor(ctx, c.Target)
+}
+
+// Record opens the pages one after the other in the viewer, keeps each on screen for pageDuration and saves the
+// screencast of the tab as JPEG frames in framesDir. onPage is called after every page and may be nil.
+func Record(ctx context.Context, pageUrls []string, framesDir string, pageDuration time.Duration, options CaptureOptions, onPage func()) ([]Frame, error) {
+	options = options.withDefaults()
+	if len(pageUrls) == 0 {
+		return nil, fmt.Errorf("no pages to record")
+	}
+
+	tabCtx, closeTab, err := options.openTab(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer closeTab()
+
+	recorder := &frameRecorder{ctx: tabCtx, framesDir: framesDir}
+	chromedp.ListenTarget(tabCtx, recorder.listen)
+
+	err = chromedp.Run(tabCtx,
+		options.Viewport.emulate(),
+		chromedp.Navigate(pageUrls[0]),
+
+		// Wait for the viewer to load before the recording starts
+		chromedp.Sleep(3*time.Second),
+
+		cdppage.StartScreencast().WithFormat(cdppage.ScreencastFormatJpeg).WithQuality(85),
+	)
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+
+	for i, pageUrl := range pageUrls {
+		// Changing the location instead of navigating lets the viewer flip the page with its own animation
+		if i > 0 {
+			if err := chromedp.Run(tabCtx, chromedp.EvaluateAsDevTools(fmt.Sprintf("window.location.href = %q", pageUrl), nil)); err != nil {
+				return nil, tracerr.Wrap(err)
+			}
+		}
+
+		if err := chromedp.Run(tabCtx, chromedp.Sleep(pageDuration)); err != nil {
+			return nil, tracerr.Wrap(err)
+		}
+
+		if onPage != nil {
+			onPage()
+		}
+	}
+
+	end := time.Now()
+	if err := chromedp.Run(tabCtx, cdppage.StopScreencast()); err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+
+	return recorder.frames(end)
+}
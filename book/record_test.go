@@ -0,0 +1,19 @@
+package book
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFrameDurations(testing *testing.T) {
+	start := time.Now()
+	times := []time.Time{start, start.Add(2 * time.Second), start.Add(time.Second)}
+	frames := frameDurations([]string{"1.jpg", "2.jpg", "3.jpg"}, times, start.Add(4*time.Second))
+
+	expected := []time.Duration{2 * time.Second, 0, 3 * time.Second}
+	for i, frame := range frames {
+		if frame.Duration != expected[i] {
+			testing.Fatalf("expected frame %d to last %s, got %s", i+1, expected[i], frame.Duration)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package book
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// validators are the response headers a downloaded image can be revalidated with
+type validators struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+}
+
+// validatorsPath is the hidden file next to the image its validators are kept in. It is named after the page and
+// image rather than the image file, so it still applies once the image is converted to another format.
+func (i *PageImage) validatorsPath(outputFolder string) string {
+	return filepath.Join(outputFolder, "."+i.FileName("json"))
+}
+
+// readValidators returns the validators saved with the image, if the server sent any
+func (i *PageImage) readValidators(outputFolder string) (validators, bool) {
+	data, err := os.ReadFile(i.validatorsPath(outputFolder))
+	if err != nil {
+		return validators{}, false
+	}
+
+	v := validators{}
+	if err := json.Unmarshal(data, &v); err != nil || v == (validators{}) {
+		return validators{}, false
+	}
+
+	return v, true
+}
+
+// writeValidators saves the validators of the response the image was downloaded with, or removes stale ones if it
+// has none
+func (i *PageImage) writeValidators(outputFolder string, header http.Header) error {
+	path := i.validatorsPath(outputFolder)
+	v := validators{ETag: header.Get("ETag"), LastModified: header.Get("Last-Modified")}
+	if v == (validators{}) {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0644)
+}
+
+// setConditional makes the request only return the image if it changed since it was saved
+func (v validators) setConditional(req *http.Request) {
+	if v.ETag != "" {
+		req.Header.Set("If-None-Match", v.ETag)
+	}
+	if v.LastModified != "" {
+		req.Header.Set("If-Modified-Since", v.LastModified)
+	}
+}
@@ -0,0 +1,172 @@
+package book
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ztrue/tracerr"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultRevealScript reveals hidden FlipHTML5 texts and clicks the triggers that show answers before a page is captured
+const DefaultRevealScript = `
+(() => {
+	// Find and make all text elements visible
+	document.querySelectorAll('[id^="E+_Text_"], .leo-comp--txt').forEach(el => {
+		if (window.getComputedStyle(el).opacity === '0') {
+			el.style.opacity = '1';
+			if (window.getComputedStyle(el).visibility === 'hidden') {
+				el.style.visibility = 'visible';
+			}
+			if (window.getComputedStyle(el).display === 'none') {
+				el.style.display = '';
+			}
+		}
+	});
+	
+	// Find and click all rectangle triggers
+	document.querySelectorAll('[id^="E+_Rectangle_"], .leo-comp--shape-rect.leo-action-trigger').forEach(rect => {
+		try {
+			let needsTemp = false;
+			if (window.getComputedStyle(rect).opacity === '0') {
+				rect.style.opacity = '0.01';
+				needsTemp = true;
+			}
+			if (rect.click) {
+				rect.click();
+			}
+			// Don't revert opacity - keep the results visible
+		} catch (e) {
+			console.error("Error clicking element:", e);
+		}
+	});
+	
+	return "Revealed hidden elements";
+})()
+`
+
+// RevealConfig lists selectors for publishers whose hidden content the built-in reveal script doesn't find
+type RevealConfig struct {
+	Reveal  []string `yaml:"reveal"`  // elements to make visible
+	Click   []string `yaml:"click"`   // triggers to click
+	Hide    []string `yaml:"hide"`    // elements to hide, such as overlays covering the answers
+	Script  string   `yaml:"script"`  // javascript file to run after the selectors, relative to the config file
+	Replace bool     `yaml:"replace"` // skip the built-in reveal script
+}
+
+// LoadRevealScript builds the reveal script from a javascript file or a YAML selectors config (.yaml or .yml).
+// The custom steps run after the built-in script, unless the config sets replace.
+func LoadRevealScript(path string) (string, error) {
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext != ".yaml" && ext != ".yml" {
+		custom, err := readScript(path)
+		if err != nil {
+			return "", err
+		}
+
+		return DefaultRevealScript + ";\n" + custom, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", tracerr.Wrap(err)
+	}
+
+	var config RevealConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return "", fmt.Errorf("invalid reveal config %s: %w", path, err)
+	}
+
+	if config.Script != "" && !filepath.IsAbs(config.Script) {
+		config.Script = filepath.Join(filepath.Dir(path), config.Script)
+	}
+
+	return config.script()
+}
+
+// script turns the config into the javascript to run on each page
+func (c *RevealConfig) script() (string, error) {
+	steps := make([]string, 0)
+	if !c.Replace {
+		steps = append(steps, DefaultRevealScript)
+	}
+
+	if len(c.Reveal) > 0 || len(c.Click) > 0 || len(c.Hide) > 0 {
+		steps = append(steps, fmt.Sprintf(selectorsScript, jsonList(c.Reveal), jsonList(c.Click), jsonList(c.Hide)))
+	}
+
+	if c.Script != "" {
+		custom, err := readScript(c.Script)
+		if err != nil {
+			return "", err
+		}
+		steps = append(steps, custom)
+	}
+
+	if len(steps) == 0 {
+		return "", fmt.Errorf("the reveal config replaces the built-in script but has no selectors or script")
+	}
+
+	return strings.Join(steps, ";\n"), nil
+}
+
+// readScript reads a custom javascript file, wrapped in a function so its variables and return statements stay local
+func readScript(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", tracerr.Wrap(err)
+	}
+
+	return "(() => {\n" + string(data) + "\n})()", nil
+}
+
+func jsonList(selectors []string) string {
+	if selectors == nil {
+		selectors = []string{}
+	}
+
+	data, _ := json.Marshal(selectors)
+	return string(data)
+}
+
+// selectorsScript reveals, clicks and hides the elements of a RevealConfig. A bad selector is logged and skipped.
+const selectorsScript = `
+(() => {
+	const reveal = %s;
+	const click = %s;
+	const hide = %s;
+
+	const each = (selectors, fn) => selectors.forEach(selector => {
+		try {
+			document.querySelectorAll(selector).forEach(fn);
+		} catch (e) {
+			console.error("Error with selector:", selector, e);
+		}
+	});
+
+	if (hide.length > 0) {
+		const style = document.createElement('style');
+		style.textContent = hide.map(selector => selector + ' { display: none !important; }').join('\n');
+		document.head.appendChild(style);
+	}
+
+	each(reveal, el => {
+		el.style.opacity = '1';
+		el.style.visibility = 'visible';
+		if (window.getComputedStyle(el).display === 'none') {
+			el.style.display = 'block';
+		}
+	});
+
+	each(click, el => {
+		if (el.click) {
+			el.click();
+		}
+	});
+
+	return "Revealed custom selectors";
+})()
+`
@@ -0,0 +1,55 @@
+package book
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadRevealScriptJavascript(testing *testing.T) {
+	path := filepath.Join(testing.TempDir(), "custom.js")
+	os.WriteFile(path, []byte("document.body.click();"), 0644)
+
+	script, err := LoadRevealScript(path)
+	if err != nil {
+		testing.Fatalf("unexpected error: %v", err)
+	}
+
+	if !strings.HasPrefix(script, DefaultRevealScript) || !strings.Contains(script, "document.body.click();") {
+		testing.Fatalf("expected the custom script to extend the built-in one, got %s", script)
+	}
+}
+
+func TestLoadRevealScriptConfig(testing *testing.T) {
+	dir := testing.TempDir()
+	os.WriteFile(filepath.Join(dir, "extra.js"), []byte("console.log('extra');"), 0644)
+
+	path := filepath.Join(dir, "publisher.yaml")
+	config := "replace: true\nclick:\n  - .show-answer\nhide:\n  - '#overlay'\nscript: extra.js\n"
+	os.WriteFile(path, []byte(config), 0644)
+
+	script, err := LoadRevealScript(path)
+	if err != nil {
+		testing.Fatalf("unexpected error: %v", err)
+	}
+
+	if strings.Contains(script, DefaultRevealScript) {
+		testing.Fatalf("expected the built-in script to be replaced")
+	}
+
+	for _, expected := range []string{`const click = [".show-answer"];`, `const hide = ["#overlay"];`, "console.log('extra');"} {
+		if !strings.Contains(script, expected) {
+			testing.Fatalf("expected the script to contain %s, got %s", expected, script)
+		}
+	}
+}
+
+func TestLoadRevealScriptEmptyReplace(testing *testing.T) {
+	path := filepath.Join(testing.TempDir(), "empty.yml")
+	os.WriteFile(path, []byte("replace: true\n"), 0644)
+
+	if _, err := LoadRevealScript(path); err == nil {
+		testing.Fatalf("expected an error for a config without any steps")
+	}
+}
@@ -0,0 +1,52 @@
+package book
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+)
+
+// Revision fingerprints the page images of the book. The config has no dependable edition number or timestamp, but
+// publishers replacing pages also replace their image URLs, so a changed revision means a changed book.
+func (b *Book) Revision() string {
+	hash := sha256.New()
+	for _, page := range b.Pages {
+		fmt.Fprintf(hash, "%d", page.Number)
+		for _, url := range page.ImageUrls {
+			fmt.Fprintf(hash, " %s", url)
+		}
+		fmt.Fprintln(hash)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))[:16]
+}
+
+// PageImageUrls returns the image URLs of every page by page number
+func (b *Book) PageImageUrls() map[int][]string {
+	urls := make(map[int][]string, len(b.Pages))
+	for _, page := range b.Pages {
+		urls[page.Number] = page.ImageUrls
+	}
+
+	return urls
+}
+
+// RemoveCached deletes the image from the folder in whatever format it was saved, so it is downloaded again
+func (i *PageImage) RemoveCached(outputFolder string) error {
+	for {
+		path, ok := i.CachedPath(outputFolder)
+		if !ok {
+			break
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+	}
+
+	if err := os.Remove(i.validatorsPath(outputFolder)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,19 @@
+//go:build !windows
+
+package book
+
+import (
+	"syscall"
+
+	"github.com/ztrue/tracerr"
+)
+
+// SharedMemorySize returns the size of /dev/shm, which containers often keep too small for Chrome
+func SharedMemorySize() (int64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(sharedMemoryPath, &stat); err != nil {
+		return 0, tracerr.Wrap(err)
+	}
+
+	return int64(stat.Blocks) * int64(stat.Bsize), nil
+}
@@ -0,0 +1,10 @@
+//go:build windows
+
+package book
+
+import "fmt"
+
+// SharedMemorySize returns the size of /dev/shm, which Windows doesn't have
+func SharedMemorySize() (int64, error) {
+	return 0, fmt.Errorf("%s is not available on windows", sharedMemoryPath)
+}
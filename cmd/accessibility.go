@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	pdfcpu_api "github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+	"github.com/ztrue/tracerr"
+)
+
+// pageAltText returns the alt text of the PDF page of an image: the description of --caption-url followed by the
+// text read with --ocr-cmd, or its place in the document for pages without either, such as contents pages
+func pageAltText(imageFile string, pageIndex int) string {
+	parts := make([]string, 0, 2)
+	for _, path := range []string{captionPath(imageFile), ocrTextPath(imageFile)} {
+		if text, err := os.ReadFile(path); err == nil && len(text) > 0 {
+			parts = append(parts, string(text))
+		}
+	}
+	if len(parts) == 0 {
+		return fmt.Sprintf("Page %d, an image without text", pageIndex)
+	}
+
+	return strings.Join(parts, "\n\n")
+}
+
+// tagPdf makes the PDF a tagged PDF for screen readers. Every page is an image, so it is marked as a figure whose alt
+// text describes the page, and the figures are put into a document in the order of the pages, which is the order they
+// are read in. Pages are imported one per image, so the images give the pages in order.
+func tagPdf(pdfPath string, imageFiles []string) error {
+	ctx, err := pdfcpu_api.ReadContextFile(pdfPath)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+	if ctx.PageCount != len(imageFiles) {
+		return fmt.Errorf("can't tag %s, it has %d pages for %d images", pdfPath, ctx.PageCount, len(imageFiles))
+	}
+
+	root, err := ctx.Catalog()
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	structRoot := types.Dict{"Type": types.Name("StructTreeRoot")}
+	structRootRef, err := ctx.IndRefForNewObject(structRoot)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+	document := types.Dict{"Type": types.Name("StructElem"), "S": types.Name("Document"), "P": *structRootRef}
+	documentRef, err := ctx.IndRefForNewObject(document)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	figures := types.Array{}
+	parents := types.Array{}
+	for i, imageFile := range imageFiles {
+		pageDict, pageRef, _, err := ctx.PageDict(i+1, false)
+		if err != nil {
+			return tracerr.Wrap(err)
+		}
+
+		// the content of the page is marked as the only figure on it
+		content, err := ctx.PageContent(pageDict)
+		if err != nil {
+			return tracerr.Wrap(err)
+		}
+		marked := append([]byte("/Figure <</MCID 0>> BDC\n"), content...)
+		marked = append(marked, []byte("\nEMC\n")...)
+		contentRef, err := ctx.StreamDictIndRef(marked)
+		if err != nil {
+			return tracerr.Wrap(err)
+		}
+
+		alt, err := types.EscapeUTF16String(pageAltText(imageFile, i+1))
+		if err != nil {
+			return tracerr.Wrap(err)
+		}
+		figureRef, err := ctx.IndRefForNewObject(types.Dict{
+			"Type": types.Name("StructElem"),
+			"S":    types.Name("Figure"),
+			"P":    *documentRef,
+			"Pg":   *pageRef,
+			"K":    types.Integer(0),
+			"Alt":  types.StringLiteral(*alt),
+		})
+		if err != nil {
+			return tracerr.Wrap(err)
+		}
+
+		pageDict["Contents"] = *contentRef
+		pageDict["StructParents"] = types.Integer(i)
+		pageDict["Tabs"] = types.Name("S")
+		figures = append(figures, *figureRef)
+		parents = append(parents, types.Integer(i), types.Array{*figureRef})
+	}
+
+	document["K"] = figures
+	structRoot["K"] = *documentRef
+	structRoot["ParentTree"] = types.Dict{"Nums": parents}
+	structRoot["ParentTreeNextKey"] = types.Integer(len(imageFiles))
+	root["StructTreeRoot"] = *structRootRef
+	root["MarkInfo"] = types.Dict{"Marked": types.Boolean(true)}
+	root["ViewerPreferences"] = types.Dict{"DisplayDocTitle": types.Boolean(true)}
+
+	return tracerr.Wrap(pdfcpu_api.WriteContextFile(ctx, pdfPath))
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"os"
+	"path/filepath"
+	"testing"
+
+	pdfcpu_api "github.com/pdfcpu/pdfcpu/pkg/api"
+	book "github.com/ygunayer/fh5dl/book"
+)
+
+func TestTagPdf(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "1-1.png")
+	second := filepath.Join(dir, "2-1.png")
+	writeTestPng(t, first, image.Pt(200, 100))
+	writeTestPng(t, second, image.Pt(200, 100))
+	if err := os.WriteFile(ocrTextPath(first), []byte("Kapitel 1\nÜbungen"), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	pdfPath := filepath.Join(dir, "book.pdf")
+	if err := generatePDF([]string{first, second}, pdfPath); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := tagPdf(pdfPath, []string{first, second}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// the metadata is written after tagging, which has to keep the tags
+	args := &Args{}
+	if err := setDocumentInfo(pdfPath, args.documentInfo(&book.Book{Title: "Übungsbuch", Language: "de"})); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, err := pdfcpu_api.ReadContextFile(pdfPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	root, err := ctx.Catalog()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if marked := root.DictEntry("MarkInfo").BooleanEntry("Marked"); marked == nil || !*marked {
+		t.Errorf("expected the PDF to be marked as tagged, got %v", root["MarkInfo"])
+	}
+
+	structRoot, err := ctx.DereferenceDict(root["StructTreeRoot"])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	document, err := ctx.DereferenceDict(structRoot["K"])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	figures, err := ctx.DereferenceArray(document["K"])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(figures) != 2 {
+		t.Fatalf("expected a figure for each page, got %v", figures)
+	}
+
+	expected := []string{"Kapitel 1\nÜbungen", "Page 2, an image without text"}
+	for i, figureRef := range figures {
+		figure, err := ctx.DereferenceDict(figureRef)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		alt, err := ctx.DereferenceText(figure["Alt"])
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if alt != expected[i] {
+			t.Errorf("expected the alt text of page %d to be %q, got %q", i+1, expected[i], alt)
+		}
+
+		pageDict, _, _, err := ctx.PageDict(i+1, false)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		content, err := ctx.PageContent(pageDict)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !bytes.HasPrefix(content, []byte("/Figure <</MCID 0>> BDC")) {
+			t.Errorf("expected the content of page %d to be marked, got %q", i+1, content)
+		}
+	}
+}
+
+func TestPageAltText(t *testing.T) {
+	imageFile := filepath.Join(t.TempDir(), "1-1.jpg")
+	if alt := pageAltText(imageFile, 4); alt != "Page 4, an image without text" {
+		t.Errorf("unexpected alt text %q", alt)
+	}
+
+	if err := os.WriteFile(ocrTextPath(imageFile), []byte("Sales by year"), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := os.WriteFile(captionPath(imageFile), []byte("A bar chart."), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if alt := pageAltText(imageFile, 4); alt != "A bar chart.\n\nSales by year" {
+		t.Errorf("expected the description before the text, got %q", alt)
+	}
+}
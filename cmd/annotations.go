@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+
+	pdfcpu_api "github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+	book "github.com/ygunayer/fh5dl/book"
+	"github.com/ztrue/tracerr"
+)
+
+// annotationTitle is the author shown on the notes added to the PDF
+const annotationTitle = "fh5dl"
+
+// addAnnotations adds the notes saved with the captures as PDF text annotations on their pages. Pages are imported
+// at the size of their image, one point per pixel, so the image size gives the page size.
+func addAnnotations(pdfPath string, imageFiles []string) error {
+	annotations := make(map[int][]model.AnnotationRenderer)
+	for i, imageFile := range imageFiles {
+		notes, err := book.ReadAnnotations(imageFile)
+		if err != nil {
+			return err
+		}
+		if len(notes) == 0 {
+			continue
+		}
+
+		size, err := imageSize(imageFile)
+		if err != nil {
+			return err
+		}
+
+		for n, note := range notes {
+			id := fmt.Sprintf("fh5dl-note-%d-%d", i+1, n+1)
+			annotations[i+1] = append(annotations[i+1], textAnnotation(note, float64(size.X), float64(size.Y), id))
+		}
+	}
+
+	if len(annotations) == 0 {
+		return nil
+	}
+
+	return tracerr.Wrap(pdfcpu_api.AddAnnotationsMapFile(pdfPath, "", annotations, model.NewDefaultConfiguration(), false))
+}
+
+// textAnnotation places a note on a page of the given size. PDF coordinates start at the bottom left corner.
+func textAnnotation(note book.Annotation, pageWidth float64, pageHeight float64, id string) model.TextAnnotation {
+	rect := types.NewRectangle(
+		note.X*pageWidth,
+		(1-note.Y-note.Height)*pageHeight,
+		(note.X+note.Width)*pageWidth,
+		(1-note.Y)*pageHeight,
+	)
+
+	return model.NewTextAnnotation(*rect, note.Text, id, annotationTitle, 0, nil, nil, "", "", false, "Note")
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	pdfcpu_api "github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	book "github.com/ygunayer/fh5dl/book"
+)
+
+func TestAddAnnotations(t *testing.T) {
+	dir := t.TempDir()
+
+	files := make([]string, 0)
+	for i := 1; i <= 2; i++ {
+		path := filepath.Join(dir, fmt.Sprintf("interactive-%d.png", i))
+		file, err := os.Create(path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		png.Encode(file, image.NewRGBA(image.Rect(0, 0, 200, 100)))
+		file.Close()
+
+		files = append(files, path)
+	}
+
+	// only the second page has a note
+	notes, _ := json.Marshal([]book.Annotation{{Text: "Remember this", X: 0.5, Y: 0, Width: 0.25, Height: 0.5}})
+	os.WriteFile(book.AnnotationsPath(files[1]), notes, 0644)
+
+	pdfPath := filepath.Join(dir, "book.pdf")
+	if err := generatePDF(files, pdfPath); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := addAnnotations(pdfPath, files); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	file, err := os.Open(pdfPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer file.Close()
+
+	annotations, err := pdfcpu_api.Annotations(file, nil, model.NewDefaultConfiguration())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, ok := annotations[1]; ok {
+		t.Errorf("expected no annotations on page 1")
+	}
+
+	page, ok := annotations[2]
+	if !ok {
+		t.Fatalf("expected annotations on page 2, got %v", annotations)
+	}
+
+	for _, annots := range page {
+		for _, annot := range annots.Map {
+			rect := annot.RectString()
+			if annot.ContentString() != "Remember this" || rect != "(100,  50, 150, 100)" {
+				t.Errorf("unexpected annotation %q at %s", annot.ContentString(), rect)
+			}
+		}
+	}
+}
@@ -0,0 +1,385 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	book "github.com/ygunayer/fh5dl/book"
+	"github.com/ygunayer/fh5dl/internal/progress"
+	"github.com/ztrue/tracerr"
+)
+
+// batchEntry is a single book queued for a batch download
+type batchEntry struct {
+	Name        string `json:"-"` // where the entry came from, used in log messages
+	Url         string `json:"-"`
+	Interactive bool   `json:"-"`
+	Replace     bool   `json:"-"` // the book was written without some of its pages, and its output is replaced
+
+	// output options of manifest entries, overriding the ones of the batch when set, and kept in the book report
+	// so a retry downloads the book the same way
+	Title    string `json:"title,omitempty"`
+	Series   string `json:"series,omitempty"`
+	Volume   int    `json:"volume,omitempty"`
+	Folder   string `json:"folder,omitempty"`
+	Filename string `json:"filename,omitempty"`
+}
+
+// parseBatchLine turns a line from a url list into a batch entry, returning false for blank lines and comments
+func parseBatchLine(line string) (batchEntry, bool) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return batchEntry{}, false
+	}
+
+	entry := batchEntry{Url: line}
+
+	// a trailing -i enables interactive mode for this entry
+	if strings.HasSuffix(entry.Url, "-i") {
+		entry.Interactive = true
+		entry.Url = strings.TrimSpace(strings.TrimSuffix(entry.Url, "-i"))
+	}
+
+	return entry, true
+}
+
+// readUrlList reads batch entries from a plain text list with one url per line and # comments
+func readUrlList(r io.Reader, source string) ([]batchEntry, error) {
+	entries := make([]batchEntry, 0)
+
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+
+		entry, ok := parseBatchLine(scanner.Text())
+		if !ok {
+			continue
+		}
+
+		entry.Name = fmt.Sprintf("%s:%d", source, lineNumber)
+		entries = append(entries, entry)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+
+	return entries, nil
+}
+
+// readUrlListFile reads batch entries from the given file, or from stdin if the path is "-". Files ending in .yaml
+// or .yml are read as manifests.
+func readUrlListFile(path string) ([]batchEntry, error) {
+	if path == "-" {
+		return readUrlList(os.Stdin, "stdin")
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+	defer file.Close()
+
+	if isManifest(path) {
+		return readManifest(file, filepath.Base(path))
+	}
+
+	return readUrlList(file, filepath.Base(path))
+}
+
+// readBooksDirectory reads batch entries from every .txt file and manifest in the books directory. Files that can't
+// be read are reported and left out. Every line of a .txt file is read, not only the first, as books/example.txt
+// starts with comments and the books editor of the terminal UI adds books to books/books.txt one per line.
+func readBooksDirectory(booksDir string, reporter progress.Reporter) ([]batchEntry, error) {
+	files, err := os.ReadDir(booksDir)
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+
+	entries := make([]batchEntry, 0)
+	for _, file := range files {
+		if file.IsDir() || !(strings.HasSuffix(file.Name(), ".txt") || isManifest(file.Name())) {
+			continue
+		}
+
+		fileEntries, err := readUrlListFile(filepath.Join(booksDir, file.Name()))
+		if err != nil {
+			reporter.Logf(progress.LevelError, "Cannot read file %s: %v", file.Name(), err)
+			continue
+		}
+
+		// keep the plain file name for single-url files, which is what most book files contain
+		if len(fileEntries) == 1 && !isManifest(file.Name()) {
+			fileEntries[0].Name = file.Name()
+		}
+
+		entries = append(entries, fileEntries...)
+	}
+
+	return entries, nil
+}
+
+// applyEntry applies the output options of a manifest entry
+func (args *Args) applyEntry(entry batchEntry) {
+	if entry.Replace {
+		args.Force, args.Update = true, false
+	}
+	if entry.Title != "" {
+		args.Title = entry.Title
+	}
+	if entry.Series != "" {
+		args.Series = entry.Series
+	}
+	if entry.Volume > 0 {
+		args.Volume = entry.Volume
+	}
+	if entry.Folder != "" {
+		args.Folder = entry.Folder
+	}
+	if entry.Filename != "" {
+		args.Filename = entry.Filename
+	}
+}
+
+// skippedBookReport creates the report of a book that was skipped before downloading
+func skippedBookReport(url string) *bookReport {
+	report := newBookReport(url)
+	report.Status = reportStatusSkipped
+	report.finish(nil)
+
+	return report
+}
+
+// stdinIsPiped reports whether stdin is connected to a pipe or file rather than a terminal
+func stdinIsPiped() bool {
+	stat, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+
+	return stat.Mode()&os.ModeCharDevice == 0
+}
+
+// runBatch downloads every entry in order, each into its own folder under the output folder.
+// Every download starts from a copy of base, which carries the options shared by the whole batch.
+func runBatch(entries []batchEntry, base Args) error {
+	_, err := runBatchReport(entries, base)
+	return err
+}
+
+// runBatchReport is runBatch, also returning the report of the batch
+func runBatchReport(entries []batchEntry, base Args) (*batchReport, error) {
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no book urls to download")
+	}
+
+	info := color.New(color.FgCyan).SprintFunc()
+	success := color.New(color.FgGreen).SprintFunc()
+	warning := color.New(color.FgYellow).SprintFunc()
+
+	// every book of the batch reports to the same place
+	reporter := base.reporter()
+
+	// Display batch statistics
+	reporter.Logf(progress.LevelInfo, "%s Found %d books to download", info("INFO:"), len(entries))
+	reporter.Logf(progress.LevelInfo, "%s Using concurrency: %d", info("INFO:"), base.Concurrency)
+	reporter.Logf(progress.LevelInfo, "%s Output folder: %s", info("INFO:"), base.OutputFolder)
+	if base.BatchSize > 0 {
+		reporter.Logf(progress.LevelInfo, "%s Batch size for interactive captures: %d", info("INFO:"), base.BatchSize)
+	}
+
+	// Create output folder if it doesn't exist
+	if _, err := os.Stat(base.OutputFolder); os.IsNotExist(err) {
+		if err := os.MkdirAll(base.OutputFolder, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create output folder: %w", err)
+		}
+	}
+
+	// Process each entry
+	failedDownloads := 0
+	successfulDownloads := 0
+	skippedDownloads := 0
+
+	// Track start time for ETA calculation
+	startTime := time.Now()
+	options := base
+	summary := &batchReport{OutputFolder: base.OutputFolder, StartedAt: startTime, Options: &options}
+	if absOutputFolder, err := filepath.Abs(base.OutputFolder); err == nil {
+		summary.OutputFolder = absOutputFolder
+	}
+
+	// Create a map to track downloaded URLs to avoid duplicates
+	downloadedURLs := make(map[string]bool)
+
+	// Other terminals can pause, resume or cancel the batch, and batches take turns by priority
+	job := startJob(base, len(entries), reporter)
+	defer job.Finish()
+	if job != nil {
+		reporter.Logf(progress.LevelInfo, "%s Running as job %s, pause it with: fh5dl jobs pause %s", info("INFO:"), job.Id(), job.Id())
+	}
+	logf := func(format string, args ...interface{}) {
+		reporter.Logf(progress.LevelInfo, "%s %s", info("JOB:"), fmt.Sprintf(format, args...))
+	}
+	notStarted := 0
+
+	for i, entry := range entries {
+		if base.control.Stopped() || !job.Turn(context.Background(), logf) {
+			for _, rest := range entries[i:] {
+				summary.add(skippedBookReport(rest.Url))
+			}
+			notStarted = len(entries) - i
+			skippedDownloads += notStarted
+			break
+		}
+		job.Progress(i, entry.Name)
+
+		// Calculate ETA
+		if i > 0 {
+			elapsed := time.Since(startTime)
+			timePerBook := elapsed / time.Duration(i)
+			eta := timePerBook * time.Duration(len(entries)-i)
+			reporter.Logf(progress.LevelInfo, "%s ETA: %s remaining for batch completion (avg: %s per book)",
+				info("TIME:"), formatDuration(eta), formatDuration(timePerBook))
+		}
+
+		url := entry.Url
+
+		// Check if we've already downloaded this URL
+		if _, exists := downloadedURLs[url]; exists {
+			reporter.Logf(progress.LevelInfo, "%s [%d/%d] Skipping %s (Already downloaded this URL)",
+				warning("SKIP:"), i+1, len(entries), entry.Name)
+			skippedDownloads++
+			summary.add(skippedBookReport(url))
+			continue
+		}
+
+		// Extract book ID to use as file name
+		bookID, err := extractBookID(url)
+		if err != nil {
+			// Generate a safe filename from the original name
+			bookID = generateSafeID(entry.Name)
+		}
+
+		// Set up arguments for the download
+		args := base
+		args.Url = url
+		args.Interactive = entry.Interactive
+		args.applyEntry(entry)
+
+		// Create a dedicated folder for this book. Books with a folder template or in a library layout go where
+		// those put them in the output folder instead, and only their images and temp files are kept apart.
+		bookOutputFolder := filepath.Join(base.OutputFolder, bookID)
+		outputFolder := bookOutputFolder
+		if !args.ownFolder() {
+			bookOutputFolder = filepath.Join(base.OutputFolder, libraryWorkFolder, bookID)
+			outputFolder = base.OutputFolder
+		}
+		if _, err := os.Stat(bookOutputFolder); os.IsNotExist(err) {
+			if err := os.MkdirAll(bookOutputFolder, 0755); err != nil {
+				reporter.Logf(progress.LevelError, "Failed to create book output folder: %v", err)
+				failedDownloads++
+				failed := newBookReport(url)
+				failed.Entry = &entry
+				failed.finish(err)
+				summary.add(failed)
+				continue
+			}
+		}
+
+		// Check if the PDF already exists
+		pdfPath := filepath.Join(bookOutputFolder, bookID+base.outputExtension())
+		if args.ownFolder() && args.Filename == "" && args.Title == "" && outputExists(pdfPath) && args.conflictPolicy() == conflictSkip && !args.Update {
+			reporter.Logf(progress.LevelInfo, "%s [%d/%d] Skipping %s (PDF already exists)",
+				warning("SKIP:"), i+1, len(entries), entry.Name)
+			skippedDownloads++
+			summary.add(skippedBookReport(url))
+			continue
+		}
+
+		// Print progress
+		reporter.Logf(progress.LevelInfo, "%s [%d/%d] Downloading: %s", info("INFO:"), i+1, len(entries), entry.Name)
+		if entry.Interactive {
+			reporter.Logf(progress.LevelInfo, "%s Interactive mode enabled", info("INFO:"))
+		}
+		reporter.Logf(progress.LevelInfo, "%s URL: %s", info("INFO:"), url)
+		reporter.Logf(progress.LevelInfo, "%s Output: %s", info("INFO:"), outputFolder)
+
+		args.OutputFolder = outputFolder
+		args.ImageOutputFolder = filepath.Join(bookOutputFolder, "images")
+		args.WorkDir = bookOutputFolder // keep temp files of each book separate
+
+		// Run the download with a timeout to prevent hanging
+		downloadCtx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+		bookStartTime := time.Now()
+		report, err := downloadPdf2(downloadCtx, &args)
+		bookDuration := time.Since(bookStartTime)
+		cancel()
+		report.Entry = &entry
+		summary.add(report)
+
+		if err != nil {
+			reporter.Logf(progress.LevelError, "Failed to download %s: %v", entry.Name, err)
+			if known, ok := classifyError(err); ok {
+				reporter.Logf(progress.LevelError, "%s", known.hint)
+			}
+			failedDownloads++
+
+			// give the site a break instead of getting the next book rate limited too
+			if errors.Is(err, book.ErrRateLimited) && i < len(entries)-1 {
+				reporter.Logf(progress.LevelWarn, "Rate limited, waiting %s before the next book", rateLimitPause)
+				time.Sleep(rateLimitPause)
+			}
+		} else {
+			successfulDownloads++
+			downloadedURLs[url] = true // Mark as downloaded
+			reporter.Logf(progress.LevelInfo, "%s Download completed for %s in %s",
+				success("SUCCESS:"), entry.Name, formatDuration(bookDuration))
+		}
+
+		// Brief pause between downloads to clean up resources
+		if i < len(entries)-1 {
+			reporter.Logf(progress.LevelInfo, "%s Cleaning up resources before next download...", info("INFO:"))
+			time.Sleep(2 * time.Second)
+		}
+	}
+
+	// Show final statistics
+	totalTime := time.Since(startTime)
+	reporter.Logf(progress.LevelInfo, "%s Batch download completed in %s", success("SUCCESS:"), formatDuration(totalTime))
+	reporter.Logf(progress.LevelInfo, "Total books: %d", len(entries))
+	reporter.Logf(progress.LevelInfo, "Successful: %d", successfulDownloads)
+	reporter.Logf(progress.LevelInfo, "Skipped: %d", skippedDownloads)
+	reporter.Logf(progress.LevelInfo, "Failed: %d", failedDownloads)
+	summary.TotalSeconds = totalTime.Seconds()
+	reporter.Logf(progress.LevelInfo, "Downloaded: %s at %s/s", formatBytes(summary.DownloadedBytes), formatBytes(int64(summary.BytesPerSecond)))
+
+	// Write the run-level report so failure details survive the terminal scrollback
+	if reportBase, err := writeBatchReport(summary, base.ReportFormat); err != nil {
+		reporter.Logf(progress.LevelError, "Failed to write batch report: %v", err)
+	} else {
+		if base.ReportFormat != "none" {
+			reporter.Logf(progress.LevelInfo, "%s Batch report written to %s", info("INFO:"), reportBase)
+		}
+		if failedDownloads > 0 || summary.hasIncomplete() {
+			reporter.Logf(progress.LevelInfo, "%s Retry the failed and incomplete books with: fh5dl retry %s.retry.txt", info("INFO:"), reportBase)
+		}
+	}
+
+	if failedDownloads > 0 {
+		return summary, fmt.Errorf("%d of %d downloads failed", failedDownloads, len(entries))
+	}
+	if notStarted > 0 {
+		return summary, fmt.Errorf("batch cancelled, %d of %d books were not started", notStarted, len(entries))
+	}
+
+	return summary, nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ygunayer/fh5dl/internal/progress"
+)
+
+func TestReadUrlList(t *testing.T) {
+	input := `# books for this week
+abcde/fghij
+
+  https://online.fliphtml5.com/abcde/klmno/ -i
+# abcde/skipped
+`
+
+	entries, err := readUrlList(strings.NewReader(input), "urls.txt")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+
+	if entries[0].Url != "abcde/fghij" || entries[0].Interactive || entries[0].Name != "urls.txt:2" {
+		t.Fatalf("unexpected first entry: %+v", entries[0])
+	}
+
+	if entries[1].Url != "https://online.fliphtml5.com/abcde/klmno/" || !entries[1].Interactive {
+		t.Fatalf("unexpected second entry: %+v", entries[1])
+	}
+}
+
+func TestReadBooksDirectoryReportsUnreadableFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "catalog.txt"), []byte("abcde/fghij\n"), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "broken.yaml"), []byte("books: [\n"), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var failures []string
+	reporter := progress.NewFunc(func(event progress.Event) {
+		if event.Level == progress.LevelError {
+			failures = append(failures, event.Message)
+		}
+	})
+
+	entries, err := readBooksDirectory(dir, reporter)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Url != "abcde/fghij" || entries[0].Name != "catalog.txt" {
+		t.Errorf("expected the readable file, got %+v", entries)
+	}
+	if len(failures) != 1 || !strings.Contains(failures[0], "Cannot read file broken.yaml") {
+		t.Errorf("expected the broken manifest to be reported, got %q", failures)
+	}
+}
+
+func TestReadBooksDirectoryReadsEveryLine(t *testing.T) {
+	dir := t.TempDir()
+	books := `# example batch download file
+# each line should contain the fliphtml5 url or id
+
+abcde/fghij
+https://online.fliphtml5.com/abcde/klmno/ -i
+`
+	if err := os.WriteFile(filepath.Join(dir, "books.txt"), []byte(books), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	entries, err := readBooksDirectory(dir, progress.NewFunc(func(progress.Event) {}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Url != "abcde/fghij" || entries[1].Url != "https://online.fliphtml5.com/abcde/klmno/" || !entries[1].Interactive {
+		t.Fatalf("expected every book of the file after its comments, got %+v", entries)
+	}
+	if entries[0].Name != "books.txt:4" {
+		t.Errorf("expected the entries of a file with several books to name their line, got %q", entries[0].Name)
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ygunayer/fh5dl/internal/progress"
+	"github.com/ztrue/tracerr"
+	"golang.org/x/sync/errgroup"
+)
+
+// captionPrompt is what the captioning model is asked about every page
+const captionPrompt = "Describe this page of a book for someone who can't see it: its pictures, diagrams, tables and " +
+	"layout. Don't transcribe its text word for word. Answer with the description only."
+
+// captionSuffix is what the description of a page image is kept as next to the image, so later runs don't ask again
+const captionSuffix = ".caption.txt"
+
+// captionTimeout is how long describing a single page may take, local models on a CPU are slow
+const captionTimeout = 5 * time.Minute
+
+// captionConcurrency is how many pages are described at once, local servers mostly describe one at a time anyway
+const captionConcurrency = 2
+
+// captionPath is where the description of a page image is kept
+func captionPath(imagePath string) string {
+	return trimExtension(imagePath) + captionSuffix
+}
+
+// validCaptionUrl checks the value of the --caption-url flag
+func validCaptionUrl(captionUrl string) bool {
+	parsed, err := url.Parse(captionUrl)
+	return err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != ""
+}
+
+// captionRequest is the part of an OpenAI compatible chat completion request a caption needs
+type captionRequest struct {
+	Model    string           `json:"model,omitempty"`
+	Messages []captionMessage `json:"messages"`
+}
+
+type captionMessage struct {
+	Role    string        `json:"role"`
+	Content []captionPart `json:"content"`
+}
+
+type captionPart struct {
+	Type     string           `json:"type"`
+	Text     string           `json:"text,omitempty"`
+	ImageUrl *captionImageUrl `json:"image_url,omitempty"`
+}
+
+type captionImageUrl struct {
+	Url string `json:"url"`
+}
+
+// captionResponse is the part of a chat completion response with the description in it
+type captionResponse struct {
+	Choices []struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+	} `json:"choices"`
+}
+
+// describeImage sends a page image to --caption-url as an OpenAI compatible chat completion request, which local
+// servers such as Ollama and llama.cpp take as well as hosted APIs, and returns the description of the page
+func describeImage(ctx context.Context, args *Args, imagePath string) (string, error) {
+	data, err := os.ReadFile(imagePath)
+	if err != nil {
+		return "", tracerr.Wrap(err)
+	}
+
+	image := "data:" + http.DetectContentType(data) + ";base64," + base64.StdEncoding.EncodeToString(data)
+	body, err := json.Marshal(captionRequest{
+		Model: args.CaptionModel,
+		Messages: []captionMessage{{
+			Role: "user",
+			Content: []captionPart{
+				{Type: "text", Text: captionPrompt},
+				{Type: "image_url", ImageUrl: &captionImageUrl{Url: image}},
+			},
+		}},
+	})
+	if err != nil {
+		return "", tracerr.Wrap(err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, args.CaptionUrl, bytes.NewReader(body))
+	if err != nil {
+		return "", tracerr.Wrap(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if args.CaptionKey != "" {
+		req.Header.Set("Authorization", "Bearer "+args.CaptionKey)
+	}
+
+	// the endpoint isn't FlipHTML5, so it doesn't go through the book transport and its fixtures
+	res, err := (&http.Client{Timeout: captionTimeout}).Do(req)
+	if err != nil {
+		return "", tracerr.Wrap(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+		return "", fmt.Errorf("the captioning endpoint returned %s: %s", res.Status, strings.TrimSpace(string(message)))
+	}
+
+	var parsed captionResponse
+	if err := json.NewDecoder(res.Body).Decode(&parsed); err != nil {
+		return "", fmt.Errorf("failed to read the response of the captioning endpoint: %w", err)
+	}
+	if len(parsed.Choices) == 0 || strings.TrimSpace(parsed.Choices[0].Message.Content) == "" {
+		return "", fmt.Errorf("the captioning endpoint returned no description")
+	}
+
+	return normalizeText(parsed.Choices[0].Message.Content), nil
+}
+
+// describePages describes the page images with --caption-url and returns the descriptions by page number. Images
+// without a page number and placeholders are left out. Descriptions are kept next to the images and reused by later
+// runs, and by the PDF to tag its pages with. A page that can't be described is listed in the report without failing
+// the book, and asked about again by the next run.
+func describePages(ctx context.Context, args *Args, report *bookReport, imageFiles []string, pageNumbers map[string]int) (map[int]string, error) {
+	reporter := args.reporter()
+	task := reporter.Start("caption", "Describing the pages", len(imageFiles))
+	defer task.Finish()
+
+	var mu sync.Mutex
+	descriptions := make([]string, len(imageFiles))
+	eg, egCtx := errgroup.WithContext(ctx)
+	eg.SetLimit(captionConcurrency)
+	for i, imageFile := range imageFiles {
+		pageNumber, ok := pageNumbers[imageFile]
+		if !ok || slices.Contains(report.PlaceholderPages, pageNumber) {
+			task.Add(1)
+			continue
+		}
+
+		eg.Go(func() error {
+			defer task.Add(1)
+
+			descriptionPath := captionPath(imageFile)
+			if description, err := os.ReadFile(descriptionPath); err == nil {
+				descriptions[i] = string(description)
+				return nil
+			}
+
+			description, err := describeImage(egCtx, args, imageFile)
+			if err != nil {
+				if egCtx.Err() != nil {
+					return tracerr.Wrap(err)
+				}
+
+				captionErr := fmt.Sprintf("page %d: %v", pageNumber, err)
+				reporter.Logf(progress.LevelWarn, "Failed to describe %s", captionErr)
+				mu.Lock()
+				report.CaptionErrors = append(report.CaptionErrors, captionErr)
+				mu.Unlock()
+				return nil
+			}
+			descriptions[i] = description
+
+			return tracerr.Wrap(os.WriteFile(descriptionPath, []byte(description), 0644))
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+
+	pages := joinByPage(imageFiles, pageNumbers, report.PlaceholderPages, descriptions)
+	for pageNumber, description := range pages {
+		if description == "" {
+			delete(pages, pageNumber)
+		}
+	}
+
+	return pages, nil
+}
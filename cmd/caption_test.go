@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"image"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestDescribePages(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+
+		var request captionRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if r.Header.Get("Authorization") != "Bearer secret" || request.Model != "llava" {
+			t.Errorf("expected the key and the model to be sent, got %q and %q", r.Header.Get("Authorization"), request.Model)
+		}
+
+		image := request.Messages[0].Content[1].ImageUrl.Url
+		if !strings.HasPrefix(image, "data:image/png;base64,") {
+			t.Errorf("expected the page as a data URL, got %.40s", image)
+		}
+
+		// the pages are told apart by their size
+		if len(image) > 400 {
+			http.Error(w, "model is overloaded", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "content": " A bar chart of sales by year. "}}]}`))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	first := filepath.Join(dir, "1-1.png")
+	second := filepath.Join(dir, "2-1.png")
+	placeholder := filepath.Join(dir, "3-placeholder.png")
+	writeTestPng(t, first, image.Pt(20, 10))
+	writeTestPng(t, second, image.Pt(2000, 1000))
+	writeTestPng(t, placeholder, image.Pt(20, 10))
+
+	args := &Args{CaptionUrl: server.URL, CaptionModel: "llava", CaptionKey: "secret"}
+	report := &bookReport{PlaceholderPages: []int{3}}
+	pageNumbers := map[string]int{first: 1, second: 2, placeholder: 3}
+	descriptions, err := describePages(context.Background(), args, report, []string{first, second, placeholder}, pageNumbers)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(descriptions) != 1 || descriptions[1] != "A bar chart of sales by year." {
+		t.Errorf("expected only the first page to be described, got %q", descriptions)
+	}
+	if len(report.CaptionErrors) != 1 || !strings.Contains(report.CaptionErrors[0], "model is overloaded") {
+		t.Errorf("expected the failed page in the report, got %v", report.CaptionErrors)
+	}
+	if _, err := os.Stat(captionPath(second)); !os.IsNotExist(err) {
+		t.Errorf("expected no description to be kept for the failed page, got %v", err)
+	}
+
+	// the kept description is reused, the failed page is asked about again
+	if _, err := describePages(context.Background(), args, &bookReport{}, []string{first, second}, pageNumbers); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if requests.Load() != 3 {
+		t.Errorf("expected 3 requests, got %d", requests.Load())
+	}
+}
+
+func TestValidCaptionUrl(t *testing.T) {
+	cases := map[string]bool{
+		"http://localhost:11434/v1/chat/completions":  true,
+		"https://api.example.com/v1/chat/completions": true,
+		"localhost:11434":   false,
+		"ftp://example.com": false,
+		"https://":          false,
+	}
+
+	for captionUrl, expected := range cases {
+		if actual := validCaptionUrl(captionUrl); actual != expected {
+			t.Errorf("expected %v for %q, got %v", expected, captionUrl, actual)
+		}
+	}
+}
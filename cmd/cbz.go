@@ -0,0 +1,138 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	book "github.com/ygunayer/fh5dl/book"
+	"github.com/ztrue/tracerr"
+)
+
+// comicInfoFile is the metadata file of comic archives, read by Komga, Kavita and most comic readers
+const comicInfoFile = "ComicInfo.xml"
+
+// comicInfo is the part of the ComicInfo 2.0 schema that fh5dl knows about a book. The order of the fields is
+// the order of the schema.
+type comicInfo struct {
+	XMLName     xml.Name `xml:"ComicInfo"`
+	Title       string   `xml:"Title,omitempty"`
+	Series      string   `xml:"Series,omitempty"`
+	Volume      int      `xml:"Volume,omitempty"`
+	Writer      string   `xml:"Writer,omitempty"`
+	Genre       string   `xml:"Genre,omitempty"`
+	Web         string   `xml:"Web,omitempty"`
+	PageCount   int      `xml:"PageCount,omitempty"`
+	LanguageISO string   `xml:"LanguageISO,omitempty"`
+}
+
+// comicInfo returns the ComicInfo of the book. The title and language of the book already have the --title and
+// --language overrides applied.
+func (args *Args) comicInfo(b *book.Book) comicInfo {
+	return comicInfo{
+		Title:       b.Title,
+		Series:      args.series(b),
+		Volume:      args.Volume,
+		Writer:      args.Author,
+		Genre:       args.Subject,
+		Web:         b.Url,
+		LanguageISO: b.Language,
+	}
+}
+
+// generateCbz writes the images into a comic book archive, named so that they sort in order
+func generateCbz(imageFiles []string, cbzPath string) error {
+	file, err := os.Create(cbzPath)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+	defer file.Close()
+
+	archive := zip.NewWriter(file)
+	for i, imageFile := range imageFiles {
+		// the images are compressed already
+		w, err := archive.CreateHeader(&zip.FileHeader{
+			Name:   fmt.Sprintf("%04d%s", i+1, strings.ToLower(filepath.Ext(imageFile))),
+			Method: zip.Store,
+		})
+		if err != nil {
+			return tracerr.Wrap(err)
+		}
+
+		if err := copyFileTo(w, imageFile); err != nil {
+			return err
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	return tracerr.Wrap(file.Close())
+}
+
+func copyFileTo(w io.Writer, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+	defer file.Close()
+
+	_, err = io.Copy(w, file)
+	return tracerr.Wrap(err)
+}
+
+// setComicInfo writes the ComicInfo into the archive, with the page count of the archive
+func setComicInfo(cbzPath string, info comicInfo) error {
+	archive, err := zip.OpenReader(cbzPath)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+	defer archive.Close()
+
+	tmpPath := cbzPath + ".tmp"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+	defer os.Remove(tmpPath)
+	defer file.Close()
+
+	updated := zip.NewWriter(file)
+	info.PageCount = 0
+	for _, f := range archive.File {
+		if f.Name == comicInfoFile {
+			continue
+		}
+		if err := updated.Copy(f); err != nil {
+			return tracerr.Wrap(err)
+		}
+		info.PageCount++
+	}
+
+	data, err := xml.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+	w, err := updated.Create(comicInfoFile)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+	if _, err := w.Write(append([]byte(xml.Header), data...)); err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	if err := updated.Close(); err != nil {
+		return tracerr.Wrap(err)
+	}
+	if err := file.Close(); err != nil {
+		return tracerr.Wrap(err)
+	}
+	archive.Close()
+
+	return tracerr.Wrap(os.Rename(tmpPath, cbzPath))
+}
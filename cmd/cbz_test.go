@@ -0,0 +1,61 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGenerateCbz(t *testing.T) {
+	dir := t.TempDir()
+
+	imageFiles := []string{filepath.Join(dir, "b.JPG"), filepath.Join(dir, "a.png")}
+	for _, imageFile := range imageFiles {
+		if err := os.WriteFile(imageFile, []byte(filepath.Base(imageFile)), 0644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	cbzPath := filepath.Join(dir, "Atlas Vol. 01.cbz")
+	if err := generateCbz(imageFiles, cbzPath); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := setComicInfo(cbzPath, comicInfo{Title: "Atlas", Series: "Atlas", Volume: 1, LanguageISO: "en"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	archive, err := zip.OpenReader(cbzPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer archive.Close()
+
+	names := make([]string, 0)
+	var info comicInfo
+	for _, f := range archive.File {
+		names = append(names, f.Name)
+		if f.Name != comicInfoFile {
+			continue
+		}
+
+		r, err := f.Open()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		data, _ := io.ReadAll(r)
+		r.Close()
+		if err := xml.Unmarshal(data, &info); err != nil {
+			t.Fatalf("expected a valid ComicInfo.xml, got %v", err)
+		}
+	}
+
+	if len(names) != 3 || names[0] != "0001.jpg" || names[1] != "0002.png" || names[2] != comicInfoFile {
+		t.Errorf("expected the pages in order and the ComicInfo.xml, got %v", names)
+	}
+	if info.Series != "Atlas" || info.Volume != 1 || info.PageCount != 2 {
+		t.Errorf("unexpected ComicInfo %+v", info)
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// clipboardCommands are the programs that print the clipboard, in the order they are tried
+func clipboardCommands() [][]string {
+	switch runtime.GOOS {
+	case "darwin":
+		return [][]string{{"pbpaste"}}
+	case "windows":
+		return [][]string{{"powershell", "-NoProfile", "-Command", "Get-Clipboard"}}
+	}
+
+	return [][]string{
+		{"wl-paste", "--no-newline"},
+		{"xclip", "-selection", "clipboard", "-o"},
+		{"xsel", "--clipboard", "--output"},
+	}
+}
+
+// readClipboard returns the text on the clipboard. Terminals paste on their own shortcuts, this is for Ctrl+V,
+// which they pass on to programs.
+func readClipboard() (string, error) {
+	for _, command := range clipboardCommands() {
+		if _, err := exec.LookPath(command[0]); err != nil {
+			continue
+		}
+
+		out, err := exec.Command(command[0], command[1:]...).Output()
+		if err != nil {
+			return "", fmt.Errorf("failed to read the clipboard with %s: %w", command[0], err)
+		}
+
+		return strings.TrimSpace(string(out)), nil
+	}
+
+	return "", fmt.Errorf("no clipboard program found, paste with the shortcut of the terminal instead")
+}
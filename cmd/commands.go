@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	arg "github.com/alexflint/go-arg"
+	"github.com/ztrue/tracerr"
+)
+
+// command is a subcommand invoked as `fh5dl <name> [args...]`
+type command func(argv []string) error
+
+// commands maps subcommand names to their implementations
+var commands = map[string]command{
+	"retry":    retryCommand,
+	"estimate": estimateCommand,
+	"diff":     diffCommand,
+	"monitor":  monitorCommand,
+	"info":     infoCommand,
+	"doctor":   doctorCommand,
+	"jobs":     jobsCommand,
+	"catalog":  catalogCommand,
+	"index":    indexCommand,
+}
+
+// parseCommandArgs parses the arguments of a subcommand into dest, printing help when requested
+func parseCommandArgs(name string, dest interface{}, argv []string) (bool, error) {
+	parser, err := arg.NewParser(arg.Config{Program: "fh5dl " + name}, dest)
+	if err != nil {
+		return false, tracerr.Wrap(err)
+	}
+
+	err = parser.Parse(argv)
+	if errors.Is(err, arg.ErrHelp) {
+		parser.WriteHelp(os.Stdout)
+		return false, nil
+	}
+	if err != nil {
+		parser.WriteUsage(os.Stderr)
+		return false, err
+	}
+
+	return true, nil
+}
+
+// defaultArgs returns the options of a download with every flag left at its default, ignoring the environment
+func defaultArgs() (Args, error) {
+	var args Args
+	parser, err := arg.NewParser(arg.Config{IgnoreEnv: true}, &args)
+	if err != nil {
+		return args, tracerr.Wrap(err)
+	}
+
+	return args, tracerr.Wrap(parser.Parse(nil))
+}
+
+// defaultConcurrency returns the default number of concurrent downloads (number of CPUs - 1)
+func defaultConcurrency() int {
+	concurrency := runtime.NumCPU() - 1
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	return concurrency
+}
+
+type RetryArgs struct {
+	Source       string `arg:"positional,required" help:"Batch report (.json) or retry list (.retry.txt) of a previous run"`
+	Concurrency  int    `arg:"-c" help:"(Optional) Number of concurrent downloads. Defaults to (number of CPUs available - 1)"`
+	OutputFolder string `arg:"-o" help:"(Optional) Output folder for the PDFs. Defaults to the output folder of the original run"`
+	BatchSize    int    `arg:"-b" help:"(Optional) Batch size for interactive captures. Defaults to 8" default:"8"`
+	ReportFormat string `arg:"--report" help:"(Optional) Summary report to write: json, markdown, all or none" default:"json"`
+	Progress     string `arg:"--progress" help:"(Optional) How to show progress: auto, bar, plain or json" default:"auto"`
+}
+
+// retryCommand re-attempts only the books that failed or were written without some of their pages in a previous batch run
+func retryCommand(argv []string) error {
+	var args RetryArgs
+	if ok, err := parseCommandArgs("retry", &args, argv); !ok {
+		return err
+	}
+
+	if !validReportFormat(args.ReportFormat) {
+		return fmt.Errorf("invalid report format %q, expected json, markdown, all or none", args.ReportFormat)
+	}
+
+	if !validProgressMode(args.Progress) {
+		return fmt.Errorf("invalid progress mode %q, expected auto, bar, plain or json", args.Progress)
+	}
+
+	entries, base, err := readRetryEntries(args.Source)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	if len(entries) == 0 {
+		fmt.Printf("No failed or incomplete books to retry in %s\n", args.Source)
+		return nil
+	}
+
+	if args.OutputFolder != "" {
+		base.OutputFolder = args.OutputFolder
+	}
+
+	if args.Concurrency <= 0 {
+		args.Concurrency = defaultConcurrency()
+	}
+
+	fmt.Printf("Retrying %d failed or incomplete books from %s\n", len(entries), args.Source)
+
+	// the books are downloaded with the options of the original run, but in the way this run is asked to. The
+	// caption key isn't kept in the report, so it is read from the environment again.
+	base.Urls = nil
+	base.FromFile = ""
+	base.Concurrency = args.Concurrency
+	base.BatchSize = args.BatchSize
+	base.ReportFormat = args.ReportFormat
+	base.Progress = args.Progress
+	base.CaptionKey = os.Getenv("FH5DL_CAPTION_KEY")
+
+	// the options are checked and set up as for a download started from the command line, as the report may have
+	// been edited or written by another version
+	if err := prepareArgs(&base); err != nil {
+		return err
+	}
+
+	// previous pages and captures are still in each book's image folder, so only the missing ones are fetched again
+	return runBatch(entries, base)
+}
+
+// readRetryEntries reads the failed books from a batch report or a retry list, along with the options of the original
+// run. A retry list only gives its output folder, a batch report also keeps the other options. Options missing from
+// the report are left at their defaults.
+func readRetryEntries(path string) ([]batchEntry, Args, error) {
+	base, err := defaultArgs()
+	if err != nil {
+		return nil, base, err
+	}
+	base.OutputFolder = filepath.Dir(path)
+
+	if !strings.HasSuffix(path, ".json") {
+		entries, err := readUrlListFile(path)
+		return entries, base, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, base, tracerr.Wrap(err)
+	}
+
+	options := base
+	report := batchReport{Options: &options}
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, base, fmt.Errorf("failed to read batch report %s: %w", path, err)
+	}
+
+	if report.Options != nil {
+		outputFolder := base.OutputFolder
+		base = *report.Options
+		base.OutputFolder = outputFolder
+	}
+	if report.OutputFolder != "" {
+		base.OutputFolder = report.OutputFolder
+	}
+
+	return report.failedEntries(), base, nil
+}
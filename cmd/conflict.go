@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/ygunayer/fh5dl/internal/progress"
+)
+
+// policies for what to do when the PDF of a book already exists
+const (
+	conflictSkip      = "skip"
+	conflictOverwrite = "overwrite"
+	conflictRename    = "rename"
+	conflictPrompt    = "prompt"
+)
+
+// conflictPolicies lists the policies in the order the terminal UI cycles through them
+var conflictPolicies = []string{conflictSkip, conflictOverwrite, conflictRename, conflictPrompt}
+
+// validConflictPolicy checks the value of the --on-conflict flag
+func validConflictPolicy(policy string) bool {
+	for _, p := range conflictPolicies {
+		if p == policy {
+			return true
+		}
+	}
+
+	return false
+}
+
+// nextConflictPolicy returns the policy after the given one, wrapping around at the end
+func nextConflictPolicy(policy string) string {
+	for i, p := range conflictPolicies {
+		if p == policy {
+			return conflictPolicies[(i+1)%len(conflictPolicies)]
+		}
+	}
+
+	return conflictPolicies[0]
+}
+
+// conflictPolicy returns the effective policy, -f always means overwrite
+func (args *Args) conflictPolicy() string {
+	if args.Force {
+		return conflictOverwrite
+	}
+
+	if args.OnConflict == "" {
+		return conflictSkip
+	}
+
+	return args.OnConflict
+}
+
+// resolveConflict decides what to do with an existing PDF, calling ask for the answer of the user if the policy says
+// so. It returns the (possibly renamed) path to write to, or an empty path if the book should be skipped.
+func resolveConflict(pdfPath string, policy string, ask func(pdfPath string) string) string {
+	if policy == conflictPrompt {
+		policy = ask(pdfPath)
+	}
+
+	switch policy {
+	case conflictOverwrite:
+		return pdfPath
+	case conflictRename:
+		return numberedPdfPath(pdfPath)
+	default:
+		return ""
+	}
+}
+
+// promptMutex keeps the books of a concurrent batch from asking at the same time
+var promptMutex sync.Mutex
+
+// askConflict asks the user on the terminal what to do with an existing PDF. When nobody can answer (stdin is not a
+// terminal) the PDF is skipped.
+func (args *Args) askConflict(pdfPath string) string {
+	if stdinIsPiped() {
+		return conflictSkip
+	}
+
+	promptMutex.Lock()
+	defer promptMutex.Unlock()
+
+	return promptConflict(os.Stdin, os.Stderr, pdfPath)
+}
+
+// checkConflictPrompt turns --on-conflict prompt into skip when the progress isn't shown on a terminal, as the
+// question would end up among the progress lines or the events read by another program
+func (args *Args) checkConflictPrompt() {
+	if args.OnConflict != conflictPrompt || args.Force {
+		return
+	}
+
+	if mode := progressMode(args.Progress); mode != progress.ModeBar {
+		args.reporter().Logf(progress.LevelWarn, "Existing PDFs are skipped, --progress %s cannot ask about them", mode)
+		args.OnConflict = conflictSkip
+	}
+}
+
+// promptConflict asks whether to skip, overwrite or rename an existing PDF, reading the answer from in. The PDF is
+// skipped if in ends before an answer.
+func promptConflict(in io.Reader, out io.Writer, pdfPath string) string {
+	fmt.Fprintf(out, "PDF %s already exists. [s]kip, [o]verwrite or [r]ename? ", pdfPath)
+
+	reader := bufio.NewReader(in)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && line == "" {
+			return conflictSkip
+		}
+
+		switch strings.ToLower(strings.TrimSpace(line)) {
+		case "", "s", "skip":
+			return conflictSkip
+		case "o", "overwrite":
+			return conflictOverwrite
+		case "r", "rename":
+			return conflictRename
+		}
+
+		if err != nil {
+			return conflictSkip
+		}
+		fmt.Fprint(out, "Please answer s, o or r: ")
+	}
+}
+
+// numberedPdfPath returns the first "<name> (n).pdf" next to pdfPath that doesn't exist yet, keeping the extension of other output formats
+func numberedPdfPath(pdfPath string) string {
+	dir := filepath.Dir(pdfPath)
+	extension := filepath.Ext(pdfPath)
+	name := strings.TrimSuffix(filepath.Base(pdfPath), extension)
+
+	for n := 2; ; n++ {
+		candidate := filepath.Join(dir, fmt.Sprintf("%s (%d)%s", name, n, extension))
+		if !outputExists(candidate) {
+			return candidate
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ygunayer/fh5dl/internal/progress"
+)
+
+func TestResolveConflict(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"Catalog.pdf", "Brochure.pdf", "Brochure (2).pdf", "Comic.cbz"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("output"), 0644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		file     string
+		policy   string
+		answer   string
+		expected string
+		prompted string
+	}{
+		{name: "skip", file: "Catalog.pdf", policy: conflictSkip},
+		{name: "overwrite", file: "Catalog.pdf", policy: conflictOverwrite, expected: "Catalog.pdf"},
+		{name: "rename", file: "Catalog.pdf", policy: conflictRename, expected: "Catalog (2).pdf"},
+		{name: "rename past taken numbers", file: "Brochure.pdf", policy: conflictRename, expected: "Brochure (3).pdf"},
+		{name: "rename keeps the extension", file: "Comic.cbz", policy: conflictRename, expected: "Comic (2).cbz"},
+		{name: "prompt overwrite", file: "Catalog.pdf", policy: conflictPrompt, answer: "o\n", expected: "Catalog.pdf"},
+		{name: "prompt rename", file: "Catalog.pdf", policy: conflictPrompt, answer: "rename\n", expected: "Catalog (2).pdf"},
+		{name: "prompt default", file: "Catalog.pdf", policy: conflictPrompt, answer: "\n"},
+		{name: "prompt without an answer", file: "Catalog.pdf", policy: conflictPrompt},
+		{name: "prompt asks again", file: "Catalog.pdf", policy: conflictPrompt, answer: "maybe\nO\n", expected: "Catalog.pdf",
+			prompted: "Please answer s, o or r: "},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var out strings.Builder
+			asked := false
+			ask := func(pdfPath string) string {
+				asked = true
+				return promptConflict(strings.NewReader(test.answer), &out, pdfPath)
+			}
+
+			expected := ""
+			if test.expected != "" {
+				expected = filepath.Join(dir, test.expected)
+			}
+			if resolved := resolveConflict(filepath.Join(dir, test.file), test.policy, ask); resolved != expected {
+				t.Errorf("expected %q, got %q", expected, resolved)
+			}
+
+			if asked != (test.policy == conflictPrompt) {
+				t.Errorf("expected the user to be asked only with the prompt policy, asked: %v", asked)
+			}
+			if asked && !strings.HasPrefix(out.String(), "PDF "+filepath.Join(dir, test.file)+" already exists.") {
+				t.Errorf("unexpected prompt %q", out.String())
+			}
+			if !strings.HasSuffix(out.String(), test.prompted) {
+				t.Errorf("expected the prompt to end with %q, got %q", test.prompted, out.String())
+			}
+		})
+	}
+}
+
+func TestCheckConflictPrompt(t *testing.T) {
+	var log strings.Builder
+	reporter, _ := progress.New(progress.ModePlain, progress.Options{Out: &log})
+
+	for _, mode := range []string{progress.ModePlain, progress.ModeJSON} {
+		args := Args{OnConflict: conflictPrompt, Progress: mode, Reporter: reporter}
+		args.checkConflictPrompt()
+		if args.conflictPolicy() != conflictSkip {
+			t.Errorf("expected existing PDFs to be skipped with --progress %s, got %s", mode, args.conflictPolicy())
+		}
+	}
+	if !strings.Contains(log.String(), "--progress json cannot ask") {
+		t.Errorf("expected a warning, got %q", log.String())
+	}
+
+	args := Args{OnConflict: conflictPrompt, Progress: progress.ModeBar, Reporter: reporter}
+	args.checkConflictPrompt()
+	if args.conflictPolicy() != conflictPrompt {
+		t.Errorf("expected the user to be asked with --progress bar, got %s", args.conflictPolicy())
+	}
+}
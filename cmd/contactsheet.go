@@ -0,0 +1,220 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"os"
+	"strconv"
+
+	book "github.com/ygunayer/fh5dl/book"
+	"github.com/ygunayer/fh5dl/internal/progress"
+	"github.com/ztrue/tracerr"
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/sync/errgroup"
+)
+
+// contact sheet layout, in pixels
+const (
+	contactColumns     = 8   // pages per row, more when the sheet would be too tall for a JPEG
+	contactCellWidth   = 200 // width the thumbnails are scaled to
+	contactGap         = 16  // space around the cells
+	contactLabelHeight = 24  // space under each thumbnail for its page number
+	contactFontSize    = 14
+)
+
+// contactSheetSuffix is what the name of a contact sheet ends with, next to where the book would be written
+const contactSheetSuffix = ".contact.jpg"
+
+// contactThumbnail is the downloaded thumbnail of a page, with no path if it couldn't be downloaded
+type contactThumbnail struct {
+	pageNumber int
+	path       string
+	size       image.Point
+}
+
+// contactLayout returns the columns and rows of a grid of cells, adding columns until the sheet fits into a JPEG
+func contactLayout(cells int, cellHeight int) (int, int) {
+	columns := min(contactColumns, max(cells, 1))
+	for {
+		rows := (cells + columns - 1) / columns
+		if rows*(cellHeight+contactLabelHeight+contactGap)+contactGap <= maxStripHeight || columns >= cells {
+			return columns, rows
+		}
+		columns++
+	}
+}
+
+// downloadThumbnails downloads the thumbnails of the pages into dir. Pages whose thumbnail is missing or fails to
+// download are returned without a path, so the contact sheet still shows where they are.
+func downloadThumbnails(ctx context.Context, args *Args, b *book.Book, pages []int, dir string) ([]contactThumbnail, error) {
+	reporter := args.reporter()
+	task := reporter.Start("download", "Downloading thumbnails", len(pages))
+	defer task.Finish()
+
+	thumbnails := make([]contactThumbnail, len(pages))
+	eg, downloadCtx := errgroup.WithContext(ctx)
+	eg.SetLimit(args.Concurrency)
+
+	for slot, pageNumber := range pages {
+		thumbnails[slot].pageNumber = pageNumber
+
+		thumbnail, ok := b.ThumbnailImage(pageNumber)
+		if !ok {
+			task.Add(1)
+			continue
+		}
+
+		eg.Go(func() error {
+			defer task.Add(1)
+
+			if err := args.control.wait(downloadCtx); err != nil {
+				return tracerr.Wrap(err)
+			}
+
+			result, err := thumbnail.Download(downloadCtx, dir, args.downloadOptions())
+			if err != nil {
+				if downloadCtx.Err() != nil || errors.Is(err, book.ErrRateLimited) {
+					return tracerr.Wrap(err)
+				}
+				reporter.Logf(progress.LevelWarn, "Failed to download the thumbnail of page %d: %v", pageNumber, err)
+				return nil
+			}
+
+			size, err := imageSize(result.FullPath)
+			if err != nil {
+				reporter.Logf(progress.LevelWarn, "Failed to read the thumbnail of page %d: %v", pageNumber, err)
+				return nil
+			}
+
+			thumbnails[slot].path = result.FullPath
+			thumbnails[slot].size = size
+			return nil
+		})
+	}
+
+	if err := eg.Wait(); err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+
+	return thumbnails, nil
+}
+
+// writeContactSheet draws the thumbnails in a grid, each with its page number under it, and saves it as a JPEG.
+// Pages without a thumbnail are left as gray cells.
+func writeContactSheet(thumbnails []contactThumbnail, sheetPath string) error {
+	cellHeight := 0
+	for _, thumbnail := range thumbnails {
+		if thumbnail.path != "" {
+			cellHeight = max(cellHeight, thumbnail.size.Y*contactCellWidth/thumbnail.size.X)
+		}
+	}
+	if cellHeight == 0 {
+		return fmt.Errorf("none of the thumbnails could be downloaded")
+	}
+
+	columns, rows := contactLayout(len(thumbnails), cellHeight)
+	width := columns*(contactCellWidth+contactGap) + contactGap
+	height := rows*(cellHeight+contactLabelHeight+contactGap) + contactGap
+
+	canvas := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(canvas, canvas.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+
+	regular, err := opentype.Parse(goregular.TTF)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+	face, err := opentype.NewFace(regular, &opentype.FaceOptions{Size: contactFontSize, DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+	defer face.Close()
+
+	for i, thumbnail := range thumbnails {
+		x := contactGap + (i%columns)*(contactCellWidth+contactGap)
+		y := contactGap + (i/columns)*(cellHeight+contactLabelHeight+contactGap)
+
+		if thumbnail.path == "" {
+			draw.Draw(canvas, image.Rect(x, y, x+contactCellWidth, y+cellHeight), image.NewUniform(placeholderBackground), image.Point{}, draw.Src)
+		} else {
+			img, err := decodeImage(thumbnail.path)
+			if err != nil {
+				return err
+			}
+
+			// thumbnails shorter than the cell are centered in it
+			scaledHeight := thumbnail.size.Y * contactCellWidth / thumbnail.size.X
+			top := y + (cellHeight-scaledHeight)/2
+			draw.ApproxBiLinear.Scale(canvas, image.Rect(x, top, x+contactCellWidth, top+scaledHeight), img, img.Bounds(), draw.Over, nil)
+		}
+
+		label := strconv.Itoa(thumbnail.pageNumber)
+		labelX := x + (contactCellWidth-font.MeasureString(face, label).Ceil())/2
+		drawText(canvas, face, label, labelX, y+cellHeight+contactLabelHeight-6)
+	}
+
+	output, err := os.Create(sheetPath)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+	defer output.Close()
+
+	if err := jpeg.Encode(output, canvas, &jpeg.Options{Quality: stripQuality}); err != nil {
+		return fmt.Errorf("failed to encode %s: %w", sheetPath, err)
+	}
+
+	return tracerr.Wrap(output.Close())
+}
+
+// contactSheet writes the contact sheet of the selected pages of a book in place of the book itself, from the
+// thumbnails alone
+func contactSheet(ctx context.Context, args *Args, report *bookReport, b *book.Book, sheetPath string) error {
+	reporter := args.reporter()
+
+	if outputExists(sheetPath) {
+		resolvedPath := resolveConflict(sheetPath, args.conflictPolicy(), args.askConflict)
+		if resolvedPath == "" {
+			reporter.Logf(progress.LevelInfo, "Contact sheet %s already exists. Skipping.", sheetPath)
+			report.Status = reportStatusSkipped
+			report.PdfPath = sheetPath
+			return nil
+		}
+		sheetPath = resolvedPath
+	}
+
+	dir, err := newWorkTempDir(args.WorkDir, "fh5dl-contact-")
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+	defer os.RemoveAll(dir)
+
+	pages := expectedPages(b, args.pageSet())
+	thumbnails, err := downloadThumbnails(ctx, args, b, pages, dir)
+	if err != nil {
+		return err
+	}
+
+	missing := 0
+	for _, thumbnail := range thumbnails {
+		if thumbnail.path == "" {
+			missing++
+		}
+	}
+	if missing > 0 {
+		reporter.Logf(progress.LevelWarn, "%d of %d pages have no thumbnail and are left blank", missing, len(pages))
+	}
+
+	if err := writeContactSheet(thumbnails, sheetPath); err != nil {
+		return err
+	}
+
+	report.PdfPath = sheetPath
+	reporter.Logf(progress.LevelInfo, "Contact sheet of %d pages written to %s", len(pages), sheetPath)
+	return nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"path/filepath"
+	"testing"
+
+	book "github.com/ygunayer/fh5dl/book"
+	"github.com/ygunayer/fh5dl/internal/progress"
+)
+
+func TestContactLayout(t *testing.T) {
+	if columns, rows := contactLayout(10, 280); columns != contactColumns || rows != 2 {
+		t.Errorf("expected %d columns in 2 rows, got %d in %d", contactColumns, columns, rows)
+	}
+	if columns, rows := contactLayout(3, 280); columns != 3 || rows != 1 {
+		t.Errorf("expected a single row for a few pages, got %d columns in %d rows", columns, rows)
+	}
+
+	columns, rows := contactLayout(1000, 2000)
+	if rows*(2000+contactLabelHeight+contactGap)+contactGap > maxStripHeight {
+		t.Errorf("expected the sheet to fit into a JPEG, got %d columns in %d rows", columns, rows)
+	}
+}
+
+func TestContactSheet(t *testing.T) {
+	var thumbnail bytes.Buffer
+	png.Encode(&thumbnail, image.NewGray(image.Rect(0, 0, 40, 60))) // black
+
+	transport := book.Transport
+	book.Transport = thumbnailTransport(thumbnail.Bytes())
+	defer func() { book.Transport = transport }()
+
+	b := &book.Book{Pages: []book.Page{
+		{ThumbnailUrl: "https://example.com/thumb/1.jpg"},
+		{},
+		{ThumbnailUrl: "https://example.com/thumb/3.jpg"},
+	}}
+	args := &Args{Concurrency: 2, Reporter: progress.NewFunc(func(progress.Event) {})}
+	report := newBookReport("abcde/fghij")
+	sheetPath := filepath.Join(t.TempDir(), "Book"+contactSheetSuffix)
+
+	if err := contactSheet(context.Background(), args, report, b, sheetPath); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report.PdfPath != sheetPath {
+		t.Errorf("expected the sheet in the report, got %q", report.PdfPath)
+	}
+
+	sheet, err := decodeImage(sheetPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// three cells of 200x300 in a row
+	cellHeight := 60 * contactCellWidth / 40
+	expected := image.Pt(3*(contactCellWidth+contactGap)+contactGap, cellHeight+contactLabelHeight+2*contactGap)
+	if size := sheet.Bounds().Size(); size != expected {
+		t.Fatalf("expected a %v sheet, got %v", expected, size)
+	}
+
+	middle := func(cell int) color.Gray {
+		x := contactGap + cell*(contactCellWidth+contactGap) + contactCellWidth/2
+		return color.GrayModel.Convert(sheet.At(x, contactGap+cellHeight/2)).(color.Gray)
+	}
+	if first := middle(0); first.Y > 0x20 {
+		t.Errorf("expected the thumbnail of page 1, got %v", first)
+	}
+	if second := middle(1); second.Y < 0xd0 || second.Y == 0xff {
+		t.Errorf("expected a gray cell for page 2 without a thumbnail, got %v", second)
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/fatih/color"
+	book "github.com/ygunayer/fh5dl/book"
+	"github.com/ygunayer/fh5dl/internal/progress"
+)
+
+// applyContainerProfile adjusts the options for runs in Docker or Kubernetes, where the output goes to a log
+// collector, the system temp dir is often a small in-memory filesystem and /dev/shm is 64MB by default
+func (args *Args) applyContainerProfile() {
+	if !args.Container {
+		return
+	}
+
+	// terminals attached to containers are rarely looked at, the logs are
+	if args.Progress == progressAuto || args.Progress == "" {
+		args.Progress = progress.ModePlain
+	}
+	color.NoColor = true
+
+	// the output folder is usually a mounted volume, unlike the temp dir
+	if args.WorkDir == "" {
+		args.WorkDir = args.OutputFolder
+	}
+
+	size, err := book.SharedMemorySize()
+	result := checkSharedMemory(size, err)
+	if result.Status != checkOk {
+		args.reporter().Logf(progress.LevelWarn, "%s. %s", result.Detail, result.Fix)
+	}
+	args.sharedMemory = err == nil && size >= book.MinSharedMemory
+}
+
+// checkSharedMemory tells whether /dev/shm of the given size is large enough for Chrome
+func checkSharedMemory(size int64, err error) checkResult {
+	if err != nil {
+		return checkResult{Status: checkOk, Detail: "no /dev/shm, Chrome keeps its shared memory in the temp dir"}
+	}
+
+	if size < book.MinSharedMemory {
+		return checkResult{
+			Status: checkWarn,
+			Detail: fmt.Sprintf("/dev/shm has only %s, so Chrome uses the slower temp dir instead", formatBytes(size)),
+			Fix:    fmt.Sprintf("Give the container at least %s of shared memory with docker run --shm-size=1g, or in Kubernetes mount an emptyDir with medium Memory at /dev/shm", formatBytes(book.MinSharedMemory)),
+		}
+	}
+
+	return checkResult{Status: checkOk, Detail: fmt.Sprintf("/dev/shm has %s, which Chrome uses with --container", formatBytes(size))}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+	book "github.com/ygunayer/fh5dl/book"
+	"github.com/ygunayer/fh5dl/internal/progress"
+)
+
+func TestApplyContainerProfile(t *testing.T) {
+	defer func(noColor bool) { color.NoColor = noColor }(color.NoColor)
+	quiet := progress.NewFunc(func(event progress.Event) {})
+
+	args := Args{Progress: progressAuto, OutputFolder: "output"}
+	args.applyContainerProfile()
+	if args.Progress != progressAuto || args.WorkDir != "" {
+		t.Errorf("expected nothing to change without --container, got %+v", args)
+	}
+
+	args = Args{Progress: progressAuto, OutputFolder: "output", Container: true, Reporter: quiet}
+	args.applyContainerProfile()
+	if args.Progress != progress.ModePlain || args.WorkDir != "output" || !color.NoColor {
+		t.Errorf("unexpected container settings %+v", args)
+	}
+
+	args = Args{Progress: progress.ModeJSON, OutputFolder: "output", WorkDir: "/scratch", Container: true, Reporter: quiet}
+	args.applyContainerProfile()
+	if args.Progress != progress.ModeJSON || args.WorkDir != "/scratch" {
+		t.Errorf("expected explicit settings to be kept, got %+v", args)
+	}
+}
+
+func TestCheckSharedMemory(t *testing.T) {
+	if result := checkSharedMemory(64<<20, nil); result.Status != checkWarn || !strings.Contains(result.Detail, "64.0 MiB") || !strings.Contains(result.Fix, "--shm-size") {
+		t.Errorf("expected a warning for the Docker default, got %+v", result)
+	}
+
+	if result := checkSharedMemory(book.MinSharedMemory, nil); result.Status != checkOk {
+		t.Errorf("expected enough shared memory, got %+v", result)
+	}
+
+	if result := checkSharedMemory(0, errors.New("no such file or directory")); result.Status != checkOk {
+		t.Errorf("expected a missing /dev/shm to be fine, got %+v", result)
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// errBookCancelled is the failure of a book that was cancelled while downloading
+var errBookCancelled = errors.New("download cancelled")
+
+// downloadControl lets the terminal UI pause the image downloads of the running book, cancel the book, or stop a
+// batch from starting more books. A nil control never pauses or cancels anything.
+type downloadControl struct {
+	mu      sync.Mutex
+	paused  bool
+	resumed chan struct{} // closed when the downloads resume
+	cancel  context.CancelCauseFunc
+	stopped bool
+}
+
+func newDownloadControl() *downloadControl {
+	return &downloadControl{}
+}
+
+// SetPaused pauses or resumes the image downloads. Images already being downloaded are finished first.
+func (c *downloadControl) SetPaused(paused bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if paused == c.paused {
+		return
+	}
+
+	c.paused = paused
+	if paused {
+		c.resumed = make(chan struct{})
+	} else {
+		close(c.resumed)
+	}
+}
+
+// Paused tells whether the image downloads are paused
+func (c *downloadControl) Paused() bool {
+	if c == nil {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.paused
+}
+
+// CancelBook cancels the book being downloaded. The next book of a batch still starts.
+func (c *downloadControl) CancelBook() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cancel != nil {
+		c.cancel(errBookCancelled)
+	}
+}
+
+// Stop cancels the book being downloaded and keeps a batch from starting the rest
+func (c *downloadControl) Stop() {
+	c.mu.Lock()
+	c.stopped = true
+	c.mu.Unlock()
+
+	c.CancelBook()
+}
+
+// Stopped tells whether the batch was stopped
+func (c *downloadControl) Stopped() bool {
+	if c == nil {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stopped
+}
+
+// bookContext returns the context of a book, which CancelBook cancels
+func (c *downloadControl) bookContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	bookCtx, cancel := context.WithCancelCause(ctx)
+	if c == nil {
+		return bookCtx, func() { cancel(nil) }
+	}
+
+	c.mu.Lock()
+	c.cancel = cancel
+	c.mu.Unlock()
+
+	return bookCtx, func() {
+		c.mu.Lock()
+		c.cancel = nil
+		c.mu.Unlock()
+		cancel(nil)
+	}
+}
+
+// wait blocks while the downloads are paused, returning early if ctx is cancelled
+func (c *downloadControl) wait(ctx context.Context) error {
+	if c == nil {
+		return ctx.Err()
+	}
+
+	c.mu.Lock()
+	resumed := c.resumed
+	paused := c.paused
+	c.mu.Unlock()
+
+	if paused {
+		select {
+		case <-resumed:
+		case <-ctx.Done():
+		}
+	}
+
+	return ctx.Err()
+}
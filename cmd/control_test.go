@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDownloadControlPause(t *testing.T) {
+	control := newDownloadControl()
+	control.SetPaused(true)
+
+	waited := make(chan error)
+	go func() { waited <- control.wait(context.Background()) }()
+
+	select {
+	case <-waited:
+		t.Fatal("expected the paused control to block")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	control.SetPaused(false)
+	select {
+	case err := <-waited:
+		if err != nil {
+			t.Errorf("unexpected error %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected resuming to unblock the download")
+	}
+}
+
+func TestDownloadControlCancel(t *testing.T) {
+	control := newDownloadControl()
+	control.SetPaused(true)
+
+	ctx, cancel := control.bookContext(context.Background())
+	defer cancel()
+
+	// a paused download is let go when its book is cancelled
+	waited := make(chan error)
+	go func() { waited <- control.wait(ctx) }()
+	control.CancelBook()
+
+	select {
+	case err := <-waited:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected the download to be cancelled, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected cancelling to unblock the download")
+	}
+	if !errors.Is(context.Cause(ctx), errBookCancelled) {
+		t.Errorf("unexpected cause %v", context.Cause(ctx))
+	}
+	if control.Stopped() {
+		t.Error("expected cancelling a book to keep the batch going")
+	}
+
+	control.Stop()
+	if !control.Stopped() {
+		t.Error("expected the batch to be stopped")
+	}
+}
+
+func TestNilDownloadControl(t *testing.T) {
+	var control *downloadControl
+
+	ctx, cancel := control.bookContext(context.Background())
+	if err := control.wait(ctx); err != nil || control.Paused() || control.Stopped() {
+		t.Errorf("expected a nil control to never pause or stop, got %v", err)
+	}
+	cancel()
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	book "github.com/ygunayer/fh5dl/book"
+	"github.com/ygunayer/fh5dl/internal/progress"
+	"github.com/ztrue/tracerr"
+)
+
+// coverSuffix is what the name of a cover ends with before its extension, next to where the book would be written
+const coverSuffix = ".cover"
+
+// downloadCover downloads the images of the first page into dir and returns the largest, as some books put the
+// cover together from a background and smaller images on top of it
+func downloadCover(ctx context.Context, args *Args, images []book.PageImage, dir string) (string, error) {
+	cover := ""
+	pixels := 0
+	var lastErr error
+	for _, img := range images {
+		if img.PageNumber != 1 {
+			continue
+		}
+
+		result, err := img.Download(ctx, dir, args.downloadOptions())
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		size, err := imageSize(result.FullPath)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if size.X*size.Y > pixels {
+			cover, pixels = result.FullPath, size.X*size.Y
+		}
+	}
+
+	if cover == "" && lastErr != nil {
+		return "", fmt.Errorf("failed to download the cover: %w", lastErr)
+	}
+	if cover == "" {
+		return "", fmt.Errorf("the book has no image for its first page")
+	}
+
+	return cover, nil
+}
+
+// coverOnly writes the cover of a book as <title>.cover.<ext> in place of the book itself
+func coverOnly(ctx context.Context, args *Args, report *bookReport, images []book.PageImage, outputDir string, title string) error {
+	reporter := args.reporter()
+
+	dir, err := newWorkTempDir(args.WorkDir, "fh5dl-cover-")
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cover, err := downloadCover(ctx, args, images, dir)
+	if err != nil {
+		return err
+	}
+	cover, err = convertImage(cover, args.ImageFormat)
+	if err != nil {
+		return err
+	}
+
+	coverPath := filepath.Join(outputDir, title+coverSuffix+filepath.Ext(cover))
+	if outputExists(coverPath) {
+		resolvedPath := resolveConflict(coverPath, args.conflictPolicy(), args.askConflict)
+		if resolvedPath == "" {
+			reporter.Logf(progress.LevelInfo, "Cover %s already exists. Skipping.", coverPath)
+			report.Status = reportStatusSkipped
+			report.PdfPath = coverPath
+			return nil
+		}
+		coverPath = resolvedPath
+	}
+
+	// the temp dir may be on another file system, so the cover is copied rather than moved
+	output, err := os.Create(coverPath)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+	defer output.Close()
+	if err := copyFileTo(output, cover); err != nil {
+		return err
+	}
+	if err := output.Close(); err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	report.PdfPath = coverPath
+	reporter.Logf(progress.LevelInfo, "Cover written to %s", coverPath)
+	return nil
+}
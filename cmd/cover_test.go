@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"path/filepath"
+	"testing"
+
+	book "github.com/ygunayer/fh5dl/book"
+	"github.com/ygunayer/fh5dl/internal/progress"
+)
+
+func TestCoverOnly(t *testing.T) {
+	encode := func(width, height int) string {
+		var buffer bytes.Buffer
+		png.Encode(&buffer, image.NewGray(image.Rect(0, 0, width, height)))
+		return buffer.String()
+	}
+
+	transport := book.Transport
+	book.Transport = configTransport{
+		"https://example.com/1-text.png": encode(10, 10),
+		"https://example.com/1.png":      encode(40, 60),
+		"https://example.com/2.png":      encode(80, 120),
+	}
+	defer func() { book.Transport = transport }()
+
+	images := []book.PageImage{
+		{PageNumber: 1, ImageNumber: 1, OverallOrder: 1, Url: "https://example.com/1-text.png"},
+		{PageNumber: 1, ImageNumber: 2, OverallOrder: 2, Url: "https://example.com/1.png"},
+		{PageNumber: 2, ImageNumber: 1, OverallOrder: 3, Url: "https://example.com/2.png"},
+	}
+	args := &Args{Reporter: progress.NewFunc(func(progress.Event) {})}
+	dir := t.TempDir()
+
+	report := newBookReport("abcde/fghij")
+	if err := coverOnly(context.Background(), args, report, images, dir, "Book"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	coverPath := filepath.Join(dir, "Book.cover.png")
+	if report.PdfPath != coverPath {
+		t.Errorf("expected the cover at %s, got %q", coverPath, report.PdfPath)
+	}
+	if size, err := imageSize(coverPath); err != nil || size != image.Pt(40, 60) {
+		t.Errorf("expected the largest image of the first page, got %v (%v)", size, err)
+	}
+
+	report = newBookReport("abcde/fghij")
+	if err := coverOnly(context.Background(), args, report, images, dir, "Book"); err != nil || report.Status != reportStatusSkipped {
+		t.Errorf("expected an existing cover to be skipped, got %s (%v)", report.Status, err)
+	}
+
+	if err := coverOnly(context.Background(), args, newBookReport(""), images[2:], dir, "Other"); err == nil {
+		t.Errorf("expected an error for a book without an image for its first page")
+	}
+}
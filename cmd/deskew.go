@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"math"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/math/f64"
+)
+
+// maxSkew is the largest tilt in degrees looked for, scans are rarely off by more and designed pages are often
+// tilted on purpose by more
+const maxSkew = 5.0
+
+// skewStep is the precision of the detected tilt in degrees
+const skewStep = 0.1
+
+// minSkew is the smallest tilt in degrees that is corrected, less than that isn't worth re-encoding the page for
+const minSkew = 0.2
+
+// skewSampleWidth is the width pages are scaled down to for detecting the tilt
+const skewSampleWidth = 800
+
+// darkThreshold is the gray level below which a pixel is considered ink
+const darkThreshold = 128
+
+// detectSkew finds how many degrees the lines of text on a page are tilted, clockwise being positive. Every dark
+// pixel votes in a Hough accumulator for the lines through it at angles around the horizontal, and the angle whose
+// votes are the most concentrated on few lines wins.
+func detectSkew(img image.Image) float64 {
+	bounds := img.Bounds()
+	if bounds.Dx() == 0 || bounds.Dy() == 0 {
+		return 0
+	}
+
+	sampleHeight := max(1, bounds.Dy()*skewSampleWidth/bounds.Dx())
+	sample := image.NewGray(image.Rect(0, 0, skewSampleWidth, sampleHeight))
+	draw.ApproxBiLinear.Scale(sample, sample.Bounds(), img, bounds, draw.Src, nil)
+
+	points := make([]image.Point, 0)
+	for y := 0; y < sampleHeight; y++ {
+		for x := 0; x < skewSampleWidth; x++ {
+			if sample.GrayAt(x, y).Y < darkThreshold {
+				points = append(points, image.Pt(x, y))
+			}
+		}
+	}
+	if len(points) == 0 {
+		return 0
+	}
+
+	diagonal := int(math.Hypot(float64(skewSampleWidth), float64(sampleHeight))) + 1
+	accumulator := make([]int, 2*diagonal+1)
+	best, bestScore := 0.0, -1.0
+	for angle := -maxSkew; angle <= maxSkew+skewStep/2; angle += skewStep {
+		theta := (90 + angle) * math.Pi / 180
+		cos, sin := math.Cos(theta), math.Sin(theta)
+
+		clear(accumulator)
+		for _, p := range points {
+			rho := int(math.Round(float64(p.X)*cos+float64(p.Y)*sin)) + diagonal
+			accumulator[rho]++
+		}
+
+		score := 0.0
+		for _, votes := range accumulator {
+			score += float64(votes) * float64(votes)
+		}
+		if score > bestScore {
+			best, bestScore = angle, score
+		}
+	}
+
+	return math.Round(best/skewStep) * skewStep
+}
+
+// rotateImage turns an image by the given degrees counterclockwise around its center, keeping its size and filling
+// the uncovered corners with the color of its top left pixel
+func rotateImage(img image.Image, degrees float64) *image.RGBA {
+	bounds := img.Bounds()
+	rotated := image.NewRGBA(image.Rectangle{Max: bounds.Size()})
+	draw.Draw(rotated, rotated.Bounds(), image.NewUniform(color.RGBAModel.Convert(img.At(bounds.Min.X, bounds.Min.Y))), image.Point{}, draw.Src)
+
+	radians := -degrees * math.Pi / 180
+	cos, sin := math.Cos(radians), math.Sin(radians)
+	cx := float64(bounds.Min.X) + float64(bounds.Dx())/2
+	cy := float64(bounds.Min.Y) + float64(bounds.Dy())/2
+	dx := float64(bounds.Dx()) / 2
+	dy := float64(bounds.Dy()) / 2
+
+	// maps the source into the destination: move the center to the origin, rotate, move it to the middle of rotated
+	transform := f64.Aff3{
+		cos, -sin, dx - cos*cx + sin*cy,
+		sin, cos, dy - sin*cx - cos*cy,
+	}
+	draw.BiLinear.Transform(rotated, transform, img, bounds, draw.Src, nil)
+
+	return rotated
+}
+
+// deskewPages straightens the pages whose text is tilted, as on carelessly scanned books. It returns the image files
+// with the straightened pages in place of the originals, and how many there are.
+func deskewPages(ctx context.Context, imageFiles []string, pageNumbers map[string]int) ([]string, int, error) {
+	return transformPages(ctx, imageFiles, pageNumbers, "deskewed", func(img image.Image, _ int) image.Image {
+		skew := detectSkew(img)
+		if math.Abs(skew) < minSkew {
+			return nil
+		}
+
+		return rotateImage(img, skew)
+	})
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"image"
+	"image/draw"
+	"math"
+	"testing"
+)
+
+// textPage draws lines of "text" on a white page
+func textPage(size image.Point) *image.RGBA {
+	img := image.NewRGBA(image.Rectangle{Max: size})
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	for y := size.Y / 10; y < size.Y*9/10; y += size.Y / 30 {
+		draw.Draw(img, image.Rect(size.X/10, y, size.X*9/10, y+size.Y/100), image.Black, image.Point{}, draw.Src)
+	}
+
+	return img
+}
+
+func TestDetectSkew(t *testing.T) {
+	page := textPage(image.Pt(800, 1100))
+	if skew := detectSkew(page); skew != 0 {
+		t.Errorf("expected a straight page, got %g degrees", skew)
+	}
+
+	for _, tilt := range []float64{-2, 1.5, 3} {
+		tilted := rotateImage(page, tilt)
+		skew := detectSkew(tilted)
+		if math.Abs(skew+tilt) > 0.15 {
+			t.Errorf("expected %g degrees for a page turned %g degrees, got %g", -tilt, tilt, skew)
+		}
+
+		if straightened := detectSkew(rotateImage(tilted, skew)); math.Abs(straightened) > 0.15 {
+			t.Errorf("expected the page turned %g degrees to be straightened, still %g degrees off", tilt, straightened)
+		}
+	}
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	book "github.com/ygunayer/fh5dl/book"
+	"github.com/ztrue/tracerr"
+	"golang.org/x/image/font"
+)
+
+type DiffArgs struct {
+	Old      string `arg:"positional,required" help:"Earlier version of the book: a PDF written by fh5dl, its .meta.json file, or the ID or URL of the book"`
+	New      string `arg:"positional,required" help:"Later version of the book, in the same forms"`
+	Json     bool   `arg:"--json" help:"(Optional) Print the differences as JSON"`
+	Pdf      string `arg:"--pdf" help:"(Optional) Write the changed and added pages of the later version into this PDF, framed and labelled"`
+	Provider string `arg:"--provider" help:"(Optional) Flipbook platform of books given by URL. Detected from the URL by default"`
+}
+
+// bookSnapshot is what a book looked like at one point, either when a PDF was written or now
+type bookSnapshot struct {
+	Source     string
+	Title      string
+	Revision   string
+	PageImages map[int][]string
+}
+
+// bookDiff lists the pages that differ between two snapshots of a book
+type bookDiff struct {
+	Added   []int `json:"added"`
+	Removed []int `json:"removed"`
+	Changed []int `json:"changed"`
+}
+
+// colors of the frames around changed and added pages in the --pdf output
+var (
+	diffChangedColor = color.RGBA{R: 0xe6, G: 0x7e, B: 0x22, A: 0xff}
+	diffAddedColor   = color.RGBA{R: 0x27, G: 0xae, B: 0x60, A: 0xff}
+)
+
+// diffCommand compares two versions of a book and lists the pages that were added, removed or changed
+func diffCommand(argv []string) error {
+	var args DiffArgs
+	if ok, err := parseCommandArgs("diff", &args, argv); !ok {
+		return err
+	}
+
+	ctx := context.Background()
+	older, err := loadSnapshot(ctx, args.Old, args.Provider)
+	if err != nil {
+		return err
+	}
+	newer, err := loadSnapshot(ctx, args.New, args.Provider)
+	if err != nil {
+		return err
+	}
+
+	diff := diffSnapshots(older, newer)
+	if args.Json {
+		data, err := json.MarshalIndent(diff, "", "  ")
+		if err != nil {
+			return tracerr.Wrap(err)
+		}
+		fmt.Println(string(data))
+	} else {
+		printDiff(older, newer, diff)
+	}
+
+	if args.Pdf == "" {
+		return nil
+	}
+	if len(diff.Added) == 0 && len(diff.Changed) == 0 {
+		fmt.Fprintf(os.Stderr, "No changed or added pages, not writing %s\n", args.Pdf)
+		return nil
+	}
+
+	return writeDiffPdf(ctx, newer, diff, args.Pdf)
+}
+
+// loadSnapshot reads the metadata of a PDF written by fh5dl, or resolves the book if the source isn't a file
+func loadSnapshot(ctx context.Context, source string, providerName string) (*bookSnapshot, error) {
+	if _, err := os.Stat(source); err == nil {
+		var metadata *bookMetadata
+		if strings.HasSuffix(source, ".meta.json") {
+			metadata, err = readMetadataFile(source)
+		} else {
+			metadata, err = readMetadata(source)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if metadata == nil {
+			return nil, fmt.Errorf("%s has no %s next to it to compare", source, filepath.Base(metadataPath(source)))
+		}
+		if metadata.PageImages == nil {
+			return nil, fmt.Errorf("%s was written before fh5dl kept the page images of books, download it again to compare it", source)
+		}
+
+		return &bookSnapshot{Source: source, Title: metadata.Title, Revision: metadata.Revision, PageImages: metadata.PageImages}, nil
+	}
+
+	p, err := resolveProvider(providerName, source)
+	if err != nil {
+		return nil, err
+	}
+	b, err := p.Resolve(ctx, source)
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+
+	return &bookSnapshot{Source: b.Url, Title: b.Title, Revision: b.Revision(), PageImages: b.PageImageUrls()}, nil
+}
+
+// diffSnapshots compares the pages of two snapshots by page number
+func diffSnapshots(older *bookSnapshot, newer *bookSnapshot) bookDiff {
+	diff := bookDiff{Added: []int{}, Removed: []int{}, Changed: []int{}}
+	for page, urls := range newer.PageImages {
+		previous, ok := older.PageImages[page]
+		if !ok {
+			diff.Added = append(diff.Added, page)
+		} else if !slices.Equal(previous, urls) {
+			diff.Changed = append(diff.Changed, page)
+		}
+	}
+	for page := range older.PageImages {
+		if _, ok := newer.PageImages[page]; !ok {
+			diff.Removed = append(diff.Removed, page)
+		}
+	}
+
+	sort.Ints(diff.Added)
+	sort.Ints(diff.Removed)
+	sort.Ints(diff.Changed)
+
+	return diff
+}
+
+func printDiff(older *bookSnapshot, newer *bookSnapshot, diff bookDiff) {
+	fmt.Printf("Comparing %s (%d pages) with %s (%d pages)\n", older.Source, len(older.PageImages), newer.Source, len(newer.PageImages))
+	if len(diff.Added) == 0 && len(diff.Removed) == 0 && len(diff.Changed) == 0 {
+		fmt.Println("No changes")
+		return
+	}
+
+	fmt.Printf("Added pages: %s\n", formatPageList(diff.Added))
+	fmt.Printf("Removed pages: %s\n", formatPageList(diff.Removed))
+	fmt.Printf("Changed pages: %s\n", formatPageList(diff.Changed))
+}
+
+func formatPageList(pages []int) string {
+	if len(pages) == 0 {
+		return "none"
+	}
+
+	parts := make([]string, len(pages))
+	for i, page := range pages {
+		parts[i] = fmt.Sprint(page)
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// writeDiffPdf downloads the changed and added pages of the later version and writes them into a PDF, each framed
+// and labelled with what happened to it
+func writeDiffPdf(ctx context.Context, newer *bookSnapshot, diff bookDiff, pdfPath string) error {
+	dir, err := os.MkdirTemp("", "fh5dl-diff-")
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+	defer os.RemoveAll(dir)
+
+	pages := append(slices.Clone(diff.Changed), diff.Added...)
+	sort.Ints(pages)
+
+	imageFiles := make([]string, 0, len(pages))
+	for _, page := range pages {
+		label, frame := fmt.Sprintf("Page %d changed", page), diffChangedColor
+		if _, ok := slices.BinarySearch(diff.Added, page); ok {
+			label, frame = fmt.Sprintf("Page %d added", page), diffAddedColor
+		}
+
+		for i, url := range newer.PageImages[page] {
+			img := book.PageImage{PageNumber: page, ImageNumber: i + 1, Url: url}
+			downloaded, err := img.Download(ctx, dir, book.DownloadOptions{})
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to download image %d of page %d: %v\n", i+1, page, err)
+				continue
+			}
+
+			marked, err := markDiffPage(downloaded.FullPath, label, frame)
+			if err != nil {
+				return err
+			}
+			imageFiles = append(imageFiles, marked)
+		}
+	}
+
+	if len(imageFiles) == 0 {
+		return fmt.Errorf("none of the changed or added pages could be downloaded")
+	}
+	if err := generatePDF(imageFiles, pdfPath); err != nil {
+		return err
+	}
+
+	fmt.Printf("Wrote %d changed and added pages to %s\n", len(pages), pdfPath)
+	return nil
+}
+
+// markDiffPage draws a frame around the page and a label in its top left corner
+func markDiffPage(path string, label string, frame color.RGBA) (string, error) {
+	img, err := decodeImage(path)
+	if err != nil {
+		return "", err
+	}
+
+	bounds := img.Bounds()
+	marked := image.NewRGBA(image.Rectangle{Max: bounds.Size()})
+	draw.Draw(marked, marked.Bounds(), img, bounds.Min, draw.Src)
+
+	thickness := max(2, min(marked.Bounds().Dx(), marked.Bounds().Dy())/100)
+	frameColor := image.NewUniform(frame)
+	inner := marked.Bounds().Inset(thickness)
+	for _, edge := range []image.Rectangle{
+		{Max: image.Pt(marked.Bounds().Dx(), inner.Min.Y)},
+		{Min: image.Pt(0, inner.Max.Y), Max: marked.Bounds().Max},
+		{Min: image.Pt(0, inner.Min.Y), Max: image.Pt(inner.Min.X, inner.Max.Y)},
+		{Min: image.Pt(inner.Max.X, inner.Min.Y), Max: image.Pt(marked.Bounds().Dx(), inner.Max.Y)},
+	} {
+		draw.Draw(marked, edge, frameColor, image.Point{}, draw.Src)
+	}
+
+	fonts, err := newTocFonts(marked.Bounds().Dy())
+	if err != nil {
+		return "", err
+	}
+	defer fonts.heading.Close()
+	defer fonts.entry.Close()
+
+	height := fonts.entry.Metrics().Height.Ceil()
+	padding := height / 2
+	labelWidth := font.MeasureString(fonts.entry, label).Ceil()
+	draw.Draw(marked, image.Rect(inner.Min.X, inner.Min.Y, inner.Min.X+labelWidth+2*padding, inner.Min.Y+height+padding), frameColor, image.Point{}, draw.Src)
+	drawText(marked, fonts.entry, label, inner.Min.X+padding, inner.Min.Y+height)
+
+	markedPath := trimExtension(path) + "-diff.png"
+	if err := writePng(markedPath, marked); err != nil {
+		return "", err
+	}
+
+	return markedPath, nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"image"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+	older := &bookSnapshot{PageImages: map[int][]string{1: {"a.jpg"}, 2: {"b.jpg"}, 3: {"c.jpg"}}}
+	newer := &bookSnapshot{PageImages: map[int][]string{1: {"a.jpg"}, 2: {"b2.jpg"}, 4: {"d.jpg"}}}
+
+	diff := diffSnapshots(older, newer)
+	expected := bookDiff{Added: []int{4}, Removed: []int{3}, Changed: []int{2}}
+	if !reflect.DeepEqual(diff, expected) {
+		t.Errorf("expected %+v, got %+v", expected, diff)
+	}
+}
+
+func TestLoadSnapshotFromMetadata(t *testing.T) {
+	dir := t.TempDir()
+	pdfPath := filepath.Join(dir, "Book.pdf")
+	writeTestPng(t, pdfPath, image.Pt(1, 1))
+
+	if _, err := loadSnapshot(context.Background(), pdfPath, ""); err == nil {
+		t.Errorf("expected an error for a PDF without metadata")
+	}
+
+	metadata := &bookMetadata{Title: "Book", Revision: "abc", PageImages: map[int][]string{1: {"a.jpg"}}}
+	if err := writeMetadata(pdfPath, metadata); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, source := range []string{pdfPath, metadataPath(pdfPath)} {
+		snapshot, err := loadSnapshot(context.Background(), source, "")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if snapshot.Revision != "abc" || !reflect.DeepEqual(snapshot.PageImages, metadata.PageImages) {
+			t.Errorf("unexpected snapshot %+v from %s", snapshot, source)
+		}
+	}
+}
+
+func TestMarkDiffPage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "0001-01.png")
+	writeTestPng(t, path, image.Pt(400, 600))
+
+	marked, err := markDiffPage(path, "Page 1 changed", diffChangedColor)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	img, err := decodeImage(marked)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if img.Bounds().Size() != image.Pt(400, 600) {
+		t.Errorf("expected the page to keep its size, got %v", img.Bounds().Size())
+	}
+	if r, g, b, _ := img.At(0, 300).RGBA(); r>>8 != 0xe6 || g>>8 != 0x7e || b>>8 != 0x22 {
+		t.Errorf("expected the page to be framed, got %x %x %x", r>>8, g>>8, b>>8)
+	}
+}
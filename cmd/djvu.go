@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/ztrue/tracerr"
+)
+
+// djvulibre tools used for DjVu export: c44 encodes a single page, djvm bundles the pages into one document
+var djvuTools = []string{"c44", "djvm"}
+
+// checkDjvuTools makes sure the djvulibre tools are installed before any download starts
+func checkDjvuTools() error {
+	for _, tool := range djvuTools {
+		if _, err := exec.LookPath(tool); err != nil {
+			return fmt.Errorf("DjVu export needs %s from djvulibre (http://djvu.sourceforge.net) in PATH: %w", strings.Join(djvuTools, " and "), err)
+		}
+	}
+
+	return nil
+}
+
+// generateDjvu encodes every image as a DjVu page with c44 and bundles the pages into a single document with djvm
+func generateDjvu(imageFiles []string, djvuPath string, workDir string) error {
+	tmpdir, err := newWorkTempDir(workDir, "fh5dl-djvu-")
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	pages := make([]string, 0, len(imageFiles))
+	for i, imageFile := range imageFiles {
+		// c44 only reads JPEG and PNM, so every image is converted to PNM first
+		ppmPath := filepath.Join(tmpdir, fmt.Sprintf("%05d.ppm", i))
+		if err := writePpm(imageFile, ppmPath); err != nil {
+			return err
+		}
+
+		pagePath := filepath.Join(tmpdir, fmt.Sprintf("%05d.djvu", i))
+		if output, err := exec.Command("c44", ppmPath, pagePath).CombinedOutput(); err != nil {
+			return fmt.Errorf("c44 failed for %s: %w: %s", imageFile, err, strings.TrimSpace(string(output)))
+		}
+		os.Remove(ppmPath)
+
+		pages = append(pages, pagePath)
+	}
+
+	args := append([]string{"-c", djvuPath}, pages...)
+	if output, err := exec.Command("djvm", args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("djvm failed: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	return nil
+}
+
+// writePpm converts an image to a binary PPM file
+func writePpm(imagePath string, ppmPath string) error {
+	img, err := decodeImage(imagePath)
+	if err != nil {
+		return err
+	}
+
+	output, err := os.Create(ppmPath)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+	defer output.Close()
+
+	writer := bufio.NewWriter(output)
+	bounds := img.Bounds()
+	fmt.Fprintf(writer, "P6\n%d %d\n255\n", bounds.Dx(), bounds.Dy())
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, _ := img.At(x, y).RGBA()
+			writer.Write([]byte{byte(r >> 8), byte(g >> 8), byte(b >> 8)})
+		}
+	}
+
+	if err := writer.Flush(); err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	return tracerr.Wrap(output.Close())
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWritePpm(t *testing.T) {
+	dir := t.TempDir()
+
+	img := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	img.Set(0, 0, color.RGBA{R: 255, A: 255})
+	img.Set(1, 0, color.RGBA{B: 255, A: 255})
+
+	pngPath := filepath.Join(dir, "page.png")
+	file, err := os.Create(pngPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	png.Encode(file, img)
+	file.Close()
+
+	ppmPath := filepath.Join(dir, "page.ppm")
+	if err := writePpm(pngPath, ppmPath); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(ppmPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := append([]byte("P6\n2 1\n255\n"), 255, 0, 0, 0, 0, 255)
+	if !bytes.Equal(data, expected) {
+		t.Errorf("unexpected ppm %v", data)
+	}
+}
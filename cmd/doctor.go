@@ -0,0 +1,248 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	book "github.com/ygunayer/fh5dl/book"
+)
+
+// minChromeVersion is the oldest major version of Chrome interactive captures are known to work with
+const minChromeVersion = 110
+
+// doctorUrl is checked for network access, as most books come from FlipHTML5
+const doctorUrl = "https://online.fliphtml5.com/"
+
+type DoctorArgs struct {
+	OutputFolder string `arg:"-o" help:"(Optional) Output folder to check. Defaults to the current working directory" default:"."`
+	WorkDir      string `arg:"--work-dir" help:"(Optional) Folder for temporary files to check. Defaults to the system temp directory"`
+	Book         string `arg:"--book" help:"(Optional) Public FlipHTML5 book ID or URL to check the site layout with"`
+}
+
+// checkStatus is the outcome of a doctor check
+type checkStatus int
+
+const (
+	checkOk checkStatus = iota
+	checkWarn
+	checkFail
+)
+
+// checkResult is what a doctor check found, along with how to fix it if something is wrong
+type checkResult struct {
+	Status checkStatus
+	Detail string
+	Fix    string
+}
+
+// doctorCheck is one part of the environment that downloads or interactive captures depend on
+type doctorCheck struct {
+	Name string
+	Run  func(ctx context.Context) checkResult
+}
+
+// doctorCommand checks the environment of fh5dl and prints how to fix what is wrong, as most problems with
+// interactive captures come from a missing or broken Chrome, the network or the file system
+func doctorCommand(argv []string) error {
+	var args DoctorArgs
+	if ok, err := parseCommandArgs("doctor", &args, argv); !ok {
+		return err
+	}
+
+	workDir := args.WorkDir
+	if workDir == "" {
+		workDir = os.TempDir()
+	}
+
+	checks := []doctorCheck{
+		{"Chrome", func(ctx context.Context) checkResult { return checkChrome(ctx, args.WorkDir) }},
+		{"Shared memory", func(ctx context.Context) checkResult { return checkSharedMemory(book.SharedMemorySize()) }},
+		{"Display", func(ctx context.Context) checkResult { return checkDisplay() }},
+		{"Network", func(ctx context.Context) checkResult { return checkNetwork(ctx, doctorUrl) }},
+		{"FlipHTML5 layout", func(ctx context.Context) checkResult { return checkLayout(ctx, args.Book) }},
+		{"Temp folder", func(ctx context.Context) checkResult { return checkWritable(workDir) }},
+		{"Output folder", func(ctx context.Context) checkResult { return checkWritable(args.OutputFolder) }},
+		{"ffmpeg", func(ctx context.Context) checkResult { return checkFfmpegAvailable() }},
+	}
+
+	failed := runDoctor(context.Background(), os.Stdout, checks)
+	if failed > 0 {
+		return fmt.Errorf("%d of %d checks failed", failed, len(checks))
+	}
+
+	return nil
+}
+
+// runDoctor runs the checks in order and prints their results, returning the number of failed ones
+func runDoctor(ctx context.Context, w io.Writer, checks []doctorCheck) int {
+	labels := map[checkStatus]string{
+		checkOk:   color.GreenString("OK"),
+		checkWarn: color.YellowString("WARN"),
+		checkFail: color.RedString("FAIL"),
+	}
+
+	failed := 0
+	for _, check := range checks {
+		checkCtx, cancel := context.WithTimeout(ctx, time.Minute)
+		result := check.Run(checkCtx)
+		cancel()
+
+		fmt.Fprintf(w, "[%s] %s: %s\n", labels[result.Status], check.Name, result.Detail)
+		if result.Fix != "" && result.Status != checkOk {
+			fmt.Fprintf(w, "       Fix: %s\n", result.Fix)
+		}
+		if result.Status == checkFail {
+			failed++
+		}
+	}
+
+	return failed
+}
+
+// checkChrome starts Chrome the way interactive captures do, which is headless, without its sandbox and without
+// /dev/shm, and reads its version
+func checkChrome(ctx context.Context, workDir string) checkResult {
+	browser, err := book.NewBrowser(ctx, book.CaptureOptions{WorkDir: workDir})
+	if err != nil {
+		return checkResult{Status: checkFail, Detail: fmt.Sprintf("Chrome doesn't start: %v", err), Fix: chromeFix(err)}
+	}
+	defer browser.Close()
+
+	version, err := browser.Version()
+	if err != nil {
+		return checkResult{Status: checkWarn, Detail: fmt.Sprintf("Chrome starts, but its version is unknown: %v", err)}
+	}
+
+	detail := fmt.Sprintf("%s starts headless with --no-sandbox and --disable-dev-shm-usage", version)
+	if major := chromeMajorVersion(version); major > 0 && major < minChromeVersion {
+		return checkResult{
+			Status: checkWarn,
+			Detail: detail,
+			Fix:    fmt.Sprintf("Update Chrome, interactive captures are tested with version %d and later", minChromeVersion),
+		}
+	}
+
+	return checkResult{Status: checkOk, Detail: detail}
+}
+
+// chromeFix tells what to do about Chrome failing to start with the given error
+func chromeFix(err error) string {
+	message := strings.ToLower(err.Error())
+	switch {
+	case strings.Contains(message, "executable file not found") || strings.Contains(message, book.ErrChromeUnavailable.Error()):
+		return "Install Google Chrome or Chromium and make sure it is in PATH"
+	case strings.Contains(message, "sandbox"):
+		return "Chrome refused to run without its sandbox. This usually comes from a wrapper such as the Chromium snap ignoring --no-sandbox; install Chrome from its official package instead"
+	case strings.Contains(message, "display"):
+		return "Chrome tried to open a window; make sure no CHROME_FLAGS or wrapper script turns off headless mode"
+	default:
+		return "Check that Chrome starts on its own with: chrome --headless --no-sandbox --dump-dom about:blank"
+	}
+}
+
+// chromeMajorVersion returns the major version of a product such as HeadlessChrome/126.0.6478.126, 0 if unknown
+func chromeMajorVersion(product string) int {
+	_, version, ok := strings.Cut(product, "/")
+	if !ok {
+		return 0
+	}
+
+	major, _, _ := strings.Cut(version, ".")
+	number, err := strconv.Atoi(major)
+	if err != nil {
+		return 0
+	}
+
+	return number
+}
+
+// checkDisplay warns that --capture-debug, which shows the browser, can't work without a desktop session
+func checkDisplay() checkResult {
+	if runtime.GOOS != "linux" || os.Getenv("DISPLAY") != "" || os.Getenv("WAYLAND_DISPLAY") != "" {
+		return checkResult{Status: checkOk, Detail: "a desktop session is available for --capture-debug"}
+	}
+
+	return checkResult{
+		Status: checkWarn,
+		Detail: "no desktop session, --capture-debug can't show the browser",
+		Fix:    "Run --capture-debug from a desktop session, or under xvfb-run. Headless captures don't need one",
+	}
+}
+
+// checkNetwork makes sure the flipbook platform can be reached. Any response will do, only failed requests count.
+func checkNetwork(ctx context.Context, url string) checkResult {
+	start := time.Now()
+	if _, err := book.Exists(ctx, url); err != nil {
+		return checkResult{
+			Status: checkFail,
+			Detail: fmt.Sprintf("%s can't be reached: %v", url, err),
+			Fix:    "Check the internet connection, and the HTTPS_PROXY environment variable if you are behind a proxy",
+		}
+	}
+
+	return checkResult{Status: checkOk, Detail: fmt.Sprintf("%s answered in %s", url, time.Since(start).Round(time.Millisecond))}
+}
+
+// checkLayout reads a book the way downloads do, to tell whether FlipHTML5 still lays out books the way fh5dl
+// expects. It needs a book, as there isn't one that is sure to stay public.
+func checkLayout(ctx context.Context, idOrUrl string) checkResult {
+	if idOrUrl == "" {
+		return checkResult{Status: checkOk, Detail: "skipped, pass --book with a public book to check it"}
+	}
+
+	check, err := book.CheckLayout(ctx, idOrUrl)
+	switch {
+	case errors.Is(err, book.ErrLayoutChanged):
+		return checkResult{
+			Status: checkFail,
+			Detail: err.Error(),
+			Fix:    "FlipHTML5 changed how it serves books. Update fh5dl, or report the book if you have the latest version",
+		}
+	case err != nil:
+		return checkResult{
+			Status: checkWarn,
+			Detail: fmt.Sprintf("%s can't be checked: %v", idOrUrl, err),
+			Fix:    "Pass --book with a public book that opens in the browser",
+		}
+	}
+
+	return checkResult{
+		Status: checkOk,
+		Detail: fmt.Sprintf("%s has %d pages and its first image is %s", check.Book.Id, len(check.Book.Pages), check.ContentType),
+	}
+}
+
+// checkWritable makes sure files can be created in the folder, creating the folder as downloads would
+func checkWritable(dir string) checkResult {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return checkResult{Status: checkFail, Detail: fmt.Sprintf("%s can't be created: %v", dir, err), Fix: "Pick another folder or fix its permissions"}
+	}
+
+	file, err := os.CreateTemp(dir, ".fh5dl-doctor-")
+	if err != nil {
+		return checkResult{Status: checkFail, Detail: fmt.Sprintf("%s isn't writable: %v", dir, err), Fix: "Pick another folder or fix its permissions"}
+	}
+	file.Close()
+	os.Remove(file.Name())
+
+	return checkResult{Status: checkOk, Detail: fmt.Sprintf("%s is writable", dir)}
+}
+
+// checkFfmpegAvailable looks for ffmpeg, which only --record needs
+func checkFfmpegAvailable() checkResult {
+	path, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		return checkResult{Status: checkWarn, Detail: "ffmpeg is not in PATH, --record won't work", Fix: "Install ffmpeg (https://ffmpeg.org) to use --record"}
+	}
+
+	return checkResult{Status: checkOk, Detail: fmt.Sprintf("found at %s", path)}
+}
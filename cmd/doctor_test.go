@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	book "github.com/ygunayer/fh5dl/book"
+)
+
+func TestRunDoctor(t *testing.T) {
+	checks := []doctorCheck{
+		{"Good", func(ctx context.Context) checkResult {
+			return checkResult{Status: checkOk, Detail: "fine", Fix: "unused"}
+		}},
+		{"Meh", func(ctx context.Context) checkResult {
+			return checkResult{Status: checkWarn, Detail: "could be better", Fix: "do this"}
+		}},
+		{"Bad", func(ctx context.Context) checkResult {
+			return checkResult{Status: checkFail, Detail: "broken", Fix: "do that"}
+		}},
+	}
+
+	var out bytes.Buffer
+	if failed := runDoctor(context.Background(), &out, checks); failed != 1 {
+		t.Errorf("expected 1 failed check, got %d", failed)
+	}
+
+	for _, line := range []string{"Good: fine", "Fix: do this", "Bad: broken", "Fix: do that"} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("expected %q in the output:\n%s", line, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "unused") {
+		t.Errorf("expected no fix for passing checks:\n%s", out.String())
+	}
+}
+
+func TestChromeMajorVersion(t *testing.T) {
+	cases := map[string]int{
+		"HeadlessChrome/126.0.6478.126": 126,
+		"Chrome/99.0.1":                 99,
+		"Chromium":                      0,
+		"HeadlessChrome/dev":            0,
+	}
+
+	for product, expected := range cases {
+		if actual := chromeMajorVersion(product); actual != expected {
+			t.Errorf("expected %d for %s, got %d", expected, product, actual)
+		}
+	}
+}
+
+func TestChromeFix(t *testing.T) {
+	if fix := chromeFix(book.ErrChromeUnavailable); !strings.Contains(fix, "Install") {
+		t.Errorf("expected an install hint, got %s", fix)
+	}
+	if fix := chromeFix(errors.New("Running as root without --no-sandbox is not supported")); !strings.Contains(fix, "snap") {
+		t.Errorf("expected a sandbox hint, got %s", fix)
+	}
+}
+
+func TestCheckWritable(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "output")
+	if result := checkWritable(dir); result.Status != checkOk {
+		t.Errorf("expected the folder to be writable, got %+v", result)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("expected the test file to be removed, got %v", entries)
+	}
+
+	file := filepath.Join(t.TempDir(), "file")
+	os.WriteFile(file, nil, 0644)
+	if result := checkWritable(filepath.Join(file, "output")); result.Status != checkFail || result.Fix == "" {
+		t.Errorf("expected a folder inside a file to fail, got %+v", result)
+	}
+}
+
+// unreachableTransport fails every request like a network without a connection
+type unreachableTransport struct{}
+
+func (unreachableTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return nil, errors.New("dial tcp: no route to host")
+}
+
+func TestCheckNetwork(t *testing.T) {
+	defer func(original http.RoundTripper) { book.Transport = original }(book.Transport)
+	book.Transport = unreachableTransport{}
+
+	if result := checkNetwork(context.Background(), doctorUrl); result.Status != checkFail || !strings.Contains(result.Detail, "no route to host") {
+		t.Errorf("expected the network check to fail, got %+v", result)
+	}
+}
+
+func TestCheckLayout(t *testing.T) {
+	if result := checkLayout(context.Background(), ""); result.Status != checkOk || !strings.Contains(result.Detail, "skipped") {
+		t.Errorf("expected the check to be skipped without a book, got %+v", result)
+	}
+
+	defer func(original http.RoundTripper) { book.Transport = original }(book.Transport)
+
+	book.Transport = unreachableTransport{}
+	if result := checkLayout(context.Background(), "abcde/fghij"); result.Status != checkWarn {
+		t.Errorf("expected a warning when the book can't be reached, got %+v", result)
+	}
+
+	// an image where the book information should be
+	book.Transport = thumbnailTransport([]byte("\x89PNG\r\n\x1a\n"))
+	result := checkLayout(context.Background(), "abcde/fghij")
+	if result.Status != checkFail || !strings.Contains(result.Detail, "layout may have changed") || !strings.Contains(result.Fix, "Update") {
+		t.Errorf("expected the layout check to fail, got %+v", result)
+	}
+}
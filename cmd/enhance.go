@@ -0,0 +1,95 @@
+package main
+
+import (
+	"image"
+	"image/draw"
+)
+
+// enhance presets
+const (
+	enhanceText = "text"
+)
+
+// enhanceInkPercentile is the share of the darkest pixels that become pure black with the text preset
+const enhanceInkPercentile = 0.005
+
+// enhanceSharpen is how strongly the text preset sharpens, as the share of the difference to a blurred page added
+const enhanceSharpen = 0.5
+
+// enhanceTextPage stretches the levels of a page so its paper turns white and its darkest ink black, then sharpens it
+// a little. The paper is taken to be the most common of the light gray levels. Pages that are mostly dark, or
+// already use the full range, are left as they are.
+func enhanceTextPage(img image.Image, _ int) image.Image {
+	bounds := img.Bounds()
+	page := image.NewRGBA(image.Rectangle{Max: bounds.Size()})
+	draw.Draw(page, page.Bounds(), img, bounds.Min, draw.Src)
+
+	histogram := [256]int{}
+	for i := 0; i < len(page.Pix); i += 4 {
+		histogram[luminance(page.Pix[i], page.Pix[i+1], page.Pix[i+2])]++
+	}
+
+	white := 128
+	for level := 128; level < 256; level++ {
+		if histogram[level] > histogram[white] {
+			white = level
+		}
+	}
+
+	black, darkest := 0, 0
+	for limit := int(float64(len(page.Pix)/4) * enhanceInkPercentile); black < white && darkest+histogram[black] <= limit; black++ {
+		darkest += histogram[black]
+	}
+
+	if histogram[white] == 0 || white-black < 32 || (white == 255 && black == 0) {
+		return nil
+	}
+
+	levels := [256]uint8{}
+	for level := range levels {
+		levels[level] = clampLevel((level - black) * 255 / (white - black))
+	}
+	for i := 0; i < len(page.Pix); i += 4 {
+		page.Pix[i] = levels[page.Pix[i]]
+		page.Pix[i+1] = levels[page.Pix[i+1]]
+		page.Pix[i+2] = levels[page.Pix[i+2]]
+	}
+
+	return sharpen(page, enhanceSharpen)
+}
+
+// luminance is the perceived brightness of a color
+func luminance(r, g, b uint8) uint8 {
+	return uint8((299*int(r) + 587*int(g) + 114*int(b)) / 1000)
+}
+
+func clampLevel(level int) uint8 {
+	return uint8(min(255, max(0, level)))
+}
+
+// sharpen applies an unsharp mask: every pixel moves away from the average of its neighbours by amount times the
+// difference
+func sharpen(img *image.RGBA, amount float64) *image.RGBA {
+	bounds := img.Bounds()
+	sharpened := image.NewRGBA(bounds)
+	copy(sharpened.Pix, img.Pix)
+
+	for y := bounds.Min.Y + 1; y < bounds.Max.Y-1; y++ {
+		for x := bounds.Min.X + 1; x < bounds.Max.X-1; x++ {
+			offset := img.PixOffset(x, y)
+			for channel := 0; channel < 3; channel++ {
+				sum := 0
+				for dy := -1; dy <= 1; dy++ {
+					for dx := -1; dx <= 1; dx++ {
+						sum += int(img.Pix[img.PixOffset(x+dx, y+dy)+channel])
+					}
+				}
+
+				value := int(img.Pix[offset+channel])
+				sharpened.Pix[offset+channel] = clampLevel(value + int(amount*float64(value-sum/9)))
+			}
+		}
+	}
+
+	return sharpened
+}
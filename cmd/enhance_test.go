@@ -0,0 +1,31 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+func TestEnhanceTextPage(t *testing.T) {
+	// a photocopy: gray paper with dark gray text
+	page := image.NewRGBA(image.Rect(0, 0, 100, 100))
+	draw.Draw(page, page.Bounds(), image.NewUniform(color.Gray{Y: 200}), image.Point{}, draw.Src)
+	draw.Draw(page, image.Rect(10, 10, 90, 20), image.NewUniform(color.Gray{Y: 90}), image.Point{}, draw.Src)
+
+	enhanced := enhanceTextPage(page, 1)
+	if enhanced == nil {
+		t.Fatalf("expected the page to be enhanced")
+	}
+
+	if paper := color.GrayModel.Convert(enhanced.At(50, 60)).(color.Gray); paper.Y != 255 {
+		t.Errorf("expected the paper to turn white, got %d", paper.Y)
+	}
+	if ink := color.GrayModel.Convert(enhanced.At(50, 15)).(color.Gray); ink.Y != 0 {
+		t.Errorf("expected the text to turn black, got %d", ink.Y)
+	}
+
+	if enhanceTextPage(enhanced, 1) != nil {
+		t.Errorf("expected an enhanced page to be left as it is")
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"errors"
+	"time"
+
+	book "github.com/ygunayer/fh5dl/book"
+)
+
+// rateLimitPause is how long a batch waits before the next book after being rate limited
+const rateLimitPause = time.Minute
+
+// error kinds recorded in the reports
+const (
+	errorKindNotFound          = "not-found"
+	errorKindPrivate           = "private"
+	errorKindRateLimited       = "rate-limited"
+	errorKindConfigParse       = "config-parse"
+	errorKindLayoutChanged     = "layout-changed"
+	errorKindChromeUnavailable = "chrome-unavailable"
+)
+
+// knownError is a failure of the book package along with what to tell the user about it
+type knownError struct {
+	err       error
+	kind      string
+	hint      string
+	retryable bool // whether trying again later can succeed
+}
+
+var knownErrors = []knownError{
+	{book.ErrBookNotFound, errorKindNotFound, "Check the book ID or URL, the book may have been removed.", false},
+	{book.ErrPrivateBook, errorKindPrivate, "The book is private or needs a login, which fh5dl can't download.", false},
+	{book.ErrRateLimited, errorKindRateLimited, "FlipHTML5 is limiting requests. Try again later or with a lower -c.", true},
+	// before ErrConfigParse, which a changed layout usually wraps too
+	{book.ErrLayoutChanged, errorKindLayoutChanged, "FlipHTML5 may have changed its site layout. Please update fh5dl; run fh5dl doctor --book with the book to check.", false},
+	{book.ErrConfigParse, errorKindConfigParse, "The book information has a format fh5dl doesn't know yet. Please report the book.", false},
+	{book.ErrChromeUnavailable, errorKindChromeUnavailable, "Interactive mode needs Google Chrome or Chromium installed and in PATH. Run fh5dl doctor to check.", true},
+}
+
+// classifyError finds the known failure behind err, if there is one
+func classifyError(err error) (knownError, bool) {
+	for _, known := range knownErrors {
+		if errors.Is(err, known.err) {
+			return known, true
+		}
+	}
+
+	return knownError{}, false
+}
+
+// retryableErrorKind tells whether a book that failed with the given kind of error is worth trying again.
+// Unknown failures are, as they are usually network problems.
+func retryableErrorKind(kind string) bool {
+	for _, known := range knownErrors {
+		if known.kind == kind {
+			return known.retryable
+		}
+	}
+
+	return true
+}
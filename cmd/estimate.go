@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	book "github.com/ygunayer/fh5dl/book"
+	"github.com/ztrue/tracerr"
+)
+
+type EstimateArgs struct {
+	Url         string `arg:"positional,required" help:"ID or URL of the book"`
+	Concurrency int    `arg:"-c" help:"(Optional) Number of concurrent downloads to estimate for. Defaults to (number of CPUs available - 1)"`
+	Samples     int    `arg:"--samples" help:"(Optional) Number of images to sample" default:"5"`
+	Provider    string `arg:"--provider" help:"(Optional) Flipbook platform of the book. Detected from the URL by default"`
+}
+
+// downloadEstimate is the expected size and duration of downloading the images of a book
+type downloadEstimate struct {
+	Images         int
+	Samples        int // images that were sampled
+	Bytes          int64
+	AverageBytes   int64
+	AverageLatency time.Duration
+	BytesPerSecond float64 // bandwidth of a single connection
+	Duration       time.Duration
+}
+
+// estimateCommand estimates the download size and time of a book by sampling a few of its images
+func estimateCommand(argv []string) error {
+	var args EstimateArgs
+	if ok, err := parseCommandArgs("estimate", &args, argv); !ok {
+		return err
+	}
+
+	if args.Concurrency <= 0 {
+		args.Concurrency = defaultConcurrency()
+	}
+	if args.Samples <= 0 {
+		args.Samples = 1
+	}
+
+	p, err := resolveProvider(args.Provider, args.Url)
+	if err != nil {
+		return err
+	}
+
+	b, err := p.Resolve(context.Background(), args.Url)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	images := p.Images(b)
+	if len(images) > maxBookImages {
+		images = images[:maxBookImages]
+	}
+	if len(images) == 0 {
+		return fmt.Errorf("book %s has no images", b.Id)
+	}
+
+	fmt.Printf("Book: %s (%d pages, %d images)\n", b.Title, len(b.Pages), len(images))
+
+	estimate, err := estimateImages(context.Background(), images, args.Concurrency, args.Samples)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Sampled %d images: %s on average, %s latency, %s/s per connection\n",
+		estimate.Samples, formatBytes(estimate.AverageBytes), estimate.AverageLatency.Round(time.Millisecond), formatBytes(int64(estimate.BytesPerSecond)))
+	fmt.Printf("Estimated download size: %s\n", formatBytes(estimate.Bytes))
+	fmt.Printf("Estimated download time: %s with concurrency %d\n", formatDuration(estimate.Duration), args.Concurrency)
+	fmt.Println("Interactive captures (-i) and PDF generation are not included in the estimate")
+
+	return nil
+}
+
+// estimateImages estimates the download of the images by sampling a few of them
+func estimateImages(ctx context.Context, images []book.PageImage, concurrency int, samples int) (downloadEstimate, error) {
+	heads, gets := probeImages(ctx, sampleImages(images, samples))
+	if len(heads) == 0 && len(gets) == 0 {
+		return downloadEstimate{}, fmt.Errorf("failed to probe any of the sampled images")
+	}
+
+	estimate := estimateDownload(len(images), concurrency, append(heads, gets...), gets)
+	estimate.Samples = len(heads) + len(gets)
+
+	return estimate, nil
+}
+
+// sampleImages picks up to count images spread evenly across the book
+func sampleImages(images []book.PageImage, count int) []book.PageImage {
+	if count >= len(images) {
+		return images
+	}
+
+	samples := make([]book.PageImage, 0, count)
+	for i := 0; i < count; i++ {
+		samples = append(samples, images[i*len(images)/count])
+	}
+
+	return samples
+}
+
+// probeImages sends a HEAD request for every sample, falling back to GET when the size is unknown. At least one
+// sample is always fetched with GET to measure the bandwidth.
+func probeImages(ctx context.Context, samples []book.PageImage) ([]*book.ImageProbe, []*book.ImageProbe) {
+	heads := make([]*book.ImageProbe, 0, len(samples))
+	gets := make([]*book.ImageProbe, 0)
+
+	for _, sample := range samples {
+		probe, err := sample.Probe(ctx, http.MethodHead)
+		if err == nil && probe.Bytes >= 0 {
+			heads = append(heads, probe)
+			continue
+		}
+
+		if probe, err := sample.Probe(ctx, http.MethodGet); err == nil {
+			gets = append(gets, probe)
+		}
+	}
+
+	if len(gets) == 0 {
+		for _, sample := range samples {
+			if probe, err := sample.Probe(ctx, http.MethodGet); err == nil {
+				gets = append(gets, probe)
+				break
+			}
+		}
+	}
+
+	return heads, gets
+}
+
+// estimateDownload extrapolates the sampled sizes and response times to the whole book. Images are downloaded
+// concurrency at a time, each taking the average latency plus the time to transfer the average size.
+func estimateDownload(images int, concurrency int, probes []*book.ImageProbe, transfers []*book.ImageProbe) downloadEstimate {
+	estimate := downloadEstimate{Images: images}
+	if len(probes) == 0 {
+		return estimate
+	}
+
+	var totalBytes int64
+	var totalLatency time.Duration
+	for _, probe := range probes {
+		totalBytes += probe.Bytes
+		totalLatency += probe.Latency
+	}
+	estimate.AverageBytes = totalBytes / int64(len(probes))
+	estimate.AverageLatency = totalLatency / time.Duration(len(probes))
+	estimate.Bytes = estimate.AverageBytes * int64(images)
+
+	var transferredBytes int64
+	var transferTime time.Duration
+	for _, transfer := range transfers {
+		transferredBytes += transfer.Bytes
+		transferTime += transfer.Duration - transfer.Latency
+	}
+	if transferTime > 0 {
+		estimate.BytesPerSecond = float64(transferredBytes) / transferTime.Seconds()
+	}
+
+	perImage := estimate.AverageLatency
+	if estimate.BytesPerSecond > 0 {
+		perImage += time.Duration(float64(estimate.AverageBytes) / estimate.BytesPerSecond * float64(time.Second))
+	}
+
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	waves := (images + concurrency - 1) / concurrency
+	estimate.Duration = perImage * time.Duration(waves)
+
+	return estimate
+}
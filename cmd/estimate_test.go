@@ -0,0 +1,43 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	book "github.com/ygunayer/fh5dl/book"
+)
+
+func TestSampleImages(t *testing.T) {
+	images := make([]book.PageImage, 10)
+	for i := range images {
+		images[i].PageNumber = i + 1
+	}
+
+	samples := sampleImages(images, 3)
+	if len(samples) != 3 || samples[0].PageNumber != 1 || samples[1].PageNumber != 4 || samples[2].PageNumber != 7 {
+		t.Errorf("unexpected samples %v", samples)
+	}
+
+	if all := sampleImages(images, 20); len(all) != 10 {
+		t.Errorf("expected every image when sampling more than there are, got %d", len(all))
+	}
+}
+
+func TestEstimateDownload(t *testing.T) {
+	head := &book.ImageProbe{Bytes: 1000, Latency: 100 * time.Millisecond}
+	get := &book.ImageProbe{Bytes: 3000, Latency: 100 * time.Millisecond, Duration: 1100 * time.Millisecond}
+
+	estimate := estimateDownload(10, 4, []*book.ImageProbe{head, get}, []*book.ImageProbe{get})
+
+	if estimate.AverageBytes != 2000 || estimate.Bytes != 20000 {
+		t.Errorf("unexpected size estimate: %d average, %d total", estimate.AverageBytes, estimate.Bytes)
+	}
+
+	// 3000 bytes in a second, so each image takes 100ms latency + 666ms transfer, in 3 waves of 4
+	if estimate.BytesPerSecond != 3000 {
+		t.Errorf("unexpected bandwidth %.1f", estimate.BytesPerSecond)
+	}
+	if estimate.Duration < 2290*time.Millisecond || estimate.Duration > 2310*time.Millisecond {
+		t.Errorf("unexpected duration %s", estimate.Duration)
+	}
+}
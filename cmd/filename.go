@@ -0,0 +1,125 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// maxFilenameBytes keeps file names well below the 255 byte/character limit of common file systems,
+// leaving room for the suffixes of reports, locks and split volumes
+const maxFilenameBytes = 150
+
+// maxPathLength is the classic windows MAX_PATH limit (260 minus the terminating NUL)
+const maxPathLength = 259
+
+// windowsReservedNames are device names windows refuses to use as file names, regardless of extension
+var windowsReservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// transliterations covers letters that don't decompose into an ascii base letter plus combining marks
+var transliterations = map[rune]string{
+	'ß': "ss", 'æ': "ae", 'Æ': "AE", 'œ': "oe", 'Œ': "OE", 'ø': "o", 'Ø': "O",
+	'ł': "l", 'Ł': "L", 'đ': "d", 'Đ': "D", 'ð': "d", 'Ð': "D", 'þ': "th", 'Þ': "TH", 'ı': "i",
+	'‘': "'", '’': "'", '“': "'", '”': "'", '«': "'", '»': "'",
+	'–': "-", '—': "-", '…': "...", '€': "EUR", '£': "GBP", '©': "(c)", '®': "(R)", '™': "TM",
+}
+
+// sanitizeFilename turns a book title into a file name that is valid on windows, macos and linux:
+// it removes reserved and non-printable characters, trailing dots and spaces, avoids reserved device
+// names and limits the length. With ascii set, accented letters are transliterated and other
+// non-ascii characters are dropped. The result is empty if nothing usable remains.
+func sanitizeFilename(filename string, ascii bool) string {
+	if ascii {
+		filename = transliterate(filename)
+	}
+
+	var sb strings.Builder
+	lastWasSpace := false
+	for _, r := range filename {
+		switch {
+		case strings.ContainsRune(`/\:*?"<>|`, r):
+			continue
+		case unicode.IsSpace(r):
+			// collapse tabs, newlines and unusual spaces into a single plain space
+			if !lastWasSpace {
+				sb.WriteRune(' ')
+			}
+			lastWasSpace = true
+			continue
+		case !unicode.IsPrint(r):
+			// control characters, zero-width and other formatting characters
+			continue
+		}
+
+		sb.WriteRune(r)
+		lastWasSpace = false
+	}
+
+	name := truncateUtf8(sb.String(), maxFilenameBytes)
+	name = strings.TrimLeft(name, " ")
+	name = strings.TrimRight(name, ". ")
+
+	// "CON", "con.backup" and the like can't be created on windows
+	stem := name
+	if i := strings.Index(stem, "."); i >= 0 {
+		stem = stem[:i]
+	}
+	if windowsReservedNames[strings.ToUpper(strings.TrimSpace(stem))] {
+		name = "_" + name
+	}
+
+	return name
+}
+
+// fitFilename shortens name so that dir/name+suffix stays within the windows path length limit
+func fitFilename(dir string, name string, suffix string) string {
+	available := maxPathLength - len(dir) - 1 - len(suffix)
+	if available >= len(name) {
+		return name
+	}
+
+	// keep at least a few characters, overly deep folders are the user's problem
+	if available < 16 {
+		available = 16
+	}
+
+	return strings.TrimRight(truncateUtf8(name, available), ". ")
+}
+
+// transliterate replaces accented and special letters with ascii approximations and drops anything else
+func transliterate(s string) string {
+	var sb strings.Builder
+	for _, r := range norm.NFD.String(s) {
+		switch {
+		case r < utf8.RuneSelf:
+			sb.WriteRune(r)
+		case unicode.Is(unicode.Mn, r):
+			// combining marks left over from decomposing accented letters
+		case transliterations[r] != "":
+			sb.WriteString(transliterations[r])
+		case unicode.IsSpace(r):
+			sb.WriteRune(' ')
+		}
+	}
+
+	return sb.String()
+}
+
+// truncateUtf8 cuts s to at most max bytes without splitting a multi-byte character
+func truncateUtf8(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+
+	for max > 0 && !utf8.RuneStart(s[max]) {
+		max--
+	}
+
+	return s[:max]
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSanitizeFilename(t *testing.T) {
+	cases := []struct {
+		input    string
+		ascii    bool
+		expected string
+	}{
+		{"Chapter 1: Intro?", false, "Chapter 1 Intro"},
+		{"CON", false, "_CON"},
+		{"lpt1.final", false, "_lpt1.final"},
+		{"Catalog 2024...  ", false, "Catalog 2024"},
+		{"Zero​width\ttab\x07bell", false, "Zerowidth tabbell"},
+		{"Crème brûlée & Straße", true, "Creme brulee & Strasse"},
+		{"Crème brûlée", false, "Crème brûlée"},
+		{"日本語", true, ""},
+	}
+
+	for _, c := range cases {
+		actual := sanitizeFilename(c.input, c.ascii)
+		if actual != c.expected {
+			t.Errorf("sanitizeFilename(%q, %t): expected %q, got %q", c.input, c.ascii, c.expected, actual)
+		}
+	}
+}
+
+func TestSanitizeFilenameLength(t *testing.T) {
+	actual := sanitizeFilename(strings.Repeat("é", 200), false)
+	if len(actual) > maxFilenameBytes {
+		t.Fatalf("expected at most %d bytes, got %d", maxFilenameBytes, len(actual))
+	}
+
+	if !strings.HasPrefix(strings.Repeat("é", 200), actual) {
+		t.Fatalf("expected a prefix on a character boundary, got %q", actual)
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+
+	"github.com/ygunayer/fh5dl/internal/history"
+	"github.com/ygunayer/fh5dl/internal/progress"
+)
+
+// historyPath is where downloads are recorded, a variable so tests can use their own
+var historyPath = history.DefaultPath
+
+// recordHistory adds the download of a book to the history. The history is only a convenience, so failing to
+// write it is only printed.
+func recordHistory(args *Args, report *bookReport) {
+	path, err := historyPath()
+	if err == nil {
+		outputFolder, _ := filepath.Abs(args.OutputFolder)
+		err = history.Append(path, history.Entry{
+			Time:         report.StartedAt,
+			Url:          report.Url,
+			BookId:       report.BookId,
+			Title:        report.Title,
+			Status:       report.Status,
+			Error:        report.Error,
+			Interactive:  report.Interactive,
+			Pages:        report.Pages,
+			OutputFolder: outputFolder,
+			Output:       report.PdfPath,
+		})
+	}
+	if err != nil {
+		args.reporter().Logf(progress.LevelError, "Error writing download history: %v", err)
+	}
+}
+
+// openFolder opens the folder in the file manager of the desktop
+func openFolder(path string) error {
+	var command *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		command = exec.Command("open", path)
+	case "windows":
+		command = exec.Command("explorer", path)
+	default:
+		command = exec.Command("xdg-open", path)
+	}
+
+	if err := command.Start(); err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+
+	// don't leave a zombie behind while the terminal UI keeps running
+	go command.Wait()
+	return nil
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	book "github.com/ygunayer/fh5dl/book"
+	"github.com/ygunayer/fh5dl/internal/progress"
+)
+
+// hook kinds, passed to hook commands as FH5DL_HOOK
+const (
+	hookImage = "image"
+	hookPdf   = "pdf"
+)
+
+// hookEnv is the metadata passed to hook commands as environment variables
+type hookEnv map[string]string
+
+// optionEnvNames are the variables the options are read from, see the env tags of Args
+var optionEnvNames = sync.OnceValue(func() map[string]bool {
+	names := make(map[string]bool)
+	fields := reflect.TypeOf(Args{})
+	for i := 0; i < fields.NumField(); i++ {
+		for _, part := range strings.Split(fields.Field(i).Tag.Get("arg"), ",") {
+			if name, ok := strings.CutPrefix(strings.TrimSpace(part), "env:"); ok {
+				names[name] = true
+			}
+		}
+	}
+	return names
+})
+
+// childEnv is the environment of the commands fh5dl runs, with the given variables added. The variables of the
+// options are left out, so an fh5dl run by a hook doesn't pick up the options of this one, and secrets such as
+// FH5DL_CAPTION_KEY don't reach commands that have no use for them.
+func childEnv(extra ...string) []string {
+	env := make([]string, 0, len(os.Environ())+len(extra))
+	for _, variable := range os.Environ() {
+		name, _, _ := strings.Cut(variable, "=")
+		if !optionEnvNames()[name] {
+			env = append(env, variable)
+		}
+	}
+	return append(env, extra...)
+}
+
+// hookRunner runs the user commands configured with --post-image-cmd and --post-pdf-cmd for a single book.
+// A failing hook is reported as a warning and recorded in the book report, but doesn't fail the download.
+type hookRunner struct {
+	mutex    sync.Mutex
+	reporter progress.Reporter
+	report   *bookReport
+	env      hookEnv
+}
+
+func newHookRunner(reporter progress.Reporter, report *bookReport, b *book.Book) *hookRunner {
+	return &hookRunner{
+		reporter: reporter,
+		report:   report,
+		env: hookEnv{
+			"FH5DL_BOOK_ID":       b.Id,
+			"FH5DL_BOOK_TITLE":    b.Title,
+			"FH5DL_URL":           b.Url,
+			"FH5DL_BOOK_PAGES":    strconv.Itoa(len(b.Pages)),
+			"FH5DL_BOOK_LANGUAGE": b.Language,
+			"FH5DL_OCR_LANGUAGE":  ocrLanguage(b.Language),
+		},
+	}
+}
+
+// image runs the image hook on a downloaded image or interactive capture
+func (h *hookRunner) image(ctx context.Context, command string, file string, pageNumber int) {
+	h.run(ctx, command, hookImage, file, hookEnv{"FH5DL_PAGE": strconv.Itoa(pageNumber)})
+}
+
+// capture runs the image hook on an interactive capture and the popups captured with it
+func (h *hookRunner) capture(ctx context.Context, command string, capture *book.InteractivePageImage) {
+	h.image(ctx, command, capture.FullPath, capture.PageNumber)
+	for _, popup := range capture.Popups {
+		h.image(ctx, command, popup, capture.PageNumber)
+	}
+}
+
+// pdf runs the PDF hook on a generated PDF, such as a volume or the plain PDF of --keep-original. The metadata is the
+// one of the whole book, written next to the PDF it was split from.
+func (h *hookRunner) pdf(ctx context.Context, command string, file string, metadata string) {
+	h.run(ctx, command, hookPdf, file, hookEnv{"FH5DL_METADATA": metadata})
+}
+
+// run runs a hook command with the file appended as its last argument and the metadata of the book in FH5DL_* variables
+func (h *hookRunner) run(ctx context.Context, command string, kind string, file string, extra hookEnv) {
+	if h == nil || command == "" {
+		return
+	}
+
+	cmd := shellCommand(ctx, command, file)
+	cmd.Env = childEnv("FH5DL_HOOK="+kind, "FH5DL_FILE="+file)
+	for _, env := range []hookEnv{h.env, extra} {
+		for key, value := range env {
+			cmd.Env = append(cmd.Env, key+"="+value)
+		}
+	}
+
+	output, err := cmd.CombinedOutput()
+	trimmed := strings.TrimSpace(string(output))
+	if err != nil {
+		hookErr := fmt.Sprintf("%s hook failed for %s: %v", kind, file, err)
+		if trimmed != "" {
+			hookErr += ": " + trimmed
+		}
+
+		h.reporter.Logf(progress.LevelWarn, "%s", hookErr)
+
+		h.mutex.Lock()
+		h.report.HookErrors = append(h.report.HookErrors, hookErr)
+		h.mutex.Unlock()
+		return
+	}
+
+	if trimmed != "" {
+		h.reporter.Logf(progress.LevelInfo, "%s", trimmed)
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+
+	book "github.com/ygunayer/fh5dl/book"
+	"github.com/ygunayer/fh5dl/internal/progress"
+)
+
+func TestHookRunner(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hook commands in this test use sh")
+	}
+
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out.txt")
+	reporter, _ := progress.New(progress.ModePlain, progress.Options{Out: &strings.Builder{}})
+	report := newBookReport("abcde/fghij")
+	hooks := newHookRunner(reporter, report, &book.Book{Id: "abcde/fghij", Title: "Catalog"})
+
+	hooks.image(context.Background(), `printf '%s %s %s' "$FH5DL_BOOK_TITLE" "$FH5DL_PAGE" >`+out, "/tmp/1-1.jpg", 3)
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := string(data); got != "Catalog 3 /tmp/1-1.jpg" {
+		t.Errorf("unexpected hook output %q", got)
+	}
+
+	hooks.pdf(context.Background(), "exit 3", filepath.Join(dir, "Catalog.pdf"), filepath.Join(dir, "Catalog.meta.json"))
+	if len(report.HookErrors) != 1 || !strings.Contains(report.HookErrors[0], "pdf hook failed") {
+		t.Errorf("expected the failed hook to be recorded, got %v", report.HookErrors)
+	}
+}
+
+func TestHookEnvironment(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hook commands in this test use sh")
+	}
+
+	// options of this run, which an fh5dl run by the hook mustn't pick up
+	t.Setenv("FH5DL_PAGES", "1-3")
+	t.Setenv("FH5DL_TITLE", "Other")
+	t.Setenv("FH5DL_CAPTION_KEY", "secret")
+
+	dir := t.TempDir()
+	out := filepath.Join(dir, "env.txt")
+	reporter, _ := progress.New(progress.ModePlain, progress.Options{Out: &strings.Builder{}})
+	hooks := newHookRunner(reporter, newBookReport("abcde/fghij"), &book.Book{Id: "abcde/fghij", Title: "Catalog", Language: "en"})
+
+	// the second volume of a split book, whose metadata is the one of the whole book
+	pdfPath := filepath.Join(dir, "Catalog.pdf")
+	volume := volumePath(pdfPath, 2, 3)
+	hooks.pdf(context.Background(), `env >`+out+`; true`, volume, metadataPath(pdfPath))
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	env := strings.Split(string(data), "\n")
+	for _, variable := range env {
+		name, _, _ := strings.Cut(variable, "=")
+		if optionEnvNames()[name] {
+			t.Errorf("expected the hook not to see the option variable %s", variable)
+		}
+	}
+	for _, expected := range []string{"FH5DL_BOOK_TITLE=Catalog", "FH5DL_BOOK_LANGUAGE=en", "FH5DL_HOOK=pdf",
+		"FH5DL_FILE=" + volume, "FH5DL_METADATA=" + filepath.Join(dir, "Catalog.meta.json")} {
+		if !slices.Contains(env, expected) {
+			t.Errorf("expected %s in the environment of the hook", expected)
+		}
+	}
+}
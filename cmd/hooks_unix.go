@@ -0,0 +1,18 @@
+//go:build !windows
+
+package main
+
+import (
+	"context"
+	"os/exec"
+)
+
+// shellCommand runs the command with sh, appending the file as its last argument
+func shellCommand(ctx context.Context, command string, file string) *exec.Cmd {
+	return exec.CommandContext(ctx, "sh", "-c", command+` "$@"`, "fh5dl", file)
+}
+
+// shellScript runs the command with sh as it is, for commands that take their file from the environment
+func shellScript(ctx context.Context, command string) *exec.Cmd {
+	return exec.CommandContext(ctx, "sh", "-c", command)
+}
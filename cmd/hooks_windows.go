@@ -0,0 +1,26 @@
+//go:build windows
+
+package main
+
+import (
+	"context"
+	"os/exec"
+	"syscall"
+)
+
+// shellCommand runs the command with cmd.exe, appending the quoted file as its last argument
+func shellCommand(ctx context.Context, command string, file string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "cmd")
+	// cmd.exe has its own quoting rules, so the command line is passed as is instead of escaping each argument
+	cmd.SysProcAttr = &syscall.SysProcAttr{CmdLine: `cmd /S /C "` + command + ` "` + file + `""`}
+
+	return cmd
+}
+
+// shellScript runs the command with cmd.exe as it is, for commands that take their file from the environment
+func shellScript(ctx context.Context, command string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "cmd")
+	cmd.SysProcAttr = &syscall.SysProcAttr{CmdLine: `cmd /S /C "` + command + `"`}
+
+	return cmd
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+
+	book "github.com/ygunayer/fh5dl/book"
+	"github.com/ztrue/tracerr"
+)
+
+// formats downloaded images are kept in
+const (
+	imageFormatOriginal = "original"
+	imageFormatJpg      = "jpg"
+	imageFormatPng      = "png"
+)
+
+// convertQuality is the JPEG quality of images converted with --image-format jpg
+const convertQuality = 95
+
+// cachedImagePath returns the path of an image downloaded into the image folder by an earlier run, in the format
+// images are kept in
+func cachedImagePath(imageOutputRoot string, img book.PageImage, format string) (string, bool) {
+	if format == "" || format == imageFormatOriginal {
+		return img.CachedPath(imageOutputRoot)
+	}
+
+	path := filepath.Join(imageOutputRoot, img.FileName(format))
+	if _, err := os.Stat(path); err != nil {
+		return "", false
+	}
+
+	return path, true
+}
+
+// convertImage re-encodes a downloaded image into the format, returning the path of the converted image, and
+// removes the original. Images already in the format, or kept as served, are left untouched.
+func convertImage(imagePath string, format string) (string, error) {
+	if format == "" || format == imageFormatOriginal {
+		return imagePath, nil
+	}
+
+	file, err := os.Open(imagePath)
+	if err != nil {
+		return "", tracerr.Wrap(err)
+	}
+	img, served, err := image.Decode(file)
+	file.Close()
+	if err != nil {
+		return "", fmt.Errorf("failed to decode %s: %w", imagePath, err)
+	}
+
+	outputPath := trimExtension(imagePath) + "." + format
+	if served == "jpeg" && format == imageFormatJpg || served == format {
+		if outputPath != imagePath {
+			return outputPath, tracerr.Wrap(os.Rename(imagePath, outputPath))
+		}
+		return imagePath, nil
+	}
+
+	switch format {
+	case imageFormatJpg:
+		err = writeJpeg(outputPath, img, convertQuality)
+	case imageFormatPng:
+		err = writePng(outputPath, img)
+	default:
+		err = fmt.Errorf("unknown image format %q", format)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if outputPath != imagePath {
+		if err := os.Remove(imagePath); err != nil {
+			return "", tracerr.Wrap(err)
+		}
+	}
+
+	return outputPath, nil
+}
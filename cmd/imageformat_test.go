@@ -0,0 +1,45 @@
+package main
+
+import (
+	"image"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConvertImage(t *testing.T) {
+	dir := t.TempDir()
+
+	// a PNG served under the usual .jpg name
+	served := filepath.Join(dir, "1-1.jpg")
+	writeTestPng(t, served, image.Pt(20, 30))
+
+	converted, err := convertImage(served, imageFormatOriginal)
+	if err != nil || converted != served {
+		t.Fatalf("expected the image to be kept as served, got %s, %v", converted, err)
+	}
+
+	converted, err = convertImage(served, imageFormatJpg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	file, err := os.Open(converted)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer file.Close()
+	if _, format, err := image.DecodeConfig(file); err != nil || format != "jpeg" {
+		t.Errorf("expected a JPEG, got %s, %v", format, err)
+	}
+
+	converted, err = convertImage(converted, imageFormatPng)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if converted != filepath.Join(dir, "1-1.png") {
+		t.Errorf("unexpected converted path %s", converted)
+	}
+	if _, err := os.Stat(served); !os.IsNotExist(err) {
+		t.Errorf("expected the jpg to be replaced by the png")
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ztrue/tracerr"
+)
+
+// indexFile is the HTML index written into the output folder
+const indexFile = "index.html"
+
+type IndexArgs struct {
+	Folder   string `arg:"positional" help:"Output folder of the books. Defaults to the current working directory" default:"."`
+	Title    string `arg:"--title" help:"(Optional) Title of the index page" default:"fh5dl library"`
+	NoCovers bool   `arg:"--no-covers" help:"(Optional) Don't download the first pages of the books for covers"`
+}
+
+// indexPage is what the index template is rendered with
+type indexPage struct {
+	Title     string
+	Generated string
+	Books     []indexBook
+}
+
+// indexBook is a book on the index page, with links relative to the index
+type indexBook struct {
+	Title      string
+	Author     string
+	Pages      int
+	Downloaded string
+	Source     string
+	Cover      string
+	Files      []indexFileLink
+}
+
+type indexFileLink struct {
+	Label string
+	Href  string
+}
+
+var indexTemplate = template.Must(template.New(indexFile).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2rem; background: #f6f6f6; color: #222; }
+header p { color: #666; }
+main { display: grid; grid-template-columns: repeat(auto-fill, minmax(200px, 1fr)); gap: 1.5rem; }
+article { background: #fff; border-radius: 6px; box-shadow: 0 1px 3px rgba(0, 0, 0, .15); overflow: hidden; }
+article img, article .cover { display: block; width: 100%; aspect-ratio: 3 / 4; object-fit: cover; background: #ddd; }
+article div { padding: .75rem; }
+article h2 { font-size: 1rem; margin: 0 0 .25rem; }
+article p { font-size: .85rem; color: #666; margin: .25rem 0; }
+article a { margin-right: .5rem; }
+</style>
+</head>
+<body>
+<header>
+<h1>{{.Title}}</h1>
+<p>{{len .Books}} books, updated {{.Generated}}</p>
+</header>
+<main>
+{{- range .Books}}
+<article>
+<a href="{{(index .Files 0).Href}}">{{if .Cover}}<img src="{{.Cover}}" alt="" loading="lazy">{{else}}<span class="cover"></span>{{end}}</a>
+<div>
+<h2>{{.Title}}</h2>
+{{- if .Author}}
+<p>{{.Author}}</p>
+{{- end}}
+<p>{{.Pages}} pages, downloaded {{.Downloaded}}</p>
+<p>{{range .Files}}<a href="{{.Href}}">{{.Label}}</a>{{end}}{{if .Source}}<a href="{{.Source}}" rel="noreferrer">Original</a>{{end}}</p>
+</div>
+</article>
+{{- end}}
+</main>
+</body>
+</html>
+`))
+
+// indexCommand writes a static HTML index of the books in an output folder
+func indexCommand(argv []string) error {
+	var args IndexArgs
+	if ok, err := parseCommandArgs("index", &args, argv); !ok {
+		return err
+	}
+
+	count, err := writeIndex(context.Background(), args.Folder, args.Title, args.NoCovers, func(format string, a ...interface{}) {
+		fmt.Fprintf(os.Stderr, format+"\n", a...)
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Listed %d books in %s\n", count, filepath.Join(args.Folder, indexFile))
+	return nil
+}
+
+// writeIndex writes index.html into the folder, listing the same books as the OPDS catalog
+func writeIndex(ctx context.Context, folder string, title string, noCovers bool, warnf func(format string, args ...interface{})) (int, error) {
+	if title == "" {
+		title = "fh5dl library"
+	}
+
+	books, err := findCatalogBooks(folder)
+	if err != nil {
+		return 0, err
+	}
+
+	page := indexPage{Title: title, Generated: time.Now().Format("2006-01-02 15:04"), Books: make([]indexBook, 0, len(books))}
+	missingCovers := 0
+	for _, b := range books {
+		entry := indexBook{
+			Title:      b.metadata.Title,
+			Author:     b.metadata.Author,
+			Pages:      b.metadata.Pages,
+			Downloaded: b.metadata.CreatedAt.Format("2006-01-02"),
+		}
+		if strings.HasPrefix(b.metadata.Url, "http://") || strings.HasPrefix(b.metadata.Url, "https://") {
+			entry.Source = b.metadata.Url
+		}
+		if entry.Title == "" {
+			entry.Title = trimExtension(path.Base(b.files[0]))
+		}
+
+		for i, file := range b.files {
+			label := strings.ToUpper(strings.TrimPrefix(path.Ext(file), "."))
+			if len(b.files) > 1 {
+				label = fmt.Sprintf("Volume %d", i+1)
+			}
+			entry.Files = append(entry.Files, indexFileLink{Label: label, Href: catalogLink("", file)})
+		}
+
+		if !noCovers {
+			cover, err := catalogCover(ctx, folder, b.metadata)
+			if err != nil {
+				missingCovers++
+			} else if cover != "" {
+				entry.Cover = catalogLink("", cover)
+			}
+		}
+
+		page.Books = append(page.Books, entry)
+	}
+	if missingCovers > 0 {
+		warnf("Couldn't make the covers of %d books, they are listed without one", missingCovers)
+	}
+
+	var out strings.Builder
+	if err := indexTemplate.Execute(&out, page); err != nil {
+		return 0, tracerr.Wrap(err)
+	}
+
+	indexPath := filepath.Join(folder, indexFile)
+	if err := os.WriteFile(indexPath+".tmp", []byte(out.String()), 0644); err != nil {
+		return 0, tracerr.Wrap(err)
+	}
+
+	return len(books), tracerr.Wrap(os.Rename(indexPath+".tmp", indexPath))
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteIndex(t *testing.T) {
+	dir := t.TempDir()
+
+	// a book split into volumes
+	base := filepath.Join(dir, "atlas", "Atlas")
+	volumes := []string{base + " - Part 1.pdf", base + " - Part 2.pdf"}
+	if err := os.MkdirAll(filepath.Dir(base), 0755); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, volume := range volumes {
+		if err := os.WriteFile(volume, []byte("%PDF"), 0644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	metadata := &bookMetadata{BookId: "abcde/fghij", Title: "Atlas <Europe>", Pages: 400, Url: "https://online.fliphtml5.com/abcde/fghij/", Volumes: volumes}
+	if err := writeMetadata(base+".pdf", metadata); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	count, err := writeIndex(context.Background(), dir, "Maps", true, t.Logf)
+	if err != nil || count != 1 {
+		t.Fatalf("expected 1 book, got %d (%v)", count, err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, indexFile))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, expected := range []string{
+		"<title>Maps</title>",
+		"<h2>Atlas &lt;Europe&gt;</h2>",
+		"400 pages",
+		`<a href="atlas/Atlas%20-%20Part%202.pdf">Volume 2</a>`,
+		`<a href="https://online.fliphtml5.com/abcde/fghij/" rel="noreferrer">Original</a>`,
+	} {
+		if !strings.Contains(string(data), expected) {
+			t.Errorf("expected %q in the index:\n%s", expected, data)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	book "github.com/ygunayer/fh5dl/book"
+	"github.com/ygunayer/fh5dl/provider"
+	"github.com/ztrue/tracerr"
+)
+
+type InfoArgs struct {
+	Url      string `arg:"positional,required" help:"ID or URL of the book"`
+	Json     bool   `arg:"--json" help:"(Optional) Print the information as JSON"`
+	Provider string `arg:"--provider" help:"(Optional) Flipbook platform of the book. Detected from the URL by default"`
+}
+
+// bookInfo is what is known about a book before downloading it, along with what its platform supports
+type bookInfo struct {
+	Id               string                `json:"id"`
+	Url              string                `json:"url"`
+	Title            string                `json:"title"`
+	Provider         string                `json:"provider"`
+	ProviderDetected bool                  `json:"providerDetected"` // picked from the URL rather than --provider
+	Pages            int                   `json:"pages"`
+	Images           int                   `json:"images"`
+	OutlineEntries   int                   `json:"outlineEntries"`
+	Language         string                `json:"language,omitempty"`
+	Capabilities     provider.Capabilities `json:"capabilities"`
+}
+
+// infoCommand prints the information of a book and the features of its platform, without downloading it
+func infoCommand(argv []string) error {
+	var args InfoArgs
+	if ok, err := parseCommandArgs("info", &args, argv); !ok {
+		return err
+	}
+
+	p, err := resolveProvider(args.Provider, args.Url)
+	if err != nil {
+		return err
+	}
+
+	b, err := p.Resolve(context.Background(), args.Url)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	info := describeBook(p, b, args.Provider == "")
+	if args.Json {
+		data, err := json.MarshalIndent(info, "", "  ")
+		if err != nil {
+			return tracerr.Wrap(err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	printBookInfo(os.Stdout, info)
+	return nil
+}
+
+func describeBook(p provider.Provider, b *book.Book, detected bool) bookInfo {
+	return bookInfo{
+		Id:               b.Id,
+		Url:              b.Url,
+		Title:            b.Title,
+		Provider:         p.Name(),
+		ProviderDetected: detected,
+		Pages:            len(b.Pages),
+		Images:           len(p.Images(b)),
+		OutlineEntries:   countOutlineEntries(b.Outline),
+		Language:         b.Language,
+		Capabilities:     provider.CapabilitiesOf(p),
+	}
+}
+
+func countOutlineEntries(entries []book.OutlineEntry) int {
+	count := len(entries)
+	for _, entry := range entries {
+		count += countOutlineEntries(entry.Children)
+	}
+
+	return count
+}
+
+// printBookInfo lists the features of the platform along with the flags that depend on them, so users know which
+// flags make sense for the book
+func printBookInfo(w io.Writer, info bookInfo) {
+	fmt.Fprintf(w, "Book: %s\n", info.Title)
+	fmt.Fprintf(w, "ID: %s\n", info.Id)
+	fmt.Fprintf(w, "URL: %s\n", info.Url)
+	if info.ProviderDetected {
+		fmt.Fprintf(w, "Provider: %s (detected from the URL)\n", info.Provider)
+	} else {
+		fmt.Fprintf(w, "Provider: %s\n", info.Provider)
+	}
+	fmt.Fprintf(w, "Pages: %d (%d images)\n", info.Pages, info.Images)
+	if info.Language != "" {
+		fmt.Fprintf(w, "Language: %s\n", info.Language)
+	}
+	if info.OutlineEntries > 0 {
+		fmt.Fprintf(w, "Table of contents: %d entries\n", info.OutlineEntries)
+	}
+
+	fmt.Fprintf(w, "Features of %s:\n", info.Provider)
+	features := []struct {
+		name      string
+		supported bool
+	}{
+		{"Interactive capture (-i, --reveal-script)", info.Capabilities.InteractiveCapture},
+		{"Table of contents (PDF bookmarks, --toc-page)", info.Capabilities.TableOfContents},
+		{"Links and notes layers (--annotations)", info.Capabilities.Layers},
+		{"Thumbnails (--thumbnail-fallback)", info.Capabilities.Thumbnails},
+	}
+	for _, feature := range features {
+		supported := "no"
+		if feature.supported {
+			supported = "yes"
+		}
+		fmt.Fprintf(w, "  %s: %s\n", feature.name, supported)
+	}
+}
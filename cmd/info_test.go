@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	book "github.com/ygunayer/fh5dl/book"
+	"github.com/ygunayer/fh5dl/provider"
+)
+
+func TestDescribeBook(t *testing.T) {
+	p, ok := provider.Get("fliphtml5")
+	if !ok {
+		t.Fatal("expected the fliphtml5 provider to be registered")
+	}
+
+	b := &book.Book{
+		Id:    "abcde/fghij",
+		Title: "Biology Workbook",
+		Pages: []book.Page{{Number: 1, ImageUrls: []string{"1.jpg"}}, {Number: 2, ImageUrls: []string{"2.jpg", "2b.jpg"}}},
+		Outline: []book.OutlineEntry{
+			{Title: "Cells", Page: 1, Children: []book.OutlineEntry{{Title: "Membranes", Page: 2}}},
+		},
+	}
+
+	info := describeBook(p, b, true)
+	if info.Pages != 2 || info.Images != 3 || info.OutlineEntries != 2 || !info.Capabilities.TableOfContents {
+		t.Errorf("unexpected info %+v", info)
+	}
+
+	var out bytes.Buffer
+	printBookInfo(&out, info)
+	for _, line := range []string{"Provider: fliphtml5 (detected from the URL)", "Table of contents: 2 entries", "Thumbnails (--thumbnail-fallback): yes"} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("expected %q in the output:\n%s", line, out.String())
+		}
+	}
+
+	wordpress, _ := provider.Get("wordpress")
+	out.Reset()
+	printBookInfo(&out, describeBook(wordpress, b, false))
+	if !strings.Contains(out.String(), "Interactive capture (-i, --reveal-script): no") || strings.Contains(out.String(), "detected") {
+		t.Errorf("expected no interactive capture for wordpress embeds:\n%s", out.String())
+	}
+}
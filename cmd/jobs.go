@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ygunayer/fh5dl/internal/jobs"
+	"github.com/ygunayer/fh5dl/internal/progress"
+)
+
+// jobsDir is where batch runs register themselves, replaced by tests
+var jobsDir = jobs.DefaultDir
+
+type JobsArgs struct {
+	Action string `arg:"positional" help:"list, pause, resume or cancel" default:"list"`
+	Id     string `arg:"positional" help:"ID of the job to pause, resume or cancel, as listed"`
+}
+
+// jobsCommand lists the batch runs on this machine, or pauses, resumes or cancels one of them
+func jobsCommand(argv []string) error {
+	var args JobsArgs
+	if ok, err := parseCommandArgs("jobs", &args, argv); !ok {
+		return err
+	}
+
+	dir, err := jobsDir()
+	if err != nil {
+		return err
+	}
+
+	if args.Action == "list" {
+		statuses, err := jobs.List(dir)
+		if err != nil {
+			return err
+		}
+		printJobs(os.Stdout, statuses)
+		return nil
+	}
+
+	request := jobs.Request(args.Action)
+	if request != jobs.RequestPause && request != jobs.RequestResume && request != jobs.RequestCancel {
+		return fmt.Errorf("invalid action %q, expected list, pause, resume or cancel", args.Action)
+	}
+	if args.Id == "" {
+		return fmt.Errorf("the ID of the job to %s is required, see fh5dl jobs", args.Action)
+	}
+
+	if err := jobs.Control(dir, args.Id, request); err != nil {
+		return err
+	}
+
+	fmt.Printf("Asked job %s to %s, it does so before its next book\n", args.Id, args.Action)
+	return nil
+}
+
+func printJobs(w io.Writer, statuses []jobs.Status) {
+	if len(statuses) == 0 {
+		fmt.Fprintln(w, "No jobs running")
+		return
+	}
+
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "ID\tPRIORITY\tSTATE\tBOOKS\tRUNNING FOR\tCURRENT\tNAME")
+	for _, status := range statuses {
+		fmt.Fprintf(table, "%s\t%d\t%s\t%d/%d\t%s\t%s\t%s\n", status.Id, status.Priority, status.State, status.Done, status.Total,
+			formatDuration(time.Since(status.StartedAt)), status.Current, status.Name)
+	}
+	table.Flush()
+}
+
+// startJob registers a batch as a job, so it can be controlled from other terminals. Batches run without one if
+// the jobs folder isn't usable.
+func startJob(base Args, total int, reporter progress.Reporter) *jobs.Job {
+	dir, err := jobsDir()
+	if err != nil {
+		reporter.Logf(progress.LevelWarn, "Couldn't register the batch as a job, it can't be paused: %v", err)
+		return nil
+	}
+
+	name := fmt.Sprintf("%d books into %s", total, base.OutputFolder)
+	if base.FromFile != "" && base.FromFile != "-" {
+		name = fmt.Sprintf("%s into %s", base.FromFile, base.OutputFolder)
+	}
+
+	job, err := jobs.Start(dir, name, base.Priority, total)
+	if err != nil {
+		reporter.Logf(progress.LevelWarn, "Couldn't register the batch as a job, it can't be paused: %v", err)
+		return nil
+	}
+
+	return job
+}
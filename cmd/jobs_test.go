@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ygunayer/fh5dl/internal/jobs"
+)
+
+func TestJobsCommand(t *testing.T) {
+	dir := t.TempDir()
+	defer func(original func() (string, error)) { jobsDir = original }(jobsDir)
+	jobsDir = func() (string, error) { return dir, nil }
+
+	job, err := jobs.Start(dir, "books.txt into output", 2, 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer job.Finish()
+
+	if err := jobsCommand([]string{"pause", job.Id()}); err != nil {
+		t.Errorf("unexpected error pausing the job: %v", err)
+	}
+	if err := jobsCommand([]string{"resume", "nosuch"}); !errors.Is(err, jobs.ErrNotFound) {
+		t.Errorf("expected an unknown job not to be found, got %v", err)
+	}
+	if err := jobsCommand([]string{"stop", job.Id()}); err == nil {
+		t.Error("expected an error for an unknown action")
+	}
+	if err := jobsCommand([]string{"cancel"}); err == nil {
+		t.Error("expected an error without a job ID")
+	}
+}
+
+func TestPrintJobs(t *testing.T) {
+	var out bytes.Buffer
+	printJobs(&out, nil)
+	if !strings.Contains(out.String(), "No jobs running") {
+		t.Errorf("unexpected output %s", out.String())
+	}
+
+	out.Reset()
+	printJobs(&out, []jobs.Status{{Id: "a1b2c3", Name: "books.txt into output", Priority: 2, State: jobs.StateWaiting, Done: 3, Total: 10, Current: "abcde/fghij"}})
+	for _, part := range []string{"a1b2c3", "waiting", "3/10", "abcde/fghij", "books.txt into output"} {
+		if !strings.Contains(out.String(), part) {
+			t.Errorf("expected %q in the output:\n%s", part, out.String())
+		}
+	}
+}
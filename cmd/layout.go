@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	book "github.com/ygunayer/fh5dl/book"
+)
+
+// output layouts
+const (
+	layoutDefault = "default"
+	layoutKomga   = "komga"
+	layoutKavita  = "kavita"
+)
+
+// libraryWorkFolder keeps the images and temp files of batch books written outside of their own folders, hidden
+// from the scans of Komga and Kavita
+const libraryWorkFolder = ".fh5dl"
+
+// outputPlaceholders are the values --folder and --filename templates can use
+var outputPlaceholders = []string{"title", "series", "volume", "id", "author"}
+
+var placeholderRegex = regexp.MustCompile(`\{(\w+)\}`)
+
+// validLayout checks the value of the --layout flag
+func validLayout(layout string) bool {
+	return layout == "" || layout == layoutDefault || layout == layoutKomga || layout == layoutKavita
+}
+
+// validOutputTemplate checks that a --folder or --filename template only uses known placeholders
+func validOutputTemplate(template string) error {
+	for _, match := range placeholderRegex.FindAllStringSubmatch(template, -1) {
+		known := false
+		for _, placeholder := range outputPlaceholders {
+			known = known || match[1] == placeholder
+		}
+		if !known {
+			return fmt.Errorf("unknown placeholder %s in %q, expected one of {%s}", match[0], template, strings.Join(outputPlaceholders, "}, {"))
+		}
+	}
+
+	return nil
+}
+
+// libraryLayout reports whether books are written for Komga and Kavita, which both read a folder per series with
+// the volumes of the series in it
+func (args *Args) libraryLayout() bool {
+	return args.Layout == layoutKomga || args.Layout == layoutKavita
+}
+
+// ownFolder reports whether batch books are written into a folder of their own, named after their id
+func (args *Args) ownFolder() bool {
+	return args.Folder == "" && !args.libraryLayout()
+}
+
+// series returns the series of the book, the book itself unless --series is set
+func (args *Args) series(b *book.Book) string {
+	if args.Series != "" {
+		return args.Series
+	}
+
+	return b.Title
+}
+
+// outputTemplates returns the --folder and --filename templates, filled in by the layout. The library layout names
+// volumes "<series> Vol. 01", which both servers parse the volume number from and which sorts in order.
+func (args *Args) outputTemplates() (string, string) {
+	folder, filename := args.Folder, args.Filename
+	if !args.libraryLayout() {
+		return folder, filename
+	}
+
+	if folder == "" {
+		folder = "{series}"
+	}
+	if filename == "" && args.Volume > 0 {
+		filename = "{series} Vol. {volume}"
+	}
+
+	return folder, filename
+}
+
+// outputNames returns the folder and the file name of the book in the output folder. The file name is empty if
+// the book has no usable title.
+func (args *Args) outputNames(outputDir string, b *book.Book) (string, string) {
+	values := map[string]string{
+		"title":  b.Title,
+		"series": args.series(b),
+		"id":     strings.ReplaceAll(b.Id, "/", "_"),
+		"author": args.Author,
+	}
+	if args.Volume > 0 {
+		values["volume"] = fmt.Sprintf("%02d", args.Volume)
+	}
+
+	folder, filename := args.outputTemplates()
+	if folder != "" {
+		// every level of the folder is a file name of its own, and none may lead out of the output folder
+		for _, segment := range strings.Split(expandOutputTemplate(folder, values), "/") {
+			segment = sanitizeFilename(segment, args.AsciiNames)
+			if segment != "" && segment != "." && segment != ".." {
+				outputDir = filepath.Join(outputDir, segment)
+			}
+		}
+	}
+
+	if filename == "" {
+		filename = "{title}"
+	}
+
+	return outputDir, sanitizeFilename(expandOutputTemplate(filename, values), args.AsciiNames)
+}
+
+// expandOutputTemplate replaces the placeholders of the template with their values
+func expandOutputTemplate(template string, values map[string]string) string {
+	return placeholderRegex.ReplaceAllStringFunc(template, func(placeholder string) string {
+		return values[strings.Trim(placeholder, "{}")]
+	})
+}
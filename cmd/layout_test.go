@@ -0,0 +1,44 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	book "github.com/ygunayer/fh5dl/book"
+)
+
+func TestOutputNames(t *testing.T) {
+	b := &book.Book{Id: "abcde/fghij", Title: "Biology: Cells"}
+	cases := []struct {
+		args         Args
+		folder, name string
+	}{
+		{Args{}, "out", "Biology Cells"},
+		{Args{Layout: layoutKomga}, filepath.Join("out", "Biology Cells"), "Biology Cells"},
+		{Args{Layout: layoutKavita, Series: "Course Notes", Volume: 3}, filepath.Join("out", "Course Notes"), "Course Notes Vol. 03"},
+		{Args{Folder: "{series}/{id}", Series: "Notes", Filename: "{volume} - {title}", Volume: 12}, filepath.Join("out", "Notes", "abcde_fghij"), "12 - Biology Cells"},
+		// folders can't lead out of the output folder
+		{Args{Folder: "../{title}/./"}, filepath.Join("out", "Biology Cells"), "Biology Cells"},
+	}
+
+	for _, c := range cases {
+		folder, name := c.args.outputNames("out", b)
+		if folder != c.folder || name != c.name {
+			t.Errorf("expected %q / %q for %+v, got %q / %q", c.folder, c.name, c.args, folder, name)
+		}
+	}
+
+	// books without a usable title leave the file name to the caller
+	if _, name := (&Args{}).outputNames("out", &book.Book{Id: "abcde/fghij", Title: "***"}); name != "" {
+		t.Errorf("expected no file name, got %q", name)
+	}
+}
+
+func TestValidOutputTemplate(t *testing.T) {
+	if err := validOutputTemplate("{series}/{title} ({id})"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := validOutputTemplate("{name}"); err == nil {
+		t.Errorf("expected an error for an unknown placeholder")
+	}
+}
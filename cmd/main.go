@@ -2,200 +2,314 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"image"
 	"os"
 	"path/filepath"
-	"runtime"
 	"sort"
 	"strconv"
 	"strings"
 	"sync"
-	"sync/atomic"
 	"time"
 
 	arg "github.com/alexflint/go-arg"
 	pdfcpu_api "github.com/pdfcpu/pdfcpu/pkg/api"
 	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
-	"github.com/schollz/progressbar/v3"
-	book "github.com/ygunayer/fh5dl/internal/book"
+	book "github.com/ygunayer/fh5dl/book"
+	"github.com/ygunayer/fh5dl/internal/fixture"
+	"github.com/ygunayer/fh5dl/internal/lockfile"
+	"github.com/ygunayer/fh5dl/internal/progress"
+	"github.com/ygunayer/fh5dl/provider"
 	"github.com/ztrue/tracerr"
 	"golang.org/x/sync/errgroup"
 	// terminal ui imports
 )
 
+// staleLockAge is how old a lock file has to be before it is taken over even if its owner might still be alive
+const staleLockAge = 24 * time.Hour
+
+// maxBookImages is the most images downloaded for a single book, some books list duplicate or unneeded images
+const maxBookImages = 1000
+
+// pageSizeSamples is how many downloaded images are looked at to find the native page size for interactive captures
+const pageSizeSamples = 10
+
 type Args struct {
-	Url               string `arg:"positional" help:"ID or URL of the PDF to download"`
-	Concurrency       int    `arg:"-c" help:"(Optional) Number of concurrent downloads. Defaults to (number of CPUs available - 1)"`
-	OutputFolder      string `arg:"-o" help:"(Optional) Output folder for the PDF. Defaults to the current working directory" default:"."`
-	ImageOutputFolder string `arg:"--image-out" help:"(Optional) Output folder for downloaded images. Defaults to a temporary directory" default:""`
-	Force             bool   `arg:"-f" help:"(Optional) Overwrite existing PDF file if it exists"`
-	Interactive       bool   `arg:"-i" help:"(Optional) Capture screenshots with interactive elements revealed"`
-	TerminalUI        bool   `arg:"-t, --termui" help:"(Optional) Use the terminal UI instead of command line arguments"`
-	BatchSize         int    `arg:"-b" help:"(Optional) Batch size for interactive captures. Defaults to 8" default:"8"`
+	Urls              []string `arg:"positional" help:"IDs or URLs of the PDFs to download. Several books are downloaded as a batch"`
+	Url               string   `arg:"-"`
+	Concurrency       int      `arg:"-c,env:FH5DL_CONCURRENCY" help:"(Optional) Number of concurrent downloads. Defaults to (number of CPUs available - 1)"`
+	OutputFolder      string   `arg:"-o,env:FH5DL_OUTPUT_FOLDER" help:"(Optional) Output folder for the PDF. Defaults to the current working directory" default:"."`
+	ImageOutputFolder string   `arg:"--image-out,env:FH5DL_IMAGE_OUT" help:"(Optional) Output folder for downloaded images. Defaults to a temporary directory" default:""`
+	ImageFormat       string   `arg:"--image-format,env:FH5DL_IMAGE_FORMAT" help:"(Optional) Format downloaded images are kept in: original keeps the bytes as served, jpg or png converts them" default:"original"`
+	Force             bool     `arg:"-f,env:FH5DL_FORCE" help:"(Optional) Overwrite existing PDF file if it exists. Same as --on-conflict overwrite"`
+	OnConflict        string   `arg:"--on-conflict,env:FH5DL_ON_CONFLICT" help:"(Optional) What to do if the PDF already exists: skip, overwrite, rename or prompt" default:"skip"`
+	Interactive       bool     `arg:"-i,env:FH5DL_INTERACTIVE" help:"(Optional) Capture screenshots with interactive elements revealed"`
+	TerminalUI        bool     `arg:"-t, --termui,env:FH5DL_TERMUI" help:"(Optional) Use the terminal UI instead of command line arguments"`
+	Theme             string   `arg:"--theme,env:FH5DL_THEME" help:"(Optional) Colors of the terminal UI: auto, dark, light or none. auto follows the background of the terminal, and NO_COLOR turns colors off" default:"auto"`
+	BatchSize         int      `arg:"-b,env:FH5DL_BATCH_SIZE" help:"(Optional) Batch size for interactive captures. Defaults to 8" default:"8"`
+	FromFile          string   `arg:"--from-file,env:FH5DL_FROM_FILE" help:"(Optional) Read URLs from a text file, one per line with # comments. Use - to read from stdin"`
+	AsciiNames        bool     `arg:"--ascii-names,env:FH5DL_ASCII_NAMES" help:"(Optional) Transliterate output file names to plain ASCII"`
+	WorkDir           string   `arg:"--work-dir,env:FH5DL_WORK_DIR" help:"(Optional) Folder for temporary files such as cached images and browser profiles. Defaults to the system temp directory"`
+	ReportFormat      string   `arg:"--report,env:FH5DL_REPORT" help:"(Optional) Summary report to write next to each PDF: json, markdown, all or none" default:"json"`
+	ComparePages      bool     `arg:"--compare-pages,env:FH5DL_COMPARE_PAGES" help:"(Optional) With -i, put the original page before each interactive capture, e.g. to see questions and answers separately"`
+	KeepOriginal      bool     `arg:"--keep-original,env:FH5DL_KEEP_ORIGINAL" help:"(Optional) With -i, also write the output of the original page images as <title>.orig.pdf"`
+	RevealScript      string   `arg:"--reveal-script,env:FH5DL_REVEAL_SCRIPT" help:"(Optional) With -i, javascript file or YAML selectors config to reveal hidden content the built-in script misses"`
+	CaptureDebug      bool     `arg:"--capture-debug,env:FH5DL_CAPTURE_DEBUG" help:"(Optional) With -i, show the browser with DevTools and save the DOM and console errors of pages that fail to capture"`
+	Annotations       bool     `arg:"--annotations,env:FH5DL_ANNOTATIONS" help:"(Optional) With -i, add the notes and stickies shown by the viewer as PDF text annotations"`
+	CapturePopups     bool     `arg:"--capture-popups,env:FH5DL_CAPTURE_POPUPS" help:"(Optional) With -i, also capture the popups and lightboxes opened by triggers, added after their page"`
+	CaptureScale      float64  `arg:"--capture-scale,env:FH5DL_CAPTURE_SCALE" help:"(Optional) Device scale factor for interactive captures, such as 2 for print quality. Defaults to matching the page images"`
+	Container         bool     `arg:"--container,env:FH5DL_CONTAINER" help:"(Optional) Settings for Docker and Kubernetes: plain progress without colors, temp files in the output folder, container Chrome flags and a /dev/shm check"`
+	MobileCapture     bool     `arg:"--mobile-capture,env:FH5DL_MOBILE_CAPTURE" help:"(Optional) With -i, capture pages in an emulated phone, for viewers that show a simpler single page layout on phones"`
+	Pages             string   `arg:"--pages,env:FH5DL_PAGES" help:"(Optional) Only download these pages, such as 1-10,15,20- for pages 1 to 10, 15 and 20 onwards"`
+	Sample            int      `arg:"--sample,env:FH5DL_SAMPLE" help:"(Optional) Only download the first and last few pages, as many as given, into <title>.sample.pdf to check the book and the settings"`
+	Strict            bool     `arg:"--strict,env:FH5DL_STRICT" help:"(Optional) Fail the book if the output is missing pages of the book, instead of warning about them"`
+	AllowMissingPages *int     `arg:"--allow-missing-pages,env:FH5DL_ALLOW_MISSING_PAGES" help:"(Optional) Fail the book if more than this many pages are missing, such as pages whose images can't be downloaded. Defaults to skipping any number of them"`
+	ThumbnailFallback bool     `arg:"--thumbnail-fallback,env:FH5DL_THUMBNAIL_FALLBACK" help:"(Optional) Use the upscaled thumbnail of pages whose image can't be downloaded, listed in the report"`
+	ContactSheet      bool     `arg:"--contact-sheet,env:FH5DL_CONTACT_SHEET" help:"(Optional) Only download the thumbnails and write a contact sheet of the pages as <title>.contact.jpg, to preview the book"`
+	CoverOnly         bool     `arg:"--cover-only,env:FH5DL_COVER_ONLY" help:"(Optional) Only download the first page at its full size and write it as <title>.cover.jpg, for cover art"`
+	UpscaleBelow      int      `arg:"--upscale-below,env:FH5DL_UPSCALE_BELOW" help:"(Optional) Upscale pages narrower than this many pixels to this width, for books that only publish small images"`
+	UpscaleCmd        string   `arg:"--upscale-cmd,env:FH5DL_UPSCALE_CMD" help:"(Optional) With --upscale-below, command that upscales a page instead of the built-in Lanczos scaling, with the page as its last argument and the output path in FH5DL_OUTPUT"`
+	Rotate            []string `arg:"--rotate,env:FH5DL_ROTATE" help:"(Optional) Turn pages clockwise, such as 10-20:90 for landscape inserts. Takes more than one, such as 10-20:90 45:270"`
+	Deskew            bool     `arg:"--deskew,env:FH5DL_DESKEW" help:"(Optional) Straighten pages whose text is slightly tilted, as on scanned books"`
+	Enhance           string   `arg:"--enhance,env:FH5DL_ENHANCE" help:"(Optional) Enhance the page images with a preset: text whitens the paper, darkens the ink and sharpens, for photocopied handouts"`
+	TrimMargins       bool     `arg:"--trim-margins,env:FH5DL_TRIM_MARGINS" help:"(Optional) Crop the white or black borders off the pages, alike for pages of the same size, for tighter PDFs on tablets"`
+	PlaceholderPages  bool     `arg:"--placeholder-pages,env:FH5DL_PLACEHOLDER_PAGES" help:"(Optional) Put a page saying why in place of every missing page, so the page numbers of the PDF match the book"`
+	TocPage           bool     `arg:"--toc-page,env:FH5DL_TOC_PAGE" help:"(Optional) Start the PDF with a generated table of contents page linking to the chapters, for readers without a bookmarks panel"`
+	Title             string   `arg:"--title,env:FH5DL_TITLE" help:"(Optional) Title to use instead of the one of the book, for the file name and the PDF metadata"`
+	Author            string   `arg:"--author,env:FH5DL_AUTHOR" help:"(Optional) Author written into the PDF metadata"`
+	Subject           string   `arg:"--subject,env:FH5DL_SUBJECT" help:"(Optional) Subject written into the PDF metadata"`
+	Language          string   `arg:"--language,env:FH5DL_LANGUAGE" help:"(Optional) Language of the book as a code such as en or pt-BR, for the PDF metadata and OCR hooks. Detected from the book when available"`
+	Format            string   `arg:"--format,env:FH5DL_FORMAT" help:"(Optional) Output format: pdf, djvu, strip, cbz, txt or md. djvu needs c44 and djvm from djvulibre, txt and md need --ocr-cmd" default:"pdf"`
+	OcrCmd            string   `arg:"--ocr-cmd,env:FH5DL_OCR_CMD" help:"(Optional) Command that prints the text of the page image in FH5DL_FILE, such as tesseract $FH5DL_FILE stdout, for --format txt and md, to tag PDFs for screen readers and for --tts-cmd"`
+	TtsCmd            string   `arg:"--tts-cmd,env:FH5DL_TTS_CMD" help:"(Optional) With --ocr-cmd, command that reads the text on its stdin aloud into the MP3 at FH5DL_OUTPUT, to narrate every chapter into <title>.audio"`
+	CaptionUrl        string   `arg:"--caption-url,env:FH5DL_CAPTION_URL" help:"(Optional) OpenAI compatible chat completions endpoint, local or hosted, that describes every page for the metadata and the alt text of the PDF, such as http://localhost:11434/v1/chat/completions"`
+	CaptionModel      string   `arg:"--caption-model,env:FH5DL_CAPTION_MODEL" help:"(Optional) With --caption-url, model that describes the pages, such as llava"`
+	CaptionKey        string   `arg:"--caption-key,env:FH5DL_CAPTION_KEY" help:"(Optional) With --caption-url, API key sent as a bearer token. Better set in FH5DL_CAPTION_KEY than on the command line" json:"-"`
+	Layout            string   `arg:"--layout,env:FH5DL_LAYOUT" help:"(Optional) How to name the output: default, or komga or kavita for a folder per series as those servers expect" default:"default"`
+	Series            string   `arg:"--series,env:FH5DL_SERIES" help:"(Optional) Series of the book, for --layout and the ComicInfo.xml of cbz files. Defaults to the title of the book"`
+	Volume            int      `arg:"--volume,env:FH5DL_VOLUME" help:"(Optional) Volume number of the book in its series, for --layout and the ComicInfo.xml of cbz files"`
+	Folder            string   `arg:"--folder,env:FH5DL_FOLDER" help:"(Optional) Folder of the book in the output folder, such as {series}/{title}. Takes {title}, {series}, {volume}, {id} and {author}"`
+	Filename          string   `arg:"--filename,env:FH5DL_FILENAME" help:"(Optional) File name of the book without the extension, such as {series} Vol. {volume}. Takes the same placeholders as --folder"`
+	SplitEvery        int      `arg:"--split-every,env:FH5DL_SPLIT_EVERY" help:"(Optional) Split the output into volumes of at most this many pages"`
+	SplitMaxSize      string   `arg:"--split-max-size,env:FH5DL_SPLIT_MAX_SIZE" help:"(Optional) Split the output into volumes of at most this size, such as 50MB"`
+	StripHeight       int      `arg:"--strip-height,env:FH5DL_STRIP_HEIGHT" help:"(Optional) Maximum height in pixels of each image with --format strip. Defaults to 65500, the most a JPEG can hold"`
+	Record            string   `arg:"--record,env:FH5DL_RECORD" help:"(Optional) Also record a walkthrough of the book in the viewer as mp4 or gif, for animations and media a PDF can't hold. Needs ffmpeg"`
+	RecordPageSeconds float64  `arg:"--record-page-seconds,env:FH5DL_RECORD_PAGE_SECONDS" help:"(Optional) How long each page is shown in the recording. Defaults to 3"`
+	PostImageCmd      string   `arg:"--post-image-cmd,env:FH5DL_POST_IMAGE_CMD" help:"(Optional) Command to run on every downloaded image, with the image path as its last argument"`
+	PostPdfCmd        string   `arg:"--post-pdf-cmd,env:FH5DL_POST_PDF_CMD" help:"(Optional) Command to run on every generated PDF, with the PDF path as its last argument"`
+	RecordFixtures    string   `arg:"--record-fixtures,env:FH5DL_RECORD_FIXTURES" help:"(Optional) Save every HTTP response into this folder, for reproducing problems with a book offline"`
+	Replay            string   `arg:"--replay,env:FH5DL_REPLAY" help:"(Optional) Serve HTTP responses from a folder written by --record-fixtures instead of the network"`
+	SupportBundle     string   `arg:"--support-bundle,env:FH5DL_SUPPORT_BUNDLE" help:"(Optional) When the download fails, write a support bundle for a bug report to this zip file without asking"`
+	Provider          string   `arg:"--provider,env:FH5DL_PROVIDER" help:"(Optional) Flipbook platform of the books. Detected from the URL by default"`
+	Progress          string   `arg:"--progress,env:FH5DL_PROGRESS" help:"(Optional) How to show progress: auto, bar, plain or json. auto uses bars in a terminal and plain lines otherwise" default:"auto"`
+	HttpTimeout       float64  `arg:"--http-timeout,env:FH5DL_HTTP_TIMEOUT" help:"(Optional) Seconds to wait for each image request before giving up on it. Defaults to 30"`
+	Retries           *int     `arg:"--retries,env:FH5DL_RETRIES" help:"(Optional) How many times to retry an image that failed to download. Defaults to 2"`
+	RetryBackoff      float64  `arg:"--retry-backoff,env:FH5DL_RETRY_BACKOFF" help:"(Optional) Seconds to wait before the first retry of an image, doubled for every further one. Defaults to 2"`
+	Update            bool     `arg:"--update,env:FH5DL_UPDATE" help:"(Optional) With --image-out, write the PDF again if the book changed since, downloading only the changed pages. Up to date PDFs are skipped"`
+	Revalidate        bool     `arg:"--revalidate,env:FH5DL_REVALIDATE" help:"(Optional) Ask the server whether images downloaded by an earlier run changed, and download them again if they did"`
+	Opds              bool     `arg:"--opds,env:FH5DL_OPDS" help:"(Optional) Update the OPDS catalog.xml of the output folder after downloading, for e-reader apps"`
+	Priority          int      `arg:"--priority,env:FH5DL_PRIORITY" help:"(Optional) Priority of a batch. While batches with a higher priority run, others wait between books. Defaults to 0"`
+	Profile           string   `arg:"--profile,env:FH5DL_PROFILE" help:"(Optional) Write CPU and heap profiles and the phase timings of the run into this folder"`
+
+	// Reporter receives the progress of the download, created from Progress when not set
+	Reporter progress.Reporter `arg:"-" json:"-"`
+
+	// profiler records the phase timings of the books with --profile
+	profiler *profiler
+
+	// sharedMemory lets Chrome use /dev/shm, which --container checks the size of
+	sharedMemory bool
+
+	// control pauses or cancels the download from the terminal UI
+	control *downloadControl
 }
 
-func downloadImages(ctx context.Context, args *Args, images []book.PageImage) ([]book.DownloadedImage, error) {
+// warmUpConnections is how many connections to the image host are opened before downloading images
+const warmUpConnections = 4
+
+// downloadImages downloads the images of a book, returning the images that still failed after retrying. Only rate
+// limiting and cancellation stop the download, other pages are left for the missing pages policy to decide on.
+func downloadImages(ctx context.Context, args *Args, hooks *hookRunner, images []book.PageImage) ([]book.DownloadedImage, []failedImage, error) {
 	imageOutputRoot := ""
 	if args.ImageOutputFolder != "" {
 		realdir, err := filepath.Abs(args.ImageOutputFolder)
 		if err != nil {
-			return nil, tracerr.Wrap(err)
+			return nil, nil, tracerr.Wrap(err)
 		}
 
 		if _, err := os.Stat(realdir); os.IsNotExist(err) {
 			err = os.MkdirAll(realdir, os.ModePerm)
 			if err != nil {
-				return nil, tracerr.Wrap(err)
+				return nil, nil, tracerr.Wrap(err)
 			}
 		}
 
 		imageOutputRoot = realdir
 	} else {
-		tmpdir, err := os.MkdirTemp("", "fh5dl-")
+		tmpdir, err := newWorkTempDir(args.WorkDir, "fh5dl-")
 		if err != nil {
-			return nil, tracerr.Wrap(err)
+			return nil, nil, tracerr.Wrap(err)
 		}
 
 		imageOutputRoot = tmpdir
 	}
 
-	// use a more efficient method for large downloads
-	downloadedImages := make([]book.DownloadedImage, 0, len(images))
-	mutex := sync.Mutex{}
+	// every image has its own slot for its result, so workers never wait on each other to record one
+	downloaded := make([]*book.DownloadedImage, len(images))
+	failed := make([]*failedImage, len(images))
 
-	// for better memory management, process in batches
-	batchSize := 50 // smaller batches for more frequent updates
-	if len(images) <= batchSize {
-		batchSize = len(images)
-	}
-
-	numBatches := (len(images) + batchSize - 1) / batchSize // ceiling division
-
-	// if more than 200 images, show more detailed progress
-	if len(images) > 200 {
-		fmt.Printf("Processing %d images in %d batches of %d\n", len(images), numBatches, batchSize)
-	}
-
-	mainBar := progressbar.NewOptions(len(images),
-		progressbar.OptionSetDescription("Downloading images"),
-		progressbar.OptionEnableColorCodes(true),
-		progressbar.OptionShowCount(),
-		progressbar.OptionShowIts(),
-		progressbar.OptionSetWidth(50),
-		progressbar.OptionThrottle(65*time.Millisecond),
-		progressbar.OptionOnCompletion(func() {
-			fmt.Println()
-		}),
-	)
-
-	// track download speeds
-	startTime := time.Now()
-	var completedImages int32
-
-	for batchIdx := 0; batchIdx < numBatches; batchIdx++ {
-		start := batchIdx * batchSize
-		end := (batchIdx + 1) * batchSize
-		if end > len(images) {
-			end = len(images)
-		}
-
-		batchImages := images[start:end]
-
-		// log batch progress
-		if numBatches > 1 {
-			fmt.Printf("Batch %d/%d: %d images\n", batchIdx+1, numBatches, len(batchImages))
-		}
-
-		eg, batchCtx := errgroup.WithContext(ctx)
-		eg.SetLimit(args.Concurrency)
-
-		for _, image := range batchImages {
-			image := image // create copy for closure
-
-			eg.Go(func() error {
-				// first check if the file already exists to avoid unnecessary network requests
-				expectedPath := filepath.Join(imageOutputRoot, fmt.Sprintf("%d-%d.jpg", image.PageNumber, image.ImageNumber))
-				if _, err := os.Stat(expectedPath); err == nil {
-					// file already exists
-					mutex.Lock()
-					downloadedImages = append(downloadedImages, book.DownloadedImage{
-						PageNumber:   image.PageNumber,
-						ImageNumber:  image.ImageNumber,
-						OverallOrder: image.OverallOrder,
-						Url:          image.Url,
-						FullPath:     expectedPath,
-					})
-					mutex.Unlock()
-
-					atomic.AddInt32(&completedImages, 1)
-					if err := mainBar.Add(1); err != nil {
-						return tracerr.Wrap(err)
-					}
+	// open connections to the image host before the workers all try to at once
+	for _, image := range images {
+		if _, ok := cachedImagePath(imageOutputRoot, image, args.ImageFormat); !ok || args.Revalidate {
+			book.WarmUp(ctx, image.Url, min(args.Concurrency, warmUpConnections))
+			break
+		}
+	}
 
-					return nil
+	reporter := args.reporter()
+	task := reporter.Start("download", "Downloading images", len(images))
+	defer task.Finish()
+
+	// the limit bounds how many downloads, and so response buffers, are in flight at once
+	eg, downloadCtx := errgroup.WithContext(ctx)
+	eg.SetLimit(args.Concurrency)
+
+	for slot, image := range images {
+		image := image // create copy for closure
+
+		eg.Go(func() error {
+			// first check if the file already exists to avoid unnecessary network requests, unless it is to be revalidated
+			if expectedPath, ok := cachedImagePath(imageOutputRoot, image, args.ImageFormat); ok && !args.Revalidate {
+				// file already exists
+				downloaded[slot] = &book.DownloadedImage{
+					PageNumber:   image.PageNumber,
+					ImageNumber:  image.ImageNumber,
+					OverallOrder: image.OverallOrder,
+					Url:          image.Url,
+					FullPath:     expectedPath,
 				}
 
-				// download the image if it doesn't exist
-				result, err := image.Download(batchCtx, imageOutputRoot)
-				if err != nil {
-					return tracerr.Wrap(err)
-				}
+				task.Add(1)
+				return nil
+			}
 
-				mutex.Lock()
-				downloadedImages = append(downloadedImages, *result)
-				mutex.Unlock()
+			// wait here while paused, so the images in flight are finished but no new ones are started
+			if err := args.control.wait(downloadCtx); err != nil {
+				return tracerr.Wrap(err)
+			}
 
-				// update progress and stats
-				completed := atomic.AddInt32(&completedImages, 1)
-				if completed%10 == 0 && completed > 0 {
-					// calculate download speed and eta
-					elapsed := time.Since(startTime)
-					imagesPerSecond := float64(completed) / elapsed.Seconds()
-					if imagesPerSecond > 0 {
-						eta := time.Duration(float64(len(images)-int(completed))/imagesPerSecond) * time.Second
-						fmt.Printf("\rRate: %.1f img/s, ETA: %s",
-							imagesPerSecond, formatDuration(eta))
-					}
+			// download the image if it doesn't exist
+			result, err := image.Download(downloadCtx, imageOutputRoot, args.downloadOptions())
+			if err != nil {
+				if downloadCtx.Err() != nil || errors.Is(err, book.ErrRateLimited) {
+					return tracerr.Wrap(err)
 				}
 
-				if err := mainBar.Add(1); err != nil {
-					return tracerr.Wrap(err)
+				reporter.Logf(progress.LevelWarn, "Failed to download image %d of page %d: %v", image.ImageNumber, image.PageNumber, err)
+				failed[slot] = &failedImage{
+					Page:   image.PageNumber,
+					Image:  image.ImageNumber,
+					Url:    image.Url,
+					Status: imageFailureStatus(err),
+					Error:  err.Error(),
 				}
 
+				task.Add(1)
 				return nil
-			})
-		}
+			}
 
-		if err := eg.Wait(); err != nil {
-			return nil, tracerr.Wrap(err)
+			result.FullPath, err = convertImage(result.FullPath, args.ImageFormat)
+			if err != nil {
+				return err
+			}
+
+			downloaded[slot] = result
+
+			// images that turned out to be unchanged were handled by an earlier run
+			if result.Attempts > 0 || !result.Revalidated {
+				hooks.image(downloadCtx, args.PostImageCmd, result.FullPath, result.PageNumber)
+			}
+
+			task.Add(1)
+			return nil
+		})
+	}
+
+	if err := eg.Wait(); err != nil {
+		return nil, nil, tracerr.Wrap(err)
+	}
+
+	task.Finish()
+
+	// the slots follow the order of the images, which is the reading order
+	downloadedImages := make([]book.DownloadedImage, 0, len(images))
+	failedImages := make([]failedImage, 0)
+	for slot := range images {
+		if downloaded[slot] != nil {
+			downloadedImages = append(downloadedImages, *downloaded[slot])
 		}
+		if failed[slot] != nil {
+			failedImages = append(failedImages, *failed[slot])
+		}
+	}
 
-		// force gc between batches to reduce memory pressure
-		runtime.GC()
+	return downloadedImages, failedImages, nil
+}
+
+// captureViewport scales the window so interactive captures have the resolution of the downloaded page images
+// (pageSize), and don't look blurrier than the pages around them, unless --capture-scale is set
+func captureViewport(window book.Viewport, scale float64, pageSize image.Point) book.Viewport {
+	if scale > 0 {
+		window.Scale = scale
+		return window
 	}
 
-	if err := mainBar.Close(); err != nil {
-		return nil, tracerr.Wrap(err)
+	return window.ForPage(pageSize.X, pageSize.Y)
+}
+
+// nativePageSize returns the size of the largest of the first few downloaded images, which are usually full pages
+func nativePageSize(images []book.DownloadedImage) (image.Point, error) {
+	largest := image.Point{}
+	for i, img := range images {
+		if i == pageSizeSamples {
+			break
+		}
+
+		size, err := imageSize(img.FullPath)
+		if err != nil {
+			return image.Point{}, err
+		}
+
+		if size.X*size.Y > largest.X*largest.Y {
+			largest = size
+		}
 	}
 
-	// sort images by order
-	sort.Slice(downloadedImages, func(i, j int) bool {
-		return downloadedImages[i].OverallOrder < downloadedImages[j].OverallOrder
-	})
+	return largest, nil
+}
 
-	// final report
-	fmt.Printf("Downloaded %d images in %s\n", len(downloadedImages),
-		formatDuration(time.Since(startTime)))
+// captureDiagnosticsDir is where the screenshot, DOM and console messages of pages that fail to capture are saved
+func captureDiagnosticsDir(args *Args, b *book.Book) string {
+	return filepath.Join(args.OutputFolder, "fh5dl-debug", filepath.FromSlash(b.Id))
+}
 
-	return downloadedImages, nil
+// existingPopups returns the popup captures of a page from an earlier run, if popups are captured at all
+func existingPopups(args *Args, interactiveOutputRoot string, pageNumber int) []string {
+	if !args.CapturePopups {
+		return nil
+	}
+
+	return book.PopupFiles(interactiveOutputRoot, pageNumber)
 }
 
-func captureInteractivePages(ctx context.Context, args *Args, b *book.Book) ([]book.InteractivePageImage, error) {
+// captureInteractivePages captures the interactive pages of a book, returning the captures and the pages that still failed after retrying
+func captureInteractivePages(ctx context.Context, args *Args, hooks *hookRunner, p provider.Provider, b *book.Book, pageSize image.Point) ([]book.InteractivePageImage, []int, error) {
 	interactiveOutputRoot := ""
 	if args.ImageOutputFolder != "" {
 		realdir, err := filepath.Abs(args.ImageOutputFolder)
 		if err != nil {
-			return nil, tracerr.Wrap(err)
+			return nil, nil, tracerr.Wrap(err)
 		}
 
 		// Add an "interactive" subfolder
@@ -203,13 +317,13 @@ func captureInteractivePages(ctx context.Context, args *Args, b *book.Book) ([]b
 		if _, err := os.Stat(interactiveOutputRoot); os.IsNotExist(err) {
 			err = os.MkdirAll(interactiveOutputRoot, os.ModePerm)
 			if err != nil {
-				return nil, tracerr.Wrap(err)
+				return nil, nil, tracerr.Wrap(err)
 			}
 		}
 	} else {
-		tmpdir, err := os.MkdirTemp("", "fh5dl-interactive-")
+		tmpdir, err := newWorkTempDir(args.WorkDir, "fh5dl-interactive-")
 		if err != nil {
-			return nil, tracerr.Wrap(err)
+			return nil, nil, tracerr.Wrap(err)
 		}
 
 		interactiveOutputRoot = tmpdir
@@ -231,19 +345,87 @@ func captureInteractivePages(ctx context.Context, args *Args, b *book.Book) ([]b
 		batchSize = concurrencyLimit // Ensure batch size is at least as large as concurrency
 	}
 
-	fmt.Printf("Using concurrency limit of %d with batch size of %d for interactive captures\n", concurrencyLimit, batchSize)
+	// a phone screen is portrait too, but also gets the mobile layout of viewers that have one
+	window := book.SinglePageViewport
+	if args.MobileCapture {
+		window = book.MobileViewport
+	}
+
+	captureOptions := book.CaptureOptions{
+		WorkDir:      args.WorkDir,
+		Viewport:     captureViewport(window, args.CaptureScale, pageSize),
+		Popups:       args.CapturePopups,
+		Annotations:  args.Annotations,
+		DebugDir:     captureDiagnosticsDir(args, b),
+		Container:    args.Container,
+		SharedMemory: args.sharedMemory,
+	}
+	if args.CaptureDebug {
+		captureOptions.Debug = true
+		captureOptions.Logf = func(format string, a ...interface{}) {
+			args.reporter().Logf(progress.LevelInfo, format, a...)
+		}
+	}
+	if args.RevealScript != "" {
+		script, err := book.LoadRevealScript(args.RevealScript)
+		if err != nil {
+			return nil, nil, tracerr.Wrap(err)
+		}
+		captureOptions.RevealScript = script
+	}
+
+	reporter := args.reporter()
+
+	// All pages are captured in tabs of one browser, which needs far less memory than a browser per page
+	browser, err := book.NewBrowser(ctx, captureOptions)
+	if err != nil {
+		reporter.Logf(progress.LevelWarn, "Failed to start a shared browser, starting one per page instead: %v", err)
+	} else {
+		defer browser.Close()
+		captureOptions.Browser = browser
+	}
+
+	reporter.Logf(progress.LevelInfo, "Using concurrency limit of %d with batch size of %d for interactive captures", concurrencyLimit, batchSize)
 
-	// Create a list of pages we actually need to capture
-	// In FlipHTML5 books, usually page 1 is single, then 2-3 are together, 4-5 together, etc.
-	// So we need to capture pages 1, 2, 4, 6, 8, ... since odd pages (except 1) can be extracted from the even page spread
-	pagesToCapture := []int{1} // Always start with page 1 (single page)
+	// Viewers differ in how they pair pages into spreads, so look at the first two pages instead of assuming
+	secondPageUrl := ""
+	if len(b.Pages) > 1 {
+		secondPageUrl = p.PageUrl(b, 2)
+	}
 
-	for i := 2; i <= len(b.Pages); i += 2 {
-		// Add even numbered pages (2, 4, 6, 8...)
-		pagesToCapture = append(pagesToCapture, i)
+	// The viewer shows one page at a time in a portrait window, so every capture holds exactly one page. If it
+	// sticks to spreads, go back to the landscape window and pick the page out of the spread instead. A phone is
+	// kept even then, as asked for with --mobile-capture.
+	layout, err := book.DetectLayout(ctx, p.PageUrl(b, 1), secondPageUrl, captureOptions)
+	if err == nil && layout != book.LayoutSingle && !args.MobileCapture {
+		captureOptions.Viewport = captureViewport(book.DefaultViewport, args.CaptureScale, pageSize)
+		layout, err = book.DetectLayout(ctx, p.PageUrl(b, 1), secondPageUrl, captureOptions)
+	}
+	if err != nil {
+		reporter.Logf(progress.LevelWarn, "Couldn't detect the page layout, assuming a single cover page followed by spreads: %v", err)
+		if !args.MobileCapture {
+			captureOptions.Viewport = captureViewport(book.DefaultViewport, args.CaptureScale, pageSize)
+		}
+	} else {
+		reporter.Logf(progress.LevelInfo, "Detected a %s page layout", layout)
 	}
+	captureOptions.Layout = layout
 
-	fmt.Printf("Optimized page capture: Will capture %d pages instead of %d (first page + even pages for spreads)\n", len(pagesToCapture), len(b.Pages))
+	viewport := captureOptions.Viewport
+	if viewport.Mobile {
+		reporter.Logf(progress.LevelInfo, "Capturing pages in an emulated %dx%d phone at %gx scale", viewport.Width, viewport.Height, viewport.Scale)
+	} else {
+		reporter.Logf(progress.LevelInfo, "Capturing pages in a %dx%d window at %gx scale", viewport.Width, viewport.Height, viewport.Scale)
+	}
+
+	// Every page is captured on its own, the layout tells which side of a spread it is on
+	selectedPages := args.pageSet()
+	pagesToCapture := make([]int, 0, len(b.Pages))
+	for i := 1; i <= len(b.Pages); i++ {
+		if selectedPages.Contains(i) {
+			pagesToCapture = append(pagesToCapture, i)
+		}
+	}
 
 	// Process pages in batches for better resource management
 	numBatches := (len(pagesToCapture) + batchSize - 1) / batchSize // Ceiling division
@@ -252,10 +434,7 @@ func captureInteractivePages(ctx context.Context, args *Args, b *book.Book) ([]b
 	failedPages := make([]int, 0)
 	mutex := sync.Mutex{}
 
-	// Used for time estimation
-	startTime := time.Now()
-	var completedPages int32 = 0
-	totalPages := len(pagesToCapture)
+	task := reporter.Start("capture", "Capturing pages", len(pagesToCapture))
 
 	// Process batches sequentially but pages within each batch in parallel
 	for batchIndex := 0; batchIndex < numBatches; batchIndex++ {
@@ -266,27 +445,6 @@ func captureInteractivePages(ctx context.Context, args *Args, b *book.Book) ([]b
 		}
 
 		currentBatch := pagesToCapture[startIdx:endIdx]
-		fmt.Printf("Processing batch %d/%d with %d pages\n", batchIndex+1, numBatches, len(currentBatch))
-
-		// Configure progress bar with timing estimate
-		batchBar := progressbar.NewOptions(len(currentBatch),
-			progressbar.OptionSetDescription(fmt.Sprintf("Batch %d/%d", batchIndex+1, numBatches)),
-			progressbar.OptionEnableColorCodes(true),
-			progressbar.OptionShowCount(),
-			progressbar.OptionShowIts(),
-			progressbar.OptionSetTheme(progressbar.Theme{
-				Saucer:        "[green]=[reset]",
-				SaucerHead:    "[green]>[reset]",
-				SaucerPadding: " ",
-				BarStart:      "[",
-				BarEnd:        "]",
-			}),
-			progressbar.OptionOnCompletion(func() {
-				fmt.Printf("\n")
-			}),
-			progressbar.OptionSetElapsedTime(true),
-			progressbar.OptionFullWidth(),
-		)
 
 		// Create a fresh context for each batch
 		batchCtx, batchCancel := context.WithCancel(ctx)
@@ -303,37 +461,19 @@ func captureInteractivePages(ctx context.Context, args *Args, b *book.Book) ([]b
 				capturedPages = append(capturedPages, book.InteractivePageImage{
 					PageNumber:   pageNumber,
 					OverallOrder: pageNumber,
-					Url:          fmt.Sprintf("%s#p=%d", b.Url, pageNumber),
+					Url:          p.PageUrl(b, pageNumber),
 					FullPath:     fullPath,
+					Popups:       existingPopups(args, interactiveOutputRoot, pageNumber),
 				})
 				mutex.Unlock()
 
-				// If page is even and not the last page, also create a reference for the odd page
-				// but don't duplicate the actual file
-				if pageNumber > 1 && pageNumber%2 == 0 && pageNumber < len(b.Pages) {
-					oddPageNumber := pageNumber + 1
-
-					mutex.Lock()
-					capturedPages = append(capturedPages, book.InteractivePageImage{
-						PageNumber:   oddPageNumber,
-						OverallOrder: oddPageNumber,
-						Url:          fmt.Sprintf("%s#p=%d", b.Url, oddPageNumber),
-						FullPath:     fullPath, // Use the same file path as the even page
-					})
-					mutex.Unlock()
-				}
-
-				// Update progress counters
-				atomic.AddInt32(&completedPages, 1)
-				if err := batchBar.Add(1); err != nil {
-					fmt.Fprintf(os.Stderr, "Error updating progress bar: %v\n", err)
-				}
+				task.Add(1)
 			} else {
 				// File doesn't exist, queue for processing
 				pageNum := pageNumber // Create a copy for the closure
 				eg.Go(func() error {
 					// Page URL is the direct URL to the page in the flipbook viewer
-					pageUrl := fmt.Sprintf("%s#p=%d", b.Url, pageNum)
+					pageUrl := p.PageUrl(b, pageNum)
 
 					// Create an isolated context for this particular page
 					pageCtx, cancelPage := context.WithCancel(batchCtx)
@@ -342,54 +482,22 @@ func captureInteractivePages(ctx context.Context, args *Args, b *book.Book) ([]b
 					// Add a small delay between starting each browser to reduce race conditions
 					time.Sleep(time.Millisecond * 200)
 
-					// Use quiet mode for less log clutter during captures
-					result, err := book.CaptureInteractivePageQuiet(pageCtx, pageUrl, interactiveOutputRoot, pageNum, pageNum)
+					// Capture the page into its own folder, logging only with --capture-debug
+					result, err := book.CaptureInteractivePage(pageCtx, pageUrl, interactiveOutputRoot, pageNum, pageNum, captureOptions)
 					if err != nil {
-						fmt.Fprintf(os.Stderr, "\nError capturing page %d: %v\n", pageNum, err)
+						reporter.Logf(progress.LevelError, "Error capturing page %d: %v", pageNum, err)
 						mutex.Lock()
 						failedPages = append(failedPages, pageNum)
 						mutex.Unlock()
 					} else {
+						hooks.capture(pageCtx, args.PostImageCmd, result)
+
 						mutex.Lock()
 						capturedPages = append(capturedPages, *result)
-
-						// If page is even and not the last page, also create a reference for the odd page
-						// but don't duplicate the actual file
-						if pageNum > 1 && pageNum%2 == 0 && pageNum < len(b.Pages) {
-							oddPageNumber := pageNum + 1
-
-							capturedPages = append(capturedPages, book.InteractivePageImage{
-								PageNumber:   oddPageNumber,
-								OverallOrder: oddPageNumber,
-								Url:          fmt.Sprintf("%s#p=%d", b.Url, oddPageNumber),
-								FullPath:     result.FullPath, // Use the same file path as the even page
-							})
-						}
 						mutex.Unlock()
 					}
 
-					// Update progress and display estimated time to completion
-					atomic.AddInt32(&completedPages, 1)
-					if err := batchBar.Add(1); err != nil {
-						fmt.Fprintf(os.Stderr, "Error updating progress bar: %v\n", err)
-					}
-
-					// Calculate and display estimated time remaining
-					elapsed := time.Since(startTime)
-					completed := atomic.LoadInt32(&completedPages)
-					if completed > 0 {
-						pagesPerSecond := float64(completed) / elapsed.Seconds()
-						if pagesPerSecond > 0 {
-							remaining := float64(totalPages-int(completed)) / pagesPerSecond
-							remainingTime := time.Duration(remaining * float64(time.Second))
-							fmt.Printf("\rEST remaining: %s, Progress: %d/%d (%.1f%%)                    ",
-								formatDuration(remainingTime),
-								completed,
-								totalPages,
-								float64(completed)/float64(totalPages)*100)
-						}
-					}
-
+					task.Add(1)
 					return nil
 				})
 			}
@@ -397,31 +505,25 @@ func captureInteractivePages(ctx context.Context, args *Args, b *book.Book) ([]b
 
 		// Wait for batch to complete
 		if err := eg.Wait(); err != nil {
-			fmt.Fprintf(os.Stderr, "Error in batch %d: %v\n", batchIndex+1, err)
+			reporter.Logf(progress.LevelError, "Error in batch %d: %v", batchIndex+1, err)
 			// Continue to next batch despite errors
 		}
 
 		// Close batch context
 		batchCancel()
 
-		if err := batchBar.Close(); err != nil {
-			fmt.Fprintf(os.Stderr, "Error closing batch progress bar: %v\n", err)
-		}
-
-		// Force garbage collection between batches
-		runtime.GC()
-
 		// Add a pause between batches to let resources be properly cleaned up
 		if batchIndex < numBatches-1 {
-			fmt.Printf("Pausing between batches for cleanup...\n")
 			time.Sleep(time.Second * 2)
 		}
 	}
 
+	task.Finish()
+
 	// Report failed pages
 	if len(failedPages) > 0 {
 		sort.Ints(failedPages)
-		fmt.Printf("\nWARNING: Failed to capture %d pages: %v\n", len(failedPages), failedPages)
+		reporter.Logf(progress.LevelWarn, "Failed to capture %d pages: %v", len(failedPages), failedPages)
 	}
 
 	// Sort the captured pages
@@ -431,55 +533,42 @@ func captureInteractivePages(ctx context.Context, args *Args, b *book.Book) ([]b
 
 	// If no pages were captured, return an error
 	if len(capturedPages) == 0 {
-		return nil, fmt.Errorf("failed to capture any pages")
+		return nil, failedPages, fmt.Errorf("failed to capture any pages")
 	}
 
 	// Retry failed pages in sequential mode if there are failures
 	if len(failedPages) > 0 && len(failedPages) < len(pagesToCapture) {
-		fmt.Printf("\nRetrying %d failed pages in sequential mode...\n", len(failedPages))
+		stillFailed := make([]int, 0)
 
-		retryBar := progressbar.Default(int64(len(failedPages)), "Retrying failed pages")
+		reporter.Logf(progress.LevelInfo, "Retrying %d failed pages in sequential mode...", len(failedPages))
+		retryTask := reporter.Start("capture-retry", "Retrying failed pages", len(failedPages))
 
 		for _, pageNum := range failedPages {
-			pageUrl := fmt.Sprintf("%s#p=%d", b.Url, pageNum)
+			pageUrl := p.PageUrl(b, pageNum)
 
 			// Give extra time between retries
 			time.Sleep(time.Second * 3)
 
-			// Create a fresh context for each retry
+			// Create a fresh context and browser for each retry, in case the shared browser is what failed
 			retryCtx, cancelRetry := context.WithCancel(ctx)
-			result, err := book.CaptureInteractivePageQuiet(retryCtx, pageUrl, interactiveOutputRoot, pageNum, pageNum)
+			retryOptions := captureOptions
+			retryOptions.Browser = nil
+			result, err := book.CaptureInteractivePage(retryCtx, pageUrl, interactiveOutputRoot, pageNum, pageNum, retryOptions)
 			cancelRetry()
 
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "Still failed to capture page %d on retry: %v\n", pageNum, err)
+				reporter.Logf(progress.LevelError, "Still failed to capture page %d on retry: %v", pageNum, err)
+				stillFailed = append(stillFailed, pageNum)
 			} else {
+				hooks.capture(ctx, args.PostImageCmd, result)
+
 				mutex.Lock()
 				capturedPages = append(capturedPages, *result)
-
-				// If page is even and not the last page, also create a reference for the odd page
-				// but don't duplicate the actual file
-				if pageNum > 1 && pageNum%2 == 0 && pageNum < len(b.Pages) {
-					oddPageNumber := pageNum + 1
-
-					capturedPages = append(capturedPages, book.InteractivePageImage{
-						PageNumber:   oddPageNumber,
-						OverallOrder: oddPageNumber,
-						Url:          fmt.Sprintf("%s#p=%d", b.Url, oddPageNumber),
-						FullPath:     result.FullPath, // Use the same file path as the even page
-					})
-				}
-
 				mutex.Unlock()
-				fmt.Printf("Successfully captured page %d on retry\n", pageNum)
+				reporter.Logf(progress.LevelInfo, "Successfully captured page %d on retry", pageNum)
 			}
 
-			if err := retryBar.Add(1); err != nil {
-				fmt.Fprintf(os.Stderr, "Error updating retry progress bar: %v\n", err)
-			}
-
-			// Force GC after each retry
-			runtime.GC()
+			retryTask.Add(1)
 		}
 
 		// Sort the captured pages again after retries
@@ -487,12 +576,75 @@ func captureInteractivePages(ctx context.Context, args *Args, b *book.Book) ([]b
 			return capturedPages[i].OverallOrder < capturedPages[j].OverallOrder
 		})
 
-		if err := retryBar.Close(); err != nil {
-			fmt.Fprintf(os.Stderr, "Error closing retry progress bar: %v\n", err)
+		retryTask.Finish()
+
+		failedPages = stillFailed
+	}
+
+	return capturedPages, failedPages, nil
+}
+
+// resolveProvider returns the provider with the given name, or the one that supports the url if no name is given
+func resolveProvider(name string, url string) (provider.Provider, error) {
+	if name == "" {
+		return provider.For(url)
+	}
+
+	p, ok := provider.Get(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown provider %q, expected one of %s", name, strings.Join(provider.Names(), ", "))
+	}
+
+	return p, nil
+}
+
+// acquireBookLocks locks the PDF path and the image output folder (if one is set) for this process
+func acquireBookLocks(args *Args, pdfPath string) (func(), error) {
+	paths := []string{pdfPath + ".lock"}
+	if args.ImageOutputFolder != "" {
+		imageDir, err := filepath.Abs(args.ImageOutputFolder)
+		if err != nil {
+			return nil, tracerr.Wrap(err)
+		}
+
+		if err := os.MkdirAll(imageDir, os.ModePerm); err != nil {
+			return nil, tracerr.Wrap(err)
+		}
+
+		paths = append(paths, filepath.Join(imageDir, ".fh5dl.lock"))
+	}
+
+	locks := make([]*lockfile.Lock, 0, len(paths))
+	release := func() {
+		for _, lock := range locks {
+			if err := lock.Release(); err != nil {
+				args.reporter().Logf(progress.LevelError, "Error releasing lock: %v", err)
+			}
+		}
+	}
+
+	for _, path := range paths {
+		lock, err := lockfile.Acquire(path, staleLockAge)
+		if err != nil {
+			release()
+			return nil, err
+		}
+
+		locks = append(locks, lock)
+	}
+
+	return release, nil
+}
+
+// newWorkTempDir creates a temporary folder inside the work dir, falling back to the system temp dir when no work dir is set
+func newWorkTempDir(workDir string, pattern string) (string, error) {
+	if workDir != "" {
+		if err := os.MkdirAll(workDir, os.ModePerm); err != nil {
+			return "", err
 		}
 	}
 
-	return capturedPages, nil
+	return os.MkdirTemp(workDir, pattern)
 }
 
 // formatDuration formats time.Duration to a human-readable string (HH:MM:SS)
@@ -515,176 +667,611 @@ func die(err error) {
 	os.Exit(1)
 }
 
-// downloadPdf2 is a wrapper function that can be called from the terminal UI
-func downloadPdf2(ctx context.Context, args *Args) error {
+// downloadPdf2 is a wrapper function that can be called from the terminal UI.
+// The returned report is never nil and is also written next to the PDF unless reports are disabled.
+func downloadPdf2(ctx context.Context, args *Args) (report *bookReport, err error) {
+	report = newBookReport(args.Url)
+	report.Interactive = args.Interactive
+	support.recordBook(args.Url)
+	ctx, cancel := args.control.bookContext(ctx)
+	defer cancel()
+	defer func() {
+		if err != nil && errors.Is(context.Cause(ctx), errBookCancelled) {
+			err = errBookCancelled
+		}
+		report.finish(err)
+		args.profiler.record(report)
+		recordHistory(args, report)
+		if reportErr := writeBookReport(report, args.ReportFormat); reportErr != nil {
+			args.reporter().Logf(progress.LevelError, "Error writing report: %v", reportErr)
+		}
+	}()
+
 	// Make sure the args struct is properly initialized
 	if args.Concurrency <= 0 {
-		args.Concurrency = runtime.NumCPU() - 1
-		if args.Concurrency <= 0 {
-			args.Concurrency = 1
-		}
+		args.Concurrency = defaultConcurrency()
 	}
+	reporter := args.reporter()
 
 	// Process the book
-	b, err := book.Get(args.Url)
+	p, err := resolveProvider(args.Provider, args.Url)
 	if err != nil {
-		return tracerr.Wrap(err)
+		return report, err
+	}
+
+	b, err := p.Resolve(ctx, args.Url)
+	if err != nil {
+		return report, tracerr.Wrap(err)
+	}
+
+	if args.Title != "" {
+		b.Title = args.Title
+	}
+	if args.Language != "" {
+		b.Language = book.ParseLanguage(args.Language)
+	}
+
+	// the pages of a sample are only known once the length of the book is
+	if args.Sample > 0 {
+		args.Pages = samplePages(args.Sample, len(b.Pages))
+	}
+
+	if b.Security.PasswordProtected {
+		reporter.Logf(progress.LevelWarn, "The book is password protected, its pages may fail to download")
 	}
 
+	report.BookId = b.Id
+	report.Title = b.Title
+	report.Language = b.Language
+	report.Pages = len(b.Pages)
+
 	// Create the output directory if it doesn't exist
 	outputDir, err := filepath.Abs(args.OutputFolder)
 	if err != nil {
-		return tracerr.Wrap(err)
+		return report, tracerr.Wrap(err)
 	}
 
 	if _, err := os.Stat(outputDir); os.IsNotExist(err) {
 		err = os.MkdirAll(outputDir, os.ModePerm)
 		if err != nil {
-			return tracerr.Wrap(err)
+			return report, tracerr.Wrap(err)
 		}
 	}
 
 	// Check if PDF already exists
-	sanitizedTitle := sanitizeFilename(b.Title)
-	pdfPath := filepath.Join(outputDir, sanitizedTitle+".pdf")
-	if _, err := os.Stat(pdfPath); err == nil && !args.Force {
-		fmt.Printf("PDF %s already exists. Skipping.\n", pdfPath)
-		return nil
+	outputDir, sanitizedTitle := args.outputNames(outputDir, b)
+	if err := os.MkdirAll(outputDir, os.ModePerm); err != nil {
+		return report, tracerr.Wrap(err)
+	}
+	if sanitizedTitle == "" {
+		// nothing usable left of the title, fall back to the book id
+		sanitizedTitle = strings.ReplaceAll(b.Id, "/", "_")
+	}
+	// a sample never takes the place of the book
+	sampleSuffix := ""
+	if args.Sample > 0 {
+		sampleSuffix = ".sample"
+	}
+	sanitizedTitle = fitFilename(outputDir, sanitizedTitle, sampleSuffix+" (99).report.json") + sampleSuffix
+
+	if args.ContactSheet {
+		if !provider.CapabilitiesOf(p).Thumbnails {
+			return report, fmt.Errorf("contact sheets are made from page thumbnails, which %s books don't have", p.Name())
+		}
+		return report, contactSheet(ctx, args, report, b, filepath.Join(outputDir, sanitizedTitle+contactSheetSuffix))
+	}
+	if args.CoverOnly {
+		return report, coverOnly(ctx, args, report, p.Images(b), outputDir, sanitizedTitle)
+	}
+	pdfPath, err := resolveOutputPath(args.reporter(), outputDir, sanitizedTitle, args.outputExtension(), b.Id)
+	if err != nil {
+		return report, err
+	}
+	report.reportBase = trimExtension(pdfPath)
+
+	// Make sure no other fh5dl process is working on the same book and output
+	releaseLocks, err := acquireBookLocks(args, pdfPath)
+	if err != nil {
+		return report, err
 	}
+	defer releaseLocks()
+
+	// Decide what to do if the PDF already exists
+	var update bookUpdate
+	if outputExists(pdfPath) && args.Update {
+		previous, err := readMetadata(pdfPath)
+		if err != nil {
+			return report, err
+		}
 
-	// Get all the images in the book
-	images := b.FindAllImages()
+		update = planUpdate(previous, b)
+		if update.upToDate {
+			reporter.Logf(progress.LevelInfo, "PDF %s is up to date. Skipping.", pdfPath)
+			report.Status = reportStatusSkipped
+			report.PdfPath = pdfPath
+			return report, nil
+		}
+
+		if update.changed == nil {
+			reporter.Logf(progress.LevelInfo, "PDF %s has no earlier revision to compare with, writing it again", pdfPath)
+		} else {
+			reporter.Logf(progress.LevelInfo, "Book changed since %s was written, %d pages changed", pdfPath, len(update.changed))
+			report.ChangedPages = update.changed
+		}
+	} else if outputExists(pdfPath) {
+		resolvedPath := resolveConflict(pdfPath, args.conflictPolicy(), args.askConflict)
+		if resolvedPath == "" {
+			reporter.Logf(progress.LevelInfo, "PDF %s already exists. Skipping.", pdfPath)
+			report.Status = reportStatusSkipped
+			report.PdfPath = pdfPath
+			return report, nil
+		}
+
+		if resolvedPath != pdfPath {
+			reporter.Logf(progress.LevelInfo, "PDF %s already exists. Writing %s instead.", pdfPath, resolvedPath)
+			pdfPath = resolvedPath
+			report.reportBase = trimExtension(pdfPath)
+		}
+	}
+
+	// Get the images of the selected pages
+	images := selectImages(p.Images(b), args.pageSet())
 
 	// Optimize: Limit number of images to download if the book has too many
 	// Some books have duplicate images or too many unneeded images
-	if len(images) > 1000 {
-		fmt.Printf("WARNING: Book has %d images. Limiting to first 1000 to avoid excessive downloads.\n", len(images))
-		images = images[:1000]
+	if len(images) > maxBookImages {
+		reporter.Logf(progress.LevelWarn, "Book has %d images. Limiting to first %d to avoid excessive downloads.", len(images), maxBookImages)
+		images = images[:maxBookImages]
+	}
+
+	report.ImagesTotal = len(images)
+	hooks := newHookRunner(reporter, report, b)
+
+	if len(update.changed) > 0 {
+		if err := removeChangedImages(args.ImageOutputFolder, images, update.changed); err != nil {
+			return report, err
+		}
 	}
 
 	// Download images with progress tracking
 	downloadStartTime := time.Now()
-	downloadedImages, err := downloadImages(ctx, args, images)
+	downloadedImages, failedImages, err := downloadImages(ctx, args, hooks, images)
+	report.FailedImages = failedImages
 	if err != nil {
-		return tracerr.Wrap(err)
+		return report, tracerr.Wrap(err)
 	}
 
-	downloadDuration := time.Since(downloadStartTime)
-	fmt.Printf("Images downloaded in %s\n", formatDuration(downloadDuration))
+	report.addDownloadedImages(downloadedImages, time.Since(downloadStartTime))
+	reporter.Logf(progress.LevelInfo, "%s", report.throughputSummary())
+
+	if args.ThumbnailFallback {
+		pageSize, err := nativePageSize(downloadedImages)
+		if (err != nil || pageSize.X == 0) && b.PageWidth > 0 {
+			pageSize = image.Pt(b.PageWidth, b.PageHeight)
+		}
+
+		thumbnails, err := thumbnailFallback(ctx, args, b, downloadedImages, expectedPages(b, args.pageSet()), pageSize)
+		if err != nil {
+			return report, err
+		}
+
+		for _, thumbnail := range thumbnails {
+			report.ThumbnailPages = append(report.ThumbnailPages, thumbnail.PageNumber)
+		}
+		downloadedImages = append(downloadedImages, thumbnails...)
+		sort.SliceStable(downloadedImages, func(i, j int) bool {
+			return downloadedImages[i].PageNumber < downloadedImages[j].PageNumber
+		})
+	}
+
+	imageFiles := downloadedImageFiles(downloadedImages)
+	pageNumbers := imagePageNumbers(downloadedImages, nil)
+	originalFiles := make([]string, 0)
 
 	// If interactive mode is enabled, also capture screenshots
 	if args.Interactive {
 		captureStartTime := time.Now()
-		interactiveImages, err := captureInteractivePages(ctx, args, b)
+		pageSize, err := nativePageSize(downloadedImages)
+		if err != nil && b.PageWidth > 0 && b.PageHeight > 0 {
+			// the size the book information gives is the next best thing
+			pageSize = image.Pt(b.PageWidth, b.PageHeight)
+		} else if err != nil {
+			reporter.Logf(progress.LevelWarn, "Couldn't detect the page size, capturing at the default resolution: %v", err)
+		}
+
+		interactiveImages, failedPages, err := captureInteractivePages(ctx, args, hooks, p, b, pageSize)
+		report.FailedPages = failedPages
+		if len(failedPages) > 0 {
+			report.DiagnosticsFolder = captureDiagnosticsDir(args, b)
+		}
 		if err != nil {
-			return tracerr.Wrap(err)
+			return report, tracerr.Wrap(err)
 		}
 
 		captureDuration := time.Since(captureStartTime)
-		fmt.Printf("Interactive captures completed in %s\n", formatDuration(captureDuration))
+		report.CaptureSeconds = captureDuration.Seconds()
+		report.CapturedPages = len(interactiveImages)
+		reporter.Logf(progress.LevelInfo, "Interactive captures completed in %s", formatDuration(captureDuration))
 
-		// Generate PDF with interactive screenshots
+		// Generate the output with interactive screenshots
 		if len(interactiveImages) > 0 {
-			// Build a PDF from the downloaded images
-			pdfStartTime := time.Now()
-			err = generateInteractivePDF(downloadedImages, interactiveImages, pdfPath, args.Force)
+			if args.KeepOriginal {
+				originalFiles = imageFiles
+			}
+			imageFiles = interactivePageFiles(downloadedImages, interactiveImages, args.ComparePages)
+			pageNumbers = imagePageNumbers(downloadedImages, interactiveImages)
+		}
+	}
+
+	// Pages lost to failed downloads or the image limit would otherwise only be noticed when reading
+	report.MissingPages = missingPages(expectedPages(b, args.pageSet()), imageFiles, pageNumbers)
+	if len(report.MissingPages) > 0 {
+		if allowed := args.allowedMissingPages(); allowed >= 0 && len(report.MissingPages) > allowed {
+			return report, missingPagesError(report.MissingPages)
+		}
+		reporter.Logf(progress.LevelWarn, "The output is missing %d pages of the book: %v", len(report.MissingPages), report.MissingPages)
+	}
+	if len(imageFiles) == 0 {
+		return report, fmt.Errorf("none of the pages of book %s could be downloaded", b.Id)
+	}
+	if args.PlaceholderPages && len(report.MissingPages) > 0 {
+		imageFiles, err = addPlaceholders(b, imageFiles, pageNumbers, report.MissingPages, report.FailedImages)
+		if err != nil {
+			return report, err
+		}
+		report.PlaceholderPages = report.MissingPages
+	}
+
+	// the page processing options below share a phase in the timings
+	processStartTime := time.Now()
+	if len(args.Rotate) > 0 {
+		// validated before any book is downloaded
+		rotations, _ := parseRotations(args.Rotate)
+		imageFiles, err = rotatePages(ctx, rotations, imageFiles, pageNumbers)
+		if err != nil {
+			return report, err
+		}
+	}
+
+	if args.Deskew {
+		imageFiles, report.DeskewedImages, err = deskewPages(ctx, imageFiles, pageNumbers)
+		if err != nil {
+			return report, err
+		}
+		if report.DeskewedImages > 0 {
+			reporter.Logf(progress.LevelInfo, "Straightened %d tilted pages", report.DeskewedImages)
+		}
+	}
+
+	if args.UpscaleBelow > 0 {
+		imageFiles, report.UpscaledImages, err = upscalePages(ctx, args, imageFiles, pageNumbers)
+		if err != nil {
+			return report, err
+		}
+		if report.UpscaledImages > 0 {
+			reporter.Logf(progress.LevelInfo, "Upscaled %d pages narrower than %d pixels", report.UpscaledImages, args.UpscaleBelow)
+		}
+	}
+
+	if args.Enhance == enhanceText {
+		imageFiles, report.EnhancedImages, err = transformPages(ctx, imageFiles, pageNumbers, "enhanced", enhanceTextPage)
+		if err != nil {
+			return report, err
+		}
+	}
+
+	if args.TrimMargins {
+		imageFiles, err = trimPages(ctx, imageFiles, pageNumbers)
+		if err != nil {
+			return report, err
+		}
+	}
+	report.ProcessSeconds = time.Since(processStartTime).Seconds()
+
+	var toc []tocPage
+	if args.TocPage {
+		toc, err = tableOfContents(reporter, b, imageFiles, pageNumbers)
+		if err != nil {
+			return report, err
+		}
+
+		tocFiles := make([]string, len(toc))
+		for i, page := range toc {
+			tocFiles[i] = page.Path
+		}
+		imageFiles = append(tocFiles, imageFiles...)
+	}
+
+	format := args.outputFormat()
+	var texts map[int]string
+	if args.OcrCmd != "" {
+		texts, err = pageTexts(ctx, args, b, imageFiles, pageNumbers, report.PlaceholderPages)
+		if err != nil {
+			return report, err
+		}
+
+		// the plain PDF is tagged with the same texts, which are kept next to its own images
+		if len(originalFiles) > 0 && format == outputPdf {
+			if err := writePageSidecars(originalFiles, pageNumbers, texts, ocrTextPath); err != nil {
+				return report, err
+			}
+		}
+	}
+
+	var descriptions map[int]string
+	if args.CaptionUrl != "" {
+		descriptions, err = describePages(ctx, args, report, imageFiles, pageNumbers)
+		if err != nil {
+			return report, err
+		}
+
+		// the plain PDF is tagged with the same descriptions, rather than paying for describing its images too
+		if len(originalFiles) > 0 && format == outputPdf {
+			if err := writePageSidecars(originalFiles, pageNumbers, descriptions, captionPath); err != nil {
+				return report, err
+			}
+		}
+	}
+
+	outputPaths := make([]string, 0)
+	originalPaths := make([]string, 0)
+	err = runPdfPhase(reporter, report, format, func() error {
+		if isTextFormat(format) {
+			outputPaths = []string{pdfPath}
+			return writeTextExport(pdfPath, format, b.Title, expectedPages(b, args.pageSet()), texts)
+		}
+
+		outputPaths, err = generateVolumes(args, imageFiles, pdfPath)
+		if err != nil {
+			return err
+		}
+
+		if len(toc) > 0 {
+			// the contents pages are as tall as the first page of the book
+			size, err := imageSize(toc[0].Path)
 			if err != nil {
-				return tracerr.Wrap(err)
+				return err
+			}
+			if err := addTocLinks(outputPaths[0], toc, size.Y); err != nil {
+				return err
 			}
+		}
 
-			pdfDuration := time.Since(pdfStartTime)
-			fmt.Printf("PDF generation completed in %s\n", formatDuration(pdfDuration))
-		} else {
-			// If no interactive images were captured, generate a regular PDF
-			pdfStartTime := time.Now()
-			err = generatePDF(downloadedImages, pdfPath, args.Force)
+		if len(originalFiles) > 0 {
+			// the plain version without interactive captures, next to the interactive one
+			originalPaths, err = generateVolumes(args, originalFiles, originalOutputPath(pdfPath))
 			if err != nil {
-				return tracerr.Wrap(err)
+				return err
 			}
+		}
 
-			pdfDuration := time.Since(pdfStartTime)
-			fmt.Printf("PDF generation completed in %s\n", formatDuration(pdfDuration))
+		if format == outputCbz {
+			for _, outputPath := range append(outputPaths, originalPaths...) {
+				if err := setComicInfo(outputPath, args.comicInfo(b)); err != nil {
+					return err
+				}
+			}
 		}
-	} else {
-		// Generate a regular PDF
-		pdfStartTime := time.Now()
-		err = generatePDF(downloadedImages, pdfPath, args.Force)
+		if format != outputPdf {
+			return nil
+		}
+
+		info := args.documentInfo(b)
+		for _, outputPath := range append(outputPaths, originalPaths...) {
+			if err := setDocumentInfo(outputPath, info); err != nil {
+				return err
+			}
+		}
+
+		report.OutputPages, err = countPdfPages(outputPaths)
 		if err != nil {
-			return tracerr.Wrap(err)
+			return err
 		}
+		if report.OutputPages != len(imageFiles) {
+			mismatch := fmt.Errorf("the PDF has %d pages but %d images were added", report.OutputPages, len(imageFiles))
+			if args.Strict {
+				return mismatch
+			}
+			reporter.Logf(progress.LevelWarn, "%v", mismatch)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return report, err
+	}
+
+	report.PdfPath = outputPaths[0]
+	if len(outputPaths) > 1 {
+		report.Volumes = outputPaths
+		reporter.Logf(progress.LevelInfo, "Split into %d volumes", len(outputPaths))
+	}
+	report.OriginalPaths = originalPaths
+
+	// Remember which book the PDF belongs to, so books sharing a title can be told apart
+	err = writeMetadata(pdfPath, &bookMetadata{
+		BookId:       b.Id,
+		Url:          b.Url,
+		Title:        b.Title,
+		Author:       args.Author,
+		Subject:      args.Subject,
+		Language:     b.Language,
+		Pages:        len(b.Pages),
+		Interactive:  args.Interactive,
+		CreatedAt:    time.Now(),
+		Stats:        &report.transferStats,
+		Volumes:      report.Volumes,
+		Revision:     b.Revision(),
+		PageImages:   b.PageImageUrls(),
+		Descriptions: descriptions,
+	})
+	if err != nil {
+		return report, err
+	}
+
+	for _, outputPath := range append(outputPaths, originalPaths...) {
+		hooks.pdf(ctx, args.PostPdfCmd, outputPath, metadataPath(pdfPath))
+	}
+
+	if args.TtsCmd != "" {
+		// the output is done at this point, so a failed narration doesn't fail the book
+		audioPaths, err := narrateBook(ctx, args, b, expectedPages(b, args.pageSet()), texts, audioPath(pdfPath))
+		if err != nil {
+			reporter.Logf(progress.LevelError, "Failed to narrate the book: %v", err)
+			report.AudioError = err.Error()
+		} else {
+			report.AudioPaths = audioPaths
+			reporter.Logf(progress.LevelInfo, "Narrated %d chapters into %s", len(audioPaths), audioPath(pdfPath))
+		}
+	}
 
-		pdfDuration := time.Since(pdfStartTime)
-		fmt.Printf("PDF generation completed in %s\n", formatDuration(pdfDuration))
+	if args.Record != "" {
+		// the PDF is done at this point, so a failed recording doesn't fail the book
+		recordingPath := args.recordingPath(pdfPath)
+		if err := recordBook(ctx, args, reporter, p, b, recordingPath); err != nil {
+			reporter.Logf(progress.LevelError, "Failed to record the book: %v", err)
+			report.RecordingError = err.Error()
+		} else {
+			report.RecordingPath = recordingPath
+			reporter.Logf(progress.LevelInfo, "Recorded the book to %s", recordingPath)
+		}
 	}
 
 	totalDuration := time.Since(downloadStartTime)
-	fmt.Printf("Total processing time: %s\n", formatDuration(totalDuration))
+	reporter.Logf(progress.LevelInfo, "Total processing time: %s", formatDuration(totalDuration))
 
-	return nil
+	return report, nil
 }
 
-// generateInteractivePDF combines regular images with interactive screenshots
-func generateInteractivePDF(downloadedImages []book.DownloadedImage, interactiveImages []book.InteractivePageImage, pdfPath string, force bool) error {
-	// First check if the PDF already exists and should be overwritten
-	if _, err := os.Stat(pdfPath); err == nil && !force {
-		return fmt.Errorf("PDF %s already exists. Use -f flag to overwrite", pdfPath)
+// runPdfPhase reports the progress of generating the output document and records its duration
+func runPdfPhase(reporter progress.Reporter, report *bookReport, format string, generate func() error) error {
+	pdfStartTime := time.Now()
+	task := reporter.Start("pdf", "Generating "+strings.ToUpper(format), 1)
+	err := generate()
+	task.Finish()
+	if err != nil {
+		return tracerr.Wrap(err)
 	}
 
-	// Create a PDF configuration
-	pdfConfig := model.NewDefaultConfiguration()
+	pdfDuration := time.Since(pdfStartTime)
+	report.PdfSeconds = pdfDuration.Seconds()
+	reporter.Logf(progress.LevelInfo, "%s generation completed in %s", strings.ToUpper(format), formatDuration(pdfDuration))
 
+	return nil
+}
+
+// interactivePageFiles combines regular images with interactive screenshots, using one image per page.
+// With compare set, the original image of every captured page is kept right before its screenshot.
+func interactivePageFiles(downloadedImages []book.DownloadedImage, interactiveImages []book.InteractivePageImage, compare bool) []string {
 	// Map page numbers to the actual images that should be used
-	pageMap := make(map[int]string)
+	originals := make(map[int]string)
+	captures := make(map[int]book.InteractivePageImage)
+	pageNums := make([]int, 0)
 
 	// First, add all normal images to the map
 	for _, img := range downloadedImages {
-		pageMap[img.PageNumber] = img.FullPath
+		if _, ok := originals[img.PageNumber]; !ok {
+			pageNums = append(pageNums, img.PageNumber)
+		}
+		originals[img.PageNumber] = img.FullPath
 	}
 
 	// Then, override with interactive images where available
 	for _, intImg := range interactiveImages {
-		pageMap[intImg.PageNumber] = intImg.FullPath
+		_, hasOriginal := originals[intImg.PageNumber]
+		_, hasCapture := captures[intImg.PageNumber]
+		if !hasOriginal && !hasCapture {
+			pageNums = append(pageNums, intImg.PageNumber)
+		}
+		captures[intImg.PageNumber] = intImg
 	}
 
 	// Sort the page numbers for consistent ordering
-	pageNums := make([]int, 0, len(pageMap))
-	for num := range pageMap {
-		pageNums = append(pageNums, num)
-	}
 	sort.Ints(pageNums)
 
-	// Create the ordered list of images to include in the PDF
+	// Create the ordered list of images to include in the output
 	var images []string
 	for _, num := range pageNums {
-		images = append(images, pageMap[num])
+		original, hasOriginal := originals[num]
+		capture, hasCapture := captures[num]
+
+		if hasOriginal && (!hasCapture || compare) {
+			images = append(images, original)
+		}
+		if hasCapture {
+			// popups go right after the page that opens them
+			images = append(images, capture.FullPath)
+			images = append(images, capture.Popups...)
+		}
 	}
 
-	// Generate the PDF using the ImportImagesFile function which is compatible with newer pdfcpu versions
-	err := pdfcpu_api.ImportImagesFile(images, pdfPath, nil, pdfConfig)
-	if err != nil {
-		return tracerr.Wrap(err)
+	return images
+}
+
+// downloadedImageFiles returns the paths of the downloaded images in order
+func downloadedImageFiles(images []book.DownloadedImage) []string {
+	imageFiles := make([]string, len(images))
+	for i, img := range images {
+		imageFiles[i] = img.FullPath
+	}
+
+	return imageFiles
+}
+
+// generateOutput writes the images into the output file. A file is built next to the output and renamed over it once
+// complete, so that an update or overwrite replaces the earlier output in one step, and a failed run leaves it as it
+// was. Strips are folders of images, which generateStrip starts afresh.
+func generateOutput(args *Args, imageFiles []string, outputPath string) error {
+	if args.outputFormat() == outputStrip {
+		return generateStrip(imageFiles, outputPath, args.StripHeight)
+	}
+
+	tmpPath := trimExtension(outputPath) + ".tmp" + filepath.Ext(outputPath)
+	if err := writeOutput(args, imageFiles, tmpPath); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	return tracerr.Wrap(os.Rename(tmpPath, outputPath))
+}
+
+// writeOutput writes the images into a file of the output format
+func writeOutput(args *Args, imageFiles []string, outputPath string) error {
+	switch args.outputFormat() {
+	case outputDjvu:
+		return generateDjvu(imageFiles, outputPath, args.WorkDir)
+	case outputCbz:
+		return generateCbz(imageFiles, outputPath)
+	}
+
+	if err := generatePDF(imageFiles, outputPath); err != nil {
+		return err
+	}
+
+	if args.Annotations {
+		if err := addAnnotations(outputPath, imageFiles); err != nil {
+			return err
+		}
+	}
+
+	if args.OcrCmd != "" || args.CaptionUrl != "" {
+		return tagPdf(outputPath, imageFiles)
 	}
 
 	return nil
 }
 
-// generatePDF generates a PDF from the downloaded images
-func generatePDF(images []book.DownloadedImage, pdfPath string, force bool) error {
-	// Check if the PDF already exists
-	if _, err := os.Stat(pdfPath); err == nil && !force {
-		return fmt.Errorf("PDF %s already exists. Use -f flag to overwrite", pdfPath)
+// generatePDF generates a PDF with one page per image. An existing PDF at the path is replaced, as pdfcpu would
+// otherwise add the pages after the ones already in it.
+func generatePDF(imageFiles []string, pdfPath string) error {
+	if err := os.Remove(pdfPath); err != nil && !os.IsNotExist(err) {
+		return tracerr.Wrap(err)
 	}
 
 	// Create a PDF configuration
 	pdfConfig := model.NewDefaultConfiguration()
 
-	// Create a list of image paths
-	imageFiles := make([]string, len(images))
-	for i, img := range images {
-		imageFiles[i] = img.FullPath
-	}
-
-	// Generate the PDF using the ImportImagesFile function
+	// Generate the PDF using the ImportImagesFile function which is compatible with newer pdfcpu versions
 	err := pdfcpu_api.ImportImagesFile(imageFiles, pdfPath, nil, pdfConfig)
 	if err != nil {
 		return tracerr.Wrap(err)
@@ -695,42 +1282,341 @@ func generatePDF(images []book.DownloadedImage, pdfPath string, force bool) erro
 
 // Main function with error handling
 func mainWithErrors() error {
+	// Subcommands have their own arguments
+	if len(os.Args) > 1 {
+		if command, ok := commands[os.Args[1]]; ok {
+			return command(os.Args[2:])
+		}
+	}
+
 	// Parse the command line arguments first
 	var args Args
 
 	// Parse arguments
 	argP := arg.MustParse(&args)
+	support.bundle = args.SupportBundle
 
 	// Check if Terminal UI is requested via the flag
 	if args.TerminalUI {
+		if !validTheme(args.Theme) {
+			return fmt.Errorf("invalid theme %q, expected auto, dark, light or none", args.Theme)
+		}
+
 		// Launch the Terminal UI
-		RunTerminalUI()
+		RunTerminalUI(args.Theme)
 		return nil
 	}
 
-	// For regular CLI mode, URL is required
-	if args.Url == "" {
-		argP.WriteHelp(os.Stderr)
-		return fmt.Errorf("URL or ID is required")
+	if err := prepareArgs(&args); err != nil {
+		return err
+	}
+
+	if args.Profile != "" {
+		profiler, err := startProfile(args.Profile)
+		if err != nil {
+			return err
+		}
+		args.profiler = profiler
+
+		defer func() {
+			if err := profiler.stop(args.reporter()); err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing profiles: %v\n", err)
+			}
+		}()
 	}
 
 	// Set default concurrency
 	if args.Concurrency <= 0 {
-		args.Concurrency = runtime.NumCPU() - 1
-		if args.Concurrency <= 0 {
-			args.Concurrency = 1
+		args.Concurrency = defaultConcurrency()
+	}
+
+	// Read a url list from a file, or from stdin when urls are piped in
+	if args.FromFile == "" && len(args.Urls) == 0 && stdinIsPiped() {
+		args.FromFile = "-"
+	}
+
+	entries := make([]batchEntry, 0)
+	if args.FromFile != "" {
+		fileEntries, err := readUrlListFile(args.FromFile)
+		if err != nil {
+			return tracerr.Wrap(err)
+		}
+
+		entries = append(entries, fileEntries...)
+	}
+
+	for _, url := range args.Urls {
+		if entry, ok := parseBatchLine(url); ok {
+			entry.Name = entry.Url
+			entries = append(entries, entry)
+		}
+	}
+
+	// Several books (or any url list) go through the batch machinery
+	if args.FromFile != "" || len(entries) > 1 {
+		if args.Title != "" {
+			return fmt.Errorf("--title can only be used when downloading a single book")
 		}
+
+		err := runBatch(batchEntriesFromArgs(&args, entries), args)
+		args.updateCatalog()
+		return err
 	}
 
+	// For regular CLI mode, URL is required
+	if len(entries) == 0 {
+		argP.WriteHelp(os.Stderr)
+		return fmt.Errorf("URL or ID is required")
+	}
+
+	args.Url = entries[0].Url
+	args.Interactive = args.Interactive || entries[0].Interactive
+
 	// Run the download with the provided arguments
 	ctx := context.Background()
-	return downloadPdf2(ctx, &args)
+	_, err := downloadPdf2(ctx, &args)
+	args.updateCatalog()
+	return err
+}
+
+// prepareArgs checks the options of a download and sets up what they ask for, for the command line and for books
+// retried from a report
+func prepareArgs(args *Args) error {
+	if !validConflictPolicy(args.OnConflict) {
+		return fmt.Errorf("invalid conflict policy %q, expected skip, overwrite, rename or prompt", args.OnConflict)
+	}
+
+	if !validReportFormat(args.ReportFormat) {
+		return fmt.Errorf("invalid report format %q, expected json, markdown, all or none", args.ReportFormat)
+	}
+
+	if !validProgressMode(args.Progress) {
+		return fmt.Errorf("invalid progress mode %q, expected auto, bar, plain or json", args.Progress)
+	}
+
+	if args.CaptureScale < 0 || args.CaptureScale > book.MaxCaptureScale {
+		return fmt.Errorf("invalid capture scale %g, expected a number between 0 and %d", args.CaptureScale, book.MaxCaptureScale)
+	}
+
+	if !validRecordFormat(args.Record) {
+		return fmt.Errorf("invalid recording format %q, expected mp4 or gif", args.Record)
+	}
+
+	if args.Record != "" {
+		if err := checkFfmpeg(); err != nil {
+			return err
+		}
+	}
+
+	if args.RevealScript != "" {
+		if _, err := book.LoadRevealScript(args.RevealScript); err != nil {
+			return err
+		}
+	}
+
+	if _, err := args.splitOptions(); err != nil {
+		return err
+	}
+
+	if !validOutputFormat(args.Format) {
+		return fmt.Errorf("invalid output format %q, expected pdf, djvu, strip, cbz, txt or md", args.Format)
+	}
+
+	if !validLayout(args.Layout) {
+		return fmt.Errorf("invalid layout %q, expected default, komga or kavita", args.Layout)
+	}
+	if args.Volume < 0 {
+		return fmt.Errorf("invalid volume %d, expected a volume number", args.Volume)
+	}
+	for _, template := range []string{args.Folder, args.Filename} {
+		if err := validOutputTemplate(template); err != nil {
+			return err
+		}
+	}
+
+	if args.Language != "" && book.ParseLanguage(args.Language) == "" {
+		return fmt.Errorf("invalid language %q, expected a language code such as en or pt-BR", args.Language)
+	}
+
+	if args.Pages != "" {
+		if _, err := book.ParsePageSet(args.Pages); err != nil {
+			return err
+		}
+	}
+
+	switch args.ImageFormat {
+	case imageFormatOriginal, imageFormatJpg, imageFormatPng:
+	case "webp":
+		return fmt.Errorf("images can't be converted to webp, use --image-format original to keep webp images as served")
+	default:
+		return fmt.Errorf("invalid image format %q, expected original, jpg or png", args.ImageFormat)
+	}
+
+	if _, err := parseRotations(args.Rotate); err != nil {
+		return err
+	}
+
+	if args.Enhance != "" && args.Enhance != enhanceText {
+		return fmt.Errorf("invalid enhance preset %q, expected text", args.Enhance)
+	}
+
+	if args.UpscaleBelow < 0 {
+		return fmt.Errorf("invalid upscale width %d, expected a number of pixels", args.UpscaleBelow)
+	}
+	if args.UpscaleCmd != "" && args.UpscaleBelow == 0 {
+		return fmt.Errorf("--upscale-cmd needs --upscale-below to tell which pages to upscale")
+	}
+
+	if args.Update && args.ImageOutputFolder == "" {
+		return fmt.Errorf("--update needs --image-out to reuse the pages that didn't change")
+	}
+
+	if args.HttpTimeout < 0 {
+		return fmt.Errorf("invalid HTTP timeout %v, expected a number of seconds", args.HttpTimeout)
+	}
+	if args.Retries != nil && *args.Retries < 0 {
+		return fmt.Errorf("invalid number of retries %d, expected 0 or more", *args.Retries)
+	}
+	if args.RetryBackoff < 0 {
+		return fmt.Errorf("invalid retry backoff %v, expected a number of seconds", args.RetryBackoff)
+	}
+
+	if args.AllowMissingPages != nil {
+		if *args.AllowMissingPages < 0 {
+			return fmt.Errorf("invalid number of missing pages %d, expected 0 or more", *args.AllowMissingPages)
+		}
+		if args.Strict {
+			return fmt.Errorf("--strict already allows no missing pages, it can't be combined with --allow-missing-pages")
+		}
+	}
+
+	if args.Sample < 0 {
+		return fmt.Errorf("invalid sample size %d, expected a number of pages", args.Sample)
+	}
+	if args.Sample > 0 && args.Pages != "" {
+		return fmt.Errorf("--sample already picks the pages, it can't be combined with --pages")
+	}
+
+	if args.CoverOnly {
+		for flag, set := range map[string]bool{"-i": args.Interactive, "--update": args.Update, "--record": args.Record != "",
+			"--contact-sheet": args.ContactSheet, "--sample": args.Sample > 0, "--pages": args.Pages != ""} {
+			if set {
+				return fmt.Errorf("--cover-only only downloads the first page, it can't be combined with %s", flag)
+			}
+		}
+	}
+
+	if args.ContactSheet {
+		for flag, set := range map[string]bool{"-i": args.Interactive, "--update": args.Update, "--record": args.Record != ""} {
+			if set {
+				return fmt.Errorf("--contact-sheet only downloads thumbnails, it can't be combined with %s", flag)
+			}
+		}
+	}
+
+	if args.TocPage {
+		if args.outputFormat() != outputPdf {
+			return fmt.Errorf("--toc-page only works with --format pdf")
+		}
+		if args.SplitEvery > 0 || args.SplitMaxSize != "" {
+			return fmt.Errorf("--toc-page can't be combined with --split-every or --split-max-size")
+		}
+	}
+
+	if isTextFormat(args.outputFormat()) {
+		if args.OcrCmd == "" {
+			return fmt.Errorf("--format %s needs --ocr-cmd to read the text of the pages", args.Format)
+		}
+		if args.SplitEvery > 0 || args.SplitMaxSize != "" {
+			return fmt.Errorf("--format %s can't be combined with --split-every or --split-max-size", args.Format)
+		}
+		if args.KeepOriginal {
+			return fmt.Errorf("--format %s can't be combined with --keep-original", args.Format)
+		}
+	} else if args.OcrCmd != "" && args.outputFormat() != outputPdf && args.TtsCmd == "" {
+		return fmt.Errorf("--ocr-cmd is only used by --format pdf, txt and md, and by --tts-cmd")
+	}
+	if args.CaptionUrl != "" && !validCaptionUrl(args.CaptionUrl) {
+		return fmt.Errorf("invalid caption URL %q, expected an http or https URL", args.CaptionUrl)
+	}
+	if (args.CaptionModel != "" || args.CaptionKey != "") && args.CaptionUrl == "" {
+		return fmt.Errorf("--caption-model and --caption-key need --caption-url to send the pages to")
+	}
+	if args.TtsCmd != "" && args.OcrCmd == "" {
+		return fmt.Errorf("--tts-cmd needs --ocr-cmd to read the text of the pages")
+	}
+
+	if args.outputFormat() == outputDjvu {
+		if err := checkDjvuTools(); err != nil {
+			return err
+		}
+	}
+
+	if args.Provider != "" {
+		if _, ok := provider.Get(args.Provider); !ok {
+			return fmt.Errorf("unknown provider %q, expected one of %s", args.Provider, strings.Join(provider.Names(), ", "))
+		}
+	}
+
+	if err := setupFixtures(args); err != nil {
+		return err
+	}
+
+	args.applyContainerProfile()
+	args.checkConflictPrompt()
+
+	return nil
+}
+
+// setupFixtures routes the requests of the book package through the recorder or the replayer
+func setupFixtures(args *Args) error {
+	if args.RecordFixtures != "" && args.Replay != "" {
+		return fmt.Errorf("--record-fixtures and --replay can't be used together")
+	}
+
+	if args.RecordFixtures != "" {
+		recorder, err := fixture.NewRecorder(args.RecordFixtures, book.Transport)
+		if err != nil {
+			return err
+		}
+		book.Transport = recorder
+	}
+
+	if args.Replay != "" {
+		// captures load the viewer in Chrome, which can't be served from fixtures
+		if args.Interactive {
+			return fmt.Errorf("--replay can't be used with -i")
+		}
+
+		replayer, err := fixture.NewReplayer(args.Replay)
+		if err != nil {
+			return err
+		}
+		book.Transport = replayer
+	}
+
+	return nil
+}
+
+// batchEntriesFromArgs applies command line flags that affect every entry of a batch
+func batchEntriesFromArgs(args *Args, entries []batchEntry) []batchEntry {
+	if args.Interactive {
+		for i := range entries {
+			entries[i].Interactive = true
+		}
+	}
+
+	return entries
 }
 
 // Main entry point
 func main() {
 	if err := mainWithErrors(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		if known, ok := classifyError(err); ok {
+			fmt.Fprintln(os.Stderr, known.hint)
+		}
+		offerSupportBundle(err)
 		os.Exit(1)
 	}
 }
@@ -751,14 +1637,5 @@ func extractPageNumber(filename string) int {
 // Helper function to run the terminal UI, called when -t or --termui is specified
 func runTerminalUI() {
 	// Call the terminal UI implementation from termui.go
-	RunTerminalUI()
-}
-
-// sanitizeFilename sanitizes a filename to remove invalid characters
-func sanitizeFilename(filename string) string {
-	invalidChars := []string{"/", "\\", ":", "*", "?", "\"", "<", ">", "|"}
-	for _, char := range invalidChars {
-		filename = strings.ReplaceAll(filename, char, "")
-	}
-	return filename
+	RunTerminalUI(themeAuto)
 }
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/png"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	arg "github.com/alexflint/go-arg"
+	book "github.com/ygunayer/fh5dl/book"
+)
+
+func TestInteractivePageFiles(t *testing.T) {
+	downloaded := []book.DownloadedImage{
+		{PageNumber: 1, FullPath: "1-1.jpg"},
+		{PageNumber: 2, FullPath: "2-1.jpg"},
+		{PageNumber: 3, FullPath: "3-1.jpg"},
+	}
+	interactive := []book.InteractivePageImage{
+		{PageNumber: 2, FullPath: "interactive-2.png"},
+	}
+
+	if got := strings.Join(interactivePageFiles(downloaded, interactive, false), ","); got != "1-1.jpg,interactive-2.png,3-1.jpg" {
+		t.Errorf("unexpected pages %s", got)
+	}
+
+	if got := strings.Join(interactivePageFiles(downloaded, interactive, true), ","); got != "1-1.jpg,2-1.jpg,interactive-2.png,3-1.jpg" {
+		t.Errorf("unexpected comparison pages %s", got)
+	}
+
+	interactive[0].Popups = []string{"interactive-2-popup-01.png"}
+	if got := strings.Join(interactivePageFiles(downloaded, interactive, false), ","); got != "1-1.jpg,interactive-2.png,interactive-2-popup-01.png,3-1.jpg" {
+		t.Errorf("unexpected pages with popups %s", got)
+	}
+}
+
+func TestDownloadImagesKeepsOrder(t *testing.T) {
+	var page bytes.Buffer
+	png.Encode(&page, image.NewRGBA(image.Rect(0, 0, 4, 4)))
+
+	transport := book.Transport
+	book.Transport = thumbnailTransport(page.Bytes())
+	defer func() { book.Transport = transport }()
+
+	images := make([]book.PageImage, 0)
+	for i := 1; i <= 120; i++ {
+		images = append(images, book.PageImage{PageNumber: i, ImageNumber: 1, OverallOrder: i, Url: fmt.Sprintf("https://example.com/%d.png", i)})
+	}
+
+	args := &Args{ImageOutputFolder: t.TempDir(), Concurrency: 16}
+	downloaded, failed, err := downloadImages(context.Background(), args, nil, images)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(downloaded) != len(images) || len(failed) != 0 {
+		t.Fatalf("expected every image to be downloaded, got %d and %d failures", len(downloaded), len(failed))
+	}
+
+	for i, img := range downloaded {
+		if img.PageNumber != i+1 {
+			t.Fatalf("expected page %d at position %d, got %d", i+1, i, img.PageNumber)
+		}
+	}
+}
+
+func TestArgsEnvironmentNames(t *testing.T) {
+	fields := reflect.TypeOf(Args{})
+	for i := 0; i < fields.NumField(); i++ {
+		field := fields.Field(i)
+		tag, ok := field.Tag.Lookup("arg")
+		if !ok || tag == "-" || tag == "positional" {
+			continue
+		}
+
+		// the long flag, or the field name for flags that only have a short one
+		name := strings.ToUpper(regexp.MustCompile(`([a-z0-9])([A-Z])`).ReplaceAllString(field.Name, "${1}_${2}"))
+		for _, part := range strings.Split(tag, ",") {
+			if part = strings.TrimSpace(part); strings.HasPrefix(part, "--") {
+				name = strings.ToUpper(strings.ReplaceAll(part[2:], "-", "_"))
+				break
+			}
+		}
+
+		if !strings.Contains(tag, "env:FH5DL_"+name) {
+			t.Errorf("expected %s to be read from FH5DL_%s, got %q", field.Name, name, tag)
+		}
+	}
+}
+
+func TestArgsFromEnvironment(t *testing.T) {
+	t.Setenv("FH5DL_CONCURRENCY", "3")
+	t.Setenv("FH5DL_OUTPUT_FOLDER", "books")
+	t.Setenv("FH5DL_ROTATE", "2:90,3:180")
+	t.Setenv("FH5DL_STRICT", "true")
+	t.Setenv("FH5DL_TERMUI", "false")
+
+	var args Args
+	parser, err := arg.NewParser(arg.Config{}, &args)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := parser.Parse([]string{"-c", "5", "abcde/fghij"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if args.Concurrency != 5 {
+		t.Errorf("expected the flag to win over the environment, got %d", args.Concurrency)
+	}
+	if args.OutputFolder != "books" || !args.Strict || len(args.Rotate) != 2 {
+		t.Errorf("expected the options from the environment, got %+v", args)
+	}
+	if args.ReportFormat != "json" {
+		t.Errorf("expected the defaults of the other options, got %q", args.ReportFormat)
+	}
+
+	t.Setenv("FH5DL_CONCURRENCY", "many")
+	if err := parser.Parse([]string{"abcde/fghij"}); err == nil || !strings.Contains(err.Error(), "FH5DL_CONCURRENCY") {
+		t.Errorf("expected an invalid variable to be named in the error, got %v", err)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ztrue/tracerr"
+	"gopkg.in/yaml.v3"
+)
+
+// batchManifest is a YAML list of books with the output of each, for batches that need more than a url list
+type batchManifest struct {
+	Folder   string         `yaml:"folder"`   // --folder template of the books that don't set their own
+	Filename string         `yaml:"filename"` // --filename template of the books that don't set their own
+	Books    []manifestBook `yaml:"books"`
+}
+
+// manifestBook is a book of a manifest or monitor config, written as a plain URL or with options
+type manifestBook struct {
+	Url         string `yaml:"url"`
+	Interactive bool   `yaml:"interactive"`
+	Title       string `yaml:"title"`
+	Series      string `yaml:"series"`
+	Volume      int    `yaml:"volume"`
+	Folder      string `yaml:"folder"`
+	Filename    string `yaml:"filename"`
+}
+
+func (b *manifestBook) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		b.Url = node.Value
+		return nil
+	}
+
+	type plain manifestBook
+	return node.Decode((*plain)(b))
+}
+
+// entry returns the batch entry of the book
+func (b manifestBook) entry(name string) batchEntry {
+	return batchEntry{
+		Name:        name,
+		Url:         b.Url,
+		Interactive: b.Interactive,
+		Title:       b.Title,
+		Series:      b.Series,
+		Volume:      b.Volume,
+		Folder:      b.Folder,
+		Filename:    b.Filename,
+	}
+}
+
+// isManifest reports whether a url list file is a YAML manifest
+func isManifest(path string) bool {
+	return strings.HasSuffix(path, ".yaml") || strings.HasSuffix(path, ".yml")
+}
+
+// readManifest reads batch entries from a YAML manifest, checking the output options of every book
+func readManifest(r io.Reader, source string) ([]batchEntry, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+
+	var manifest batchManifest
+	if err := yaml.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid manifest %s: %w", source, err)
+	}
+
+	entries := make([]batchEntry, 0, len(manifest.Books))
+	for i, b := range manifest.Books {
+		if b.Url == "" {
+			return nil, fmt.Errorf("book %d of %s has no url", i+1, source)
+		}
+		if b.Volume < 0 {
+			return nil, fmt.Errorf("invalid volume %d of book %d of %s, expected a volume number", b.Volume, i+1, source)
+		}
+		if b.Folder == "" {
+			b.Folder = manifest.Folder
+		}
+		if b.Filename == "" {
+			b.Filename = manifest.Filename
+		}
+		for _, template := range []string{b.Folder, b.Filename} {
+			if err := validOutputTemplate(template); err != nil {
+				return nil, fmt.Errorf("book %d of %s: %w", i+1, source, err)
+			}
+		}
+
+		entries = append(entries, b.entry(fmt.Sprintf("%s:%d", source, i+1)))
+	}
+
+	return entries, nil
+}
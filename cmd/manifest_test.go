@@ -0,0 +1,55 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadManifest(t *testing.T) {
+	manifest := `folder: "{series}"
+books:
+  - abcde/fghij
+  - url: https://online.fliphtml5.com/abcde/klmno/
+    interactive: true
+    series: Course Notes
+    volume: 2
+    filename: "{series} Vol. {volume}"
+  - url: vwxyz/pqrst
+    folder: archive
+`
+	entries, err := readManifest(strings.NewReader(manifest), "books.yaml")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %+v", entries)
+	}
+
+	if entries[0].Url != "abcde/fghij" || entries[0].Folder != "{series}" || entries[0].Name != "books.yaml:1" {
+		t.Errorf("expected the folder of the manifest for a plain url, got %+v", entries[0])
+	}
+	if !entries[1].Interactive || entries[1].Series != "Course Notes" || entries[1].Volume != 2 || entries[1].Filename != "{series} Vol. {volume}" {
+		t.Errorf("unexpected entry %+v", entries[1])
+	}
+	if entries[2].Folder != "archive" {
+		t.Errorf("expected the folder of the book to win, got %+v", entries[2])
+	}
+
+	if _, err := readManifest(strings.NewReader("books:\n  - url: abcde/fghij\n    filename: \"{name}\"\n"), "books.yaml"); err == nil {
+		t.Errorf("expected an error for an unknown placeholder")
+	}
+	if _, err := readManifest(strings.NewReader("books:\n  - title: No url\n"), "books.yaml"); err == nil {
+		t.Errorf("expected an error for a book without a url")
+	}
+}
+
+func TestApplyEntry(t *testing.T) {
+	args := Args{Series: "Batch", Folder: "{series}"}
+	args.applyEntry(batchEntry{Title: "Week 3", Volume: 3})
+	if args.Title != "Week 3" || args.Series != "Batch" || args.Volume != 3 || args.Folder != "{series}" {
+		t.Errorf("expected the options of the entry over the batch, got %+v", args)
+	}
+	if args.ownFolder() {
+		t.Errorf("expected books with a folder template not to get a folder of their own")
+	}
+}
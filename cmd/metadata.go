@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ygunayer/fh5dl/internal/progress"
+	"github.com/ztrue/tracerr"
+)
+
+// bookMetadata is stored in a sidecar file next to every generated PDF
+type bookMetadata struct {
+	BookId      string    `json:"bookId"`
+	Url         string    `json:"url"`
+	Title       string    `json:"title"`
+	Author      string    `json:"author,omitempty"`
+	Subject     string    `json:"subject,omitempty"`
+	Language    string    `json:"language,omitempty"`
+	Pages       int       `json:"pages"`
+	Interactive bool      `json:"interactive"`
+	CreatedAt   time.Time `json:"createdAt"`
+
+	// Volumes are the files of a PDF that was split with --split-every or --split-max-size
+	Volumes []string `json:"volumes,omitempty"`
+
+	// Stats are the timings and throughput of the download that produced the PDF
+	Stats *transferStats `json:"stats,omitempty"`
+
+	// Revision and PageImages are what the book looked like when the PDF was written, for --update to tell what changed
+	Revision   string           `json:"revision,omitempty"`
+	PageImages map[int][]string `json:"pageImages,omitempty"`
+
+	// Descriptions are the descriptions of the pages from --caption-url, by page number
+	Descriptions map[int]string `json:"descriptions,omitempty"`
+}
+
+// trimExtension removes the extension of an output file such as .pdf or .djvu
+func trimExtension(path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path))
+}
+
+// metadataPath returns the path of the sidecar file of a PDF
+func metadataPath(pdfPath string) string {
+	return trimExtension(pdfPath) + ".meta.json"
+}
+
+// readMetadata reads the sidecar of a PDF, returning nil if there is none
+func readMetadata(pdfPath string) (*bookMetadata, error) {
+	return readMetadataFile(metadataPath(pdfPath))
+}
+
+// readMetadataFile reads a sidecar file, returning nil if it doesn't exist
+func readMetadataFile(path string) (*bookMetadata, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+
+	var metadata bookMetadata
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		return nil, fmt.Errorf("failed to read metadata %s: %w", path, err)
+	}
+
+	return &metadata, nil
+}
+
+// writeMetadata writes the sidecar of a PDF
+func writeMetadata(pdfPath string, metadata *bookMetadata) error {
+	data, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	return tracerr.Wrap(os.WriteFile(metadataPath(pdfPath), data, 0644))
+}
+
+// resolvePdfPath picks the PDF path for a book. If a PDF with the same name belongs to a different
+// book (according to its metadata) a numbered variant such as "Title (2).pdf" is used instead, so
+// books that share a title don't overwrite or skip each other. PDFs without metadata are assumed to
+// be earlier downloads of the same book.
+func resolvePdfPath(reporter progress.Reporter, outputDir string, name string, bookId string) (string, error) {
+	return resolveOutputPath(reporter, outputDir, name, ".pdf", bookId)
+}
+
+// resolveOutputPath is resolvePdfPath for output files with any extension
+func resolveOutputPath(reporter progress.Reporter, outputDir string, name string, extension string, bookId string) (string, error) {
+	for n := 1; ; n++ {
+		candidate := name
+		if n > 1 {
+			candidate = fmt.Sprintf("%s (%d)", name, n)
+		}
+
+		pdfPath := filepath.Join(outputDir, candidate+extension)
+		if !outputExists(pdfPath) {
+			return pdfPath, nil
+		}
+
+		metadata, err := readMetadata(pdfPath)
+		if err != nil {
+			return "", err
+		}
+
+		if metadata == nil || metadata.BookId == bookId {
+			return pdfPath, nil
+		}
+
+		if n == 1 {
+			reporter.Logf(progress.LevelWarn, "PDF %s belongs to a different book (%s), using a numbered file name",
+				filepath.Join(outputDir, name+extension), metadata.BookId)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ygunayer/fh5dl/internal/progress"
+)
+
+func TestResolvePdfPath(t *testing.T) {
+	dir := t.TempDir()
+
+	// an earlier download of another book with the same title
+	existing := filepath.Join(dir, "Catalog.pdf")
+	if err := os.WriteFile(existing, []byte("%PDF"), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := writeMetadata(existing, &bookMetadata{BookId: "abcde/fghij"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var warnings []string
+	reporter := progress.NewFunc(func(event progress.Event) {
+		if event.Level == progress.LevelWarn {
+			warnings = append(warnings, event.Message)
+		}
+	})
+
+	same, err := resolvePdfPath(reporter, dir, "Catalog", "abcde/fghij")
+	if err != nil || same != existing {
+		t.Fatalf("expected %s for the same book, got %s (%v)", existing, same, err)
+	}
+
+	other, err := resolvePdfPath(reporter, dir, "Catalog", "vwxyz/klmno")
+	expected := filepath.Join(dir, "Catalog (2).pdf")
+	if err != nil || other != expected {
+		t.Fatalf("expected %s for a different book, got %s (%v)", expected, other, err)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "belongs to a different book (abcde/fghij)") {
+		t.Errorf("expected a warning about the other book, got %q", warnings)
+	}
+}
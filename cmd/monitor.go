@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ygunayer/fh5dl/internal/progress"
+	"github.com/ztrue/tracerr"
+	"gopkg.in/yaml.v3"
+)
+
+// events passed to the notify command of the monitor as FH5DL_EVENT
+const (
+	monitorEventNew     = "new"
+	monitorEventChanged = "changed"
+)
+
+type MonitorArgs struct {
+	Config   string `arg:"positional,required" help:"YAML file with the books to monitor and the schedule to check them on"`
+	Once     bool   `arg:"--once" help:"(Optional) Check the books once and exit, for running from cron instead"`
+	Progress string `arg:"--progress" help:"(Optional) How to show progress: auto, bar, plain or json" default:"auto"`
+}
+
+// monitorConfig is the file the monitor command reads
+type monitorConfig struct {
+	Schedule    string         `yaml:"schedule"`    // cron expression or @every interval
+	Output      string         `yaml:"output"`      // folder of the books, relative to the config file, defaults to its folder
+	Concurrency int            `yaml:"concurrency"` // concurrent downloads of each book
+	Notify      string         `yaml:"notify"`      // command to run when a book is new or changed
+	Opds        bool           `yaml:"opds"`        // keep an OPDS catalog.xml of the output folder
+	Books       []manifestBook `yaml:"books"`
+}
+
+// readMonitorConfig reads and checks a monitor config
+func readMonitorConfig(path string) (*monitorConfig, *schedule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, tracerr.Wrap(err)
+	}
+
+	var config monitorConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, nil, fmt.Errorf("invalid monitor config %s: %w", path, err)
+	}
+	if len(config.Books) == 0 {
+		return nil, nil, fmt.Errorf("monitor config %s has no books", path)
+	}
+
+	s, err := parseSchedule(config.Schedule)
+	if err != nil {
+		return nil, nil, err
+	}
+	if s.next(time.Now()).IsZero() {
+		return nil, nil, fmt.Errorf("schedule %q never runs", config.Schedule)
+	}
+
+	if !filepath.IsAbs(config.Output) {
+		config.Output = filepath.Join(filepath.Dir(path), config.Output)
+	}
+	if config.Concurrency <= 0 {
+		config.Concurrency = defaultConcurrency()
+	}
+
+	return &config, s, nil
+}
+
+// monitorCommand checks the books of the config right away and then on its schedule, downloading the ones that are
+// new or changed and running the notify command for them
+func monitorCommand(argv []string) error {
+	var args MonitorArgs
+	if ok, err := parseCommandArgs("monitor", &args, argv); !ok {
+		return err
+	}
+
+	if !validProgressMode(args.Progress) {
+		return fmt.Errorf("invalid progress mode %q, expected auto, bar, plain or json", args.Progress)
+	}
+
+	config, s, err := readMonitorConfig(args.Config)
+	if err != nil {
+		return err
+	}
+
+	base := Args{
+		Concurrency:  config.Concurrency,
+		BatchSize:    8,
+		OutputFolder: config.Output,
+		ReportFormat: "json",
+		Progress:     args.Progress,
+		Update:       true,
+		Opds:         config.Opds,
+	}
+	reporter := base.reporter()
+
+	for {
+		checkMonitoredBooks(context.Background(), config, base, reporter)
+		if args.Once {
+			return nil
+		}
+
+		next := s.next(time.Now())
+		reporter.Logf(progress.LevelInfo, "Next check at %s", next.Format(time.RFC1123))
+		time.Sleep(time.Until(next))
+	}
+}
+
+// checkMonitoredBooks downloads the books that are new or changed since the last check and notifies about them.
+// Failed books are only reported, they are tried again on the next check.
+func checkMonitoredBooks(ctx context.Context, config *monitorConfig, base Args, reporter progress.Reporter) {
+	entries := make([]batchEntry, 0, len(config.Books))
+	for _, b := range config.Books {
+		entries = append(entries, b.entry(b.Url))
+	}
+
+	summary, err := runBatchReport(entries, base)
+	if err != nil {
+		reporter.Logf(progress.LevelError, "Check failed: %v", err)
+	}
+	base.updateCatalog()
+	if summary == nil {
+		return
+	}
+
+	for _, report := range summary.Books {
+		if report.Status != reportStatusSuccess {
+			continue
+		}
+
+		event := monitorEventNew
+		if report.ChangedPages != nil {
+			event = monitorEventChanged
+		}
+		reporter.Logf(progress.LevelInfo, "%s is %s: %s", report.Title, event, report.PdfPath)
+
+		if config.Notify == "" {
+			continue
+		}
+		if err := notifyChange(ctx, config.Notify, event, report); err != nil {
+			reporter.Logf(progress.LevelWarn, "%v", err)
+		}
+	}
+}
+
+// notifyChange runs the notify command with the PDF as its last argument and the change in FH5DL_* variables
+func notifyChange(ctx context.Context, command string, event string, report *bookReport) error {
+	changed := make([]string, len(report.ChangedPages))
+	for i, page := range report.ChangedPages {
+		changed[i] = fmt.Sprint(page)
+	}
+
+	cmd := shellCommand(ctx, command, report.PdfPath)
+	cmd.Env = childEnv(
+		"FH5DL_EVENT="+event,
+		"FH5DL_URL="+report.Url,
+		"FH5DL_BOOK_ID="+report.BookId,
+		"FH5DL_BOOK_TITLE="+report.Title,
+		"FH5DL_FILE="+report.PdfPath,
+		"FH5DL_CHANGED_PAGES="+strings.Join(changed, ","),
+	)
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("notify command failed for %s: %w: %s", report.Title, err, strings.TrimSpace(string(output)))
+	}
+
+	return nil
+}
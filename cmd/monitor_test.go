@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestReadMonitorConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "monitor.yaml")
+	config := `schedule: "0 6 * * *"
+output: books
+notify: notify-send fh5dl
+books:
+  - abcde/fghij
+  - url: klmno/pqrst
+    interactive: true
+`
+	if err := os.WriteFile(path, []byte(config), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	parsed, _, err := readMonitorConfig(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if parsed.Output != filepath.Join(dir, "books") || parsed.Concurrency <= 0 {
+		t.Errorf("unexpected config %+v", parsed)
+	}
+	if len(parsed.Books) != 2 || parsed.Books[0].Url != "abcde/fghij" || !parsed.Books[1].Interactive {
+		t.Errorf("unexpected books %+v", parsed.Books)
+	}
+
+	if err := os.WriteFile(path, []byte("schedule: \"@daily\"\n"), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, _, err := readMonitorConfig(path); err == nil {
+		t.Errorf("expected an error for a config without books")
+	}
+}
+
+func TestNotifyChange(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("notify commands in this test use sh")
+	}
+
+	out := filepath.Join(t.TempDir(), "out.txt")
+	report := newBookReport("abcde/fghij")
+	report.Title = "Catalog"
+	report.PdfPath = "/tmp/Catalog.pdf"
+	report.ChangedPages = []int{2, 5}
+
+	err := notifyChange(context.Background(), `printf '%s %s %s' "$FH5DL_EVENT" "$FH5DL_CHANGED_PAGES" >`+out, monitorEventChanged, report)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := string(data); got != "changed 2,5 /tmp/Catalog.pdf" {
+		t.Errorf("unexpected notify output %q", got)
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	book "github.com/ygunayer/fh5dl/book"
+	"github.com/ygunayer/fh5dl/internal/progress"
+	"github.com/ztrue/tracerr"
+	"golang.org/x/sync/errgroup"
+)
+
+// audioSuffix is what the folder of the narration of a book ends with, next to the output
+const audioSuffix = ".audio"
+
+// audioChapter is a part of the book narrated into its own MP3
+type audioChapter struct {
+	title string
+	pages []int
+}
+
+// audioPath returns the folder the narration is written into, next to the output
+func audioPath(outputPath string) string {
+	return trimExtension(outputPath) + audioSuffix
+}
+
+// audioChapters splits the pages, in order, into the top level chapters of the outline. Pages before the first
+// chapter, or all of them for books without an outline, form a chapter named after the book. Chapters without any
+// of the pages are left out.
+func audioChapters(b *book.Book, pages []int) []audioChapter {
+	starts := make([]book.OutlineEntry, 0, len(b.Outline))
+	for _, entry := range b.Outline {
+		if entry.Page > 0 {
+			starts = append(starts, entry)
+		}
+	}
+	sort.SliceStable(starts, func(i, j int) bool {
+		return starts[i].Page < starts[j].Page
+	})
+
+	chapters := make([]audioChapter, 0)
+	current := audioChapter{title: b.Title}
+	next := 0
+	for _, page := range pages {
+		for next < len(starts) && starts[next].Page <= page {
+			if len(current.pages) > 0 {
+				chapters = append(chapters, current)
+			}
+			current = audioChapter{title: starts[next].Title}
+			next++
+		}
+		current.pages = append(current.pages, page)
+	}
+	if len(current.pages) > 0 {
+		chapters = append(chapters, current)
+	}
+
+	return chapters
+}
+
+// chapterText is what is read aloud for a chapter: its title, then the text of its pages. It is empty if none of the
+// pages have text.
+func chapterText(chapter audioChapter, texts map[int]string) string {
+	parts := make([]string, 0, len(chapter.pages))
+	for _, page := range chapter.pages {
+		if text := texts[page]; text != "" {
+			parts = append(parts, text)
+		}
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+
+	return chapter.title + ".\n\n" + strings.Join(parts, "\n\n")
+}
+
+// narrateBook reads the text of the pages aloud with --tts-cmd into an MP3 for every chapter, written into dir as
+// <number> - <chapter>.mp3, and returns their paths. Chapters without text are skipped.
+func narrateBook(ctx context.Context, args *Args, b *book.Book, pages []int, texts map[int]string, dir string) ([]string, error) {
+	reporter := args.reporter()
+
+	// the narration of an earlier run may have had other chapters
+	if err := os.RemoveAll(dir); err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+
+	textDir, err := newWorkTempDir(args.WorkDir, "fh5dl-narration-")
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+	defer os.RemoveAll(textDir)
+
+	chapters := audioChapters(b, pages)
+	task := reporter.Start("narrate", "Narrating the chapters", len(chapters))
+	defer task.Finish()
+
+	paths := make([]string, len(chapters))
+	eg, egCtx := errgroup.WithContext(ctx)
+	eg.SetLimit(runtime.NumCPU())
+	for i, chapter := range chapters {
+		text := chapterText(chapter, texts)
+		if text == "" {
+			reporter.Logf(progress.LevelWarn, "Chapter %q has no text to narrate, skipping it", chapter.title)
+			task.Add(1)
+			continue
+		}
+
+		name := fmt.Sprintf("%02d", i+1)
+		if title := sanitizeFilename(chapter.title, args.AsciiNames); title != "" {
+			name += " - " + title
+		}
+		name = fitFilename(dir, name, ".mp3")
+
+		eg.Go(func() error {
+			defer task.Add(1)
+
+			textPath := filepath.Join(textDir, fmt.Sprintf("%02d.txt", i+1))
+			if err := os.WriteFile(textPath, []byte(text), 0644); err != nil {
+				return tracerr.Wrap(err)
+			}
+
+			outputPath := filepath.Join(dir, name+".mp3")
+			if err := runTtsCommand(egCtx, args.TtsCmd, chapter.title, textPath, outputPath, b.Language); err != nil {
+				return err
+			}
+
+			paths[i] = outputPath
+			return nil
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+
+	narrated := make([]string, 0, len(paths))
+	for _, path := range paths {
+		if path != "" {
+			narrated = append(narrated, path)
+		}
+	}
+	if len(narrated) == 0 {
+		return nil, fmt.Errorf("none of the pages have text to narrate")
+	}
+
+	return narrated, nil
+}
+
+// runTtsCommand runs an external text to speech engine on the text of a chapter, passed on stdin and in the file in
+// FH5DL_TEXT_FILE, which has to write an MP3 to the path in FH5DL_OUTPUT
+func runTtsCommand(ctx context.Context, command string, title string, textPath string, outputPath string, language string) error {
+	text, err := os.Open(textPath)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+	defer text.Close()
+
+	cmd := shellScript(ctx, command)
+	cmd.Stdin = text
+	cmd.Env = childEnv(
+		"FH5DL_TEXT_FILE="+textPath,
+		"FH5DL_OUTPUT="+outputPath,
+		"FH5DL_CHAPTER="+title,
+		"FH5DL_BOOK_LANGUAGE="+language,
+	)
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("narrating %q failed: %w: %s", title, err, strings.TrimSpace(string(output)))
+	}
+	if _, err := os.Stat(outputPath); err != nil {
+		return fmt.Errorf("narrating %q didn't write %s", title, outputPath)
+	}
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+
+	book "github.com/ygunayer/fh5dl/book"
+)
+
+func TestAudioChapters(t *testing.T) {
+	b := &book.Book{
+		Title: "Course Notes",
+		Outline: []book.OutlineEntry{
+			{Title: "Part 2", Page: 6, Children: []book.OutlineEntry{{Title: "Section", Page: 7}}},
+			{Title: "Part 1", Page: 3},
+			{Title: "Appendix", Page: 20},
+		},
+	}
+
+	expected := []audioChapter{
+		{title: "Course Notes", pages: []int{1, 2}},
+		{title: "Part 1", pages: []int{3, 4, 5}},
+		{title: "Part 2", pages: []int{6, 7, 8}},
+	}
+	if actual := audioChapters(b, []int{1, 2, 3, 4, 5, 6, 7, 8}); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+
+	// the chapters follow the selected pages
+	expected = []audioChapter{{title: "Part 2", pages: []int{7, 8}}}
+	if actual := audioChapters(b, []int{7, 8}); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
+
+func TestChapterText(t *testing.T) {
+	texts := map[int]string{1: "First page", 2: "", 3: "Third page"}
+	if text := chapterText(audioChapter{title: "Intro", pages: []int{1, 2, 3}}, texts); text != "Intro.\n\nFirst page\n\nThird page" {
+		t.Errorf("unexpected chapter text %q", text)
+	}
+	if text := chapterText(audioChapter{title: "Blank", pages: []int{2, 4}}, texts); text != "" {
+		t.Errorf("expected no text for a chapter without any, got %q", text)
+	}
+}
+
+func TestNarrateBook(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the TTS command in this test uses sh")
+	}
+
+	dir := filepath.Join(t.TempDir(), "book.audio")
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	stale := filepath.Join(dir, "03 - Removed.mp3")
+	if err := os.WriteFile(stale, nil, 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	b := &book.Book{Title: "Notes", Language: "en", Outline: []book.OutlineEntry{{Title: "Part: 1", Page: 2}}}
+	args := &Args{WorkDir: t.TempDir(), TtsCmd: `[ "$FH5DL_BOOK_LANGUAGE" = en ] && cat > "$FH5DL_OUTPUT"`}
+	paths, err := narrateBook(context.Background(), args, b, []int{1, 2, 3}, map[int]string{2: "Hello", 3: "world"}, dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// the first chapter has no text, and the file names can't have colons
+	expected := []string{filepath.Join(dir, "02 - Part 1.mp3")}
+	if !reflect.DeepEqual(paths, expected) {
+		t.Fatalf("expected %v, got %v", expected, paths)
+	}
+	content, err := os.ReadFile(paths[0])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(content) != "Part: 1.\n\nHello\n\nworld" {
+		t.Errorf("expected the chapter text on stdin, got %q", content)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("expected the narration of the earlier run to be removed, got %v", err)
+	}
+
+	args.TtsCmd = "true"
+	if _, err := narrateBook(context.Background(), args, b, []int{2}, map[int]string{2: "Hello"}, dir); err == nil {
+		t.Errorf("expected an error for a command that writes no MP3")
+	}
+}
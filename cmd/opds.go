@@ -0,0 +1,356 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"image"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	book "github.com/ygunayer/fh5dl/book"
+	"github.com/ygunayer/fh5dl/internal/progress"
+	"github.com/ztrue/tracerr"
+	"golang.org/x/image/draw"
+)
+
+const (
+	// catalogFile is the OPDS feed written into the output folder
+	catalogFile = "catalog.xml"
+	// coversFolder keeps the covers of the catalog, hidden so the catalog doesn't list it as a book folder
+	coversFolder = ".covers"
+	// coverWidth is the width of the covers, enough for the library views of e-readers
+	coverWidth = 512
+
+	opdsAcquisitionType = "application/atom+xml;profile=opds-catalog;kind=acquisition"
+)
+
+type CatalogArgs struct {
+	Folder   string `arg:"positional" help:"Output folder of the books. Defaults to the current working directory" default:"."`
+	BaseUrl  string `arg:"--base-url" help:"(Optional) URL the folder is served at, for absolute links. Links are relative to catalog.xml by default"`
+	Title    string `arg:"--title" help:"(Optional) Title of the catalog" default:"fh5dl library"`
+	NoCovers bool   `arg:"--no-covers" help:"(Optional) Don't download the first pages of the books for covers"`
+}
+
+// catalogOptions configures writeCatalog
+type catalogOptions struct {
+	BaseUrl  string
+	Title    string
+	NoCovers bool
+}
+
+// opdsFeed is an OPDS 1.2 acquisition feed, an Atom feed whose entries link to the books
+type opdsFeed struct {
+	XMLName   xml.Name    `xml:"feed"`
+	Xmlns     string      `xml:"xmlns,attr"`
+	XmlnsDc   string      `xml:"xmlns:dc,attr"`
+	XmlnsOpds string      `xml:"xmlns:opds,attr"`
+	Id        string      `xml:"id"`
+	Title     string      `xml:"title"`
+	Updated   string      `xml:"updated"`
+	Author    opdsAuthor  `xml:"author"`
+	Links     []opdsLink  `xml:"link"`
+	Entries   []opdsEntry `xml:"entry"`
+}
+
+type opdsAuthor struct {
+	Name string `xml:"name"`
+}
+
+type opdsLink struct {
+	Rel  string `xml:"rel,attr"`
+	Href string `xml:"href,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type opdsEntry struct {
+	Title    string       `xml:"title"`
+	Id       string       `xml:"id"`
+	Updated  string       `xml:"updated"`
+	Authors  []opdsAuthor `xml:"author,omitempty"`
+	Language string       `xml:"dc:language,omitempty"`
+	Subject  string       `xml:"dc:subject,omitempty"`
+	Summary  string       `xml:"summary,omitempty"`
+	Links    []opdsLink   `xml:"link"`
+}
+
+// catalogBook is a book found in the output folder
+type catalogBook struct {
+	metadata *bookMetadata
+	files    []string // the output files, relative to the folder
+}
+
+// catalogCommand writes an OPDS catalog of the books in an output folder
+func catalogCommand(argv []string) error {
+	var args CatalogArgs
+	if ok, err := parseCommandArgs("catalog", &args, argv); !ok {
+		return err
+	}
+
+	count, err := writeCatalog(context.Background(), args.Folder, catalogOptions{BaseUrl: args.BaseUrl, Title: args.Title, NoCovers: args.NoCovers}, func(format string, a ...interface{}) {
+		fmt.Fprintf(os.Stderr, format+"\n", a...)
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Listed %d books in %s\n", count, filepath.Join(args.Folder, catalogFile))
+	return nil
+}
+
+// updateCatalog rewrites the catalog of the output folder with --opds. A failure is only a warning, as the books
+// themselves were downloaded.
+func (args *Args) updateCatalog() {
+	if !args.Opds {
+		return
+	}
+
+	reporter := args.reporter()
+	count, err := writeCatalog(context.Background(), args.OutputFolder, catalogOptions{}, func(format string, a ...interface{}) {
+		reporter.Logf(progress.LevelWarn, format, a...)
+	})
+	if err != nil {
+		reporter.Logf(progress.LevelWarn, "Failed to update the OPDS catalog: %v", err)
+		return
+	}
+
+	reporter.Logf(progress.LevelInfo, "Updated the OPDS catalog of %d books in %s", count, filepath.Join(args.OutputFolder, catalogFile))
+}
+
+// writeCatalog writes catalog.xml into the folder, listing every book fh5dl wrote into it or its subfolders
+func writeCatalog(ctx context.Context, folder string, options catalogOptions, warnf func(format string, args ...interface{})) (int, error) {
+	if options.Title == "" {
+		options.Title = "fh5dl library"
+	}
+
+	books, err := findCatalogBooks(folder)
+	if err != nil {
+		return 0, err
+	}
+
+	feed := opdsFeed{
+		Xmlns:     "http://www.w3.org/2005/Atom",
+		XmlnsDc:   "http://purl.org/dc/terms/",
+		XmlnsOpds: "http://opds-spec.org/2010/catalog",
+		Id:        "urn:fh5dl:catalog",
+		Title:     options.Title,
+		Updated:   time.Now().UTC().Format(time.RFC3339),
+		Author:    opdsAuthor{Name: "fh5dl"},
+		Links: []opdsLink{
+			{Rel: "self", Href: catalogLink(options.BaseUrl, catalogFile), Type: opdsAcquisitionType},
+			{Rel: "start", Href: catalogLink(options.BaseUrl, catalogFile), Type: opdsAcquisitionType},
+		},
+		Entries: make([]opdsEntry, 0, len(books)),
+	}
+
+	missingCovers := 0
+	for _, b := range books {
+		entry := catalogEntry(b, options.BaseUrl)
+
+		if !options.NoCovers {
+			cover, err := catalogCover(ctx, folder, b.metadata)
+			if err != nil {
+				missingCovers++
+			} else if cover != "" {
+				entry.Links = append([]opdsLink{
+					{Rel: "http://opds-spec.org/image", Href: catalogLink(options.BaseUrl, cover), Type: "image/jpeg"},
+					{Rel: "http://opds-spec.org/image/thumbnail", Href: catalogLink(options.BaseUrl, cover), Type: "image/jpeg"},
+				}, entry.Links...)
+			}
+		}
+
+		feed.Entries = append(feed.Entries, entry)
+	}
+	if missingCovers > 0 {
+		warnf("Couldn't make the covers of %d books, they are listed without one", missingCovers)
+	}
+
+	data, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return 0, tracerr.Wrap(err)
+	}
+
+	// replace the catalog at once, e-readers may be reading it
+	catalogPath := filepath.Join(folder, catalogFile)
+	if err := os.WriteFile(catalogPath+".tmp", append([]byte(xml.Header), data...), 0644); err != nil {
+		return 0, tracerr.Wrap(err)
+	}
+
+	return len(books), tracerr.Wrap(os.Rename(catalogPath+".tmp", catalogPath))
+}
+
+// findCatalogBooks finds the books of the folder by their metadata sidecars, newest first. Sidecars whose output
+// was removed are left out.
+func findCatalogBooks(folder string) ([]catalogBook, error) {
+	books := make([]catalogBook, 0)
+	err := filepath.WalkDir(folder, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if path != folder && (strings.HasPrefix(entry.Name(), ".") || entry.Name() == "fh5dl-debug") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(entry.Name(), ".meta.json") {
+			return nil
+		}
+
+		metadata, err := readMetadataFile(path)
+		if err != nil || metadata == nil {
+			return nil
+		}
+
+		files := catalogFiles(folder, strings.TrimSuffix(path, ".meta.json"), metadata)
+		if len(files) > 0 {
+			books = append(books, catalogBook{metadata: metadata, files: files})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+
+	sort.SliceStable(books, func(i, j int) bool {
+		return books[i].metadata.CreatedAt.After(books[j].metadata.CreatedAt)
+	})
+
+	return books, nil
+}
+
+// catalogFiles returns the output files of a book that are on disk, relative to the folder
+func catalogFiles(folder string, base string, metadata *bookMetadata) []string {
+	candidates := make([]string, 0)
+	if len(metadata.Volumes) > 0 {
+		for _, volume := range metadata.Volumes {
+			candidates = append(candidates, filepath.Join(filepath.Dir(base), filepath.Base(volume)))
+		}
+	} else {
+		candidates = append(candidates, base+"."+outputPdf, base+"."+outputDjvu, base+"."+outputCbz)
+	}
+
+	files := make([]string, 0, len(candidates))
+	for _, candidate := range candidates {
+		info, err := os.Stat(candidate)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		// sidecars written before fh5dl kept the download time are dated by their output
+		if metadata.CreatedAt.IsZero() {
+			metadata.CreatedAt = info.ModTime()
+		}
+		if relative, err := filepath.Rel(folder, candidate); err == nil {
+			files = append(files, filepath.ToSlash(relative))
+		}
+	}
+
+	return files
+}
+
+func catalogEntry(b catalogBook, baseUrl string) opdsEntry {
+	metadata := b.metadata
+	entry := opdsEntry{
+		Title:    metadata.Title,
+		Id:       "urn:fh5dl:book:" + metadata.BookId,
+		Updated:  metadata.CreatedAt.UTC().Format(time.RFC3339),
+		Language: metadata.Language,
+		Subject:  metadata.Subject,
+		Summary:  fmt.Sprintf("%d pages, downloaded from %s", metadata.Pages, metadata.Url),
+	}
+	if metadata.Author != "" {
+		entry.Authors = []opdsAuthor{{Name: metadata.Author}}
+	}
+
+	for _, file := range b.files {
+		fileType := "application/pdf"
+		switch {
+		case strings.HasSuffix(file, "."+outputDjvu):
+			fileType = "image/vnd.djvu"
+		case strings.HasSuffix(file, "."+outputCbz):
+			fileType = "application/vnd.comicbook+zip"
+		}
+		entry.Links = append(entry.Links, opdsLink{Rel: "http://opds-spec.org/acquisition", Href: catalogLink(baseUrl, file), Type: fileType})
+	}
+
+	return entry
+}
+
+// catalogLink returns the link to a file of the folder, relative to the catalog unless the folder has a base URL
+func catalogLink(baseUrl string, file string) string {
+	segments := strings.Split(file, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	escaped := strings.Join(segments, "/")
+
+	if baseUrl == "" {
+		return escaped
+	}
+
+	return strings.TrimSuffix(baseUrl, "/") + "/" + escaped
+}
+
+// catalogCover returns the cover of the book relative to the folder, making it from the first page of the book
+// the first time. Books without page images in their metadata have no cover.
+func catalogCover(ctx context.Context, folder string, metadata *bookMetadata) (string, error) {
+	urls := metadata.PageImages[1]
+	if len(urls) == 0 {
+		return "", nil
+	}
+
+	hash := sha256.Sum256([]byte(urls[0]))
+	cover := path.Join(coversFolder, hex.EncodeToString(hash[:8])+".jpg")
+	coverPath := filepath.Join(folder, filepath.FromSlash(cover))
+	if _, err := os.Stat(coverPath); err == nil {
+		return cover, nil
+	}
+
+	dir, err := os.MkdirTemp("", "fh5dl-cover-")
+	if err != nil {
+		return "", tracerr.Wrap(err)
+	}
+	defer os.RemoveAll(dir)
+
+	page := book.PageImage{PageNumber: 1, ImageNumber: 1, Url: urls[0]}
+	downloaded, err := page.Download(ctx, dir, book.DownloadOptions{})
+	if err != nil {
+		return "", err
+	}
+
+	img, err := decodeImage(downloaded.FullPath)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(coverPath), 0755); err != nil {
+		return "", tracerr.Wrap(err)
+	}
+	if err := writeJpeg(coverPath, coverImage(img), 85); err != nil {
+		return "", err
+	}
+
+	return cover, nil
+}
+
+// coverImage scales the page down to the width of covers, keeping smaller pages as they are
+func coverImage(img image.Image) image.Image {
+	bounds := img.Bounds()
+	if bounds.Dx() <= coverWidth {
+		return img
+	}
+
+	height := bounds.Dy() * coverWidth / bounds.Dx()
+	scaled := image.NewRGBA(image.Rect(0, 0, coverWidth, height))
+	lanczos.Scale(scaled, scaled.Bounds(), img, bounds, draw.Src, nil)
+
+	return scaled
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWriteCatalog(t *testing.T) {
+	dir := t.TempDir()
+
+	writeBook := func(pdfPath string, metadata *bookMetadata) {
+		if err := os.MkdirAll(filepath.Dir(pdfPath), 0755); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := os.WriteFile(pdfPath, []byte("%PDF"), 0644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := writeMetadata(pdfPath, metadata); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	older := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	writeBook(filepath.Join(dir, "abcde", "Spring Catalog.pdf"), &bookMetadata{BookId: "abcde/fghij", Title: "Spring Catalog", Author: "ACME", Language: "en", Pages: 12, CreatedAt: older})
+	writeBook(filepath.Join(dir, "Atlas.pdf"), &bookMetadata{BookId: "vwxyz/klmno", Title: "Atlas", CreatedAt: older.Add(time.Hour)})
+
+	// a book whose PDF was removed isn't listed
+	if err := writeMetadata(filepath.Join(dir, "Gone.pdf"), &bookMetadata{BookId: "gone/gone", Title: "Gone"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	count, err := writeCatalog(context.Background(), dir, catalogOptions{NoCovers: true}, t.Logf)
+	if err != nil || count != 2 {
+		t.Fatalf("expected 2 books, got %d (%v)", count, err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, catalogFile))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var feed opdsFeed
+	if err := xml.Unmarshal(data, &feed); err != nil {
+		t.Fatalf("expected a valid feed, got %v:\n%s", err, data)
+	}
+	if feed.Title != "fh5dl library" || len(feed.Entries) != 2 {
+		t.Fatalf("unexpected feed %+v", feed)
+	}
+
+	// newest first
+	entry := feed.Entries[1]
+	if entry.Title != "Spring Catalog" || entry.Id != "urn:fh5dl:book:abcde/fghij" || len(entry.Authors) != 1 || entry.Authors[0].Name != "ACME" {
+		t.Errorf("unexpected entry %+v", entry)
+	}
+	if len(entry.Links) != 1 || entry.Links[0].Href != "abcde/Spring%20Catalog.pdf" || entry.Links[0].Type != "application/pdf" {
+		t.Errorf("expected a relative acquisition link, got %+v", entry.Links)
+	}
+}
+
+func TestCatalogLink(t *testing.T) {
+	cases := map[string]string{
+		"":                            "books/A%20B.pdf",
+		"https://example.com/books/":  "https://example.com/books/books/A%20B.pdf",
+		"https://example.com/library": "https://example.com/library/books/A%20B.pdf",
+	}
+
+	for baseUrl, expected := range cases {
+		if actual := catalogLink(baseUrl, "books/A B.pdf"); actual != expected {
+			t.Errorf("expected %s for %q, got %s", expected, baseUrl, actual)
+		}
+	}
+}
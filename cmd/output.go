@@ -0,0 +1,43 @@
+package main
+
+import "path/filepath"
+
+// output formats
+const (
+	outputPdf   = "pdf"
+	outputDjvu  = "djvu"
+	outputStrip = "strip" // a folder of tall images for scroll-style reading
+	outputCbz   = "cbz"   // a comic book archive of the page images, for comic and manga readers
+	outputTxt   = "txt"   // the text of the pages from --ocr-cmd, for note-taking and screen readers
+	outputMd    = "md"    // the same as txt, with a Markdown section for every page
+)
+
+// validOutputFormat checks the value of the --format flag
+func validOutputFormat(format string) bool {
+	return format == "" || format == outputPdf || format == outputDjvu || format == outputStrip || format == outputCbz ||
+		isTextFormat(format)
+}
+
+// isTextFormat tells whether the output format is the text of the pages rather than their images
+func isTextFormat(format string) bool {
+	return format == outputTxt || format == outputMd
+}
+
+// outputFormat returns the output format, defaulting to PDF
+func (args *Args) outputFormat() string {
+	if args.Format == "" {
+		return outputPdf
+	}
+
+	return args.Format
+}
+
+// originalOutputPath returns the path of the plain output kept next to an interactive one with --keep-original
+func originalOutputPath(outputPath string) string {
+	return trimExtension(outputPath) + ".orig" + filepath.Ext(outputPath)
+}
+
+// outputExtension returns the file extension of the output format, including the dot
+func (args *Args) outputExtension() string {
+	return "." + args.outputFormat()
+}
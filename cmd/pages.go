@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+
+	book "github.com/ygunayer/fh5dl/book"
+)
+
+// pageSet returns the pages selected with --pages, which is validated before any book is downloaded
+func (args *Args) pageSet() book.PageSet {
+	if args.Pages == "" {
+		return nil
+	}
+
+	pages, _ := book.ParsePageSet(args.Pages)
+	return pages
+}
+
+// selectImages keeps the images of the selected pages
+func selectImages(images []book.PageImage, pages book.PageSet) []book.PageImage {
+	if len(pages) == 0 {
+		return images
+	}
+
+	selected := make([]book.PageImage, 0, len(images))
+	for _, img := range images {
+		if pages.Contains(img.PageNumber) {
+			selected = append(selected, img)
+		}
+	}
+
+	return selected
+}
+
+// samplePages selects the first and last n pages of a book of the given number of pages, for --sample
+func samplePages(n int, total int) string {
+	if total <= 2*n {
+		return fmt.Sprintf("1-%d", max(total, 1))
+	}
+
+	return fmt.Sprintf("1-%d,%d-%d", n, total-n+1, total)
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	book "github.com/ygunayer/fh5dl/book"
+)
+
+func TestSamplePages(t *testing.T) {
+	cases := []struct {
+		n, total int
+		expected []int
+	}{
+		{2, 10, []int{1, 2, 9, 10}},
+		{3, 6, []int{1, 2, 3, 4, 5, 6}},
+		{5, 3, []int{1, 2, 3}},
+		{1, 1, []int{1}},
+	}
+
+	for _, c := range cases {
+		spec := samplePages(c.n, c.total)
+		pages, err := book.ParsePageSet(spec)
+		if err != nil {
+			t.Fatalf("unexpected error for %q: %v", spec, err)
+		}
+
+		b := &book.Book{Pages: make([]book.Page, c.total)}
+		if actual := expectedPages(b, pages); !reflect.DeepEqual(actual, c.expected) {
+			t.Errorf("expected pages %v of a sample of %d of %d pages, got %v (%s)", c.expected, c.n, c.total, actual, spec)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	pdfcpu_api "github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+	book "github.com/ygunayer/fh5dl/book"
+	"github.com/ztrue/tracerr"
+	"golang.org/x/text/language"
+)
+
+// documentInfo is the metadata written into the generated PDFs
+type documentInfo struct {
+	// Entries of the Info dictionary, without the empty ones
+	Properties map[string]string
+
+	// Language is the BCP 47 tag of the language of the text, stored as the document language
+	Language string
+}
+
+// documentInfo returns the metadata of the PDF for the book. The title and language of the book already have
+// the --title and --language overrides applied.
+func (args *Args) documentInfo(b *book.Book) documentInfo {
+	properties := make(map[string]string)
+	for key, value := range map[string]string{"Title": b.Title, "Author": args.Author, "Subject": args.Subject} {
+		if value != "" {
+			properties[key] = value
+		}
+	}
+
+	return documentInfo{Properties: properties, Language: b.Language}
+}
+
+// setDocumentInfo writes the metadata into the PDF. Info values are stored as UTF-16 so titles in any script
+// show up correctly in readers.
+func setDocumentInfo(pdfPath string, info documentInfo) error {
+	if len(info.Properties) == 0 && info.Language == "" {
+		return nil
+	}
+
+	ctx, err := pdfcpu_api.ReadContextFile(pdfPath)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	properties := make(map[string]string, len(info.Properties))
+	for key, value := range info.Properties {
+		escaped, err := types.EscapeUTF16String(value)
+		if err != nil {
+			return tracerr.Wrap(err)
+		}
+		properties[key] = *escaped
+	}
+	if err := pdfcpu.PropertiesAdd(ctx, properties); err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	if info.Language != "" {
+		root, err := ctx.Catalog()
+		if err != nil {
+			return tracerr.Wrap(err)
+		}
+		root["Lang"] = types.StringLiteral(info.Language)
+	}
+
+	return tracerr.Wrap(pdfcpu_api.WriteContextFile(ctx, pdfPath))
+}
+
+// ocrLanguage returns the Tesseract language code of a BCP 47 tag, such as "eng" for "en-US", for passing the
+// language of the book to OCR tools. Tesseract has separate models for simplified and traditional Chinese.
+func ocrLanguage(tag string) string {
+	if tag == "" {
+		return ""
+	}
+
+	parsed := language.Make(tag)
+	base, _ := parsed.Base()
+	if base.String() == "zh" {
+		if script, _ := parsed.Script(); script.String() == "Hant" {
+			return "chi_tra"
+		}
+		return "chi_sim"
+	}
+
+	return base.ISO3()
+}
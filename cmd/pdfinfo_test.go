@@ -0,0 +1,84 @@
+package main
+
+import (
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	pdfcpu_api "github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	book "github.com/ygunayer/fh5dl/book"
+)
+
+func TestSetDocumentInfo(t *testing.T) {
+	dir := t.TempDir()
+
+	imagePath := filepath.Join(dir, "1-1.png")
+	file, err := os.Create(imagePath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	png.Encode(file, image.NewRGBA(image.Rect(0, 0, 200, 100)))
+	file.Close()
+
+	pdfPath := filepath.Join(dir, "book.pdf")
+	if err := generatePDF([]string{imagePath}, pdfPath); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	args := &Args{Author: "Ayşe Yılmaz"}
+	info := args.documentInfo(&book.Book{Title: "Übungsbuch", Language: "de"})
+	if _, ok := info.Properties["Subject"]; ok {
+		t.Errorf("expected no subject, got %v", info)
+	}
+
+	if err := setDocumentInfo(pdfPath, info); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	pdf, err := os.Open(pdfPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer pdf.Close()
+
+	actual, err := pdfcpu_api.PDFInfo(pdf, pdfPath, nil, model.NewDefaultConfiguration())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if actual.Title != "Übungsbuch" || actual.Author != "Ayşe Yılmaz" || actual.Subject != "" {
+		t.Errorf("unexpected document info %q, %q, %q", actual.Title, actual.Author, actual.Subject)
+	}
+
+	ctx, err := pdfcpu_api.ReadContextFile(pdfPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	root, err := ctx.Catalog()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if lang := root.StringEntry("Lang"); lang == nil || *lang != "de" {
+		t.Errorf("expected the document language to be de, got %v", root["Lang"])
+	}
+}
+
+func TestOcrLanguage(t *testing.T) {
+	cases := map[string]string{
+		"":        "",
+		"en-US":   "eng",
+		"de":      "deu",
+		"tr":      "tur",
+		"zh-Hant": "chi_tra",
+		"zh-CN":   "chi_sim",
+	}
+
+	for tag, expected := range cases {
+		if actual := ocrLanguage(tag); actual != expected {
+			t.Errorf("expected %q for %q, got %q", expected, tag, actual)
+		}
+	}
+}
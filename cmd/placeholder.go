@@ -0,0 +1,113 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"path/filepath"
+
+	book "github.com/ygunayer/fh5dl/book"
+	"golang.org/x/image/font"
+)
+
+// placeholderBackground is the color of placeholder pages, light enough to print but not mistaken for a blank page
+var placeholderBackground = color.Gray{Y: 0xee}
+
+// placeholderReason describes why a page is missing, using the status the server answered with when there is one
+func placeholderReason(pageNumber int, failedImages []failedImage) string {
+	for _, failed := range failedImages {
+		if failed.Page != pageNumber {
+			continue
+		}
+		if failed.Status > 0 {
+			return fmt.Sprintf("source returned %d", failed.Status)
+		}
+
+		return "download failed"
+	}
+
+	return "not downloaded"
+}
+
+// imageFailureStatus returns the status code an image download failed with, or zero if it didn't get a response
+func imageFailureStatus(err error) int {
+	var statusErr *book.ImageStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode
+	}
+
+	return 0
+}
+
+// renderPlaceholder writes a page of the given size saying the page is unavailable and why
+func renderPlaceholder(pageNumber int, reason string, size image.Point, dir string) (string, error) {
+	fonts, err := newTocFonts(size.Y)
+	if err != nil {
+		return "", err
+	}
+	defer fonts.heading.Close()
+	defer fonts.entry.Close()
+
+	img := image.NewRGBA(image.Rectangle{Max: size})
+	draw.Draw(img, img.Bounds(), image.NewUniform(placeholderBackground), image.Point{}, draw.Src)
+
+	heading := fmt.Sprintf("Page %d unavailable", pageNumber)
+	headingX := (size.X - font.MeasureString(fonts.heading, heading).Ceil()) / 2
+	drawText(img, fonts.heading, heading, max(0, headingX), size.Y/2)
+
+	reasonX := (size.X - font.MeasureString(fonts.entry, reason).Ceil()) / 2
+	drawText(img, fonts.entry, reason, max(0, reasonX), size.Y/2+fonts.heading.Metrics().Height.Ceil()*3/2)
+
+	path := filepath.Join(dir, fmt.Sprintf("placeholder-%d.png", pageNumber))
+	if err := writePng(path, img); err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
+// insertPlaceholders puts the placeholder of every missing page before the images of the pages that follow it
+func insertPlaceholders(imageFiles []string, pageNumbers map[string]int, placeholders map[int]string, missing []int) []string {
+	files := make([]string, 0, len(imageFiles)+len(missing))
+	next := 0
+	for _, imageFile := range imageFiles {
+		pageNumber, ok := pageNumbers[imageFile]
+		for ok && next < len(missing) && missing[next] < pageNumber {
+			files = append(files, placeholders[missing[next]])
+			next++
+		}
+		files = append(files, imageFile)
+	}
+	for _, pageNumber := range missing[next:] {
+		files = append(files, placeholders[pageNumber])
+	}
+
+	return files
+}
+
+// addPlaceholders renders a placeholder for every missing page at the size of the first page, and returns the
+// image files with the placeholders in place of the missing pages
+func addPlaceholders(b *book.Book, imageFiles []string, pageNumbers map[string]int, missing []int, failedImages []failedImage) ([]string, error) {
+	size, err := imageSize(imageFiles[0])
+	if err != nil {
+		if b.PageWidth == 0 || b.PageHeight == 0 {
+			return nil, err
+		}
+		size = image.Pt(b.PageWidth, b.PageHeight)
+	}
+
+	placeholders := make(map[int]string)
+	for _, pageNumber := range missing {
+		path, err := renderPlaceholder(pageNumber, placeholderReason(pageNumber, failedImages), size, filepath.Dir(imageFiles[0]))
+		if err != nil {
+			return nil, err
+		}
+
+		placeholders[pageNumber] = path
+		pageNumbers[path] = pageNumber
+	}
+
+	return insertPlaceholders(imageFiles, pageNumbers, placeholders, missing), nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"image"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	book "github.com/ygunayer/fh5dl/book"
+)
+
+func TestInsertPlaceholders(t *testing.T) {
+	imageFiles := []string{"2-1.jpg", "2-2.jpg", "4-1.jpg"}
+	pageNumbers := map[string]int{"2-1.jpg": 2, "2-2.jpg": 2, "4-1.jpg": 4}
+	placeholders := map[int]string{1: "placeholder-1.png", 3: "placeholder-3.png", 5: "placeholder-5.png"}
+
+	actual := insertPlaceholders(imageFiles, pageNumbers, placeholders, []int{1, 3, 5})
+	expected := []string{"placeholder-1.png", "2-1.jpg", "2-2.jpg", "placeholder-3.png", "4-1.jpg", "placeholder-5.png"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
+
+func TestPlaceholderReason(t *testing.T) {
+	err := errors.Join(errors.New("failed after retrying"), &book.ImageStatusError{StatusCode: 404, Status: "404 Not Found"})
+	failed := []failedImage{{Page: 27, Status: imageFailureStatus(err)}, {Page: 28}}
+
+	if reason := placeholderReason(27, failed); reason != "source returned 404" {
+		t.Errorf("unexpected reason %q", reason)
+	}
+	if reason := placeholderReason(28, failed); reason != "download failed" {
+		t.Errorf("unexpected reason %q", reason)
+	}
+	if reason := placeholderReason(29, failed); reason != "not downloaded" {
+		t.Errorf("unexpected reason %q", reason)
+	}
+}
+
+func TestRenderPlaceholder(t *testing.T) {
+	path, err := renderPlaceholder(27, "source returned 404", image.Pt(400, 600), t.TempDir())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if filepath.Base(path) != "placeholder-27.png" {
+		t.Errorf("unexpected path %s", path)
+	}
+
+	size, err := imageSize(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if size != image.Pt(400, 600) {
+		t.Errorf("expected the placeholder to be 400x600, got %v", size)
+	}
+}
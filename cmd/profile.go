@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"sync"
+
+	"github.com/ygunayer/fh5dl/internal/progress"
+	"github.com/ztrue/tracerr"
+)
+
+// profile file names, written into the --profile folder
+const (
+	cpuProfileName  = "cpu.pprof"
+	heapProfileName = "heap.pprof"
+	phasesName      = "phases.json"
+)
+
+// phaseTiming is how long each phase of a book took
+type phaseTiming struct {
+	Url             string  `json:"url"`
+	Status          string  `json:"status"`
+	DownloadSeconds float64 `json:"downloadSeconds"`
+	CaptureSeconds  float64 `json:"captureSeconds"`
+	ProcessSeconds  float64 `json:"processSeconds"`
+	PdfSeconds      float64 `json:"pdfSeconds"`
+	TotalSeconds    float64 `json:"totalSeconds"`
+}
+
+// profiler records a CPU profile of the whole run, and the phase timings of every book in it
+type profiler struct {
+	dir     string
+	cpuFile *os.File
+
+	mutex  sync.Mutex
+	phases []phaseTiming
+}
+
+// startProfile starts the CPU profile of the run in dir
+func startProfile(dir string) (*profiler, error) {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+
+	cpuFile, err := os.Create(filepath.Join(dir, cpuProfileName))
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+	if err := pprof.StartCPUProfile(cpuFile); err != nil {
+		cpuFile.Close()
+		return nil, tracerr.Wrap(err)
+	}
+
+	return &profiler{dir: dir, cpuFile: cpuFile, phases: make([]phaseTiming, 0)}, nil
+}
+
+// record adds the phase timings of a finished book
+func (p *profiler) record(report *bookReport) {
+	if p == nil {
+		return
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.phases = append(p.phases, phaseTiming{
+		Url:             report.Url,
+		Status:          report.Status,
+		DownloadSeconds: report.DownloadSeconds,
+		CaptureSeconds:  report.CaptureSeconds,
+		ProcessSeconds:  report.ProcessSeconds,
+		PdfSeconds:      report.PdfSeconds,
+		TotalSeconds:    report.TotalSeconds,
+	})
+}
+
+// stop finishes the CPU profile, then writes the heap profile and the phase timings, and prints where they went
+func (p *profiler) stop(reporter progress.Reporter) error {
+	pprof.StopCPUProfile()
+	if err := p.cpuFile.Close(); err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	heapFile, err := os.Create(filepath.Join(p.dir, heapProfileName))
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+	defer heapFile.Close()
+
+	// collect first so the profile shows what is still in use rather than garbage
+	runtime.GC()
+	if err := pprof.WriteHeapProfile(heapFile); err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	data, err := json.MarshalIndent(p.phases, "", "  ")
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+	if err := os.WriteFile(filepath.Join(p.dir, phasesName), data, 0644); err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	total := phaseTiming{}
+	for _, phase := range p.phases {
+		total.DownloadSeconds += phase.DownloadSeconds
+		total.CaptureSeconds += phase.CaptureSeconds
+		total.ProcessSeconds += phase.ProcessSeconds
+		total.PdfSeconds += phase.PdfSeconds
+		total.TotalSeconds += phase.TotalSeconds
+	}
+	reporter.Logf(progress.LevelInfo, "Phases: download %.1fs, capture %.1fs, page processing %.1fs, output %.1fs of %.1fs in total",
+		total.DownloadSeconds, total.CaptureSeconds, total.ProcessSeconds, total.PdfSeconds, total.TotalSeconds)
+	reporter.Logf(progress.LevelInfo, "Wrote the profiles and phase timings to %s", p.dir)
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ygunayer/fh5dl/internal/progress"
+)
+
+func TestProfile(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "profile")
+	p, err := startProfile(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	report := &bookReport{Url: "abcde/fghij", Status: "ok", TotalSeconds: 4}
+	report.DownloadSeconds = 2
+	report.ProcessSeconds = 1
+	report.PdfSeconds = 0.5
+	p.record(report)
+
+	out := &strings.Builder{}
+	reporter, _ := progress.New(progress.ModePlain, progress.Options{Out: out})
+	if err := p.stop(reporter); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, name := range []string{cpuProfileName, heapProfileName} {
+		if info, err := os.Stat(filepath.Join(dir, name)); err != nil || info.Size() == 0 {
+			t.Errorf("expected %s to be written, got %v", name, err)
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, phasesName))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var phases []phaseTiming
+	if err := json.Unmarshal(data, &phases); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(phases) != 1 || phases[0].Url != "abcde/fghij" || phases[0].ProcessSeconds != 1 {
+		t.Errorf("unexpected phases %+v", phases)
+	}
+	if !strings.Contains(out.String(), "page processing 1.0s") {
+		t.Errorf("expected the phase totals to be printed, got %q", out.String())
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ygunayer/fh5dl/internal/progress"
+	"golang.org/x/term"
+)
+
+// progressAuto picks bars in a terminal and plain lines otherwise
+const progressAuto = "auto"
+
+// stdoutIsTerminal reports whether stdout is an interactive terminal (as opposed to cron, CI or a file)
+func stdoutIsTerminal() bool {
+	return term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// colorEnabled reports whether colored output should be used, honoring https://no-color.org
+func colorEnabled() bool {
+	return os.Getenv("NO_COLOR") == "" && stdoutIsTerminal()
+}
+
+// validProgressMode checks the value of the --progress flag
+func validProgressMode(mode string) bool {
+	switch mode {
+	case progressAuto, "", progress.ModeBar, progress.ModePlain, progress.ModeJSON:
+		return true
+	}
+
+	return false
+}
+
+// progressMode returns the mode of the reporter for the given --progress mode, picking one for auto
+func progressMode(mode string) string {
+	if mode != progressAuto && mode != "" {
+		return mode
+	}
+
+	if stdoutIsTerminal() {
+		return progress.ModeBar
+	}
+
+	return progress.ModePlain
+}
+
+// newReporter creates the progress reporter for the given --progress mode
+func newReporter(mode string) (progress.Reporter, error) {
+	return progress.New(progressMode(mode), progress.Options{Out: os.Stdout, Err: os.Stderr, Color: colorEnabled()})
+}
+
+// reporter returns the progress reporter of the download, creating one from --progress if none was set
+func (args *Args) reporter() progress.Reporter {
+	if args.Reporter != nil {
+		return args.Reporter
+	}
+
+	reporter, err := newReporter(args.Progress)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v, falling back to plain progress\n", err)
+		reporter, _ = progress.New(progress.ModePlain, progress.Options{Out: os.Stdout, Err: os.Stderr})
+	}
+
+	args.Reporter = supportReporter{reporter}
+	return args.Reporter
+}
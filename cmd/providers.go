@@ -0,0 +1,12 @@
+package main
+
+// Providers of the supported flipbook platforms, see the provider package for adding new ones
+import (
+	_ "github.com/ygunayer/fh5dl/provider/calameo"
+	_ "github.com/ygunayer/fh5dl/provider/fliphtml5"
+	_ "github.com/ygunayer/fh5dl/provider/flippingbook"
+	_ "github.com/ygunayer/fh5dl/provider/heyzine"
+	_ "github.com/ygunayer/fh5dl/provider/joomag"
+	_ "github.com/ygunayer/fh5dl/provider/simplebooklet"
+	_ "github.com/ygunayer/fh5dl/provider/wordpress"
+)
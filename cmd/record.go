@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	book "github.com/ygunayer/fh5dl/book"
+	"github.com/ygunayer/fh5dl/internal/progress"
+	"github.com/ygunayer/fh5dl/provider"
+	"github.com/ztrue/tracerr"
+)
+
+// recording formats of --record
+const (
+	recordMp4 = "mp4"
+	recordGif = "gif"
+)
+
+// defaultRecordPageSeconds is how long each page is shown in a recording
+const defaultRecordPageSeconds = 3
+
+// validRecordFormat checks the value of the --record flag
+func validRecordFormat(format string) bool {
+	return format == "" || format == recordMp4 || format == recordGif
+}
+
+// checkFfmpeg makes sure ffmpeg is installed before any download starts
+func checkFfmpeg() error {
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		return fmt.Errorf("--record needs ffmpeg (https://ffmpeg.org) in PATH: %w", err)
+	}
+
+	return nil
+}
+
+// recordingPath returns the path of the recording written next to the output
+func (args *Args) recordingPath(outputPath string) string {
+	return trimExtension(outputPath) + "." + args.Record
+}
+
+// recordBook flips through the book in the viewer and encodes the screencast into a video or GIF with ffmpeg
+func recordBook(ctx context.Context, args *Args, reporter progress.Reporter, p provider.Provider, b *book.Book, recordingPath string) error {
+	framesDir, err := newWorkTempDir(args.WorkDir, "fh5dl-record-")
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+	defer os.RemoveAll(framesDir)
+
+	pageUrls := make([]string, 0, len(b.Pages))
+	for i := 1; i <= len(b.Pages); i++ {
+		pageUrls = append(pageUrls, p.PageUrl(b, i))
+	}
+
+	pageSeconds := args.RecordPageSeconds
+	if pageSeconds <= 0 {
+		pageSeconds = defaultRecordPageSeconds
+	}
+
+	task := reporter.Start("record", "Recording pages", len(pageUrls))
+	options := book.CaptureOptions{WorkDir: args.WorkDir, Container: args.Container, SharedMemory: args.sharedMemory}
+	frames, err := book.Record(ctx, pageUrls, framesDir, time.Duration(pageSeconds*float64(time.Second)), options, func() { task.Add(1) })
+	task.Finish()
+	if err != nil {
+		return err
+	}
+
+	if len(frames) == 0 {
+		return fmt.Errorf("the viewer didn't render anything to record")
+	}
+
+	listPath := filepath.Join(framesDir, "frames.txt")
+	if err := os.WriteFile(listPath, []byte(concatList(frames)), 0644); err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	ffmpegArgs := append([]string{"-y", "-loglevel", "error", "-f", "concat", "-safe", "0", "-i", listPath}, ffmpegOutputArgs(args.Record)...)
+	ffmpegArgs = append(ffmpegArgs, recordingPath)
+	if output, err := exec.CommandContext(ctx, "ffmpeg", ffmpegArgs...).CombinedOutput(); err != nil {
+		return fmt.Errorf("ffmpeg failed: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	return nil
+}
+
+// concatList writes the frames as an ffmpeg concat demuxer script. The last frame is listed twice, as ffmpeg ignores
+// the duration of the last entry otherwise.
+func concatList(frames []book.Frame) string {
+	var list strings.Builder
+	list.WriteString("ffconcat version 1.0\n")
+	for _, frame := range frames {
+		fmt.Fprintf(&list, "file '%s'\nduration %.3f\n", escapeConcatPath(frame.Path), frame.Duration.Seconds())
+	}
+	fmt.Fprintf(&list, "file '%s'\n", escapeConcatPath(frames[len(frames)-1].Path))
+
+	return list.String()
+}
+
+// escapeConcatPath escapes single quotes for the concat demuxer
+func escapeConcatPath(path string) string {
+	return strings.ReplaceAll(filepath.ToSlash(path), "'", `'\''`)
+}
+
+// ffmpegOutputArgs returns the encoder settings of a recording format
+func ffmpegOutputArgs(format string) []string {
+	if format == recordGif {
+		// a palette generated from the frames keeps GIFs from looking dithered
+		return []string{"-vf", "fps=10,scale=960:-1:flags=lanczos,split[a][b];[a]palettegen[p];[b][p]paletteuse", "-loop", "0"}
+	}
+
+	// H.264 needs even dimensions, and yuv420p plays everywhere
+	return []string{"-vf", "scale=trunc(iw/2)*2:trunc(ih/2)*2", "-fps_mode", "vfr", "-c:v", "libx264", "-pix_fmt", "yuv420p", "-movflags", "+faststart"}
+}
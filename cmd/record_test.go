@@ -0,0 +1,23 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	book "github.com/ygunayer/fh5dl/book"
+)
+
+func TestConcatList(t *testing.T) {
+	frames := []book.Frame{
+		{Path: "/tmp/frames/frame-000001.jpg", Duration: 1500 * time.Millisecond},
+		{Path: "/tmp/it's/frame-000002.jpg", Duration: 250 * time.Millisecond},
+	}
+
+	expected := "ffconcat version 1.0\n" +
+		"file '/tmp/frames/frame-000001.jpg'\nduration 1.500\n" +
+		"file '/tmp/it'\\''s/frame-000002.jpg'\nduration 0.250\n" +
+		"file '/tmp/it'\\''s/frame-000002.jpg'\n"
+	if got := concatList(frames); got != expected {
+		t.Errorf("unexpected list:\n%s", got)
+	}
+}
@@ -0,0 +1,517 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	book "github.com/ygunayer/fh5dl/book"
+	"github.com/ztrue/tracerr"
+)
+
+// report statuses
+const (
+	reportStatusSuccess = "success"
+	reportStatusSkipped = "skipped"
+	reportStatusFailed  = "failed"
+)
+
+// slowestImageCount is how many of the slowest images are listed in reports
+const slowestImageCount = 5
+
+// bookReport summarizes the download of a single book
+type bookReport struct {
+	Url               string        `json:"url"`
+	BookId            string        `json:"bookId,omitempty"`
+	Title             string        `json:"title,omitempty"`
+	Language          string        `json:"language,omitempty"`
+	Status            string        `json:"status"`
+	Error             string        `json:"error,omitempty"`
+	ErrorKind         string        `json:"errorKind,omitempty"`
+	Interactive       bool          `json:"interactive"`
+	Pages             int           `json:"pages"`
+	ImagesTotal       int           `json:"imagesTotal"`
+	ImagesDownloaded  int           `json:"imagesDownloaded"`
+	ImagesCached      int           `json:"imagesCached"`
+	ImageBytes        int64         `json:"imageBytes"`
+	Retries           int           `json:"retries"`
+	UpdatedImages     int           `json:"updatedImages,omitempty"` // cached images downloaded again as they changed, with --revalidate
+	CapturedPages     int           `json:"capturedPages,omitempty"`
+	FailedPages       []int         `json:"failedPages,omitempty"`
+	DiagnosticsFolder string        `json:"diagnosticsFolder,omitempty"` // screenshots, DOM and console of the failed pages
+	FailedImages      []failedImage `json:"failedImages,omitempty"`      // images that couldn't be downloaded after retrying
+	MissingPages      []int         `json:"missingPages,omitempty"`      // pages of the book the output doesn't show
+	ChangedPages      []int         `json:"changedPages,omitempty"`      // pages changed since the earlier output, with --update
+	ThumbnailPages    []int         `json:"thumbnailPages,omitempty"`    // pages made from their upscaled thumbnail
+	DeskewedImages    int           `json:"deskewedImages,omitempty"`    // pages straightened with --deskew
+	EnhancedImages    int           `json:"enhancedImages,omitempty"`    // pages changed by --enhance
+	UpscaledImages    int           `json:"upscaledImages,omitempty"`    // pages upscaled with --upscale-below
+	PlaceholderPages  []int         `json:"placeholderPages,omitempty"`  // missing pages replaced with a generated page
+	OutputPages       int           `json:"outputPages,omitempty"`       // pages counted in the generated PDF
+	HookErrors        []string      `json:"hookErrors,omitempty"`
+	PdfPath           string        `json:"pdfPath,omitempty"`
+	PdfBytes          int64         `json:"pdfBytes,omitempty"`
+	Volumes           []string      `json:"volumes,omitempty"`
+	OriginalPaths     []string      `json:"originalPaths,omitempty"` // plain output written with --keep-original
+	RecordingPath     string        `json:"recordingPath,omitempty"`
+	RecordingError    string        `json:"recordingError,omitempty"`
+	AudioPaths        []string      `json:"audioPaths,omitempty"` // chapters narrated with --tts-cmd
+	AudioError        string        `json:"audioError,omitempty"`
+	CaptionErrors     []string      `json:"captionErrors,omitempty"` // pages --caption-url couldn't describe
+	Entry             *batchEntry   `json:"entry,omitempty"`         // output options of its manifest entry, in a batch
+	StartedAt         time.Time     `json:"startedAt"`
+	TotalSeconds      float64       `json:"totalSeconds"`
+	transferStats
+
+	// reportBase is the path (without extension) the report files are written to
+	reportBase string
+}
+
+// transferStats are the per-phase timings and download throughput of a book, kept in both its report and its metadata
+type transferStats struct {
+	DownloadSeconds float64       `json:"downloadSeconds"`
+	CaptureSeconds  float64       `json:"captureSeconds,omitempty"`
+	ProcessSeconds  float64       `json:"processSeconds,omitempty"` // rotating, straightening, upscaling, enhancing and trimming pages
+	PdfSeconds      float64       `json:"pdfSeconds"`
+	DownloadedBytes int64         `json:"downloadedBytes"` // bytes transferred over the network, excluding cached images
+	BytesPerSecond  float64       `json:"bytesPerSecond"`
+	ImagesPerSecond float64       `json:"imagesPerSecond"`
+	SlowestImages   []imageTiming `json:"slowestImages,omitempty"`
+}
+
+// imageTiming is the download time of a single image, used to point out slow pages
+type imageTiming struct {
+	Page    int     `json:"page"`
+	Image   int     `json:"image"`
+	Bytes   int64   `json:"bytes"`
+	Seconds float64 `json:"seconds"`
+}
+
+// failedImage is an image of the book that couldn't be downloaded
+type failedImage struct {
+	Page   int    `json:"page"`
+	Image  int    `json:"image"`
+	Url    string `json:"url"`
+	Status int    `json:"status,omitempty"` // status the server answered with, if it answered
+	Error  string `json:"error"`
+}
+
+// batchReport aggregates the reports of every book in a batch run
+type batchReport struct {
+	OutputFolder    string        `json:"outputFolder"`
+	StartedAt       time.Time     `json:"startedAt"`
+	TotalSeconds    float64       `json:"totalSeconds"`
+	Successful      int           `json:"successful"`
+	Skipped         int           `json:"skipped"`
+	Failed          int           `json:"failed"`
+	DownloadedBytes int64         `json:"downloadedBytes"` // total over the download phases of every book
+	BytesPerSecond  float64       `json:"bytesPerSecond"`
+	Books           []*bookReport `json:"books"`
+
+	// Options are the options of the run, keyed by the field names of Args, so a retry downloads the failed books
+	// the same way. Secrets such as the caption key are left out.
+	Options *Args `json:"options,omitempty"`
+
+	downloadSeconds float64
+}
+
+// newBookReport starts a successful report for the given url, the status is updated once the download finishes
+func newBookReport(url string) *bookReport {
+	return &bookReport{
+		Url:       url,
+		Status:    reportStatusSuccess,
+		StartedAt: time.Now(),
+	}
+}
+
+// finish records the final status and total duration of the report
+func (r *bookReport) finish(err error) {
+	r.TotalSeconds = time.Since(r.StartedAt).Seconds()
+
+	if err != nil {
+		r.Status = reportStatusFailed
+		r.Error = err.Error()
+		if known, ok := classifyError(err); ok {
+			r.ErrorKind = known.kind
+		}
+	}
+
+	if r.PdfPath != "" && r.Status == reportStatusSuccess {
+		paths := r.Volumes
+		if len(paths) == 0 {
+			paths = []string{r.PdfPath}
+		}
+
+		for _, path := range paths {
+			if stat, statErr := os.Stat(path); statErr == nil {
+				r.PdfBytes += stat.Size()
+			}
+		}
+	}
+}
+
+// addDownloadedImages records the download statistics of the book's images, along with the time the download phase took
+func (r *bookReport) addDownloadedImages(images []book.DownloadedImage, duration time.Duration) {
+	timings := make([]imageTiming, 0, len(images))
+	for _, image := range images {
+		if image.Attempts == 0 {
+			r.ImagesCached++
+		} else {
+			if image.Revalidated {
+				r.UpdatedImages++
+			}
+			r.ImagesDownloaded++
+			r.Retries += image.Attempts - 1
+			r.DownloadedBytes += image.Bytes
+			timings = append(timings, imageTiming{
+				Page:    image.PageNumber,
+				Image:   image.ImageNumber,
+				Bytes:   image.Bytes,
+				Seconds: image.Duration.Seconds(),
+			})
+		}
+
+		if stat, err := os.Stat(image.FullPath); err == nil {
+			r.ImageBytes += stat.Size()
+		}
+	}
+
+	r.DownloadSeconds = duration.Seconds()
+	if r.DownloadSeconds > 0 {
+		r.BytesPerSecond = float64(r.DownloadedBytes) / r.DownloadSeconds
+		r.ImagesPerSecond = float64(r.ImagesDownloaded) / r.DownloadSeconds
+	}
+
+	sort.SliceStable(timings, func(i, j int) bool {
+		return timings[i].Seconds > timings[j].Seconds
+	})
+	if len(timings) > slowestImageCount {
+		timings = timings[:slowestImageCount]
+	}
+	r.SlowestImages = timings
+}
+
+// throughputSummary describes the download speed of the book in a single line
+func (r *bookReport) throughputSummary() string {
+	summary := fmt.Sprintf("Downloaded %s in %s (%s/s, %.1f images/s)",
+		formatBytes(r.DownloadedBytes), formatSeconds(r.DownloadSeconds), formatBytes(int64(r.BytesPerSecond)), r.ImagesPerSecond)
+
+	if len(r.SlowestImages) > 0 {
+		slowest := r.SlowestImages[0]
+		summary += fmt.Sprintf(", slowest page %d took %.1fs", slowest.Page, slowest.Seconds)
+	}
+
+	return summary
+}
+
+// add appends a book report and updates the counters
+func (r *batchReport) add(report *bookReport) {
+	r.Books = append(r.Books, report)
+
+	r.DownloadedBytes += report.DownloadedBytes
+	r.downloadSeconds += report.DownloadSeconds
+	if r.downloadSeconds > 0 {
+		r.BytesPerSecond = float64(r.DownloadedBytes) / r.downloadSeconds
+	}
+
+	switch report.Status {
+	case reportStatusSuccess:
+		r.Successful++
+	case reportStatusSkipped:
+		r.Skipped++
+	default:
+		r.Failed++
+	}
+}
+
+// incomplete reports whether the book was written without some of its pages, which a retry may download
+func (r *bookReport) incomplete() bool {
+	return r.Status == reportStatusSuccess && (len(r.FailedImages) > 0 || len(r.FailedPages) > 0 || len(r.MissingPages) > 0)
+}
+
+// failedEntries returns the books that failed or were written without some of their pages as batch entries so they
+// can be retried, leaving out the ones that can't succeed on a retry, such as removed or private books. The output of
+// an incomplete book is replaced by its retry.
+func (r *batchReport) failedEntries() []batchEntry {
+	entries := make([]batchEntry, 0)
+	for _, report := range r.Books {
+		incomplete := report.incomplete()
+		if !incomplete && (report.Status != reportStatusFailed || !retryableErrorKind(report.ErrorKind)) {
+			continue
+		}
+
+		name := report.Title
+		if name == "" {
+			name = report.Url
+		}
+
+		entry := batchEntry{}
+		if report.Entry != nil {
+			entry = *report.Entry
+		}
+		entry.Name, entry.Url, entry.Interactive, entry.Replace = name, report.Url, report.Interactive, incomplete
+
+		entries = append(entries, entry)
+	}
+
+	return entries
+}
+
+// writeRetryFile writes the failed books as a url list that can be passed to `fh5dl retry` or --from-file
+func writeRetryFile(report *batchReport, path string) error {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# failed books of the batch run started at %s\n", report.StartedAt.Format(time.RFC1123))
+
+	for _, entry := range report.failedEntries() {
+		if entry.Replace {
+			fmt.Fprintf(&sb, "# %s (incomplete, retry it from the JSON report or with -f to replace its output)\n", entry.Name)
+		} else {
+			fmt.Fprintf(&sb, "# %s\n", entry.Name)
+		}
+		if entry.Interactive {
+			fmt.Fprintf(&sb, "%s -i\n", entry.Url)
+		} else {
+			fmt.Fprintf(&sb, "%s\n", entry.Url)
+		}
+	}
+
+	for _, failed := range report.Books {
+		if failed.Status == reportStatusFailed && !retryableErrorKind(failed.ErrorKind) {
+			fmt.Fprintf(&sb, "# not retried (%s): %s\n", failed.ErrorKind, failed.Url)
+		}
+	}
+
+	return tracerr.Wrap(os.WriteFile(path, []byte(sb.String()), 0644))
+}
+
+// validReportFormat checks the value of the --report flag
+func validReportFormat(format string) bool {
+	switch format {
+	case "json", "markdown", "all", "none":
+		return true
+	}
+
+	return false
+}
+
+// writeReportFiles writes the report as JSON and/or Markdown next to the given base path
+func writeReportFiles(base string, format string, report interface{}, markdown func() string) error {
+	if format == "none" || format == "" {
+		return nil
+	}
+
+	if format == "json" || format == "all" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return tracerr.Wrap(err)
+		}
+
+		if err := os.WriteFile(base+".json", data, 0644); err != nil {
+			return tracerr.Wrap(err)
+		}
+	}
+
+	if format == "markdown" || format == "all" {
+		if err := os.WriteFile(base+".md", []byte(markdown()), 0644); err != nil {
+			return tracerr.Wrap(err)
+		}
+	}
+
+	return nil
+}
+
+// writeBookReport writes the report of a single book next to its PDF
+func writeBookReport(report *bookReport, format string) error {
+	if report.reportBase == "" {
+		return nil
+	}
+
+	return writeReportFiles(report.reportBase+".report", format, report, report.markdown)
+}
+
+// hasIncomplete reports whether some books of the batch were written without some of their pages
+func (r *batchReport) hasIncomplete() bool {
+	for _, book := range r.Books {
+		if book.incomplete() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// writeBatchReport writes the aggregated report of a batch run into its output folder, along with a retry list of failed and incomplete books
+func writeBatchReport(report *batchReport, format string) (string, error) {
+	base := filepath.Join(report.OutputFolder, fmt.Sprintf("fh5dl-batch-report-%s", report.StartedAt.Format("20060102-150405")))
+	if err := writeReportFiles(base, format, report, report.markdown); err != nil {
+		return "", err
+	}
+
+	if report.Failed > 0 || report.hasIncomplete() {
+		if err := writeRetryFile(report, base+".retry.txt"); err != nil {
+			return "", err
+		}
+	}
+
+	return base, nil
+}
+
+// markdown renders the book report as a Markdown document
+func (r *bookReport) markdown() string {
+	var sb strings.Builder
+
+	title := r.Title
+	if title == "" {
+		title = r.Url
+	}
+
+	fmt.Fprintf(&sb, "# %s\n\n", title)
+	r.writeMarkdownTable(&sb)
+
+	return sb.String()
+}
+
+// writeMarkdownTable renders the fields of the report as a two column table
+func (r *bookReport) writeMarkdownTable(sb *strings.Builder) {
+	fmt.Fprintf(sb, "| | |\n|---|---|\n")
+	fmt.Fprintf(sb, "| URL | %s |\n", r.Url)
+	fmt.Fprintf(sb, "| Status | %s |\n", r.Status)
+	if r.Error != "" {
+		fmt.Fprintf(sb, "| Error | %s |\n", strings.ReplaceAll(r.Error, "\n", " "))
+	}
+	fmt.Fprintf(sb, "| Pages | %d |\n", r.Pages)
+	fmt.Fprintf(sb, "| Images | %d (%d downloaded, %d cached) |\n", r.ImagesTotal, r.ImagesDownloaded, r.ImagesCached)
+	fmt.Fprintf(sb, "| Image size | %s |\n", formatBytes(r.ImageBytes))
+	fmt.Fprintf(sb, "| Downloaded | %s (%s/s, %.1f images/s) |\n", formatBytes(r.DownloadedBytes), formatBytes(int64(r.BytesPerSecond)), r.ImagesPerSecond)
+	fmt.Fprintf(sb, "| Retries | %d |\n", r.Retries)
+	if r.UpdatedImages > 0 {
+		fmt.Fprintf(sb, "| Updated images | %d |\n", r.UpdatedImages)
+	}
+	if r.Interactive {
+		fmt.Fprintf(sb, "| Captured pages | %d |\n", r.CapturedPages)
+	}
+	if len(r.FailedPages) > 0 {
+		fmt.Fprintf(sb, "| Failed pages | %v |\n", r.FailedPages)
+		fmt.Fprintf(sb, "| Diagnostics | %s |\n", r.DiagnosticsFolder)
+	}
+	for _, failed := range r.FailedImages {
+		fmt.Fprintf(sb, "| Failed image | page %d: %s |\n", failed.Page, strings.ReplaceAll(failed.Error, "\n", " "))
+	}
+	if len(r.ChangedPages) > 0 {
+		fmt.Fprintf(sb, "| Changed pages | %v |\n", r.ChangedPages)
+	}
+	if len(r.MissingPages) > 0 {
+		fmt.Fprintf(sb, "| Missing pages | %v |\n", r.MissingPages)
+	}
+	if len(r.ThumbnailPages) > 0 {
+		fmt.Fprintf(sb, "| Pages from thumbnails | %v |\n", r.ThumbnailPages)
+	}
+	if r.DeskewedImages > 0 {
+		fmt.Fprintf(sb, "| Straightened pages | %d |\n", r.DeskewedImages)
+	}
+	if r.EnhancedImages > 0 {
+		fmt.Fprintf(sb, "| Enhanced pages | %d |\n", r.EnhancedImages)
+	}
+	if r.UpscaledImages > 0 {
+		fmt.Fprintf(sb, "| Upscaled pages | %d |\n", r.UpscaledImages)
+	}
+	if len(r.PlaceholderPages) > 0 {
+		fmt.Fprintf(sb, "| Placeholder pages | %v |\n", r.PlaceholderPages)
+	}
+	for _, hookErr := range r.HookErrors {
+		fmt.Fprintf(sb, "| Hook error | %s |\n", strings.ReplaceAll(hookErr, "\n", " "))
+	}
+	if len(r.Volumes) > 0 {
+		fmt.Fprintf(sb, "| PDF | %d volumes, %s |\n", len(r.Volumes), formatBytes(r.PdfBytes))
+		for _, volume := range r.Volumes {
+			fmt.Fprintf(sb, "| | %s |\n", volume)
+		}
+	} else if r.PdfPath != "" {
+		fmt.Fprintf(sb, "| PDF | %s (%s) |\n", r.PdfPath, formatBytes(r.PdfBytes))
+	}
+	for _, path := range r.OriginalPaths {
+		fmt.Fprintf(sb, "| Original | %s |\n", path)
+	}
+	if r.RecordingPath != "" {
+		fmt.Fprintf(sb, "| Recording | %s |\n", r.RecordingPath)
+	}
+	if r.RecordingError != "" {
+		fmt.Fprintf(sb, "| Recording error | %s |\n", strings.ReplaceAll(r.RecordingError, "\n", " "))
+	}
+	for _, captionErr := range r.CaptionErrors {
+		fmt.Fprintf(sb, "| Caption error | %s |\n", strings.ReplaceAll(captionErr, "\n", " "))
+	}
+	if len(r.AudioPaths) > 0 {
+		fmt.Fprintf(sb, "| Narration | %d chapters |\n", len(r.AudioPaths))
+		for _, path := range r.AudioPaths {
+			fmt.Fprintf(sb, "| | %s |\n", path)
+		}
+	}
+	if r.AudioError != "" {
+		fmt.Fprintf(sb, "| Narration error | %s |\n", strings.ReplaceAll(r.AudioError, "\n", " "))
+	}
+	fmt.Fprintf(sb, "| Download time | %s |\n", formatSeconds(r.DownloadSeconds))
+	if r.Interactive {
+		fmt.Fprintf(sb, "| Capture time | %s |\n", formatSeconds(r.CaptureSeconds))
+	}
+	if r.ProcessSeconds > 0 {
+		fmt.Fprintf(sb, "| Page processing time | %s |\n", formatSeconds(r.ProcessSeconds))
+	}
+	fmt.Fprintf(sb, "| PDF time | %s |\n", formatSeconds(r.PdfSeconds))
+	fmt.Fprintf(sb, "| Total time | %s |\n", formatSeconds(r.TotalSeconds))
+	if len(r.SlowestImages) > 0 {
+		slowest := make([]string, len(r.SlowestImages))
+		for i, timing := range r.SlowestImages {
+			slowest[i] = fmt.Sprintf("page %d (%.1fs, %s)", timing.Page, timing.Seconds, formatBytes(timing.Bytes))
+		}
+		fmt.Fprintf(sb, "| Slowest images | %s |\n", strings.Join(slowest, ", "))
+	}
+}
+
+// markdown renders the batch report as a Markdown document
+func (r *batchReport) markdown() string {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "# Batch report %s\n\n", r.StartedAt.Format(time.RFC1123))
+	fmt.Fprintf(&sb, "%d books: %d successful, %d skipped, %d failed in %s\n\n",
+		len(r.Books), r.Successful, r.Skipped, r.Failed, formatSeconds(r.TotalSeconds))
+	fmt.Fprintf(&sb, "Downloaded %s at %s/s\n\n", formatBytes(r.DownloadedBytes), formatBytes(int64(r.BytesPerSecond)))
+
+	for _, entry := range r.Books {
+		title := entry.Title
+		if title == "" {
+			title = entry.Url
+		}
+
+		fmt.Fprintf(&sb, "## %s\n\n", title)
+		entry.writeMarkdownTable(&sb)
+		sb.WriteString("\n")
+	}
+
+	return sb.String()
+}
+
+// formatSeconds formats a number of seconds the same way as formatDuration
+func formatSeconds(seconds float64) string {
+	return formatDuration(time.Duration(seconds * float64(time.Second)))
+}
+
+// formatBytes formats a byte count as a human-readable size
+func formatBytes(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
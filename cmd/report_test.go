@@ -0,0 +1,184 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	book "github.com/ygunayer/fh5dl/book"
+	"github.com/ztrue/tracerr"
+)
+
+func TestAddDownloadedImages(t *testing.T) {
+	images := []book.DownloadedImage{
+		{PageNumber: 1, Attempts: 1, Bytes: 1000, Duration: time.Second},
+		{PageNumber: 2, Attempts: 0}, // cached
+		{PageNumber: 3, Attempts: 2, Bytes: 3000, Duration: 5 * time.Second},
+		{PageNumber: 4, Attempts: 1, Bytes: 2000, Duration: 2 * time.Second},
+	}
+
+	report := newBookReport("abcde/fghij")
+	report.addDownloadedImages(images, 2*time.Second)
+
+	if report.ImagesDownloaded != 3 || report.ImagesCached != 1 || report.Retries != 1 {
+		t.Errorf("unexpected counts: %d downloaded, %d cached, %d retries", report.ImagesDownloaded, report.ImagesCached, report.Retries)
+	}
+
+	if report.DownloadedBytes != 6000 || report.BytesPerSecond != 3000 || report.ImagesPerSecond != 1.5 {
+		t.Errorf("unexpected throughput: %d bytes, %.1f B/s, %.1f images/s", report.DownloadedBytes, report.BytesPerSecond, report.ImagesPerSecond)
+	}
+
+	pages := make([]int, 0)
+	for _, timing := range report.SlowestImages {
+		pages = append(pages, timing.Page)
+	}
+	if len(pages) != 3 || pages[0] != 3 || pages[1] != 4 || pages[2] != 1 {
+		t.Errorf("expected the slowest pages to be [3 4 1], got %v", pages)
+	}
+}
+
+func TestFailedEntriesSkipsPermanentErrors(t *testing.T) {
+	missing := newBookReport("https://online.fliphtml5.com/abcde/missing/")
+	missing.finish(fmt.Errorf("failed to download book information: 404 Not Found: %w", book.ErrBookNotFound))
+
+	limited := newBookReport("https://online.fliphtml5.com/abcde/limited/")
+	limited.finish(tracerr.Wrap(fmt.Errorf("failed to download image: %w", book.ErrRateLimited)))
+
+	broken := newBookReport("https://online.fliphtml5.com/abcde/broken/")
+	broken.finish(errors.New("connection reset by peer"))
+
+	if missing.ErrorKind != errorKindNotFound || limited.ErrorKind != errorKindRateLimited || broken.ErrorKind != "" {
+		t.Fatalf("unexpected error kinds %q, %q, %q", missing.ErrorKind, limited.ErrorKind, broken.ErrorKind)
+	}
+
+	report := &batchReport{Books: []*bookReport{missing, limited, broken}}
+	entries := report.failedEntries()
+	if len(entries) != 2 || entries[0].Url != limited.Url || entries[1].Url != broken.Url {
+		t.Errorf("expected only the retryable books, got %+v", entries)
+	}
+}
+
+func TestFailedEntriesRetriesIncompleteBooks(t *testing.T) {
+	complete := newBookReport("https://online.fliphtml5.com/abcde/complete/")
+	complete.finish(nil)
+
+	failedImages := newBookReport("https://online.fliphtml5.com/abcde/images/")
+	failedImages.FailedImages = []failedImage{{Page: 3}}
+	failedImages.finish(nil)
+
+	missingPages := newBookReport("https://online.fliphtml5.com/abcde/missing/")
+	missingPages.MissingPages = []int{4, 5}
+	missingPages.finish(nil)
+
+	report := &batchReport{OutputFolder: t.TempDir(), StartedAt: time.Now()}
+	for _, book := range []*bookReport{complete, failedImages, missingPages} {
+		report.add(book)
+	}
+
+	entries := report.failedEntries()
+	if len(entries) != 2 || entries[0].Url != failedImages.Url || entries[1].Url != missingPages.Url {
+		t.Fatalf("expected the incomplete books, got %+v", entries)
+	}
+	if !entries[0].Replace || !entries[1].Replace {
+		t.Errorf("expected the output of incomplete books to be replaced, got %+v", entries)
+	}
+
+	args := Args{Update: true}
+	args.applyEntry(entries[0])
+	if args.conflictPolicy() != conflictOverwrite || args.Update {
+		t.Errorf("expected an incomplete book to be written again, got %+v", args)
+	}
+
+	base, err := writeBatchReport(report, "none")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	retries, err := readUrlListFile(base + ".retry.txt")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(retries) != 2 || retries[0].Url != failedImages.Url || retries[1].Url != missingPages.Url {
+		t.Errorf("expected the retry list to have the incomplete books, got %+v", retries)
+	}
+}
+
+func TestReadRetryEntriesKeepsOptions(t *testing.T) {
+	failed := newBookReport("https://online.fliphtml5.com/abcde/fghij/")
+	failed.Entry = &batchEntry{Series: "Catalogs", Volume: 2, Filename: "Spring"}
+	failed.finish(errors.New("connection reset by peer"))
+
+	retries := 0
+	options := Args{Format: outputCbz, Layout: "komga", Pages: "1-10", ImageFormat: "png", Retries: &retries, CaptionKey: "secret"}
+	report := &batchReport{OutputFolder: t.TempDir(), StartedAt: time.Now(), Options: &options}
+	report.add(failed)
+	base, err := writeBatchReport(report, "json")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	entries, retryBase, err := readRetryEntries(base + ".json")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Url != failed.Url {
+		t.Fatalf("expected the failed book, got %+v", entries)
+	}
+	if retryBase.OutputFolder != report.OutputFolder || retryBase.CaptionKey != "" {
+		t.Errorf("expected the output folder of the report and no caption key, got %q and %q", retryBase.OutputFolder, retryBase.CaptionKey)
+	}
+
+	// the book is downloaded again as it was in the original run
+	args := retryBase
+	args.applyEntry(entries[0])
+	if args.outputFormat() != outputCbz || args.Layout != "komga" || args.Pages != "1-10" || args.ImageFormat != "png" {
+		t.Errorf("expected the options of the original run, got %+v", args)
+	}
+	if args.Retries == nil || *args.Retries != 0 {
+		t.Errorf("expected the number of retries of the original run, got %v", args.Retries)
+	}
+	if args.Series != "Catalogs" || args.Volume != 2 || args.Filename != "Spring" {
+		t.Errorf("expected the options of the manifest entry, got %q, %d and %q", args.Series, args.Volume, args.Filename)
+	}
+}
+
+func TestRetryChecksOptions(t *testing.T) {
+	failed := newBookReport("https://online.fliphtml5.com/abcde/fghij/")
+	failed.finish(errors.New("connection reset by peer"))
+
+	// a retry list gives no options, so the books are retried with the defaults
+	report := &batchReport{OutputFolder: t.TempDir(), StartedAt: time.Now()}
+	report.add(failed)
+	base, err := writeBatchReport(report, "none")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, retryBase, err := readRetryEntries(base + ".retry.txt")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := prepareArgs(&retryBase); err != nil {
+		t.Errorf("expected the default options to be valid, got %v", err)
+	}
+	if retryBase.conflictPolicy() != conflictSkip || retryBase.ImageFormat != imageFormatOriginal {
+		t.Errorf("expected the default options, got %+v", retryBase)
+	}
+
+	// options of a report that was edited by hand are checked like the ones on the command line
+	options, err := defaultArgs()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	options.Format = "epub"
+	report = &batchReport{OutputFolder: t.TempDir(), StartedAt: time.Now(), Options: &options}
+	report.add(failed)
+	base, err = writeBatchReport(report, "json")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err = retryCommand([]string{base + ".json", "--report", "none", "--progress", "plain"})
+	if err == nil || !strings.Contains(err.Error(), `invalid output format "epub"`) {
+		t.Errorf("expected the options of the report to be checked, got %v", err)
+	}
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"time"
+
+	"github.com/ygunayer/fh5dl/book"
+)
+
+// downloadOptions returns the request timeout, retry policy and revalidation of image downloads, leaving unset ones to
+// the defaults
+func (args *Args) downloadOptions() book.DownloadOptions {
+	options := book.DownloadOptions{
+		Timeout:    time.Duration(args.HttpTimeout * float64(time.Second)),
+		Backoff:    time.Duration(args.RetryBackoff * float64(time.Second)),
+		Revalidate: args.Revalidate,
+	}
+	if args.Retries != nil {
+		options.Attempts = *args.Retries + 1
+	}
+
+	return options
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/draw"
+	"strconv"
+	"strings"
+
+	book "github.com/ygunayer/fh5dl/book"
+)
+
+// pageRotation turns the selected pages clockwise by a multiple of 90 degrees
+type pageRotation struct {
+	Pages   book.PageSet
+	Degrees int
+}
+
+// parseRotations parses --rotate options such as "10-20:90", the pages as with --pages and the clockwise angle.
+// When the pages of options overlap, the last one wins.
+func parseRotations(options []string) ([]pageRotation, error) {
+	rotations := make([]pageRotation, 0, len(options))
+	for _, option := range options {
+		spec, angle, ok := strings.Cut(option, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid rotation %q, expected pages and an angle such as 10-20:90", option)
+		}
+
+		pages, err := book.ParsePageSet(spec)
+		if err != nil {
+			return nil, err
+		}
+
+		degrees, err := strconv.Atoi(strings.TrimSpace(angle))
+		if err != nil || degrees%90 != 0 {
+			return nil, fmt.Errorf("invalid rotation angle %q in %q, expected 90, 180 or 270", angle, option)
+		}
+
+		rotations = append(rotations, pageRotation{Pages: pages, Degrees: (degrees%360 + 360) % 360})
+	}
+
+	return rotations, nil
+}
+
+// rotationOf returns how many degrees clockwise a page is turned
+func rotationOf(rotations []pageRotation, pageNumber int) int {
+	degrees := 0
+	for _, rotation := range rotations {
+		if pageNumber > 0 && rotation.Pages.Contains(pageNumber) {
+			degrees = rotation.Degrees
+		}
+	}
+
+	return degrees
+}
+
+// rotateClockwise turns an image clockwise by 90, 180 or 270 degrees
+func rotateClockwise(img image.Image, degrees int) image.Image {
+	bounds := img.Bounds()
+	src := image.NewRGBA(image.Rectangle{Max: bounds.Size()})
+	draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Src)
+
+	width, height := bounds.Dx(), bounds.Dy()
+	size := image.Pt(height, width)
+	if degrees == 180 {
+		size = image.Pt(width, height)
+	}
+
+	rotated := image.NewRGBA(image.Rectangle{Max: size})
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			var to image.Point
+			switch degrees {
+			case 90:
+				to = image.Pt(height-1-y, x)
+			case 180:
+				to = image.Pt(width-1-x, height-1-y)
+			case 270:
+				to = image.Pt(y, width-1-x)
+			default:
+				return img
+			}
+
+			from := src.PixOffset(x, y)
+			copy(rotated.Pix[rotated.PixOffset(to.X, to.Y):], src.Pix[from:from+4])
+		}
+	}
+
+	return rotated
+}
+
+// rotatePages turns the pages selected with --rotate, returning the image files with the turned pages in place of
+// the originals
+func rotatePages(ctx context.Context, rotations []pageRotation, imageFiles []string, pageNumbers map[string]int) ([]string, error) {
+	indexes := make([]int, 0)
+	selected := make([]string, 0)
+	for i, imageFile := range imageFiles {
+		if rotationOf(rotations, pageNumbers[imageFile]) != 0 {
+			indexes = append(indexes, i)
+			selected = append(selected, imageFile)
+		}
+	}
+	if len(selected) == 0 {
+		return imageFiles, nil
+	}
+
+	rotated, _, err := transformPages(ctx, selected, pageNumbers, "rotated", func(img image.Image, pageNumber int) image.Image {
+		return rotateClockwise(img, rotationOf(rotations, pageNumber))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]string, len(imageFiles))
+	copy(result, imageFiles)
+	for j, i := range indexes {
+		result[i] = rotated[j]
+	}
+
+	return result, nil
+}
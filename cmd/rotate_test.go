@@ -0,0 +1,43 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestParseRotations(t *testing.T) {
+	rotations, err := parseRotations([]string{"10-20,25:90", "15:-90"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for pageNumber, expected := range map[int]int{9: 0, 10: 90, 15: 270, 25: 90, 0: 0} {
+		if actual := rotationOf(rotations, pageNumber); actual != expected {
+			t.Errorf("expected page %d to be turned %d degrees, got %d", pageNumber, expected, actual)
+		}
+	}
+
+	for _, option := range []string{"10-20", "10:45", "x:90"} {
+		if _, err := parseRotations([]string{option}); err == nil {
+			t.Errorf("expected an error for %q", option)
+		}
+	}
+}
+
+func TestRotateClockwise(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	img.Set(0, 0, color.White)
+
+	cases := map[int]image.Point{90: {1, 0}, 180: {2, 1}, 270: {0, 2}}
+	for degrees, corner := range cases {
+		rotated := rotateClockwise(img, degrees)
+		if r, _, _, _ := rotated.At(corner.X, corner.Y).RGBA(); r != 0xffff {
+			t.Errorf("expected the top left pixel at %v after turning %d degrees", corner, degrees)
+		}
+	}
+
+	if size := rotateClockwise(img, 90).Bounds().Size(); size != image.Pt(2, 3) {
+		t.Errorf("expected a 2x3 image, got %v", size)
+	}
+}
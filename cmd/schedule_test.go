@@ -0,0 +1,51 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleNext(t *testing.T) {
+	// a Wednesday
+	from := time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC)
+
+	cases := []struct {
+		spec     string
+		expected time.Time
+	}{
+		{"*/15 * * * *", time.Date(2024, 5, 1, 10, 45, 0, 0, time.UTC)},
+		{"0 6 * * *", time.Date(2024, 5, 2, 6, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2024, 5, 1, 11, 0, 0, 0, time.UTC)},
+		{"0 9 * * 1-5", time.Date(2024, 5, 2, 9, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2024, 5, 5, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 6 *", time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 15 * 1", time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)},
+		{"@every 6h", time.Date(2024, 5, 1, 16, 30, 0, 0, time.UTC)},
+	}
+
+	for _, c := range cases {
+		s, err := parseSchedule(c.spec)
+		if err != nil {
+			t.Fatalf("unexpected error for %q: %v", c.spec, err)
+		}
+		if next := s.next(from); !next.Equal(c.expected) {
+			t.Errorf("expected %q to run next at %v, got %v", c.spec, c.expected, next)
+		}
+	}
+}
+
+func TestParseScheduleErrors(t *testing.T) {
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "5-1 * * * *", "*/0 * * * *", "@every 10s", "@yearly"} {
+		if _, err := parseSchedule(spec); err == nil {
+			t.Errorf("expected an error for %q", spec)
+		}
+	}
+
+	s, err := parseSchedule("0 0 30 2 *")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !s.next(time.Now()).IsZero() {
+		t.Errorf("expected February 30th to never come")
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	_ "image/png"
+	"os"
+	"path/filepath"
+
+	"github.com/ztrue/tracerr"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// maxStripHeight is the default height limit of a strip, as JPEG images can't be taller than 65535 pixels
+const maxStripHeight = 65500
+
+// stripQuality is the JPEG quality of strip images
+const stripQuality = 90
+
+// stripPage is a page image scaled to the width of the strip
+type stripPage struct {
+	path   string
+	height int // height after scaling
+}
+
+// generateStrip stacks the pages vertically into tall images for scroll-style reading. Every page is scaled
+// to the width of the widest page, and a new image (001.jpg, 002.jpg, ...) is started whenever the next page
+// would make the current one taller than stripHeight. Pages are never split between images.
+func generateStrip(imageFiles []string, stripDir string, stripHeight int) error {
+	if stripHeight <= 0 || stripHeight > maxStripHeight {
+		stripHeight = maxStripHeight
+	}
+
+	// only read the sizes first so that a single strip is decoded at a time
+	sizes := make([]image.Point, len(imageFiles))
+	width := 0
+	for i, imageFile := range imageFiles {
+		size, err := imageSize(imageFile)
+		if err != nil {
+			return err
+		}
+
+		sizes[i] = size
+		width = max(width, size.X)
+	}
+
+	strips := make([][]stripPage, 0)
+	current := make([]stripPage, 0)
+	currentHeight := 0
+	for i, imageFile := range imageFiles {
+		height := sizes[i].Y * width / sizes[i].X
+		if len(current) > 0 && currentHeight+height > stripHeight {
+			strips = append(strips, current)
+			current = make([]stripPage, 0)
+			currentHeight = 0
+		}
+
+		current = append(current, stripPage{path: imageFile, height: height})
+		currentHeight += height
+	}
+	if len(current) > 0 {
+		strips = append(strips, current)
+	}
+
+	// start from an empty folder so no strips of an earlier, longer run are left behind
+	if err := os.RemoveAll(stripDir); err != nil {
+		return tracerr.Wrap(err)
+	}
+	if err := os.MkdirAll(stripDir, os.ModePerm); err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	for i, pages := range strips {
+		if err := writeStrip(pages, width, filepath.Join(stripDir, fmt.Sprintf("%03d.jpg", i+1))); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeStrip draws the pages below each other and saves the result as a JPEG
+func writeStrip(pages []stripPage, width int, stripPath string) error {
+	height := 0
+	for _, page := range pages {
+		height += page.height
+	}
+
+	canvas := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(canvas, canvas.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+
+	y := 0
+	for _, page := range pages {
+		img, err := decodeImage(page.path)
+		if err != nil {
+			return err
+		}
+
+		draw.ApproxBiLinear.Scale(canvas, image.Rect(0, y, width, y+page.height), img, img.Bounds(), draw.Over, nil)
+		y += page.height
+	}
+
+	output, err := os.Create(stripPath)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+	defer output.Close()
+
+	if err := jpeg.Encode(output, canvas, &jpeg.Options{Quality: stripQuality}); err != nil {
+		return fmt.Errorf("failed to encode %s: %w", stripPath, err)
+	}
+
+	return tracerr.Wrap(output.Close())
+}
+
+// imageSize reads the dimensions of an image without decoding it
+func imageSize(imagePath string) (image.Point, error) {
+	file, err := os.Open(imagePath)
+	if err != nil {
+		return image.Point{}, tracerr.Wrap(err)
+	}
+	defer file.Close()
+
+	config, _, err := image.DecodeConfig(file)
+	if err != nil {
+		return image.Point{}, fmt.Errorf("failed to read %s: %w", imagePath, err)
+	}
+
+	return image.Point{X: config.Width, Y: config.Height}, nil
+}
+
+// decodeImage reads an image in any of the registered formats
+func decodeImage(imagePath string) (image.Image, error) {
+	file, err := os.Open(imagePath)
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+	defer file.Close()
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", imagePath, err)
+	}
+
+	return img, nil
+}
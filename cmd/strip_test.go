@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGenerateStrip(t *testing.T) {
+	dir := t.TempDir()
+
+	// the narrow page is scaled up to 200x200
+	sizes := []image.Point{{200, 100}, {100, 100}, {200, 150}}
+	files := make([]string, 0)
+	for i, size := range sizes {
+		path := filepath.Join(dir, fmt.Sprintf("%d.png", i))
+		file, err := os.Create(path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		png.Encode(file, image.NewRGBA(image.Rect(0, 0, size.X, size.Y)))
+		file.Close()
+
+		files = append(files, path)
+	}
+
+	stripDir := filepath.Join(dir, "Catalog.strip")
+	if err := generateStrip(files, stripDir, 300); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string]image.Point{"001.jpg": {200, 300}, "002.jpg": {200, 150}}
+	entries, _ := os.ReadDir(stripDir)
+	if len(entries) != len(expected) {
+		t.Fatalf("expected %d strips, got %d", len(expected), len(entries))
+	}
+
+	for name, size := range expected {
+		got, err := imageSize(filepath.Join(stripDir, name))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got != size {
+			t.Errorf("expected %s to be %v, got %v", name, size, got)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	book "github.com/ygunayer/fh5dl/book"
+	"github.com/ygunayer/fh5dl/internal/progress"
+	"github.com/ztrue/tracerr"
+)
+
+// supportLogLines is how many of the last log lines a support bundle keeps
+const supportLogLines = 1000
+
+// supportConfigs is how many books of a batch a support bundle snapshots the book information of
+const supportConfigs = 5
+
+// supportRecorder keeps what a support bundle needs to know about the downloads of this run
+type supportRecorder struct {
+	mu     sync.Mutex
+	books  []string
+	logs   []string
+	bundle string // the --support-bundle path, written without asking
+}
+
+var support = &supportRecorder{}
+
+func (s *supportRecorder) recordBook(url string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.books = append(s.books, url)
+}
+
+func (s *supportRecorder) recordLog(level progress.Level, message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.logs = append(s.logs, fmt.Sprintf("%s [%s] %s", time.Now().Format(time.RFC3339), level, message))
+	if len(s.logs) > supportLogLines {
+		s.logs = s.logs[len(s.logs)-supportLogLines:]
+	}
+}
+
+// snapshot returns copies of the recorded books and log lines
+func (s *supportRecorder) snapshot() ([]string, []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.books...), append([]string(nil), s.logs...)
+}
+
+// supportReporter passes everything on to the reporter, also keeping the log lines for a support bundle
+type supportReporter struct {
+	progress.Reporter
+}
+
+func (r supportReporter) Logf(level progress.Level, format string, args ...interface{}) {
+	support.recordLog(level, fmt.Sprintf(format, args...))
+	r.Reporter.Logf(level, format, args...)
+}
+
+var (
+	// credentials in URLs, such as the ones of a proxy
+	urlCredentialsRegex = regexp.MustCompile(`(://)[^/\s:@]+(:[^/\s@]*)?@`)
+	// query parameters that carry secrets, such as signed image URLs
+	secretParamRegex = regexp.MustCompile(`(?i)([?&](?:token|key|secret|password|passwd|auth|sig|signature|access_token)=)[^&\s"']+`)
+	// API keys given on the command line
+	secretFlagRegex = regexp.MustCompile(`(--caption-key[= ])\S+`)
+	// secrets in the book information, such as the password of protected books
+	secretFieldRegex = regexp.MustCompile(`(?i)(["']?(?:password|pwd|token|secret)["']?\s*:\s*)("[^"]*"|'[^']*')`)
+)
+
+// sanitize removes what shouldn't end up in a bug report: the home folder, which usually has the user name in it,
+// credentials and secrets
+func sanitize(text string) string {
+	if home, err := os.UserHomeDir(); err == nil && len(home) > 1 {
+		text = strings.ReplaceAll(text, home, "~")
+	}
+
+	text = urlCredentialsRegex.ReplaceAllString(text, "$1***@")
+	text = secretParamRegex.ReplaceAllString(text, "$1***")
+	text = secretFlagRegex.ReplaceAllString(text, "$1***")
+	return secretFieldRegex.ReplaceAllString(text, `$1"***"`)
+}
+
+// buildVersions lists the version of fh5dl and of the modules it was built with
+func buildVersions() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown, built without module information\n"
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s %s\n", info.Main.Path, info.Main.Version)
+	for _, setting := range info.Settings {
+		if strings.HasPrefix(setting.Key, "vcs.") {
+			fmt.Fprintf(&sb, "%s=%s\n", setting.Key, setting.Value)
+		}
+	}
+	sb.WriteString("\n")
+	for _, dep := range info.Deps {
+		fmt.Fprintf(&sb, "%s %s\n", dep.Path, dep.Version)
+	}
+
+	return sb.String()
+}
+
+// environmentInfo describes the system fh5dl runs on, with the quick doctor checks
+func environmentInfo() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Go: %s\n", runtime.Version())
+	fmt.Fprintf(&sb, "OS: %s/%s\n", runtime.GOOS, runtime.GOARCH)
+	fmt.Fprintf(&sb, "CPUs: %d\n", runtime.NumCPU())
+	fmt.Fprintf(&sb, "Command: %s\n", strings.Join(os.Args, " "))
+	for _, name := range []string{"HTTPS_PROXY", "HTTP_PROXY", "NO_PROXY", "NO_COLOR", "TERM"} {
+		if value := os.Getenv(name); value != "" {
+			fmt.Fprintf(&sb, "%s: %s\n", name, value)
+		}
+	}
+
+	// the checks that don't start Chrome or go to the network, which may be what failed
+	sb.WriteString("\n")
+	checks := []doctorCheck{
+		{"Shared memory", func(ctx context.Context) checkResult { return checkSharedMemory(book.SharedMemorySize()) }},
+		{"Display", func(ctx context.Context) checkResult { return checkDisplay() }},
+		{"ffmpeg", func(ctx context.Context) checkResult { return checkFfmpegAvailable() }},
+	}
+	labels := map[checkStatus]string{checkOk: "OK", checkWarn: "WARN", checkFail: "FAIL"}
+	for _, check := range checks {
+		result := check.Run(context.Background())
+		fmt.Fprintf(&sb, "[%s] %s: %s\n", labels[result.Status], check.Name, result.Detail)
+	}
+
+	return sb.String()
+}
+
+// writeSupportBundle writes a zip file to attach to bug reports, with the error, the environment, the versions,
+// the log lines and the book information of the FlipHTML5 books as they are served. Everything is sanitized,
+// and no pages are included.
+func writeSupportBundle(ctx context.Context, path string, failure error) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+	defer file.Close()
+
+	archive := zip.NewWriter(file)
+	add := func(name string, content string) error {
+		w, err := archive.Create(name)
+		if err != nil {
+			return tracerr.Wrap(err)
+		}
+		_, err = io.WriteString(w, sanitize(content))
+		return tracerr.Wrap(err)
+	}
+
+	books, logs := support.snapshot()
+
+	errorText := fmt.Sprintf("Error: %v\n", failure)
+	if known, ok := classifyError(failure); ok {
+		errorText += fmt.Sprintf("Kind: %s\nHint: %s\n", known.kind, known.hint)
+	}
+	errorText += "\n" + tracerr.Sprint(failure) + "\n"
+
+	files := []struct{ name, content string }{
+		{"error.txt", errorText},
+		{"environment.txt", environmentInfo()},
+		{"versions.txt", buildVersions()},
+		{"books.txt", strings.Join(books, "\n") + "\n"},
+		{"log.txt", strings.Join(logs, "\n") + "\n"},
+	}
+	for _, f := range files {
+		if err := add(f.name, f.content); err != nil {
+			return err
+		}
+	}
+
+	snapshots := 0
+	for i, url := range books {
+		if snapshots >= supportConfigs {
+			break
+		}
+		if p, err := resolveProvider("", url); err != nil || p.Name() != "fliphtml5" {
+			continue
+		}
+		snapshots++
+
+		// a book that can't be downloaded still tells something about the problem
+		source, body, err := book.ConfigSnapshot(ctx, url)
+		content := fmt.Sprintf("// %s\n%s", source, body)
+		if err != nil {
+			content = fmt.Sprintf("// %v\n", err)
+		}
+		if err := add(fmt.Sprintf("config/%d.js", i+1), content); err != nil {
+			return err
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return tracerr.Wrap(err)
+	}
+	return tracerr.Wrap(file.Close())
+}
+
+// offerSupportBundle writes a support bundle after a failed download, to --support-bundle or, when asked in a
+// terminal, into the current folder. Failures before any book was downloaded, such as invalid flags, get no bundle.
+func offerSupportBundle(failure error) {
+	books, _ := support.snapshot()
+	if len(books) == 0 {
+		return
+	}
+
+	path := support.bundle
+	if path == "" {
+		if stdinIsPiped() || !stdoutIsTerminal() {
+			fmt.Fprintln(os.Stderr, "Run again with --support-bundle fh5dl-support.zip to write a support bundle for a bug report.")
+			return
+		}
+
+		fmt.Print("Write a support bundle to attach to a bug report? [y/N] ")
+		line, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if answer := strings.ToLower(strings.TrimSpace(line)); answer != "y" && answer != "yes" {
+			return
+		}
+		path = fmt.Sprintf("fh5dl-support-%s.zip", time.Now().Format("20060102-150405"))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if err := writeSupportBundle(ctx, path, failure); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing support bundle: %v\n", err)
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "Support bundle written to %s. It has the book URLs, logs and versions, but no pages; please look through it before attaching it to a bug report.\n", path)
+}
@@ -0,0 +1,380 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ztrue/tracerr"
+)
+
+// newBooksFile is the file of the books folder that books added in the editor are written to
+const newBooksFile = "books.txt"
+
+// resolveTimeout limits how long the editor waits for the title of a book
+const resolveTimeout = 30 * time.Second
+
+// bookLine is a book of the books folder, with where it came from so edits can be written back
+type bookLine struct {
+	File        string // file name in the books folder
+	Line        int    // line number in the file, 0 for books added in the editor
+	Url         string
+	Interactive bool
+	Manifest    bool // books of YAML manifests are shown, but edited by hand
+	Removed     bool
+	changed     bool
+
+	// the book as resolved for the preview
+	Title     string
+	Pages     int
+	Err       error
+	Resolving bool
+}
+
+// booksEditor is the screen for looking through and changing the books of the books folder
+type booksEditor struct {
+	dir     string
+	books   []bookLine
+	cursor  int
+	adding  bool
+	input   textinput.Model
+	message string
+	dirty   bool
+	leaving bool // esc was pressed with unsaved changes
+}
+
+// bookResolvedMsg is the preview of a book of the editor
+type bookResolvedMsg struct {
+	index int
+	title string
+	pages int
+	err   error
+}
+
+// booksEditorDoneMsg tells the main menu that the editor was closed, and whether to start the batch
+type booksEditorDoneMsg struct {
+	startBatch bool
+}
+
+// loadBookLines reads the books of the books folder in the order the batch would download them
+func loadBookLines(dir string) ([]bookLine, error) {
+	files, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return []bookLine{}, nil
+	}
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+
+	books := make([]bookLine, 0)
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+
+		if isManifest(file.Name()) {
+			entries, err := readUrlListFile(filepath.Join(dir, file.Name()))
+			if err != nil {
+				return nil, err
+			}
+			for _, entry := range entries {
+				books = append(books, bookLine{File: file.Name(), Url: entry.Url, Interactive: entry.Interactive, Manifest: true})
+			}
+			continue
+		}
+		if !strings.HasSuffix(file.Name(), ".txt") {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, file.Name()))
+		if err != nil {
+			return nil, tracerr.Wrap(err)
+		}
+		for i, line := range strings.Split(string(data), "\n") {
+			if entry, ok := parseBatchLine(line); ok {
+				books = append(books, bookLine{File: file.Name(), Line: i + 1, Url: entry.Url, Interactive: entry.Interactive})
+			}
+		}
+	}
+
+	return books, nil
+}
+
+// formatBatchLine is the line of a url list for the book, the reverse of parseBatchLine
+func formatBatchLine(url string, interactive bool) string {
+	if interactive {
+		return url + " -i"
+	}
+
+	return url
+}
+
+// saveBookLines writes the changes to the books back into their files. Lines of removed books are dropped, the
+// lines of changed ones are rewritten and comments are left alone. Added books are appended to books.txt.
+func saveBookLines(dir string, books []bookLine) error {
+	edits := make(map[string]map[int]*bookLine)
+	added := make([]string, 0)
+	for i := range books {
+		b := &books[i]
+		switch {
+		case b.Manifest:
+			continue
+		case b.Line == 0:
+			if !b.Removed {
+				added = append(added, formatBatchLine(b.Url, b.Interactive))
+			}
+		case b.Removed || b.changed:
+			if edits[b.File] == nil {
+				edits[b.File] = make(map[int]*bookLine)
+			}
+			edits[b.File][b.Line] = b
+		}
+	}
+
+	for file, lines := range edits {
+		path := filepath.Join(dir, file)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return tracerr.Wrap(err)
+		}
+
+		kept := make([]string, 0)
+		for i, line := range strings.Split(string(data), "\n") {
+			b, ok := lines[i+1]
+			switch {
+			case !ok:
+				kept = append(kept, line)
+			case !b.Removed:
+				kept = append(kept, formatBatchLine(b.Url, b.Interactive))
+			}
+		}
+
+		if err := os.WriteFile(path, []byte(strings.Join(kept, "\n")), 0644); err != nil {
+			return tracerr.Wrap(err)
+		}
+	}
+
+	if len(added) == 0 {
+		return nil
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	path := filepath.Join(dir, newBooksFile)
+	existing, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return tracerr.Wrap(err)
+	}
+	content := string(existing)
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	content += strings.Join(added, "\n") + "\n"
+
+	return tracerr.Wrap(os.WriteFile(path, []byte(content), 0644))
+}
+
+// newBooksEditor opens the editor on the books folder
+func newBooksEditor(dir string) booksEditor {
+	editor := booksEditor{dir: dir}
+
+	books, err := loadBookLines(dir)
+	if err != nil {
+		editor.message = fmt.Sprintf("Failed to read %s: %v", dir, err)
+		books = []bookLine{}
+	}
+	editor.books = books
+
+	return editor
+}
+
+// resolveBookCmd resolves a book of the editor in the background
+func resolveBookCmd(index int, url string) tea.Cmd {
+	return func() tea.Msg {
+		title, pages, err := previewBook(url)
+		return bookResolvedMsg{index: index, title: title, pages: pages, err: err}
+	}
+}
+
+// resolveNext resolves the next book without a preview. Books are resolved one at a time so a long list doesn't
+// send a burst of requests to the site.
+func (e *booksEditor) resolveNext() tea.Cmd {
+	for i := range e.books {
+		b := &e.books[i]
+		if b.Removed || b.Resolving || b.Title != "" || b.Err != nil {
+			continue
+		}
+
+		b.Resolving = true
+		return resolveBookCmd(i, b.Url)
+	}
+
+	return nil
+}
+
+func (e booksEditor) Update(msg tea.Msg) (booksEditor, tea.Cmd) {
+	switch msg := msg.(type) {
+	case bookResolvedMsg:
+		if msg.index < len(e.books) {
+			b := &e.books[msg.index]
+			b.Resolving = false
+			b.Title, b.Pages, b.Err = msg.title, msg.pages, msg.err
+		}
+		return e, e.resolveNext()
+	case pasteMsg:
+		if e.adding && msg.err == nil {
+			insertText(&e.input, pastedUrl(msg.text))
+		}
+	case tea.KeyMsg:
+		if e.adding {
+			return e.updateAdding(msg)
+		}
+		return e.updateList(msg)
+	}
+
+	return e, nil
+}
+
+// updateAdding handles the keys of the url input
+func (e booksEditor) updateAdding(msg tea.KeyMsg) (booksEditor, tea.Cmd) {
+	switch {
+	case msg.String() == "enter":
+		if entry, ok := parseBatchLine(e.input.Value()); ok {
+			e.books = append(e.books, bookLine{File: newBooksFile, Url: entry.Url, Interactive: entry.Interactive})
+			e.cursor = len(e.books) - 1
+			e.dirty = true
+		}
+		e.adding = false
+	case msg.String() == "esc":
+		e.adding = false
+	case msg.Paste:
+		insertText(&e.input, pastedUrl(string(msg.Runes)))
+	case msg.Type == tea.KeyCtrlV:
+		return e, pasteCmd
+	default:
+		var cmd tea.Cmd
+		e.input, cmd = e.input.Update(msg)
+		return e, cmd
+	}
+
+	return e, nil
+}
+
+// updateList handles the keys of the list of books
+func (e booksEditor) updateList(msg tea.KeyMsg) (booksEditor, tea.Cmd) {
+	key := msg.String()
+	if key != "esc" {
+		e.leaving = false
+	}
+	e.message = ""
+
+	switch key {
+	case "up", "k":
+		if e.cursor > 0 {
+			e.cursor--
+		}
+	case "down", "j":
+		if e.cursor < len(e.books)-1 {
+			e.cursor++
+		}
+	case "a":
+		e.adding = true
+		e.input = newTextInput("")
+	case " ", "i", "d", "delete":
+		if len(e.books) == 0 {
+			break
+		}
+		b := &e.books[e.cursor]
+		if b.Manifest {
+			e.message = fmt.Sprintf("Books of %s are changed by editing the file", b.File)
+			break
+		}
+		if key == "d" || key == "delete" {
+			b.Removed = !b.Removed
+		} else {
+			b.Interactive = !b.Interactive
+			b.changed = true
+		}
+		e.dirty = true
+	case "r":
+		return e, e.resolveNext()
+	case "s", "b":
+		if err := saveBookLines(e.dir, e.books); err != nil {
+			e.message = fmt.Sprintf("Failed to save: %v", err)
+			break
+		}
+
+		reloaded := newBooksEditor(e.dir)
+		reloaded.message = "Saved"
+		if key == "b" {
+			return reloaded, func() tea.Msg { return booksEditorDoneMsg{startBatch: true} }
+		}
+		return reloaded, nil
+	case "esc", "q":
+		if e.dirty && !e.leaving {
+			e.leaving = true
+			e.message = "There are unsaved changes, press s to save them or esc again to discard them"
+			break
+		}
+		return e, func() tea.Msg { return booksEditorDoneMsg{} }
+	}
+
+	return e, nil
+}
+
+func (e booksEditor) View() string {
+	s := titleStyle.Render("FlipHTML5 Downloader - Books") + "\n\n"
+	s += fmt.Sprintf("Books in %s:\n\n", e.dir)
+
+	if len(e.books) == 0 {
+		s += infoStyle.Render("No books yet, press a to add one") + "\n"
+	}
+	for i, b := range e.books {
+		cursor := " "
+		if e.cursor == i {
+			cursor = ">"
+		}
+
+		mode := "   "
+		if b.Interactive {
+			mode = "[i]"
+		}
+
+		line := fmt.Sprintf("%s %s", mode, b.Url)
+		switch {
+		case b.Removed:
+			line += " (removed)"
+		case b.Resolving:
+			line += infoStyle.Render(" resolving...")
+		case b.Err != nil:
+			line += " " + errorStyle.Render(b.Err.Error())
+		case b.Title != "":
+			line += " " + settingValueStyle.Render(fmt.Sprintf("%s, %d pages", b.Title, b.Pages))
+		}
+		line += infoStyle.Render(" " + b.File)
+
+		if e.cursor == i {
+			line = selectedStyle.Render(line)
+		}
+		s += fmt.Sprintf("%s %s\n", cursor, line)
+	}
+
+	if e.adding {
+		s += "\nEnter the URL (or ID) of the book, with -i at the end for interactive mode:\n"
+		s += e.input.View() + "\n"
+		s += "\n" + infoStyle.Render("Press Enter to add, Ctrl+V to paste, Esc to cancel")
+		return s
+	}
+
+	if e.message != "" {
+		s += "\n" + e.message + "\n"
+	}
+	s += "\n" + infoStyle.Render("a: add, d: remove, i/space: toggle interactive, r: preview titles, s: save, b: save and download, esc: back")
+	return s
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestSaveBookLines(t *testing.T) {
+	dir := t.TempDir()
+	list := "# course books\nabcde/fghij\nabcde/klmno -i\n\nvwxyz/pqrst\n"
+	if err := os.WriteFile(filepath.Join(dir, "course.txt"), []byte(list), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "series.yaml"), []byte("books:\n  - abcde/series\n"), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	books, err := loadBookLines(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(books) != 4 || !books[1].Interactive || books[2].Line != 5 || !books[3].Manifest {
+		t.Fatalf("unexpected books %+v", books)
+	}
+
+	// remove the first book, make the second one non-interactive and add one
+	editor := booksEditor{dir: dir, books: books}
+	for _, key := range []tea.KeyMsg{
+		{Type: tea.KeyRunes, Runes: []rune("d")},
+		{Type: tea.KeyDown},
+		{Type: tea.KeyRunes, Runes: []rune("i")},
+		{Type: tea.KeyRunes, Runes: []rune("a")},
+		{Type: tea.KeyRunes, Runes: []rune("new/book -i")},
+		{Type: tea.KeyEnter},
+		{Type: tea.KeyRunes, Runes: []rune("s")},
+	} {
+		editor, _ = editor.Update(key)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "course.txt"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(data) != "# course books\nabcde/klmno\n\nvwxyz/pqrst\n" {
+		t.Errorf("unexpected course.txt %q", data)
+	}
+
+	data, err = os.ReadFile(filepath.Join(dir, newBooksFile))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(data) != "new/book -i\n" {
+		t.Errorf("unexpected %s %q", newBooksFile, data)
+	}
+
+	if len(editor.books) != 4 || editor.dirty {
+		t.Errorf("expected the saved books to be read again, got %+v", editor.books)
+	}
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ztrue/tracerr"
+)
+
+// confirmSamples is how many images the confirmation screen samples for its estimate, fewer than fh5dl estimate
+// does so the summary shows up quickly
+const confirmSamples = 3
+
+// downloadSummary is what is known about a download before it starts
+type downloadSummary struct {
+	title       string
+	pages       int // pages to download, all of them unless the pages setting picks some
+	images      int
+	output      string
+	exists      bool
+	estimate    downloadEstimate
+	estimateErr error
+}
+
+// summarizeDownload resolves the book, works out where it will be written and estimates the download
+func summarizeDownload(url string, interactive bool, settings AppSettings) (downloadSummary, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), resolveTimeout)
+	defer cancel()
+
+	args := settings.toArgs()
+	args.Url = url
+	args.Interactive = interactive
+	if args.Concurrency <= 0 {
+		args.Concurrency = defaultConcurrency()
+	}
+
+	p, err := resolveProvider("", url)
+	if err != nil {
+		return downloadSummary{}, err
+	}
+	b, err := p.Resolve(ctx, url)
+	if err != nil {
+		return downloadSummary{}, tracerr.Wrap(err)
+	}
+
+	images := selectImages(p.Images(b), args.pageSet())
+	if len(images) > maxBookImages {
+		images = images[:maxBookImages]
+	}
+
+	outputDir, err := filepath.Abs(args.OutputFolder)
+	if err != nil {
+		return downloadSummary{}, tracerr.Wrap(err)
+	}
+	outputDir, name := args.outputNames(outputDir, b)
+	if name == "" {
+		name = strings.ReplaceAll(b.Id, "/", "_")
+	}
+
+	summary := downloadSummary{
+		title:  b.Title,
+		pages:  len(expectedPages(b, args.pageSet())),
+		images: len(images),
+		output: filepath.Join(outputDir, name+args.outputExtension()),
+	}
+	summary.exists = outputExists(summary.output)
+	if len(images) > 0 {
+		summary.estimate, summary.estimateErr = estimateImages(ctx, images, args.Concurrency, confirmSamples)
+	}
+
+	return summary, nil
+}
+
+// confirmScreen shows what is about to be downloaded, and where to, before the download starts
+type confirmScreen struct {
+	url         string // with -i at the end for interactive mode
+	interactive bool
+	settings    AppSettings
+	loading     bool
+	summary     downloadSummary
+	err         error
+}
+
+// summaryMsg is the summary of the download of the confirmation screen
+type summaryMsg struct {
+	summary downloadSummary
+	err     error
+}
+
+// confirmDoneMsg tells the main menu whether to start the download
+type confirmDoneMsg struct {
+	start    bool
+	url      string
+	settings AppSettings
+}
+
+// newConfirmScreen summarizes the download in the background
+func newConfirmScreen(url string, settings AppSettings) (confirmScreen, tea.Cmd) {
+	url = strings.TrimSpace(url)
+	interactive := strings.HasSuffix(url, "-i")
+	bookUrl := strings.TrimSpace(strings.TrimSuffix(url, "-i"))
+
+	screen := confirmScreen{url: url, interactive: interactive, settings: settings, loading: true}
+	return screen, func() tea.Msg {
+		summary, err := summarizeDownload(bookUrl, interactive, settings)
+		return summaryMsg{summary: summary, err: err}
+	}
+}
+
+func (c confirmScreen) Update(msg tea.Msg) (confirmScreen, tea.Cmd) {
+	switch msg := msg.(type) {
+	case summaryMsg:
+		c.loading = false
+		c.summary, c.err = msg.summary, msg.err
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "enter", "y":
+			done := confirmDoneMsg{start: true, url: c.url, settings: c.settings}
+			return c, func() tea.Msg { return done }
+		case "esc", "n", "q":
+			return c, func() tea.Msg { return confirmDoneMsg{} }
+		}
+	}
+
+	return c, nil
+}
+
+// conflictNotice tells what happens to the existing output with the conflict policy. The terminal UI can't ask,
+// so prompt skips the book as runArgs does.
+func conflictNotice(policy string) string {
+	switch policy {
+	case conflictOverwrite:
+		return "The output already exists and will be overwritten"
+	case conflictRename:
+		return "The output already exists, the book will be written under a new name"
+	}
+
+	return "The output already exists, the book will be skipped"
+}
+
+// optionsView lists the settings the download uses
+func (c confirmScreen) optionsView() string {
+	s := c.settings
+	mode := "non-interactive"
+	if c.interactive {
+		mode = "interactive"
+	}
+	pages := s.Pages
+	if pages == "" {
+		pages = "all"
+	}
+
+	options := []string{
+		mode,
+		"format " + s.Format,
+		"layout " + s.Layout,
+		"pages " + pages,
+		"images " + s.ImageFormat,
+		fmt.Sprintf("concurrency %d", s.Concurrency),
+	}
+	if c.interactive && s.CaptureScale > 0 {
+		options = append(options, fmt.Sprintf("capture scale %g", s.CaptureScale))
+	}
+
+	return strings.Join(options, ", ")
+}
+
+func (c confirmScreen) View() string {
+	v := titleStyle.Render("FlipHTML5 Downloader - Confirm") + "\n\n"
+	label := func(name string) string { return settingLabelStyle.Render(name) }
+
+	if c.loading {
+		v += fmt.Sprintf("%s: %s\n", label("Book"), c.url)
+		v += "\n" + infoStyle.Render("Looking up the book and sampling its images...") + "\n"
+	} else if c.err != nil {
+		v += fmt.Sprintf("%s: %s\n", label("Book"), c.url)
+		v += "\n" + errorStyle.Render(c.err.Error()) + "\n"
+	} else {
+		summary := c.summary
+		v += fmt.Sprintf("%s: %s\n", label("Title"), settingValueStyle.Render(summary.title))
+		v += fmt.Sprintf("%s: %d pages, %d images\n", label("Pages"), summary.pages, summary.images)
+
+		switch {
+		case summary.estimateErr != nil:
+			v += fmt.Sprintf("%s: %s\n", label("Estimate"), errorStyle.Render(summary.estimateErr.Error()))
+		case summary.estimate.Samples > 0:
+			v += fmt.Sprintf("%s: %s in about %s\n", label("Estimate"),
+				formatBytes(summary.estimate.Bytes), formatDuration(summary.estimate.Duration))
+		}
+
+		v += fmt.Sprintf("%s: %s\n", label("Output"), summary.output)
+		if summary.exists {
+			v += warningStyle.Render(conflictNotice(c.settings.OnConflict)) + "\n"
+		}
+	}
+
+	v += fmt.Sprintf("%s: %s\n", label("Options"), c.optionsView())
+	if c.summary.estimate.Samples > 0 {
+		v += "\n" + infoStyle.Render("The estimate is for downloading the images, interactive captures and writing the output come on top") + "\n"
+	}
+
+	v += "\n" + selectedStyle.Render("Start the download? (y/n)")
+	return v
+}
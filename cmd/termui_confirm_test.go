@@ -0,0 +1,63 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestConfirmScreen(t *testing.T) {
+	settings := defaultSettings
+	settings.OnConflict = conflictRename
+
+	screen, cmd := newConfirmScreen(" abcde/fghij -i ", settings)
+	if cmd == nil || !screen.loading || !screen.interactive {
+		t.Fatalf("expected the summary to be looked up for an interactive download, got %+v", screen)
+	}
+
+	summary := downloadSummary{
+		title:    "Chemistry 101",
+		pages:    48,
+		images:   48,
+		output:   "/books/Chemistry 101.pdf",
+		exists:   true,
+		estimate: downloadEstimate{Samples: 3, Bytes: 12 * 1024 * 1024, Duration: 90 * time.Second},
+	}
+	screen, _ = screen.Update(summaryMsg{summary: summary})
+
+	view := screen.View()
+	for _, expected := range []string{"Chemistry 101", "48 pages", "12.0 MiB", "/books/Chemistry 101.pdf", "new name", "interactive, format pdf"} {
+		if !strings.Contains(view, expected) {
+			t.Errorf("expected %q in\n%s", expected, view)
+		}
+	}
+
+	_, cmd = screen.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("y")})
+	if cmd == nil {
+		t.Fatal("expected y to start the download")
+	}
+	done, ok := cmd().(confirmDoneMsg)
+	if !ok || !done.start || done.url != "abcde/fghij -i" || done.settings.OnConflict != conflictRename {
+		t.Errorf("unexpected message %+v", done)
+	}
+}
+
+func TestConfirmScreenCancel(t *testing.T) {
+	m := initialModel()
+	m.selected = true
+	m.downloadType = "single"
+	m.urlInput = newTextInput("abcde/fghij")
+	m, _ = m.confirmSingle()
+
+	next, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	m = next.(uiModel)
+	next, _ = m.Update(cmd())
+	m = next.(uiModel)
+
+	// back to the url, to fix it
+	if m.confirming || m.running || !m.selected || m.urlInput.Value() != "abcde/fghij" {
+		t.Errorf("expected to be back at the url input, got %+v", m)
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ygunayer/fh5dl/internal/history"
+)
+
+// historyRows is how many downloads the history screen shows at once
+const historyRows = 15
+
+// historyScreen lists the past downloads, newest first
+type historyScreen struct {
+	entries    []history.Entry
+	cursor     int
+	failedOnly bool
+	message    string
+}
+
+// historyDoneMsg tells the main menu that the history was closed, and which download to run again if any
+type historyDoneMsg struct {
+	rerun *history.Entry
+}
+
+// newHistoryScreen reads the history
+func newHistoryScreen() historyScreen {
+	screen := historyScreen{}
+
+	path, err := historyPath()
+	if err == nil {
+		screen.entries, err = history.Read(path)
+	}
+	if err != nil {
+		screen.message = fmt.Sprintf("Failed to read the download history: %v", err)
+	}
+
+	return screen
+}
+
+// visible returns the entries shown with the current filter
+func (h historyScreen) visible() []history.Entry {
+	if !h.failedOnly {
+		return h.entries
+	}
+
+	failed := make([]history.Entry, 0)
+	for _, entry := range h.entries {
+		if entry.Status == reportStatusFailed {
+			failed = append(failed, entry)
+		}
+	}
+
+	return failed
+}
+
+func (h historyScreen) Update(msg tea.Msg) (historyScreen, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return h, nil
+	}
+
+	entries := h.visible()
+	h.message = ""
+	switch key.String() {
+	case "up", "k":
+		if h.cursor > 0 {
+			h.cursor--
+		}
+	case "down", "j":
+		if h.cursor < len(entries)-1 {
+			h.cursor++
+		}
+	case "f":
+		h.failedOnly = !h.failedOnly
+		h.cursor = 0
+	case "enter", "r":
+		if len(entries) == 0 {
+			break
+		}
+		entry := entries[h.cursor]
+		return h, func() tea.Msg { return historyDoneMsg{rerun: &entry} }
+	case "o":
+		if len(entries) == 0 {
+			break
+		}
+		if err := openFolder(historyFolder(entries[h.cursor])); err != nil {
+			h.message = err.Error()
+		}
+	case "esc", "q":
+		return h, func() tea.Msg { return historyDoneMsg{} }
+	}
+
+	return h, nil
+}
+
+// historyFolder is the folder the book of the entry was written into
+func historyFolder(entry history.Entry) string {
+	if entry.Output != "" {
+		return filepath.Dir(entry.Output)
+	}
+
+	return entry.OutputFolder
+}
+
+func (h historyScreen) View() string {
+	s := titleStyle.Render("FlipHTML5 Downloader - History") + "\n\n"
+
+	entries := h.visible()
+	if len(entries) == 0 {
+		if h.failedOnly {
+			s += infoStyle.Render("No failed downloads") + "\n"
+		} else {
+			s += infoStyle.Render("Nothing downloaded yet") + "\n"
+		}
+	}
+
+	// scroll so the cursor stays in view
+	start := max(0, min(h.cursor-historyRows/2, len(entries)-historyRows))
+	end := min(len(entries), start+historyRows)
+	for i := start; i < end; i++ {
+		entry := entries[i]
+		cursor := " "
+		if h.cursor == i {
+			cursor = ">"
+		}
+
+		title := entry.Title
+		if title == "" {
+			title = entry.Url
+		}
+
+		status := settingValueStyle.Render(fmt.Sprintf("%-7s", entry.Status))
+		if entry.Status == reportStatusFailed {
+			status = errorStyle.Render(fmt.Sprintf("%-7s", entry.Status))
+		}
+
+		line := fmt.Sprintf("%s  %s  %s", entry.Time.Local().Format("2006-01-02 15:04"), status, title)
+		if h.cursor == i {
+			line = selectedStyle.Render(line)
+		}
+		s += fmt.Sprintf("%s %s\n", cursor, line)
+	}
+
+	if h.cursor < len(entries) {
+		entry := entries[h.cursor]
+		s += "\n" + infoStyle.Render(entry.Url) + "\n"
+		if entry.Error != "" {
+			s += errorStyle.Render(entry.Error) + "\n"
+		}
+		s += infoStyle.Render(historyFolder(entry)) + "\n"
+	}
+
+	if h.message != "" {
+		s += "\n" + h.message + "\n"
+	}
+	s += "\n" + infoStyle.Render("enter: download again, o: open the folder, f: only failed downloads, esc: back")
+	return s
+}
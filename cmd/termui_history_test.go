@@ -0,0 +1,41 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestHistoryScreen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	defer func(original func() (string, error)) { historyPath = original }(historyPath)
+	historyPath = func() (string, error) { return path, nil }
+
+	failed := newBookReport("abcde/fghij")
+	failed.finish(errors.New("rate limited"))
+	recordHistory(&Args{OutputFolder: "output"}, failed)
+	succeeded := newBookReport("abcde/klmno")
+	succeeded.finish(nil)
+	recordHistory(&Args{OutputFolder: "output"}, succeeded)
+
+	screen := newHistoryScreen()
+	if len(screen.entries) != 2 || screen.entries[0].Url != "abcde/klmno" {
+		t.Fatalf("expected the newest download first, got %+v", screen.entries)
+	}
+
+	screen, _ = screen.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("f")})
+	if visible := screen.visible(); len(visible) != 1 || visible[0].Url != "abcde/fghij" {
+		t.Fatalf("expected only the failed download, got %+v", visible)
+	}
+
+	_, cmd := screen.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd == nil {
+		t.Fatal("expected enter to run the download again")
+	}
+	done, ok := cmd().(historyDoneMsg)
+	if !ok || done.rerun == nil || done.rerun.Url != "abcde/fghij" || !filepath.IsAbs(done.rerun.OutputFolder) {
+		t.Errorf("unexpected message %+v", done)
+	}
+}
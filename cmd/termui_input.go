@@ -0,0 +1,28 @@
+package main
+
+import (
+	"github.com/charmbracelet/bubbles/cursor"
+	"github.com/charmbracelet/bubbles/textinput"
+)
+
+// newTextInput creates a single line input holding value, with the editing keys of a shell: arrows, home and end,
+// ctrl+w to delete a word and ctrl+u to delete up to the cursor. Ctrl+V is left to the screens, which read the
+// clipboard with readClipboard as the bindings of textinput can't on Wayland.
+func newTextInput(value string) textinput.Model {
+	input := textinput.New()
+	input.SetValue(value)
+	input.KeyMap.Paste.SetEnabled(false)
+	input.Cursor.SetMode(cursor.CursorStatic)
+	input.Focus()
+
+	return input
+}
+
+// insertText adds text at the cursor of the input
+func insertText(input *textinput.Model, text string) {
+	value := []rune(input.Value())
+	pos := input.Position()
+
+	input.SetValue(string(value[:pos]) + text + string(value[pos:]))
+	input.SetCursor(pos + len([]rune(text)))
+}
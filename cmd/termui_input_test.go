@@ -0,0 +1,50 @@
+package main
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestInsertText(t *testing.T) {
+	input := newTextInput("abcde/klmno")
+	input.SetCursor(6)
+
+	insertText(&input, "fghij/")
+	if input.Value() != "abcde/fghij/klmno" || input.Position() != 12 {
+		t.Errorf("unexpected input %q at %d", input.Value(), input.Position())
+	}
+}
+
+func TestUrlInputEditing(t *testing.T) {
+	m := initialModel()
+	m.cursor = 0
+	next, _ := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = next.(uiModel)
+
+	for _, key := range []tea.KeyMsg{
+		{Type: tea.KeyRunes, Runes: []rune("abcde/fghij q")},
+		{Type: tea.KeyCtrlW},
+		{Type: tea.KeyHome},
+		{Type: tea.KeyRunes, Runes: []rune("https://online.fliphtml5.com/")},
+	} {
+		next, _ = m.Update(key)
+		m = next.(uiModel)
+	}
+
+	if m.urlInput.Value() != "https://online.fliphtml5.com/abcde/fghij " {
+		t.Errorf("unexpected url %q", m.urlInput.Value())
+	}
+
+	// q is typed into the url instead of going back
+	next, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("q")})
+	if m = next.(uiModel); !m.selected {
+		t.Error("expected q to be typed into the url")
+	}
+
+	// ctrl+u deletes up to the cursor, which is after the typed q
+	next, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlU})
+	if m = next.(uiModel); m.urlInput.Value() != "abcde/fghij " {
+		t.Errorf("unexpected url %q", m.urlInput.Value())
+	}
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ygunayer/fh5dl/internal/history"
+	"github.com/ygunayer/fh5dl/internal/progress"
+)
+
+const (
+	// logRows is the height of the log pane
+	logRows = 8
+	// maxLogLines is how many log lines the run screen keeps for scrolling back
+	maxLogLines = 1000
+	// barWidth is the width of the progress bars
+	barWidth = 30
+)
+
+// runPhase is the progress of a phase of the running download
+type runPhase struct {
+	name        string
+	description string
+	current     int
+	total       int
+	finished    bool
+}
+
+// runLogLine is a message of the running download
+type runLogLine struct {
+	level   progress.Level
+	message string
+}
+
+// runScreen shows the progress of a download running inside the terminal UI, with its messages in a log pane
+// below. Only warnings and errors are shown in the pane until the details are expanded. The image downloads can be
+// paused, and the book or the whole batch cancelled.
+type runScreen struct {
+	title    string
+	batch    bool
+	control  *downloadControl
+	msgs     chan tea.Msg
+	phases   []runPhase
+	logs     []runLogLine
+	details  bool
+	scroll   int // lines scrolled back from the end of the log
+	started  time.Time
+	finished bool
+	elapsed  time.Duration
+	err      error
+}
+
+// runEventMsg is a progress event of the running download
+type runEventMsg progress.Event
+
+// runDoneMsg is sent when the download finished
+type runDoneMsg struct {
+	err error
+}
+
+// runClosedMsg tells the main menu that the finished download was looked at
+type runClosedMsg struct{}
+
+// startRun runs the download in the background, reporting to the run screen
+func startRun(title string, batch bool, run func(reporter progress.Reporter, control *downloadControl) error) (runScreen, tea.Cmd) {
+	screen := runScreen{title: title, batch: batch, control: newDownloadControl(), msgs: make(chan tea.Msg, 256), started: time.Now()}
+
+	reporter := progress.NewFunc(func(event progress.Event) {
+		screen.msgs <- runEventMsg(event)
+	})
+	// the download starts with the command, not before the program runs it
+	start := func() tea.Msg {
+		go func() {
+			screen.msgs <- runDoneMsg{err: run(reporter, screen.control)}
+		}()
+		return <-screen.msgs
+	}
+
+	return screen, start
+}
+
+// next waits for the next message of the download. Events and the end of the download come through the same
+// channel, so no event is missed.
+func (r runScreen) next() tea.Cmd {
+	return func() tea.Msg { return <-r.msgs }
+}
+
+func (r runScreen) Update(msg tea.Msg) (runScreen, tea.Cmd) {
+	switch msg := msg.(type) {
+	case runEventMsg:
+		r.handleEvent(progress.Event(msg))
+		return r, r.next()
+	case runDoneMsg:
+		r.finished = true
+		r.elapsed = time.Since(r.started)
+		r.err = msg.err
+		if msg.err != nil {
+			r.addLog(progress.LevelError, msg.err.Error())
+			if known, ok := classifyError(msg.err); ok {
+				r.addLog(progress.LevelError, known.hint)
+			}
+		}
+		return r, nil
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "d":
+			r.details = !r.details
+			r.scroll = 0
+		case "p":
+			if !r.finished {
+				r.control.SetPaused(!r.control.Paused())
+			}
+		case "c":
+			if !r.finished {
+				// a paused book is resumed so its workers see the cancellation
+				r.control.CancelBook()
+				r.control.SetPaused(false)
+				r.addLog(progress.LevelWarn, "Cancelling the book")
+			}
+		case "x":
+			if !r.finished && r.batch {
+				r.control.Stop()
+				r.control.SetPaused(false)
+				r.addLog(progress.LevelWarn, "Stopping the batch")
+			}
+		case "pgup", "up", "k":
+			r.scroll = min(r.scroll+1, max(0, len(r.visibleLogs())-logRows))
+		case "pgdown", "down", "j":
+			r.scroll = max(0, r.scroll-1)
+		case "enter", "esc", "q":
+			if r.finished {
+				return r, func() tea.Msg { return runClosedMsg{} }
+			}
+		}
+	}
+
+	return r, nil
+}
+
+func (r *runScreen) handleEvent(event progress.Event) {
+	switch event.Type {
+	case "log":
+		r.addLog(event.Level, event.Message)
+	case "start":
+		// the phases of the next book of a batch start over
+		for i := range r.phases {
+			if r.phases[i].name == event.Phase {
+				r.phases = r.phases[:i]
+				break
+			}
+		}
+		r.phases = append(r.phases, runPhase{name: event.Phase, description: event.Description, total: event.Total})
+	case "progress", "finish":
+		for i := range r.phases {
+			if r.phases[i].name == event.Phase {
+				r.phases[i].current = event.Current
+				r.phases[i].finished = event.Type == "finish"
+			}
+		}
+	}
+}
+
+func (r *runScreen) addLog(level progress.Level, message string) {
+	for _, line := range strings.Split(strings.TrimRight(message, "\n"), "\n") {
+		r.logs = append(r.logs, runLogLine{level: level, message: line})
+	}
+	if len(r.logs) > maxLogLines {
+		r.logs = r.logs[len(r.logs)-maxLogLines:]
+	}
+}
+
+// visibleLogs returns the log lines shown in the pane, all of them with the details expanded
+func (r runScreen) visibleLogs() []runLogLine {
+	if r.details {
+		return r.logs
+	}
+
+	problems := make([]runLogLine, 0)
+	for _, line := range r.logs {
+		if line.level != progress.LevelInfo {
+			problems = append(problems, line)
+		}
+	}
+
+	return problems
+}
+
+// renderBar draws a progress bar of the phase
+func renderBar(phase runPhase) string {
+	filled := barWidth
+	if phase.total > 0 && !phase.finished {
+		filled = min(barWidth, phase.current*barWidth/phase.total)
+	}
+
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", barWidth-filled)
+	count := fmt.Sprintf("%d/%d", phase.current, phase.total)
+	if phase.finished {
+		count = fmt.Sprintf("%d/%d", phase.total, phase.total)
+	}
+
+	return fmt.Sprintf("%-24s [%s] %s", phase.description, bar, count)
+}
+
+func (r runScreen) View() string {
+	s := titleStyle.Render("FlipHTML5 Downloader - "+r.title) + "\n\n"
+
+	for _, phase := range r.phases {
+		s += renderBar(phase) + "\n"
+	}
+	if len(r.phases) == 0 && !r.finished {
+		s += infoStyle.Render("Starting...") + "\n"
+	}
+
+	logs := r.visibleLogs()
+	hidden := len(r.logs) - len(logs)
+	header := fmt.Sprintf("Log (%d lines", len(logs))
+	if hidden > 0 {
+		header += fmt.Sprintf(", %d info messages hidden", hidden)
+	}
+	s += "\n" + infoStyle.Render(header+")") + "\n"
+
+	end := len(logs) - r.scroll
+	for _, line := range logs[max(0, end-logRows):end] {
+		switch line.level {
+		case progress.LevelError:
+			s += errorStyle.Render("ERROR: "+line.message) + "\n"
+		case progress.LevelWarn:
+			s += warningStyle.Render("WARNING: "+line.message) + "\n"
+		default:
+			s += line.message + "\n"
+		}
+	}
+
+	help := "d: show or hide details, up/down: scroll the log"
+	if !r.finished {
+		if r.control.Paused() {
+			s += "\n" + warningStyle.Render("Paused, the images being downloaded are finished first") + "\n"
+			help += ", p: resume"
+		} else {
+			help += ", p: pause"
+		}
+		if r.batch {
+			help += ", c: cancel the book, x: stop the batch"
+		} else {
+			help += ", c: cancel"
+		}
+	}
+	if r.finished {
+		if r.err != nil {
+			s += "\n" + errorStyle.Render(fmt.Sprintf("Failed after %s", formatDuration(r.elapsed))) + "\n"
+		} else {
+			s += "\n" + selectedStyle.Render(fmt.Sprintf("Completed in %s", formatDuration(r.elapsed))) + "\n"
+		}
+		help += ", enter: back to the menu"
+	}
+
+	return s + "\n" + infoStyle.Render(help)
+}
+
+// startRunScreen switches to the run screen for the download
+func (m uiModel) startRunScreen(title string, batch bool, run func(reporter progress.Reporter, control *downloadControl) error) (uiModel, tea.Cmd) {
+	var cmd tea.Cmd
+	m.run, cmd = startRun(title, batch, run)
+	m.running = true
+
+	return m, cmd
+}
+
+// startDownload downloads the book of the url, which ends with -i for interactive mode
+func (m uiModel) startDownload(url string, settings AppSettings) (uiModel, tea.Cmd) {
+	return m.startRunScreen("Single File", false, func(reporter progress.Reporter, control *downloadControl) error {
+		return downloadSingleFile(url, settings, reporter, control)
+	})
+}
+
+// startBatch downloads the books of the books folder
+func (m uiModel) startBatch() (uiModel, tea.Cmd) {
+	booksDir, settings := m.booksDirectory, m.settings
+	return m.startRunScreen("Batch Mode", true, func(reporter progress.Reporter, control *downloadControl) error {
+		return downloadBatch(booksDir, settings, reporter, control)
+	})
+}
+
+// confirmDownload shows the summary of the download of the url, which starts once it is confirmed
+func (m uiModel) confirmDownload(url string, settings AppSettings) (uiModel, tea.Cmd) {
+	var cmd tea.Cmd
+	m.confirm, cmd = newConfirmScreen(url, settings)
+	m.confirming = true
+
+	return m, cmd
+}
+
+// confirmSingle confirms the download of the url input
+func (m uiModel) confirmSingle() (uiModel, tea.Cmd) {
+	url := strings.TrimSpace(m.urlInput.Value())
+	if m.interactive && !strings.HasSuffix(url, "-i") {
+		url += "-i"
+	}
+
+	return m.confirmDownload(url, m.settings)
+}
+
+// confirmRerun confirms downloading a book of the history again, into the same folder
+func (m uiModel) confirmRerun(entry history.Entry) (uiModel, tea.Cmd) {
+	settings := m.settings
+	settings.OutputFolder = entry.OutputFolder
+	url := entry.Url
+	if entry.Interactive {
+		url += "-i"
+	}
+
+	return m.confirmDownload(url, settings)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ygunayer/fh5dl/internal/progress"
+)
+
+func TestRunScreen(t *testing.T) {
+	screen := runScreen{title: "Single File", msgs: make(chan tea.Msg, 1)}
+
+	events := []progress.Event{
+		{Type: "start", Phase: "download", Description: "Downloading pages", Total: 10},
+		{Type: "log", Level: progress.LevelInfo, Message: "Downloading abcde/fghij"},
+		{Type: "log", Level: progress.LevelWarn, Message: "Retrying page 3"},
+		{Type: "progress", Phase: "download", Current: 5},
+	}
+	for _, event := range events {
+		screen, _ = screen.Update(runEventMsg(event))
+	}
+
+	if len(screen.phases) != 1 || screen.phases[0].current != 5 {
+		t.Fatalf("unexpected phases %+v", screen.phases)
+	}
+	view := screen.View()
+	if !strings.Contains(view, "Retrying page 3") || strings.Contains(view, "Downloading abcde/fghij") {
+		t.Errorf("expected only the warnings in the log pane, got\n%s", view)
+	}
+
+	screen, _ = screen.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("d")})
+	if view := screen.View(); !strings.Contains(view, "Downloading abcde/fghij") {
+		t.Errorf("expected the details to show every message, got\n%s", view)
+	}
+
+	// the log can't be closed while the download runs
+	if _, cmd := screen.Update(tea.KeyMsg{Type: tea.KeyEnter}); cmd != nil {
+		t.Error("expected enter to do nothing before the download finished")
+	}
+
+	screen, _ = screen.Update(runDoneMsg{err: errors.New("page 7 failed")})
+	view = screen.View()
+	if !strings.Contains(view, "page 7 failed") || !strings.Contains(view, "Retrying page 3") {
+		t.Errorf("expected the problems to stay visible after the download, got\n%s", view)
+	}
+
+	_, cmd := screen.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd == nil {
+		t.Fatal("expected enter to close the finished download")
+	}
+	if _, ok := cmd().(runClosedMsg); !ok {
+		t.Error("expected the run screen to be closed")
+	}
+}
+
+func TestRunScreenPhases(t *testing.T) {
+	screen := runScreen{msgs: make(chan tea.Msg, 1)}
+
+	// the next book of a batch starts its phases over
+	for _, phase := range []string{"download", "pdf", "download"} {
+		screen, _ = screen.Update(runEventMsg{Type: "start", Phase: phase, Total: 1})
+	}
+	if len(screen.phases) != 1 || screen.phases[0].name != "download" {
+		t.Errorf("unexpected phases %+v", screen.phases)
+	}
+}
+
+func TestRunScreenControl(t *testing.T) {
+	screen := runScreen{batch: true, control: newDownloadControl(), msgs: make(chan tea.Msg, 1)}
+
+	screen, _ = screen.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("p")})
+	if !screen.control.Paused() || !strings.Contains(screen.View(), "Paused") {
+		t.Fatal("expected p to pause the downloads")
+	}
+
+	screen, _ = screen.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("x")})
+	if screen.control.Paused() || !screen.control.Stopped() {
+		t.Error("expected x to resume and stop the batch")
+	}
+}
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ygunayer/fh5dl/book"
+)
+
+// settingField is a setting of the settings screen. Fields with choices are cycled through with enter, the others
+// are typed in and checked by set before they are taken.
+type settingField struct {
+	label   string
+	help    string
+	choices []string
+	get     func(s AppSettings) string
+	set     func(s *AppSettings, value string) error
+}
+
+// settingGroup is a page of the settings screen
+type settingGroup struct {
+	name   string
+	fields []settingField
+}
+
+// positiveInt parses a setting that has to be a number above zero
+func positiveInt(value string) (int, error) {
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("expected a number above 0")
+	}
+
+	return n, nil
+}
+
+// choiceField is a setting with a fixed set of values
+func choiceField(label string, help string, choices []string, get func(s AppSettings) string, set func(s *AppSettings, value string)) settingField {
+	return settingField{
+		label:   label,
+		help:    help,
+		choices: choices,
+		get:     get,
+		set: func(s *AppSettings, value string) error {
+			set(s, value)
+			return nil
+		},
+	}
+}
+
+// settingGroups are the pages of the settings screen
+var settingGroups = []settingGroup{
+	{
+		name: "Downloads",
+		fields: []settingField{
+			{
+				label: "Concurrency",
+				help:  "How many images are downloaded at once",
+				get:   func(s AppSettings) string { return strconv.Itoa(s.Concurrency) },
+				set: func(s *AppSettings, value string) (err error) {
+					s.Concurrency, err = positiveInt(value)
+					return err
+				},
+			},
+			{
+				label: "Batch Size",
+				help:  "How many pages interactive mode captures at once",
+				get:   func(s AppSettings) string { return strconv.Itoa(s.BatchSize) },
+				set: func(s *AppSettings, value string) (err error) {
+					s.BatchSize, err = positiveInt(value)
+					return err
+				},
+			},
+			{
+				label: "Pages",
+				help:  "Only download these pages, such as 1-10,15,20-, or empty for every page",
+				get:   func(s AppSettings) string { return s.Pages },
+				set: func(s *AppSettings, value string) error {
+					value = strings.TrimSpace(value)
+					if value != "" {
+						if _, err := book.ParsePageSet(value); err != nil {
+							return err
+						}
+					}
+					s.Pages = value
+					return nil
+				},
+			},
+			{
+				label: "Capture Scale",
+				help:  "Device scale factor of interactive captures, 0 to match the page images",
+				get:   func(s AppSettings) string { return strconv.FormatFloat(s.CaptureScale, 'g', -1, 64) },
+				set: func(s *AppSettings, value string) error {
+					scale, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+					if err != nil || scale < 0 || scale > book.MaxCaptureScale {
+						return fmt.Errorf("expected a number between 0 and %d", book.MaxCaptureScale)
+					}
+					s.CaptureScale = scale
+					return nil
+				},
+			},
+			choiceField("Image Format", "Format the downloaded images are kept in",
+				[]string{imageFormatOriginal, imageFormatJpg, imageFormatPng},
+				func(s AppSettings) string { return s.ImageFormat },
+				func(s *AppSettings, value string) { s.ImageFormat = value }),
+		},
+	},
+	{
+		name: "Output",
+		fields: []settingField{
+			{
+				label: "Output Folder",
+				help:  "Folder the books are written into",
+				get:   func(s AppSettings) string { return s.OutputFolder },
+				set: func(s *AppSettings, value string) error {
+					if strings.TrimSpace(value) == "" {
+						return fmt.Errorf("expected a folder")
+					}
+					s.OutputFolder = strings.TrimSpace(value)
+					return nil
+				},
+			},
+			choiceField("Format", "pdf, djvu (needs djvulibre), strip of tall images or cbz",
+				[]string{outputPdf, outputDjvu, outputStrip, outputCbz},
+				func(s AppSettings) string { return s.Format },
+				func(s *AppSettings, value string) { s.Format = value }),
+			choiceField("Layout", "komga and kavita put the books into a folder per series",
+				[]string{layoutDefault, layoutKomga, layoutKavita},
+				func(s AppSettings) string { return s.Layout },
+				func(s *AppSettings, value string) { s.Layout = value }),
+			choiceField("If PDF Exists", "What to do with books that were already downloaded",
+				conflictPolicies,
+				func(s AppSettings) string { return s.OnConflict },
+				func(s *AppSettings, value string) { s.OnConflict = value }),
+			choiceField("Report", "Summary report written next to each book",
+				[]string{"json", "markdown", "all", "none"},
+				func(s AppSettings) string { return s.ReportFormat },
+				func(s *AppSettings, value string) { s.ReportFormat = value }),
+		},
+	},
+	{
+		name: "Interface",
+		fields: []settingField{
+			choiceField("Theme", "auto follows the background of the terminal, NO_COLOR turns colors off with any theme",
+				themes,
+				func(s AppSettings) string { return s.Theme },
+				func(s *AppSettings, value string) {
+					s.Theme = value
+					applyTheme(resolveTheme(value))
+				}),
+		},
+	},
+}
+
+// nextChoice returns the choice after value, wrapping around
+func nextChoice(choices []string, value string) string {
+	for i, choice := range choices {
+		if choice == value {
+			return choices[(i+1)%len(choices)]
+		}
+	}
+
+	return choices[0]
+}
+
+// settingsScreen edits the settings, a page of them at a time
+type settingsScreen struct {
+	settings AppSettings
+	group    int
+	cursor   int
+	editing  bool
+	input    textinput.Model
+	err      error
+}
+
+// settingsDoneMsg tells the main menu that the settings were closed
+type settingsDoneMsg struct {
+	settings AppSettings
+}
+
+func newSettingsScreen(settings AppSettings) settingsScreen {
+	return settingsScreen{settings: settings}
+}
+
+func (s settingsScreen) fields() []settingField {
+	return settingGroups[s.group].fields
+}
+
+func (s settingsScreen) Update(msg tea.Msg) (settingsScreen, tea.Cmd) {
+	if paste, ok := msg.(pasteMsg); ok && s.editing && paste.err == nil {
+		insertText(&s.input, pastedUrl(paste.text))
+		return s, nil
+	}
+
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return s, nil
+	}
+
+	if s.editing {
+		return s.updateEditing(key)
+	}
+
+	s.err = nil
+	switch key.String() {
+	case "up", "k":
+		if s.cursor > 0 {
+			s.cursor--
+		}
+	case "down", "j":
+		if s.cursor < len(s.fields())-1 {
+			s.cursor++
+		}
+	case "tab", "right", "l":
+		s.group = (s.group + 1) % len(settingGroups)
+		s.cursor = 0
+	case "shift+tab", "left", "h":
+		s.group = (s.group + len(settingGroups) - 1) % len(settingGroups)
+		s.cursor = 0
+	case "enter", " ":
+		field := s.fields()[s.cursor]
+		if field.choices != nil {
+			s.err = field.set(&s.settings, nextChoice(field.choices, field.get(s.settings)))
+			break
+		}
+		s.editing = true
+		s.input = newTextInput(field.get(s.settings))
+		s.input.Prompt = ""
+	case "esc", "q":
+		settings := s.settings
+		return s, func() tea.Msg { return settingsDoneMsg{settings: settings} }
+	}
+
+	return s, nil
+}
+
+// updateEditing handles the keys of a setting being typed in. A value that isn't valid is kept in the input with
+// the reason, so it can be fixed.
+func (s settingsScreen) updateEditing(key tea.KeyMsg) (settingsScreen, tea.Cmd) {
+	switch {
+	case key.String() == "enter":
+		if err := s.fields()[s.cursor].set(&s.settings, s.input.Value()); err != nil {
+			s.err = err
+			break
+		}
+		s.editing = false
+		s.err = nil
+	case key.String() == "esc":
+		s.editing = false
+		s.err = nil
+	case key.Type == tea.KeyCtrlV:
+		return s, pasteCmd
+	default:
+		var cmd tea.Cmd
+		s.input, cmd = s.input.Update(key)
+		return s, cmd
+	}
+
+	return s, nil
+}
+
+func (s settingsScreen) View() string {
+	v := titleStyle.Render("FlipHTML5 Downloader - Settings") + "\n\n"
+
+	tabs := make([]string, 0, len(settingGroups))
+	for i, group := range settingGroups {
+		if i == s.group {
+			tabs = append(tabs, selectedStyle.Render("["+group.name+"]"))
+		} else {
+			tabs = append(tabs, infoStyle.Render(" "+group.name+" "))
+		}
+	}
+	v += strings.Join(tabs, " ") + "\n\n"
+
+	for i, field := range s.fields() {
+		cursor := " "
+		label := field.label
+		if s.cursor == i {
+			cursor = ">"
+			label = selectedStyle.Render(label)
+		}
+
+		value := settingValueStyle.Render(field.get(s.settings))
+		if s.editing && s.cursor == i {
+			value = s.input.View()
+		}
+		v += fmt.Sprintf("%s %s: %s\n", cursor, settingLabelStyle.Render(label), value)
+	}
+
+	field := s.fields()[s.cursor]
+	v += "\n" + infoStyle.Render(field.help) + "\n"
+	if s.err != nil {
+		v += errorStyle.Render(fmt.Sprintf("Invalid %s: %v", strings.ToLower(field.label), s.err)) + "\n"
+	}
+
+	if s.editing {
+		v += "\n" + infoStyle.Render("Press Enter to save, Esc to cancel")
+	} else {
+		v += "\n" + infoStyle.Render("Press Enter to change a setting, Tab or left/right for the next page, Esc to go back")
+	}
+	return v
+}
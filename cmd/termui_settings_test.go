@@ -0,0 +1,71 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func typeSetting(screen settingsScreen, value string) settingsScreen {
+	screen, _ = screen.Update(tea.KeyMsg{Type: tea.KeyCtrlU})
+	screen, _ = screen.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(value)})
+	screen, _ = screen.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	return screen
+}
+
+func TestSettingsScreenValidation(t *testing.T) {
+	screen := newSettingsScreen(defaultSettings)
+
+	// pages
+	screen.cursor = 2
+	screen, _ = screen.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	screen = typeSetting(screen, "10-1")
+	if !screen.editing || screen.err == nil || !strings.Contains(screen.View(), "Invalid pages") {
+		t.Fatalf("expected the page range to be refused, got %v", screen.err)
+	}
+	screen = typeSetting(screen, "1-10,15")
+	if screen.editing || screen.settings.Pages != "1-10,15" {
+		t.Fatalf("expected the page range to be taken, got %q", screen.settings.Pages)
+	}
+
+	// capture scale
+	screen.cursor = 3
+	screen, _ = screen.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	screen = typeSetting(screen, "9000")
+	if screen.err == nil {
+		t.Fatal("expected a capture scale that is too large to be refused")
+	}
+	screen, _ = screen.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if screen.editing || screen.settings.CaptureScale != defaultSettings.CaptureScale {
+		t.Errorf("expected esc to keep the capture scale, got %v", screen.settings.CaptureScale)
+	}
+}
+
+func TestSettingsScreenGroups(t *testing.T) {
+	screen := newSettingsScreen(defaultSettings)
+
+	screen, _ = screen.Update(tea.KeyMsg{Type: tea.KeyTab})
+	if settingGroups[screen.group].name != "Output" {
+		t.Fatalf("expected tab to show the next page, got %d", screen.group)
+	}
+
+	// format cycles through its choices
+	screen.cursor = 1
+	screen, _ = screen.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if screen.settings.Format != outputDjvu {
+		t.Errorf("expected the next format, got %q", screen.settings.Format)
+	}
+
+	_, cmd := screen.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if cmd == nil {
+		t.Fatal("expected esc to close the settings")
+	}
+	done, ok := cmd().(settingsDoneMsg)
+	if !ok || done.settings.Format != outputDjvu {
+		t.Errorf("unexpected message %+v", done)
+	}
+	if args := done.settings.toArgs(); args.Format != outputDjvu || args.ImageFormat != imageFormatOriginal {
+		t.Errorf("unexpected arguments %+v", args)
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"os"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// themes of the terminal UI
+const (
+	themeAuto  = "auto" // dark or light, after the background of the terminal
+	themeDark  = "dark"
+	themeLight = "light"
+	themeNone  = "none" // no colors, as NO_COLOR asks for
+)
+
+var themes = []string{themeAuto, themeDark, themeLight, themeNone}
+
+// uiTheme are the colors of the terminal UI
+type uiTheme struct {
+	titleText lipgloss.TerminalColor
+	accent    lipgloss.TerminalColor // titles, selected items and setting names
+	muted     lipgloss.TerminalColor // help texts
+	value     lipgloss.TerminalColor
+	err       lipgloss.TerminalColor
+	warning   lipgloss.TerminalColor
+}
+
+var darkTheme = uiTheme{
+	titleText: lipgloss.Color("#FAFAFA"),
+	accent:    lipgloss.Color("#7D56F4"),
+	muted:     lipgloss.Color("#A49FA5"),
+	value:     lipgloss.Color("205"),
+	err:       lipgloss.Color("#FF5F87"),
+	warning:   lipgloss.Color("#FFAF00"),
+}
+
+// lightTheme has darker colors, the ones of the dark theme are too faint on a white background
+var lightTheme = uiTheme{
+	titleText: lipgloss.Color("#FFFFFF"),
+	accent:    lipgloss.Color("#5B34D6"),
+	muted:     lipgloss.Color("#5F5A60"),
+	value:     lipgloss.Color("#AF005F"),
+	err:       lipgloss.Color("#C4002F"),
+	warning:   lipgloss.Color("#9A5B00"),
+}
+
+var noColorTheme = uiTheme{
+	titleText: lipgloss.NoColor{},
+	accent:    lipgloss.NoColor{},
+	muted:     lipgloss.NoColor{},
+	value:     lipgloss.NoColor{},
+	err:       lipgloss.NoColor{},
+	warning:   lipgloss.NoColor{},
+}
+
+// darkBackground is whether the terminal has a dark background. It is looked up once before the terminal UI
+// starts, asking the terminal while the UI reads the keyboard would mix its answer into the keys.
+var darkBackground = true
+
+func init() {
+	applyTheme(darkTheme)
+}
+
+// validTheme checks the value of the --theme flag
+func validTheme(name string) bool {
+	for _, theme := range themes {
+		if name == theme {
+			return true
+		}
+	}
+
+	return name == ""
+}
+
+// resolveTheme returns the colors of the theme. NO_COLOR turns the colors off whatever the theme.
+func resolveTheme(name string) uiTheme {
+	if os.Getenv("NO_COLOR") != "" {
+		return noColorTheme
+	}
+
+	switch name {
+	case themeDark:
+		return darkTheme
+	case themeLight:
+		return lightTheme
+	case themeNone:
+		return noColorTheme
+	}
+
+	if darkBackground {
+		return darkTheme
+	}
+	return lightTheme
+}
+
+// applyTheme sets the styles of the terminal UI to the colors of the theme
+func applyTheme(theme uiTheme) {
+	titleStyle = lipgloss.NewStyle().
+		Bold(true).
+		Foreground(theme.titleText).
+		Background(theme.accent).
+		PaddingLeft(2).
+		PaddingRight(2).
+		MarginBottom(1)
+
+	selectedStyle = lipgloss.NewStyle().
+		Foreground(theme.accent).
+		Bold(true)
+
+	infoStyle = lipgloss.NewStyle().
+		Foreground(theme.muted)
+
+	settingLabelStyle = lipgloss.NewStyle().
+		Width(20).
+		Foreground(theme.accent)
+
+	settingValueStyle = lipgloss.NewStyle().
+		Foreground(theme.value)
+
+	errorStyle = lipgloss.NewStyle().
+		Foreground(theme.err).
+		Bold(theme == noColorTheme) // stands out without a color too
+
+	warningStyle = lipgloss.NewStyle().
+		Foreground(theme.warning)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+func TestResolveTheme(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+	defer func(original bool) { darkBackground = original }(darkBackground)
+
+	darkBackground = false
+	if resolveTheme(themeAuto) != lightTheme {
+		t.Error("expected the light theme on a light terminal")
+	}
+	darkBackground = true
+	if resolveTheme(themeAuto) != darkTheme {
+		t.Error("expected the dark theme on a dark terminal")
+	}
+	if resolveTheme(themeLight) != lightTheme || resolveTheme(themeNone) != noColorTheme {
+		t.Error("expected the chosen theme")
+	}
+
+	t.Setenv("NO_COLOR", "1")
+	for _, theme := range themes {
+		if resolveTheme(theme) != noColorTheme {
+			t.Errorf("expected NO_COLOR to turn off the colors of the %s theme", theme)
+		}
+	}
+}
+
+func TestValidTheme(t *testing.T) {
+	for _, theme := range themes {
+		if !validTheme(theme) {
+			t.Errorf("expected %s to be valid", theme)
+		}
+	}
+	if validTheme("solarized") {
+		t.Error("expected an unknown theme to be refused")
+	}
+}
+
+func TestNoColorTheme(t *testing.T) {
+	defer applyTheme(darkTheme)
+
+	applyTheme(noColorTheme)
+	if _, ok := titleStyle.GetForeground().(lipgloss.NoColor); !ok {
+		t.Errorf("expected no colors, got %v", titleStyle.GetForeground())
+	}
+}
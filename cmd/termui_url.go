@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// urlCheckDelay is how long typing has to pause before the url is looked up
+const urlCheckDelay = 400 * time.Millisecond
+
+// urlCheck is what the lookup of the url of a single download found
+type urlCheck struct {
+	checking bool
+	title    string
+	pages    int
+	err      error
+	warned   bool // enter was pressed once on a url that failed the check
+}
+
+// checkUrlMsg starts the lookup of the url, unless it was edited again since
+type checkUrlMsg struct {
+	seq int
+}
+
+// urlCheckedMsg is the result of the lookup of the url
+type urlCheckedMsg struct {
+	seq   int
+	title string
+	pages int
+	err   error
+}
+
+// pasteMsg is the text read from the clipboard for Ctrl+V
+type pasteMsg struct {
+	text string
+	err  error
+}
+
+// previewBook resolves a book for showing its title and page count
+func previewBook(url string) (string, int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), resolveTimeout)
+	defer cancel()
+
+	p, err := resolveProvider("", url)
+	if err != nil {
+		return "", 0, err
+	}
+	b, err := p.Resolve(ctx, url)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return b.Title, len(b.Pages), nil
+}
+
+// pasteCmd reads the clipboard in the background
+func pasteCmd() tea.Msg {
+	text, err := readClipboard()
+	return pasteMsg{text: text, err: err}
+}
+
+// pastedUrl cleans up pasted text for the url input, which is a single line
+func pastedUrl(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// urlChanged schedules the lookup of the edited url, dropping the result of the previous one
+func (m *uiModel) urlChanged() tea.Cmd {
+	m.urlSeq++
+	m.urlCheck = urlCheck{}
+	if strings.TrimSpace(m.urlInput.Value()) == "" {
+		return nil
+	}
+
+	seq := m.urlSeq
+	return tea.Tick(urlCheckDelay, func(time.Time) tea.Msg { return checkUrlMsg{seq: seq} })
+}
+
+// updateUrlCheck handles the messages of the url lookup
+func (m uiModel) updateUrlCheck(msg tea.Msg) (uiModel, tea.Cmd) {
+	switch msg := msg.(type) {
+	case checkUrlMsg:
+		if msg.seq != m.urlSeq {
+			return m, nil
+		}
+
+		m.urlCheck.checking = true
+		url := strings.TrimSuffix(strings.TrimSpace(m.urlInput.Value()), "-i")
+		return m, func() tea.Msg {
+			title, pages, err := previewBook(strings.TrimSpace(url))
+			return urlCheckedMsg{seq: msg.seq, title: title, pages: pages, err: err}
+		}
+	case urlCheckedMsg:
+		if msg.seq == m.urlSeq {
+			m.urlCheck = urlCheck{title: msg.title, pages: msg.pages, err: msg.err}
+		}
+	case pasteMsg:
+		if msg.err != nil {
+			m.urlCheck = urlCheck{err: msg.err}
+			return m, nil
+		}
+		insertText(&m.urlInput, pastedUrl(msg.text))
+		return m, m.urlChanged()
+	}
+
+	return m, nil
+}
+
+// confirmUrl shows the summary of the download of a url that was looked up. A url that failed the lookup needs a second enter,
+// for sites that only fail the lookup.
+func (m uiModel) confirmUrl() (uiModel, tea.Cmd) {
+	switch {
+	case strings.TrimSpace(m.urlInput.Value()) == "", m.urlCheck.checking:
+		return m, nil
+	case m.urlCheck.err != nil && !m.urlCheck.warned:
+		m.urlCheck.warned = true
+		return m, nil
+	case m.urlCheck.title == "" && m.urlCheck.err == nil:
+		// typed faster than the lookup started, look it up right away
+		m.urlCheck.checking = true
+		return m.updateUrlCheck(checkUrlMsg{seq: m.urlSeq})
+	}
+
+	return m.confirmSingle()
+}
+
+// urlCheckView shows what the lookup of the url found
+func (m uiModel) urlCheckView() string {
+	check := m.urlCheck
+	switch {
+	case check.checking:
+		return infoStyle.Render("Looking up the book...")
+	case check.err != nil && check.warned:
+		return errorStyle.Render(check.err.Error()) + "\n" + selectedStyle.Render("Press Enter again to download anyway")
+	case check.err != nil:
+		return errorStyle.Render(check.err.Error())
+	case check.title != "":
+		return settingValueStyle.Render(check.title) + infoStyle.Render(fmt.Sprintf(" (%d pages)", check.pages))
+	}
+
+	return ""
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestUrlInput(t *testing.T) {
+	m := initialModel()
+	m.selected = true
+	m.downloadType = "single"
+
+	// a paste is added as one line and looked up once typing pauses
+	next, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(" https://online.fliphtml5.com/abcde/fghij/\n"), Paste: true})
+	m = next.(uiModel)
+	if m.urlInput.Value() != "https://online.fliphtml5.com/abcde/fghij/" || cmd == nil {
+		t.Fatalf("expected the pasted url and a lookup, got %q", m.urlInput.Value())
+	}
+
+	// results of lookups of earlier urls are dropped
+	next, _ = m.Update(tea.KeyMsg{Type: tea.KeyBackspace})
+	m = next.(uiModel)
+	next, _ = m.Update(urlCheckedMsg{seq: m.urlSeq - 1, title: "Old"})
+	m = next.(uiModel)
+	if m.urlCheck.title != "" {
+		t.Errorf("expected the lookup of the earlier url to be dropped, got %+v", m.urlCheck)
+	}
+
+	// a url that failed the lookup needs a second enter
+	next, _ = m.Update(urlCheckedMsg{seq: m.urlSeq, err: errors.New("not found")})
+	m = next.(uiModel)
+	next, cmd = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = next.(uiModel)
+	if cmd != nil || !m.urlCheck.warned {
+		t.Fatalf("expected a warning instead of the download, got %+v", m.urlCheck)
+	}
+	if _, cmd = m.Update(tea.KeyMsg{Type: tea.KeyEnter}); cmd == nil {
+		t.Errorf("expected the second enter to start the download")
+	}
+}
+
+func TestPastedUrl(t *testing.T) {
+	if actual := pastedUrl("\n  https://heyzine.com/flip-book/1a2b.html \r\n"); actual != "https://heyzine.com/flip-book/1a2b.html" {
+		t.Errorf("unexpected url %q", actual)
+	}
+}
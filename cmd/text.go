@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+
+	book "github.com/ygunayer/fh5dl/book"
+	"github.com/ztrue/tracerr"
+	"golang.org/x/sync/errgroup"
+)
+
+// ocrSuffix is what the text of a page image is kept as next to the image, so later runs don't read it again
+const ocrSuffix = ".ocr.txt"
+
+// markdownHeadingRegex matches the lines of a page that Markdown would take for headings
+var markdownHeadingRegex = regexp.MustCompile(`(?m)^(\s*)#`)
+
+// ocrTextPath is where the text of a page image is kept
+func ocrTextPath(imagePath string) string {
+	return trimExtension(imagePath) + ocrSuffix
+}
+
+// readTexts reads the text of the page images with --ocr-cmd, in the order of the images. Images without a page
+// number and the skipped pages, such as placeholders, are left empty. Texts are kept next to the images and reused by
+// later runs, and by the PDF to tag its pages with.
+func readTexts(ctx context.Context, args *Args, b *book.Book, imageFiles []string, pageNumbers map[string]int, skip []int) ([]string, error) {
+	task := args.reporter().Start("ocr", "Reading the text of the pages", len(imageFiles))
+	defer task.Finish()
+
+	texts := make([]string, len(imageFiles))
+	eg, egCtx := errgroup.WithContext(ctx)
+	eg.SetLimit(runtime.NumCPU())
+	for i, imageFile := range imageFiles {
+		pageNumber, ok := pageNumbers[imageFile]
+		if !ok || slices.Contains(skip, pageNumber) {
+			task.Add(1)
+			continue
+		}
+
+		eg.Go(func() error {
+			defer task.Add(1)
+
+			textPath := ocrTextPath(imageFile)
+			if text, err := os.ReadFile(textPath); err == nil {
+				texts[i] = string(text)
+				return nil
+			}
+
+			text, err := runOcrCommand(egCtx, args.OcrCmd, imageFile, pageNumber, b.Language)
+			if err != nil {
+				return err
+			}
+			texts[i] = text
+
+			return tracerr.Wrap(os.WriteFile(textPath, []byte(text), 0644))
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+
+	return texts, nil
+}
+
+// pageTexts reads the text of the page images and returns it by page number, with the texts of the images of a page
+// joined by a blank line. Pages left out by readTexts have no entry.
+func pageTexts(ctx context.Context, args *Args, b *book.Book, imageFiles []string, pageNumbers map[string]int, skip []int) (map[int]string, error) {
+	texts, err := readTexts(ctx, args, b, imageFiles, pageNumbers, skip)
+	if err != nil {
+		return nil, err
+	}
+
+	return joinByPage(imageFiles, pageNumbers, skip, texts), nil
+}
+
+// joinByPage joins what was found in the images of every page, in the order of the images, with a blank line
+// between them. Images without a page number and the skipped pages are left out.
+func joinByPage(imageFiles []string, pageNumbers map[string]int, skip []int, values []string) map[int]string {
+	pages := make(map[int]string)
+	for i, imageFile := range imageFiles {
+		pageNumber, ok := pageNumbers[imageFile]
+		if !ok || slices.Contains(skip, pageNumber) {
+			continue
+		}
+
+		if previous := pages[pageNumber]; previous != "" && values[i] != "" {
+			pages[pageNumber] = previous + "\n\n" + values[i]
+		} else {
+			pages[pageNumber] = previous + values[i]
+		}
+	}
+
+	return pages
+}
+
+// writePageSidecars keeps what was found for every page next to the first image of the page, at the path sidecar
+// gives for it. The plain PDF of --keep-original is tagged this way with the texts and descriptions of the pages of
+// the PDF, without reading or describing its own images again. Images that already have one keep it.
+func writePageSidecars(imageFiles []string, pageNumbers map[string]int, pages map[int]string, sidecar func(imagePath string) string) error {
+	written := make(map[int]bool)
+	for _, imageFile := range imageFiles {
+		pageNumber, ok := pageNumbers[imageFile]
+		if !ok || written[pageNumber] {
+			continue
+		}
+		written[pageNumber] = true
+
+		value, ok := pages[pageNumber]
+		if !ok {
+			continue
+		}
+		if _, err := os.Stat(sidecar(imageFile)); err == nil {
+			continue
+		}
+		if err := os.WriteFile(sidecar(imageFile), []byte(value), 0644); err != nil {
+			return tracerr.Wrap(err)
+		}
+	}
+
+	return nil
+}
+
+// runOcrCommand runs an external OCR tool on a page image, passed in FH5DL_FILE, and returns the text it prints.
+// The image isn't appended to the command, as tools such as tesseract take it before their other arguments.
+func runOcrCommand(ctx context.Context, command string, imagePath string, pageNumber int, language string) (string, error) {
+	cmd := shellScript(ctx, command)
+	cmd.Env = childEnv(
+		"FH5DL_FILE="+imagePath,
+		"FH5DL_PAGE="+strconv.Itoa(pageNumber),
+		"FH5DL_BOOK_LANGUAGE="+language,
+		"FH5DL_OCR_LANGUAGE="+ocrLanguage(language),
+	)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("reading the text of %s failed: %w: %s", imagePath, err, strings.TrimSpace(stderr.String()))
+	}
+
+	return normalizeText(string(output)), nil
+}
+
+// normalizeText trims the text of a page and drops the form feed tesseract ends every page with
+func normalizeText(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\f", "")
+	return strings.TrimSpace(text)
+}
+
+// writeTextExport writes the text of the pages into a txt file, with pages separated by form feeds as pdftotext
+// does, or into a Markdown file with a section for every page. Pages without a text are marked as missing.
+func writeTextExport(path string, format string, title string, pages []int, texts map[int]string) error {
+	var sb strings.Builder
+	if format == outputMd {
+		fmt.Fprintf(&sb, "# %s\n", strings.Join(strings.Fields(title), " "))
+	}
+
+	for i, pageNumber := range pages {
+		text, ok := texts[pageNumber]
+
+		if format == outputMd {
+			fmt.Fprintf(&sb, "\n## Page %d\n\n", pageNumber)
+			switch {
+			case !ok:
+				sb.WriteString("_This page is missing._\n")
+			case text == "":
+				sb.WriteString("_This page has no text._\n")
+			default:
+				// a line starting with # would otherwise end the section of the page
+				sb.WriteString(markdownHeadingRegex.ReplaceAllString(text, `$1\#`) + "\n")
+			}
+			continue
+		}
+
+		if i > 0 {
+			sb.WriteString("\f\n")
+		}
+		if !ok {
+			fmt.Fprintf(&sb, "[Page %d is missing]\n", pageNumber)
+		} else if text != "" {
+			sb.WriteString(text + "\n")
+		}
+	}
+
+	return tracerr.Wrap(os.WriteFile(path, []byte(sb.String()), 0644))
+}
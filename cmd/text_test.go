@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	book "github.com/ygunayer/fh5dl/book"
+)
+
+func TestPageTexts(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the OCR command in this test uses sh")
+	}
+
+	dir := t.TempDir()
+	first := filepath.Join(dir, "1-1.jpg")
+	second := filepath.Join(dir, "1-2.jpg")
+	placeholder := filepath.Join(dir, "2-placeholder.jpg")
+	toc := filepath.Join(dir, "toc.jpg")
+	for _, path := range []string{first, second, placeholder, toc} {
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	args := &Args{OcrCmd: `printf 'page %s of %s\f' "$FH5DL_PAGE" "$(basename "$FH5DL_FILE")"; [ "$FH5DL_OCR_LANGUAGE" = deu ]`}
+	pageNumbers := map[string]int{first: 1, second: 1, placeholder: 2}
+	texts, err := pageTexts(context.Background(), args, &book.Book{Language: "de"}, []string{toc, first, second, placeholder}, pageNumbers, []int{2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[int]string{1: "page 1 of 1-1.jpg\n\npage 1 of 1-2.jpg"}
+	if len(texts) != len(expected) || texts[1] != expected[1] {
+		t.Errorf("expected %q, got %q", expected, texts)
+	}
+
+	// the texts are reused rather than read again
+	args.OcrCmd = "false"
+	if _, err := pageTexts(context.Background(), args, &book.Book{}, []string{first}, pageNumbers, nil); err != nil {
+		t.Errorf("expected the kept text to be reused, got %v", err)
+	}
+}
+
+func TestWritePageSidecars(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "1-1.jpg")
+	firstLayer := filepath.Join(dir, "1-2.jpg")
+	second := filepath.Join(dir, "2-1.jpg")
+	if err := os.WriteFile(ocrTextPath(second), []byte("kept"), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	pageNumbers := map[string]int{first: 1, firstLayer: 1, second: 2}
+	texts := map[int]string{1: "Contents", 2: "Chapter 1"}
+	if err := writePageSidecars([]string{first, firstLayer, second}, pageNumbers, texts, ocrTextPath); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if text, err := os.ReadFile(ocrTextPath(first)); err != nil || string(text) != "Contents" {
+		t.Errorf("expected the text of the page next to its first image, got %q (%v)", text, err)
+	}
+	if _, err := os.Stat(ocrTextPath(firstLayer)); !os.IsNotExist(err) {
+		t.Errorf("expected only the first image of a page to get the text, got %v", err)
+	}
+	if text, _ := os.ReadFile(ocrTextPath(second)); string(text) != "kept" {
+		t.Errorf("expected an existing text to be kept, got %q", text)
+	}
+}
+
+func TestRunOcrCommandFailure(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the OCR command in this test uses sh")
+	}
+
+	_, err := runOcrCommand(context.Background(), "echo not installed >&2; exit 1", "1-1.jpg", 1, "")
+	if err == nil || !strings.Contains(err.Error(), "not installed") {
+		t.Errorf("expected the error output of the command, got %v", err)
+	}
+}
+
+func TestWriteTextExport(t *testing.T) {
+	texts := map[int]string{1: "Chapter one\n# not a heading", 2: ""}
+	cases := []struct {
+		format   string
+		expected string
+	}{
+		{outputTxt, "Chapter one\n# not a heading\n\f\n\f\n[Page 3 is missing]\n"},
+		{outputMd, "# My Book\n\n## Page 1\n\nChapter one\n\\# not a heading\n\n## Page 2\n\n_This page has no text._\n\n## Page 3\n\n_This page is missing._\n"},
+	}
+
+	for _, c := range cases {
+		path := filepath.Join(t.TempDir(), "book."+c.format)
+		if err := writeTextExport(path, c.format, "My  Book", []int{1, 2, 3}, texts); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if string(content) != c.expected {
+			t.Errorf("%s: expected %q, got %q", c.format, c.expected, content)
+		}
+	}
+}
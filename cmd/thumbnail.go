@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"image"
+	"path/filepath"
+
+	book "github.com/ygunayer/fh5dl/book"
+	"github.com/ygunayer/fh5dl/internal/progress"
+	"github.com/ztrue/tracerr"
+)
+
+// thumbnailFallback downloads the thumbnail of every expected page that has no downloaded image and upscales it to
+// pageSize, to stand in for the page. The thumbnails are returned as images numbered 0 of their page.
+func thumbnailFallback(ctx context.Context, args *Args, b *book.Book, downloadedImages []book.DownloadedImage, expected []int, pageSize image.Point) ([]book.DownloadedImage, error) {
+	missing := missingPages(expected, downloadedImageFiles(downloadedImages), imagePageNumbers(downloadedImages, nil))
+	if len(missing) == 0 {
+		return nil, nil
+	}
+
+	dir := ""
+	if len(downloadedImages) > 0 {
+		dir = filepath.Dir(downloadedImages[0].FullPath)
+	} else {
+		tmpdir, err := newWorkTempDir(args.WorkDir, "fh5dl-")
+		if err != nil {
+			return nil, tracerr.Wrap(err)
+		}
+		dir = tmpdir
+	}
+
+	reporter := args.reporter()
+	thumbnails := make([]book.DownloadedImage, 0)
+	for _, pageNumber := range missing {
+		thumbnail, ok := b.ThumbnailImage(pageNumber)
+		if !ok {
+			continue
+		}
+
+		result, err := thumbnail.Download(ctx, dir, args.downloadOptions())
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, tracerr.Wrap(err)
+			}
+			reporter.Logf(progress.LevelWarn, "Failed to download the thumbnail of page %d: %v", pageNumber, err)
+			continue
+		}
+
+		if pageSize.X > 0 {
+			if err := scaleImage(result.FullPath, result.FullPath, pageSize.X); err != nil {
+				return nil, err
+			}
+		}
+
+		reporter.Logf(progress.LevelWarn, "Using the thumbnail of page %d since its image couldn't be downloaded", pageNumber)
+		thumbnails = append(thumbnails, *result)
+	}
+
+	return thumbnails, nil
+}
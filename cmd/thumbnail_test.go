@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"io"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	book "github.com/ygunayer/fh5dl/book"
+)
+
+// thumbnailTransport serves a small image for every request
+type thumbnailTransport []byte
+
+func (t thumbnailTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Status:     "200 OK",
+		Header:     http.Header{"Content-Type": []string{"image/png"}},
+		Body:       io.NopCloser(bytes.NewReader(t)),
+		Request:    req,
+	}, nil
+}
+
+func TestThumbnailFallback(t *testing.T) {
+	var thumbnail bytes.Buffer
+	png.Encode(&thumbnail, image.NewRGBA(image.Rect(0, 0, 40, 60)))
+
+	transport := book.Transport
+	book.Transport = thumbnailTransport(thumbnail.Bytes())
+	defer func() { book.Transport = transport }()
+
+	dir := t.TempDir()
+	b := &book.Book{Pages: []book.Page{
+		{ImageUrls: []string{"https://example.com/1.jpg"}},
+		{ImageUrls: []string{"https://example.com/2.jpg"}, ThumbnailUrl: "https://example.com/thumb/2.jpg"},
+		{ImageUrls: []string{"https://example.com/3.jpg"}},
+	}}
+	downloaded := []book.DownloadedImage{{PageNumber: 1, ImageNumber: 1, FullPath: filepath.Join(dir, "1-1.jpg")}}
+
+	thumbnails, err := thumbnailFallback(context.Background(), &Args{}, b, downloaded, []int{1, 2, 3}, image.Pt(400, 600))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(thumbnails) != 1 || thumbnails[0].PageNumber != 2 {
+		t.Fatalf("expected the thumbnail of page 2, got %+v", thumbnails)
+	}
+
+	size, err := imageSize(thumbnails[0].FullPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if size != image.Pt(400, 600) {
+		t.Errorf("expected the thumbnail to be upscaled to 400x600, got %v", size)
+	}
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	pdfcpu_api "github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+	book "github.com/ygunayer/fh5dl/book"
+	"github.com/ygunayer/fh5dl/internal/progress"
+	"github.com/ztrue/tracerr"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// tocHeading is the heading of the generated table of contents pages
+const tocHeading = "Contents"
+
+// tocLink is a line of a table of contents page, in image pixels, and the page of the output it points to
+type tocLink struct {
+	Rect image.Rectangle
+	Page int
+}
+
+// tocPage is a rendered table of contents page
+type tocPage struct {
+	Path  string
+	Links []tocLink
+}
+
+// tocFonts are the faces used to render the table of contents
+type tocFonts struct {
+	heading font.Face
+	entry   font.Face
+}
+
+// newTocFonts sizes the fonts to the page height, so the pages look the same at any resolution
+func newTocFonts(pageHeight int) (*tocFonts, error) {
+	size := float64(pageHeight) / 50
+
+	regular, err := opentype.Parse(goregular.TTF)
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+	bold, err := opentype.Parse(gobold.TTF)
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+
+	entry, err := opentype.NewFace(regular, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+	heading, err := opentype.NewFace(bold, &opentype.FaceOptions{Size: size * 1.6, DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+
+	return &tocFonts{heading: heading, entry: entry}, nil
+}
+
+// outputPages maps the book pages to the first page of the output that shows them, counting from 1
+func outputPages(imageFiles []string, pageNumbers map[string]int) map[int]int {
+	pages := make(map[int]int)
+	for i, imageFile := range imageFiles {
+		pageNumber, ok := pageNumbers[imageFile]
+		if !ok {
+			continue
+		}
+		if _, ok := pages[pageNumber]; !ok {
+			pages[pageNumber] = i + 1
+		}
+	}
+
+	return pages
+}
+
+// imagePageNumbers maps the downloaded images, captures and popups to the book page they belong to
+func imagePageNumbers(downloadedImages []book.DownloadedImage, interactiveImages []book.InteractivePageImage) map[string]int {
+	pageNumbers := make(map[string]int)
+	for _, img := range downloadedImages {
+		pageNumbers[img.FullPath] = img.PageNumber
+	}
+	for _, img := range interactiveImages {
+		pageNumbers[img.FullPath] = img.PageNumber
+		for _, popup := range img.Popups {
+			pageNumbers[popup] = img.PageNumber
+		}
+	}
+
+	return pageNumbers
+}
+
+// renderTocPages writes the outline as table of contents images of the given size into dir, as many as it takes
+// to fit every entry. Entries are linked to the output pages in targets, which are shifted by the number of
+// table of contents pages since those go first. Entries whose page isn't in the output are listed without a link.
+func renderTocPages(entries []book.FlatOutlineEntry, targets map[int]int, size image.Point, dir string) ([]tocPage, error) {
+	fonts, err := newTocFonts(size.Y)
+	if err != nil {
+		return nil, err
+	}
+	defer fonts.heading.Close()
+	defer fonts.entry.Close()
+
+	margin := size.X / 12
+	headingHeight := fonts.heading.Metrics().Height.Ceil()
+	lineHeight := fonts.entry.Metrics().Height.Ceil() * 3 / 2
+	top := margin + headingHeight*2
+	perPage := max(1, (size.Y-top-margin)/lineHeight)
+	pageCount := (len(entries) + perPage - 1) / perPage
+
+	pages := make([]tocPage, 0, pageCount)
+	for start := 0; start < len(entries); start += perPage {
+		end := min(start+perPage, len(entries))
+		img := image.NewRGBA(image.Rectangle{Max: size})
+		draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+
+		drawText(img, fonts.heading, tocHeading, margin, margin+headingHeight)
+
+		links := make([]tocLink, 0, end-start)
+		for i, entry := range entries[start:end] {
+			baseline := top + i*lineHeight + lineHeight/2
+			number := strconv.Itoa(entry.Page)
+			numberX := size.X - margin - font.MeasureString(fonts.entry, number).Ceil()
+			titleX := margin + entry.Depth*lineHeight
+			title := fitText(fonts.entry, entry.Title, numberX-titleX-lineHeight)
+
+			drawText(img, fonts.entry, title, titleX, baseline)
+			drawText(img, fonts.entry, number, numberX, baseline)
+
+			if target, ok := targets[entry.Page]; ok {
+				links = append(links, tocLink{
+					Rect: image.Rect(titleX, baseline-lineHeight/2-lineHeight/4, size.X-margin, baseline+lineHeight/4),
+					Page: target + pageCount,
+				})
+			}
+		}
+
+		path := filepath.Join(dir, fmt.Sprintf("toc-%d.png", len(pages)+1))
+		if err := writePng(path, img); err != nil {
+			return nil, err
+		}
+
+		pages = append(pages, tocPage{Path: path, Links: links})
+	}
+
+	return pages, nil
+}
+
+func drawText(img draw.Image, face font.Face, text string, x int, baseline int) {
+	drawer := font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(color.Black),
+		Face: face,
+		Dot:  fixed.P(x, baseline),
+	}
+	drawer.DrawString(text)
+}
+
+// fitText shortens the text with an ellipsis until it is at most width pixels wide
+func fitText(face font.Face, text string, width int) string {
+	if font.MeasureString(face, text).Ceil() <= width {
+		return text
+	}
+
+	runes := []rune(text)
+	for len(runes) > 0 {
+		runes = runes[:len(runes)-1]
+		shortened := string(runes) + "…"
+		if font.MeasureString(face, shortened).Ceil() <= width {
+			return shortened
+		}
+	}
+
+	return ""
+}
+
+func writePng(path string, img image.Image) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+	defer file.Close()
+
+	return tracerr.Wrap(png.Encode(file, img))
+}
+
+// addTocLinks makes the lines of the table of contents pages, which are the first pages of the PDF, jump to
+// their page. Pages are imported at the size of their image, one point per pixel.
+func addTocLinks(pdfPath string, pages []tocPage, pageHeight int) error {
+	annotations := make(map[int][]model.AnnotationRenderer)
+	for i, page := range pages {
+		for n, link := range page.Links {
+			rect := types.NewRectangle(
+				float64(link.Rect.Min.X),
+				float64(pageHeight-link.Rect.Max.Y),
+				float64(link.Rect.Max.X),
+				float64(pageHeight-link.Rect.Min.Y),
+			)
+			dest := &model.Destination{Typ: model.DestFit, PageNr: link.Page}
+			id := fmt.Sprintf("fh5dl-toc-%d-%d", i+1, n+1)
+			annotations[i+1] = append(annotations[i+1], model.NewLinkAnnotation(*rect, nil, dest, "", id, 0, 0, model.BSSolid, nil, false))
+		}
+	}
+
+	if len(annotations) == 0 {
+		return nil
+	}
+
+	return tracerr.Wrap(pdfcpu_api.AddAnnotationsMapFile(pdfPath, "", annotations, model.NewDefaultConfiguration(), false))
+}
+
+// tableOfContents renders the table of contents pages that go before imageFiles, at the size of the first page.
+// Books without an outline get none.
+func tableOfContents(reporter progress.Reporter, b *book.Book, imageFiles []string, pageNumbers map[string]int) ([]tocPage, error) {
+	entries := b.FlatOutline()
+	if len(entries) == 0 || len(imageFiles) == 0 {
+		reporter.Logf(progress.LevelInfo, "The book has no table of contents, skipping the contents page")
+		return nil, nil
+	}
+
+	size, err := imageSize(imageFiles[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return renderTocPages(entries, outputPages(imageFiles, pageNumbers), size, filepath.Dir(imageFiles[0]))
+}
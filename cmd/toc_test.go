@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	pdfcpu_api "github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	book "github.com/ygunayer/fh5dl/book"
+	"github.com/ygunayer/fh5dl/internal/progress"
+)
+
+func TestOutputPages(t *testing.T) {
+	files := []string{"1-1.jpg", "interactive-2.png", "interactive-2-popup-01.png", "3-1.jpg", "3-2.jpg"}
+	pageNumbers := map[string]int{"1-1.jpg": 1, "interactive-2.png": 2, "interactive-2-popup-01.png": 2, "3-1.jpg": 3, "3-2.jpg": 3}
+
+	pages := outputPages(files, pageNumbers)
+	if pages[1] != 1 || pages[2] != 2 || pages[3] != 4 || len(pages) != 3 {
+		t.Errorf("unexpected output pages %v", pages)
+	}
+}
+
+func TestTableOfContentsLinks(t *testing.T) {
+	dir := t.TempDir()
+
+	files := make([]string, 0)
+	pageNumbers := make(map[string]int)
+	for i := 1; i <= 3; i++ {
+		path := filepath.Join(dir, fmt.Sprintf("%d-1.png", i))
+		file, err := os.Create(path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		png.Encode(file, image.NewRGBA(image.Rect(0, 0, 400, 600)))
+		file.Close()
+
+		files = append(files, path)
+		pageNumbers[path] = i
+	}
+
+	b := &book.Book{Outline: []book.OutlineEntry{
+		{Title: "Introduction", Page: 1},
+		{Title: "Chapter 1", Page: 2, Children: []book.OutlineEntry{{Title: "Exercises", Page: 3}}},
+		{Title: "Index", Page: 10}, // not in the output
+	}}
+
+	reporter, _ := progress.New(progress.ModePlain, progress.Options{Out: &strings.Builder{}})
+	toc, err := tableOfContents(reporter, b, files, pageNumbers)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(toc) != 1 || len(toc[0].Links) != 3 {
+		t.Fatalf("expected a single contents page with 3 links, got %+v", toc)
+	}
+	if toc[0].Links[2].Page != 4 {
+		t.Errorf("expected the third entry to link to page 4 after the contents page, got %d", toc[0].Links[2].Page)
+	}
+
+	pdfPath := filepath.Join(dir, "book.pdf")
+	if err := generatePDF(append([]string{toc[0].Path}, files...), pdfPath); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := addTocLinks(pdfPath, toc, 600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	file, err := os.Open(pdfPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer file.Close()
+
+	annotations, err := pdfcpu_api.Annotations(file, nil, model.NewDefaultConfiguration())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	links := 0
+	for _, annots := range annotations[1] {
+		links += len(annots.Map)
+	}
+	if links != 3 || len(annotations) != 1 {
+		t.Errorf("expected 3 links on the contents page, got %v", annotations)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"os"
+	"runtime"
+
+	"github.com/ztrue/tracerr"
+	"golang.org/x/sync/errgroup"
+)
+
+// transformQuality is the JPEG quality of pages changed by a transform
+const transformQuality = 92
+
+// pageTransform changes a decoded page of the book, returning nil if the page is fine as it is. Images that don't
+// belong to a page of the book get page number 0.
+type pageTransform func(img image.Image, pageNumber int) image.Image
+
+// transformedPath is where the changed version of a page is written, next to the original
+func transformedPath(imagePath string, suffix string) string {
+	return trimExtension(imagePath) + "." + suffix + ".jpg"
+}
+
+// transformPages applies the transform to every page in parallel, and writes the changed pages next to their
+// original named with the suffix. It returns the image files with the changed pages in place of the originals, and
+// how many there are.
+func transformPages(ctx context.Context, imageFiles []string, pageNumbers map[string]int, suffix string, transform pageTransform) ([]string, int, error) {
+	transformed := make([]string, len(imageFiles))
+	copy(transformed, imageFiles)
+
+	eg, _ := errgroup.WithContext(ctx)
+	eg.SetLimit(runtime.NumCPU())
+	for i, imageFile := range imageFiles {
+		i, imageFile := i, imageFile
+
+		eg.Go(func() error {
+			img, err := decodeImage(imageFile)
+			if err != nil {
+				return err
+			}
+
+			result := transform(img, pageNumbers[imageFile])
+			if result == nil {
+				return nil
+			}
+
+			outputPath := transformedPath(imageFile, suffix)
+			if err := writeJpeg(outputPath, result, transformQuality); err != nil {
+				return err
+			}
+
+			transformed[i] = outputPath
+			return nil
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return nil, 0, err
+	}
+
+	count := 0
+	for i, imageFile := range imageFiles {
+		if transformed[i] == imageFile {
+			continue
+		}
+
+		count++
+		if pageNumber, ok := pageNumbers[imageFile]; ok {
+			pageNumbers[transformed[i]] = pageNumber
+		}
+	}
+
+	return transformed, count, nil
+}
+
+func writeJpeg(path string, img image.Image, quality int) error {
+	output, err := os.Create(path)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+	defer output.Close()
+
+	if err := jpeg.Encode(output, img, &jpeg.Options{Quality: quality}); err != nil {
+		return fmt.Errorf("failed to encode %s: %w", path, err)
+	}
+
+	return tracerr.Wrap(output.Close())
+}
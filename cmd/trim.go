@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"runtime"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/sync/errgroup"
+)
+
+// trimTolerance is how far a pixel can be from pure white or black and still count as part of a border
+const trimTolerance = 24
+
+// trimNoise is the share of pixels of a border row or column that may differ, such as specks left by compression
+const trimNoise = 0.005
+
+// trimQuality is the JPEG quality of trimmed pages
+const trimQuality = 92
+
+// trimPadding is the share of the page size left around the content, so the trimmed pages don't look cramped
+const trimPadding = 0.01
+
+// margins are the widths of the uniform borders of a page, in pixels
+type margins struct {
+	top, right, bottom, left int
+}
+
+// isBorderColor tells whether a color is close enough to white, or to black with dark set
+func isBorderColor(c color.Color, dark bool) bool {
+	r, g, b, _ := c.RGBA()
+	for _, channel := range []uint32{r >> 8, g >> 8, b >> 8} {
+		if dark && channel > trimTolerance || !dark && channel < 255-trimTolerance {
+			return false
+		}
+	}
+
+	return true
+}
+
+// pageMargins measures the white or black borders of an image. Images whose corners aren't all white or all black
+// have no border.
+func pageMargins(img image.Image) margins {
+	bounds := img.Bounds()
+	corners := []image.Point{bounds.Min, {bounds.Max.X - 1, bounds.Min.Y}, {bounds.Min.X, bounds.Max.Y - 1}, bounds.Max.Sub(image.Pt(1, 1))}
+
+	for _, dark := range []bool{false, true} {
+		uniform := true
+		for _, corner := range corners {
+			uniform = uniform && isBorderColor(img.At(corner.X, corner.Y), dark)
+		}
+		if !uniform {
+			continue
+		}
+
+		isBorder := func(x0, y0, dx, dy, length int) bool {
+			allowed := int(float64(length) * trimNoise)
+			for i := 0; i < length; i++ {
+				if !isBorderColor(img.At(x0+i*dx, y0+i*dy), dark) {
+					if allowed--; allowed < 0 {
+						return false
+					}
+				}
+			}
+			return true
+		}
+
+		width, height := bounds.Dx(), bounds.Dy()
+		m := margins{}
+		for m.top < height && isBorder(bounds.Min.X, bounds.Min.Y+m.top, 1, 0, width) {
+			m.top++
+		}
+		if m.top == height {
+			// a blank page, there is nothing to trim to
+			return margins{}
+		}
+		for isBorder(bounds.Min.X, bounds.Max.Y-1-m.bottom, 1, 0, width) {
+			m.bottom++
+		}
+		for isBorder(bounds.Min.X+m.left, bounds.Min.Y, 0, 1, height) {
+			m.left++
+		}
+		for isBorder(bounds.Max.X-1-m.right, bounds.Min.Y, 0, 1, height) {
+			m.right++
+		}
+
+		return m
+	}
+
+	return margins{}
+}
+
+// sharedMargins returns the margins every page can lose, the smallest of each side, less some padding
+func sharedMargins(pageMargins []margins, size image.Point) margins {
+	shared := pageMargins[0]
+	for _, m := range pageMargins[1:] {
+		shared.top = min(shared.top, m.top)
+		shared.right = min(shared.right, m.right)
+		shared.bottom = min(shared.bottom, m.bottom)
+		shared.left = min(shared.left, m.left)
+	}
+
+	padX := int(float64(size.X) * trimPadding)
+	padY := int(float64(size.Y) * trimPadding)
+	return margins{
+		top:    max(0, shared.top-padY),
+		right:  max(0, shared.right-padX),
+		bottom: max(0, shared.bottom-padY),
+		left:   max(0, shared.left-padX),
+	}
+}
+
+// trimmedPath is where the trimmed version of a page is written, next to the original
+func trimmedPath(imagePath string) string {
+	return trimExtension(imagePath) + ".trimmed.jpg"
+}
+
+// trimPages crops the white or black borders off the pages. Pages of the same size are cropped alike, by the
+// narrowest border of each side among them, so they still have the same size afterwards and nothing of a page with
+// less border is lost. It returns the image files with the trimmed pages in place of the originals.
+func trimPages(ctx context.Context, imageFiles []string, pageNumbers map[string]int) ([]string, error) {
+	measured := make([]margins, len(imageFiles))
+	sizes := make([]image.Point, len(imageFiles))
+
+	eg, _ := errgroup.WithContext(ctx)
+	eg.SetLimit(runtime.NumCPU())
+	for i, imageFile := range imageFiles {
+		i, imageFile := i, imageFile
+
+		eg.Go(func() error {
+			img, err := decodeImage(imageFile)
+			if err != nil {
+				return err
+			}
+
+			measured[i] = pageMargins(img)
+			sizes[i] = img.Bounds().Size()
+			return nil
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+
+	groups := make(map[image.Point][]margins)
+	for i, size := range sizes {
+		groups[size] = append(groups[size], measured[i])
+	}
+	shared := make(map[image.Point]margins)
+	for size, group := range groups {
+		shared[size] = sharedMargins(group, size)
+	}
+
+	trimmed := make([]string, len(imageFiles))
+	eg, _ = errgroup.WithContext(ctx)
+	eg.SetLimit(runtime.NumCPU())
+	for i, imageFile := range imageFiles {
+		i, imageFile := i, imageFile
+		trimmed[i] = imageFile
+
+		m := shared[sizes[i]]
+		if m == (margins{}) {
+			continue
+		}
+
+		trimmed[i] = trimmedPath(imageFile)
+		eg.Go(func() error {
+			crop := image.Rect(m.left, m.top, sizes[i].X-m.right, sizes[i].Y-m.bottom)
+			return cropImage(imageFile, trimmed[i], crop)
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+
+	for i, imageFile := range imageFiles {
+		if pageNumber, ok := pageNumbers[imageFile]; ok && trimmed[i] != imageFile {
+			pageNumbers[trimmed[i]] = pageNumber
+		}
+	}
+
+	return trimmed, nil
+}
+
+// cropImage writes the given part of an image into outputPath
+func cropImage(imagePath string, outputPath string, crop image.Rectangle) error {
+	img, err := decodeImage(imagePath)
+	if err != nil {
+		return err
+	}
+
+	crop = crop.Add(img.Bounds().Min)
+	cropped := image.NewRGBA(image.Rectangle{Max: crop.Size()})
+	draw.Draw(cropped, cropped.Bounds(), img, crop.Min, draw.Src)
+
+	return writeJpeg(outputPath, cropped, trimQuality)
+}
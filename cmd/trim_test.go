@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeBorderedPage writes a white page with a gray block as its content
+func writeBorderedPage(t *testing.T, path string, size image.Point, content image.Rectangle) {
+	img := image.NewRGBA(image.Rectangle{Max: size})
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(img, content, image.NewUniform(color.Gray{Y: 0x80}), image.Point{}, draw.Src)
+
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer file.Close()
+
+	if err := png.Encode(file, img); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestPageMargins(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 100, 200))
+	draw.Draw(img, img.Bounds(), image.Black, image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(10, 20, 70, 150), image.White, image.Point{}, draw.Src)
+
+	if m := pageMargins(img); m != (margins{top: 20, right: 30, bottom: 50, left: 10}) {
+		t.Errorf("unexpected margins %+v", m)
+	}
+
+	// a photo bleeding to the edges has no border
+	draw.Draw(img, image.Rect(0, 0, 5, 5), image.NewUniform(color.Gray{Y: 0x80}), image.Point{}, draw.Src)
+	if m := pageMargins(img); m != (margins{}) {
+		t.Errorf("expected no margins, got %+v", m)
+	}
+}
+
+func TestTrimPages(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "1-1.png")
+	second := filepath.Join(dir, "2-1.png")
+	writeBorderedPage(t, first, image.Pt(200, 400), image.Rect(50, 100, 150, 300))
+	writeBorderedPage(t, second, image.Pt(200, 400), image.Rect(30, 120, 150, 300))
+
+	pageNumbers := map[string]int{first: 1, second: 2}
+	trimmed, err := trimPages(context.Background(), []string{first, second}, pageNumbers)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if trimmed[0] != trimmedPath(first) || pageNumbers[trimmed[1]] != 2 {
+		t.Fatalf("unexpected trimmed pages %v", trimmed)
+	}
+
+	// both lose the narrowest borders, 30 on the left, 100 on top and 50 on the right and bottom, less the padding
+	for _, path := range trimmed {
+		size, err := imageSize(path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if size != image.Pt(124, 208) {
+			t.Errorf("expected %s to be 124x208, got %v", path, size)
+		}
+	}
+}
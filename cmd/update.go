@@ -0,0 +1,56 @@
+package main
+
+import (
+	"path/filepath"
+	"slices"
+	"sort"
+
+	book "github.com/ygunayer/fh5dl/book"
+	"github.com/ztrue/tracerr"
+)
+
+// bookUpdate is how the book changed since its output was written
+type bookUpdate struct {
+	upToDate bool
+	changed  []int // pages whose images changed, or all pages if the earlier revision isn't known
+}
+
+// planUpdate compares the book with the metadata of its earlier output
+func planUpdate(previous *bookMetadata, b *book.Book) bookUpdate {
+	if previous == nil || previous.Revision == "" || previous.PageImages == nil {
+		return bookUpdate{}
+	}
+	if previous.Revision == b.Revision() {
+		return bookUpdate{upToDate: true}
+	}
+
+	changed := make([]int, 0)
+	for page, urls := range b.PageImageUrls() {
+		if !slices.Equal(previous.PageImages[page], urls) {
+			changed = append(changed, page)
+		}
+	}
+	sort.Ints(changed)
+
+	return bookUpdate{changed: changed}
+}
+
+// removeChangedImages deletes the images of the changed pages from the image folder of an earlier run, so only
+// those are downloaded again
+func removeChangedImages(imageOutputFolder string, images []book.PageImage, changed []int) error {
+	root, err := filepath.Abs(imageOutputFolder)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	for _, image := range images {
+		if _, ok := slices.BinarySearch(changed, image.PageNumber); !ok {
+			continue
+		}
+		if err := image.RemoveCached(root); err != nil {
+			return tracerr.Wrap(err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	pdfcpu_api "github.com/pdfcpu/pdfcpu/pkg/api"
+	book "github.com/ygunayer/fh5dl/book"
+)
+
+func TestPlanUpdate(t *testing.T) {
+	b := &book.Book{Pages: []book.Page{
+		{Number: 1, ImageUrls: []string{"a.jpg"}},
+		{Number: 2, ImageUrls: []string{"b.jpg"}},
+		{Number: 3, ImageUrls: []string{"c.jpg"}},
+	}}
+	previous := &bookMetadata{Revision: b.Revision(), PageImages: b.PageImageUrls()}
+
+	if update := planUpdate(previous, b); !update.upToDate {
+		t.Errorf("expected the unchanged book to be up to date, got %+v", update)
+	}
+	if update := planUpdate(&bookMetadata{}, b); update.upToDate || update.changed != nil {
+		t.Errorf("expected a book without an earlier revision to be written again, got %+v", update)
+	}
+
+	b.Pages[1].ImageUrls = []string{"b2.jpg"}
+	b.Pages = append(b.Pages, book.Page{Number: 4, ImageUrls: []string{"d.jpg"}})
+	update := planUpdate(previous, b)
+	if update.upToDate || !reflect.DeepEqual(update.changed, []int{2, 4}) {
+		t.Errorf("expected pages 2 and 4 to have changed, got %+v", update)
+	}
+}
+
+func TestRemoveChangedImages(t *testing.T) {
+	dir := t.TempDir()
+	images := []book.PageImage{{PageNumber: 1, ImageNumber: 1}, {PageNumber: 2, ImageNumber: 1}}
+	for _, image := range images {
+		if err := os.WriteFile(filepath.Join(dir, image.FileName("jpg")), []byte("image"), 0644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if err := removeChangedImages(dir, images, []int{2}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := images[0].CachedPath(dir); !ok {
+		t.Errorf("expected the unchanged page to be kept")
+	}
+	if _, ok := images[1].CachedPath(dir); ok {
+		t.Errorf("expected the changed page to be removed")
+	}
+}
+
+func TestUpdateReplacesPdf(t *testing.T) {
+	dir := t.TempDir()
+	images := make([]string, 3)
+	for i := range images {
+		images[i] = filepath.Join(dir, fmt.Sprintf("%03d.png", i+1))
+		writeTestPng(t, images[i], image.Pt(20, 30))
+	}
+
+	pdfPath := filepath.Join(dir, "Book.pdf")
+	if err := generatePDF(images, pdfPath); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := generateVolumes(&Args{Update: true}, images[:2], pdfPath); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	pages, err := pdfcpu_api.PageCountFile(pdfPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pages != 2 {
+		t.Errorf("expected the updated PDF to have only the 2 new pages, got %d", pages)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "Book.tmp.pdf")); !os.IsNotExist(err) {
+		t.Errorf("expected the temporary PDF to be renamed, got %v", err)
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"math"
+	"os"
+	"runtime"
+	"strings"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/sync/errgroup"
+)
+
+// upscaleQuality is the JPEG quality of upscaled images
+const upscaleQuality = 92
+
+// lanczos is the Lanczos-3 kernel, which keeps text sharper than bilinear or bicubic scaling when enlarging pages
+var lanczos = &draw.Kernel{Support: 3, At: func(t float64) float64 {
+	if t == 0 {
+		return 1
+	}
+
+	x := math.Pi * t
+	return 3 * math.Sin(x) * math.Sin(x/3) / (x * x)
+}}
+
+// upscaledPath is where the upscaled version of a page is written, next to the original
+func upscaledPath(imagePath string, command string) string {
+	if command != "" {
+		// upscalers such as realesrgan write PNG
+		return trimExtension(imagePath) + ".upscaled.png"
+	}
+
+	return trimExtension(imagePath) + ".upscaled.jpg"
+}
+
+// upscalePages upscales the pages narrower than --upscale-below before they go into the output, with the
+// --upscale-cmd command or in process otherwise. Upscaled pages are written next to their original and reused by
+// later runs. It returns the image files with the upscaled pages in place of the originals, and how many there are.
+func upscalePages(ctx context.Context, args *Args, imageFiles []string, pageNumbers map[string]int) ([]string, int, error) {
+	small := make(map[int]string)
+	for i, imageFile := range imageFiles {
+		size, err := imageSize(imageFile)
+		if err != nil {
+			return nil, 0, err
+		}
+		if size.X < args.UpscaleBelow {
+			small[i] = imageFile
+		}
+	}
+	if len(small) == 0 {
+		return imageFiles, 0, nil
+	}
+
+	task := args.reporter().Start("upscale", "Upscaling pages", len(small))
+	defer task.Finish()
+
+	eg, egCtx := errgroup.WithContext(ctx)
+	eg.SetLimit(runtime.NumCPU())
+	for _, imageFile := range small {
+		imageFile := imageFile
+
+		eg.Go(func() error {
+			defer task.Add(1)
+
+			outputPath := upscaledPath(imageFile, args.UpscaleCmd)
+			if _, err := os.Stat(outputPath); err == nil {
+				return nil
+			}
+
+			if args.UpscaleCmd != "" {
+				return runUpscaleCommand(egCtx, args.UpscaleCmd, imageFile, outputPath)
+			}
+
+			return scaleImage(imageFile, outputPath, args.UpscaleBelow)
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return nil, 0, err
+	}
+
+	upscaled := make([]string, len(imageFiles))
+	copy(upscaled, imageFiles)
+	for i, imageFile := range small {
+		outputPath := upscaledPath(imageFile, args.UpscaleCmd)
+		if pageNumber, ok := pageNumbers[imageFile]; ok {
+			pageNumbers[outputPath] = pageNumber
+		}
+		upscaled[i] = outputPath
+	}
+
+	return upscaled, len(small), nil
+}
+
+// runUpscaleCommand runs an external upscaler with the image as its last argument and the path it should write the
+// result to in FH5DL_OUTPUT
+func runUpscaleCommand(ctx context.Context, command string, imagePath string, outputPath string) error {
+	cmd := shellCommand(ctx, command, imagePath)
+	cmd.Env = childEnv("FH5DL_FILE="+imagePath, "FH5DL_OUTPUT="+outputPath)
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("upscaling %s failed: %w: %s", imagePath, err, strings.TrimSpace(string(output)))
+	}
+	if _, err := os.Stat(outputPath); err != nil {
+		return fmt.Errorf("upscaling %s didn't write %s", imagePath, outputPath)
+	}
+
+	return nil
+}
+
+// scaleImage writes the image scaled up to the given width into outputPath, keeping its aspect ratio. Images at
+// least as wide are written as they are.
+func scaleImage(imagePath string, outputPath string, width int) error {
+	img, err := decodeImage(imagePath)
+	if err != nil {
+		return err
+	}
+
+	bounds := img.Bounds()
+	if bounds.Dx() > 0 && bounds.Dx() < width {
+		scaled := image.NewRGBA(image.Rect(0, 0, width, bounds.Dy()*width/bounds.Dx()))
+		lanczos.Scale(scaled, scaled.Bounds(), img, bounds, draw.Src, nil)
+		img = scaled
+	}
+
+	return writeJpeg(outputPath, img, upscaleQuality)
+}
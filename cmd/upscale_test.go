@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func writeTestPng(t *testing.T, path string, size image.Point) {
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer file.Close()
+
+	if err := png.Encode(file, image.NewRGBA(image.Rectangle{Max: size})); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestUpscalePages(t *testing.T) {
+	dir := t.TempDir()
+	small := filepath.Join(dir, "1-1.png")
+	large := filepath.Join(dir, "2-1.png")
+	writeTestPng(t, small, image.Pt(100, 150))
+	writeTestPng(t, large, image.Pt(800, 1200))
+
+	pageNumbers := map[string]int{small: 1, large: 2}
+	imageFiles, count, err := upscalePages(context.Background(), &Args{UpscaleBelow: 400}, []string{small, large}, pageNumbers)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if count != 1 || imageFiles[1] != large || pageNumbers[imageFiles[0]] != 1 {
+		t.Fatalf("expected only the first page to be upscaled, got %v", imageFiles)
+	}
+
+	size, err := imageSize(imageFiles[0])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if size != image.Pt(400, 600) {
+		t.Errorf("expected the page to be upscaled to 400x600, got %v", size)
+	}
+}
+
+func TestUpscaleCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the upscale command in this test uses sh")
+	}
+
+	dir := t.TempDir()
+	small := filepath.Join(dir, "1-1.png")
+	writeTestPng(t, small, image.Pt(100, 150))
+
+	args := &Args{UpscaleBelow: 400, UpscaleCmd: `cp "$FH5DL_FILE" "$FH5DL_OUTPUT"; true`}
+	imageFiles, _, err := upscalePages(context.Background(), args, []string{small}, map[string]int{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if imageFiles[0] != filepath.Join(dir, "1-1.upscaled.png") {
+		t.Errorf("unexpected upscaled file %v", imageFiles)
+	}
+	if _, err := os.Stat(imageFiles[0]); err != nil {
+		t.Errorf("expected the command to write the upscaled page: %v", err)
+	}
+}
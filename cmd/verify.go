@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	pdfcpu_api "github.com/pdfcpu/pdfcpu/pkg/api"
+	book "github.com/ygunayer/fh5dl/book"
+	"github.com/ztrue/tracerr"
+)
+
+// expectedPages returns the pages of the book the output should show, those selected with --pages
+func expectedPages(b *book.Book, pages book.PageSet) []int {
+	expected := make([]int, 0, len(b.Pages))
+	for i := 1; i <= len(b.Pages); i++ {
+		if pages.Contains(i) {
+			expected = append(expected, i)
+		}
+	}
+
+	return expected
+}
+
+// missingPages returns the expected pages that none of the output images belong to
+func missingPages(expected []int, imageFiles []string, pageNumbers map[string]int) []int {
+	present := make(map[int]bool)
+	for _, imageFile := range imageFiles {
+		if pageNumber, ok := pageNumbers[imageFile]; ok {
+			present[pageNumber] = true
+		}
+	}
+
+	missing := make([]int, 0)
+	for _, pageNumber := range expected {
+		if !present[pageNumber] {
+			missing = append(missing, pageNumber)
+		}
+	}
+	sort.Ints(missing)
+
+	return missing
+}
+
+// countPdfPages returns the number of pages over every volume of a PDF
+func countPdfPages(paths []string) (int, error) {
+	total := 0
+	for _, path := range paths {
+		count, err := pdfcpu_api.PageCountFile(path)
+		if err != nil {
+			return 0, tracerr.Wrap(err)
+		}
+		total += count
+	}
+
+	return total, nil
+}
+
+// allowedMissingPages returns how many pages of the book the output may miss, or -1 for any number of them
+func (args *Args) allowedMissingPages() int {
+	if args.Strict {
+		return 0
+	}
+	if args.AllowMissingPages != nil {
+		return *args.AllowMissingPages
+	}
+
+	return -1
+}
+
+// missingPagesError tells which pages of the book didn't make it into the output
+func missingPagesError(missing []int) error {
+	return fmt.Errorf("the output is missing %d pages of the book: %v", len(missing), missing)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	book "github.com/ygunayer/fh5dl/book"
+)
+
+func TestMissingPages(t *testing.T) {
+	b := &book.Book{Pages: make([]book.Page, 6)}
+	expected := expectedPages(b, book.PageSet{{First: 2, Last: 5}})
+	if !reflect.DeepEqual(expected, []int{2, 3, 4, 5}) {
+		t.Fatalf("unexpected expected pages %v", expected)
+	}
+
+	imageFiles := []string{"2-1.jpg", "2-2.jpg", "toc-1.png", "5-1.jpg"}
+	pageNumbers := map[string]int{"2-1.jpg": 2, "2-2.jpg": 2, "5-1.jpg": 5}
+	missing := missingPages(expected, imageFiles, pageNumbers)
+	if !reflect.DeepEqual(missing, []int{3, 4}) {
+		t.Errorf("expected pages 3 and 4 to be missing, got %v", missing)
+	}
+}
+
+func TestCountPdfPages(t *testing.T) {
+	dir := t.TempDir()
+
+	imageFiles := make([]string, 0)
+	for _, name := range []string{"1-1.png", "2-1.png", "3-1.png"} {
+		imagePath := filepath.Join(dir, name)
+		file, err := os.Create(imagePath)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		png.Encode(file, image.NewRGBA(image.Rect(0, 0, 20, 30)))
+		file.Close()
+		imageFiles = append(imageFiles, imagePath)
+	}
+
+	first := filepath.Join(dir, "book 1.pdf")
+	second := filepath.Join(dir, "book 2.pdf")
+	if err := generatePDF(imageFiles[:2], first); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := generatePDF(imageFiles[2:], second); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	count, err := countPdfPages([]string{first, second})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("expected 3 pages, got %d", count)
+	}
+}
+
+func TestAllowedMissingPages(t *testing.T) {
+	two := 2
+	cases := []struct {
+		args     Args
+		expected int
+	}{
+		{Args{}, -1},
+		{Args{Strict: true}, 0},
+		{Args{AllowMissingPages: &two}, 2},
+	}
+
+	for _, c := range cases {
+		if actual := c.args.allowedMissingPages(); actual != c.expected {
+			t.Errorf("expected %d allowed missing pages for %+v, got %d", c.expected, c.args, actual)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ztrue/tracerr"
+)
+
+// volumeOverhead is the estimated size a page adds to a document besides its image
+const volumeOverhead = 1024
+
+// splitOptions decide how an output is split into volumes
+type splitOptions struct {
+	EveryPages int   // most pages per volume, 0 for no limit
+	MaxBytes   int64 // most bytes per volume, 0 for no limit
+}
+
+func (o splitOptions) enabled() bool {
+	return o.EveryPages > 0 || o.MaxBytes > 0
+}
+
+// parseSize parses sizes such as "50MB", "1.5G" or "800k". Units are powers of 1024.
+func parseSize(size string) (int64, error) {
+	trimmed := strings.ToUpper(strings.TrimSpace(size))
+	trimmed = strings.TrimSuffix(strings.TrimSuffix(trimmed, "IB"), "B")
+
+	multiplier := int64(1)
+	if trimmed != "" {
+		switch trimmed[len(trimmed)-1] {
+		case 'K':
+			multiplier = 1 << 10
+		case 'M':
+			multiplier = 1 << 20
+		case 'G':
+			multiplier = 1 << 30
+		}
+		if multiplier > 1 {
+			trimmed = trimmed[:len(trimmed)-1]
+		}
+	}
+
+	value, err := strconv.ParseFloat(strings.TrimSpace(trimmed), 64)
+	if err != nil || value <= 0 {
+		return 0, fmt.Errorf("invalid size %q, expected a size such as 50MB", size)
+	}
+
+	return int64(value * float64(multiplier)), nil
+}
+
+// splitOptions returns the volume limits set with --split-every and --split-max-size
+func (args *Args) splitOptions() (splitOptions, error) {
+	options := splitOptions{EveryPages: args.SplitEvery}
+	if args.SplitMaxSize != "" {
+		maxBytes, err := parseSize(args.SplitMaxSize)
+		if err != nil {
+			return options, err
+		}
+		options.MaxBytes = maxBytes
+	}
+
+	return options, nil
+}
+
+// volumePath returns the path of the nth volume (starting from 1) of an output, such as "Title.part01.pdf"
+func volumePath(outputPath string, n int, total int) string {
+	width := max(2, len(strconv.Itoa(total)))
+	return fmt.Sprintf("%s.part%0*d%s", trimExtension(outputPath), width, n, filepath.Ext(outputPath))
+}
+
+// existingVolumes returns the volumes of an output that are already on disk
+func existingVolumes(outputPath string) []string {
+	pattern := glob(trimExtension(outputPath)) + ".part*" + glob(filepath.Ext(outputPath))
+	matches, _ := filepath.Glob(pattern)
+
+	return matches
+}
+
+// outputExists reports whether an output was already written, either as a single file or as volumes
+func outputExists(outputPath string) bool {
+	if _, err := os.Stat(outputPath); err == nil {
+		return true
+	}
+
+	return len(existingVolumes(outputPath)) > 0
+}
+
+// removeVolumes deletes the volumes of an earlier run, which might have been split differently
+func removeVolumes(outputPath string) error {
+	for _, match := range existingVolumes(outputPath) {
+		if err := os.Remove(match); err != nil {
+			return tracerr.Wrap(err)
+		}
+	}
+
+	return nil
+}
+
+// glob escapes the characters of a path that have a special meaning in glob patterns
+func glob(path string) string {
+	replacer := strings.NewReplacer(`*`, `\*`, `?`, `\?`, `[`, `\[`)
+	return replacer.Replace(path)
+}
+
+// planVolumes groups the images into volumes by page count and estimated size. The size of a volume is
+// estimated from the sizes of its images, as the images are embedded into documents mostly as they are.
+func planVolumes(imageFiles []string, options splitOptions) [][]string {
+	volumes := make([][]string, 0)
+	current := make([]string, 0)
+	var currentBytes int64
+
+	for _, imageFile := range imageFiles {
+		var imageBytes int64 = volumeOverhead
+		if stat, err := os.Stat(imageFile); err == nil {
+			imageBytes += stat.Size()
+		}
+
+		full := options.EveryPages > 0 && len(current) >= options.EveryPages
+		tooBig := options.MaxBytes > 0 && currentBytes+imageBytes > options.MaxBytes
+		if len(current) > 0 && (full || tooBig) {
+			volumes = append(volumes, current)
+			current = make([]string, 0)
+			currentBytes = 0
+		}
+
+		current = append(current, imageFile)
+		currentBytes += imageBytes
+	}
+
+	if len(current) > 0 {
+		volumes = append(volumes, current)
+	}
+
+	return volumes
+}
+
+// generateVolumes writes the output, split into volumes if any limits are set, and returns the paths of the files written.
+// A volume that still ends up larger than the size limit is split in half until it fits or holds a single page.
+func generateVolumes(args *Args, imageFiles []string, outputPath string) ([]string, error) {
+	options, err := args.splitOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := removeVolumes(outputPath); err != nil {
+		return nil, err
+	}
+
+	if !options.enabled() || args.outputFormat() == outputStrip {
+		return []string{outputPath}, generateOutput(args, imageFiles, outputPath)
+	}
+
+	pending := planVolumes(imageFiles, options)
+	volumes := make([]string, 0, len(pending))
+	for len(pending) > 0 {
+		volume := pending[0]
+		pending = pending[1:]
+
+		// write into a temporary file first, as the final name depends on the number of volumes
+		tmpPath := fmt.Sprintf("%s.volume%d%s", trimExtension(outputPath), len(volumes)+1, filepath.Ext(outputPath))
+		if err := generateOutput(args, volume, tmpPath); err != nil {
+			return nil, err
+		}
+
+		stat, err := os.Stat(tmpPath)
+		if err != nil {
+			return nil, tracerr.Wrap(err)
+		}
+
+		if options.MaxBytes > 0 && stat.Size() > options.MaxBytes && len(volume) > 1 {
+			os.Remove(tmpPath)
+			half := len(volume) / 2
+			pending = append([][]string{volume[:half], volume[half:]}, pending...)
+			continue
+		}
+
+		volumes = append(volumes, tmpPath)
+	}
+
+	if len(volumes) == 1 {
+		return []string{outputPath}, tracerr.Wrap(os.Rename(volumes[0], outputPath))
+	}
+
+	// a single file from an earlier run would otherwise be mistaken for this output
+	os.Remove(outputPath)
+
+	paths := make([]string, 0, len(volumes))
+	for i, tmpPath := range volumes {
+		path := volumePath(outputPath, i+1, len(volumes))
+		if err := os.Rename(tmpPath, path); err != nil {
+			return nil, tracerr.Wrap(err)
+		}
+
+		paths = append(paths, path)
+	}
+
+	return paths, nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"testing"
+
+	pdfcpu_api "github.com/pdfcpu/pdfcpu/pkg/api"
+)
+
+func TestParseSize(t *testing.T) {
+	cases := map[string]int64{
+		"50MB":  50 << 20,
+		"1.5G":  3 << 29,
+		"800k":  800 << 10,
+		"2 MiB": 2 << 20,
+		"1024":  1024,
+	}
+
+	for input, expected := range cases {
+		if got, err := parseSize(input); err != nil || got != expected {
+			t.Errorf("expected %s to be %d, got %d (%v)", input, expected, got, err)
+		}
+	}
+
+	for _, input := range []string{"", "MB", "-5MB", "lots"} {
+		if _, err := parseSize(input); err == nil {
+			t.Errorf("expected an error for %q", input)
+		}
+	}
+}
+
+func TestPlanVolumes(t *testing.T) {
+	dir := t.TempDir()
+
+	files := make([]string, 0)
+	for i := 0; i < 5; i++ {
+		path := filepath.Join(dir, fmt.Sprintf("%d.jpg", i))
+		os.WriteFile(path, make([]byte, 3000), 0644)
+		files = append(files, path)
+	}
+
+	if volumes := planVolumes(files, splitOptions{EveryPages: 2}); len(volumes) != 3 || len(volumes[2]) != 1 {
+		t.Errorf("expected volumes of 2, 2 and 1 pages, got %v", volumes)
+	}
+
+	// 3000 bytes per image plus the overhead of each page, so only two fit into 10000 bytes
+	if volumes := planVolumes(files, splitOptions{MaxBytes: 10000}); len(volumes) != 3 || len(volumes[0]) != 2 {
+		t.Errorf("expected volumes of 2, 2 and 1 pages, got %v", volumes)
+	}
+}
+
+func TestVolumePath(t *testing.T) {
+	if got := volumePath("/out/Catalog.pdf", 3, 12); got != "/out/Catalog.part03.pdf" {
+		t.Errorf("unexpected volume path %s", got)
+	}
+
+	if got := volumePath("/out/Catalog.pdf", 7, 120); got != "/out/Catalog.part007.pdf" {
+		t.Errorf("unexpected volume path %s", got)
+	}
+}
+
+func TestGenerateVolumesOverwrites(t *testing.T) {
+	dir := t.TempDir()
+	images := make([]string, 3)
+	for i := range images {
+		images[i] = filepath.Join(dir, fmt.Sprintf("%d-1.png", i+1))
+		writeTestPng(t, images[i], image.Pt(20, 30))
+	}
+
+	// the PDF of an earlier run, overwritten with --on-conflict overwrite or -f
+	pdfPath := filepath.Join(dir, "Book.pdf")
+	if err := generatePDF(images, pdfPath); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	paths, err := generateVolumes(&Args{}, images[:2], pdfPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if count, err := pdfcpu_api.PageCountFile(paths[0]); err != nil || count != 2 {
+		t.Errorf("expected only the 2 new pages, got %d (%v)", count, err)
+	}
+}
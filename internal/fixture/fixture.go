@@ -0,0 +1,130 @@
+// Package fixture records the HTTP responses of a download into a folder and serves them again later, so
+// problems with a book can be reproduced offline and shared in bug reports.
+//
+// Every response is stored as two files named after a hash of the request: <hash>.json with the request URL,
+// status and headers, and <hash>.body with the raw body.
+package fixture
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/ztrue/tracerr"
+)
+
+// response is the metadata of a recorded response
+type response struct {
+	Method string      `json:"method"`
+	Url    string      `json:"url"`
+	Status int         `json:"status"`
+	Header http.Header `json:"header"`
+}
+
+// key names the files of a request
+func key(req *http.Request) string {
+	sum := sha256.Sum256([]byte(req.Method + " " + req.URL.String()))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Recorder passes requests on to Next and saves every response into Dir
+type Recorder struct {
+	Dir  string
+	Next http.RoundTripper
+}
+
+// NewRecorder creates the folder and returns a recorder saving the responses of next into it
+func NewRecorder(dir string, next http.RoundTripper) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+
+	return &Recorder{Dir: dir, Next: next}, nil
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := r.Next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+	res.Body = io.NopCloser(bytes.NewReader(body))
+
+	meta, err := json.MarshalIndent(response{Method: req.Method, Url: req.URL.String(), Status: res.StatusCode, Header: res.Header}, "", "  ")
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+
+	base := filepath.Join(r.Dir, key(req))
+	if err := os.WriteFile(base+".body", body, 0644); err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+	if err := os.WriteFile(base+".json", meta, 0644); err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+
+	return res, nil
+}
+
+// Replayer answers requests with the responses recorded in Dir, without touching the network
+type Replayer struct {
+	Dir string
+}
+
+// NewReplayer returns a replayer for a folder written by a Recorder
+func NewReplayer(dir string) (*Replayer, error) {
+	stat, err := os.Stat(dir)
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+	if !stat.IsDir() {
+		return nil, fmt.Errorf("%s is not a folder", dir)
+	}
+
+	return &Replayer{Dir: dir}, nil
+}
+
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := filepath.Join(r.Dir, key(req))
+
+	data, err := os.ReadFile(base + ".json")
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("no recorded response for %s %s", req.Method, req.URL)
+	}
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+
+	var meta response
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+
+	body, err := os.ReadFile(base + ".body")
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", meta.Status, http.StatusText(meta.Status)),
+		StatusCode:    meta.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        meta.Header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
@@ -0,0 +1,65 @@
+package fixture
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/config.js" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("X-Fixture", "yes")
+		w.Write([]byte("var htmlConfig = {};"))
+	}))
+
+	dir := t.TempDir()
+	recorder, err := NewRecorder(dir, http.DefaultTransport)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	recording := &http.Client{Transport: recorder}
+	for _, path := range []string{"/config.js", "/missing.jpg"} {
+		res, err := recording.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		io.ReadAll(res.Body)
+		res.Body.Close()
+	}
+	server.Close()
+
+	replayer, err := NewReplayer(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	replaying := &http.Client{Transport: replayer}
+
+	res, err := replaying.Get(server.URL + "/config.js")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK || string(body) != "var htmlConfig = {};" || res.Header.Get("X-Fixture") != "yes" {
+		t.Errorf("unexpected replayed response %d %q %v", res.StatusCode, body, res.Header)
+	}
+
+	res, err = replaying.Get(server.URL + "/missing.jpg")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusNotFound {
+		t.Errorf("expected the recorded 404, got %d", res.StatusCode)
+	}
+
+	if _, err := replaying.Get(server.URL + "/other.jpg"); err == nil {
+		t.Error("expected an error for a request that wasn't recorded")
+	}
+}
@@ -0,0 +1,142 @@
+// Package history keeps a list of the books downloaded on this machine, for looking back at past downloads and
+// running failed ones again.
+//
+// The history is a file of one JSON entry per line, which every process appends to. It is cut down to the newest
+// entries once it grows past a size limit.
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/ztrue/tracerr"
+)
+
+const (
+	// MaxEntries is how many entries are kept when the history is cut down
+	MaxEntries = 500
+	// maxBytes is the size the history can grow to before it is cut down
+	maxBytes = 1 << 20
+)
+
+// Entry is a download of a book
+type Entry struct {
+	Time         time.Time `json:"time"`
+	Url          string    `json:"url"`
+	BookId       string    `json:"bookId,omitempty"`
+	Title        string    `json:"title,omitempty"`
+	Status       string    `json:"status"`
+	Error        string    `json:"error,omitempty"`
+	Interactive  bool      `json:"interactive,omitempty"`
+	Pages        int       `json:"pages,omitempty"`
+	OutputFolder string    `json:"outputFolder"`
+	Output       string    `json:"output,omitempty"`
+}
+
+// mutex keeps the downloads of a batch from cutting down the history at the same time
+var mutex sync.Mutex
+
+// DefaultPath is the history file in the cache folder of the user
+func DefaultPath() (string, error) {
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		return "", tracerr.Wrap(err)
+	}
+
+	return filepath.Join(cache, "fh5dl", "history.jsonl"), nil
+}
+
+// Append adds the entry to the history
+func Append(path string, entry Entry) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	// a single write of a whole line, so lines of other processes don't end up in the middle of it
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+	_, err = file.Write(append(data, '\n'))
+	closeErr := file.Close()
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+	if closeErr != nil {
+		return tracerr.Wrap(closeErr)
+	}
+
+	if stat, err := os.Stat(path); err == nil && stat.Size() > maxBytes {
+		return compact(path)
+	}
+
+	return nil
+}
+
+// Read returns the entries of the history, newest first. A missing history has no entries, and lines that can't
+// be read are left out.
+func Read(path string) ([]Entry, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return []Entry{}, nil
+	}
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+
+	entries := make([]Entry, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), maxBytes)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+
+	return entries, nil
+}
+
+// compact cuts the history down to the newest MaxEntries entries
+func compact(path string) error {
+	entries, err := Read(path)
+	if err != nil {
+		return err
+	}
+	if len(entries) > MaxEntries {
+		entries = entries[:MaxEntries]
+	}
+
+	var buffer bytes.Buffer
+	for i := len(entries) - 1; i >= 0; i-- {
+		data, err := json.Marshal(entries[i])
+		if err != nil {
+			return tracerr.Wrap(err)
+		}
+		buffer.Write(append(data, '\n'))
+	}
+
+	if err := os.WriteFile(path+".tmp", buffer.Bytes(), 0644); err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	return tracerr.Wrap(os.Rename(path+".tmp", path))
+}
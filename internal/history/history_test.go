@@ -0,0 +1,61 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAppendAndRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fh5dl", "history.jsonl")
+
+	entries, err := Read(path)
+	if err != nil || len(entries) != 0 {
+		t.Fatalf("expected an empty history, got %v (%v)", entries, err)
+	}
+
+	started := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	for i, status := range []string{"success", "failed"} {
+		if err := Append(path, Entry{Time: started.Add(time.Duration(i) * time.Hour), Url: "abcde/fghij", Status: status, OutputFolder: "output"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	// a line cut short by a crash doesn't hide the rest
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	file.WriteString(`{"time":`)
+	file.Close()
+
+	entries, err = Read(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Status != "failed" || entries[1].Status != "success" {
+		t.Errorf("expected the newest entry first, got %+v", entries)
+	}
+}
+
+func TestCompact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+
+	// entries large enough to go past the size limit before MaxEntries of them are written
+	large := strings.Repeat("x", maxBytes/MaxEntries)
+	for i := 0; i < MaxEntries+10; i++ {
+		if err := Append(path, Entry{Url: "abcde/fghij", Error: large, Pages: i}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	entries, err := Read(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) > MaxEntries || entries[0].Pages != MaxEntries+9 {
+		t.Errorf("expected the newest %d entries, got %d starting with %d", MaxEntries, len(entries), entries[0].Pages)
+	}
+}
@@ -0,0 +1,339 @@
+// Package jobs keeps track of the batch runs of fh5dl on this machine, so that they can be listed, paused, resumed
+// and cancelled from another terminal, and take turns by priority instead of a long batch blocking the others.
+//
+// Every running job has a status file in the jobs folder, which only the job writes. Requests from other terminals
+// go into a control file next to it, which the job reads before starting each book.
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ztrue/tracerr"
+)
+
+// ErrNotFound is returned when controlling a job that isn't running
+var ErrNotFound = errors.New("no such job")
+
+// State is what a job is doing
+type State string
+
+const (
+	StateRunning State = "running"
+	StatePaused  State = "paused"
+	StateWaiting State = "waiting" // for a job with a higher priority
+)
+
+// Request is what was asked of a job from another terminal
+type Request string
+
+const (
+	RequestPause  Request = "pause"
+	RequestResume Request = "resume"
+	RequestCancel Request = "cancel"
+)
+
+const (
+	// heartbeat is how often a job writes its status, even while a book takes long
+	heartbeat = 15 * time.Second
+	// staleAfter is when the status of a job that stopped writing it is considered left over by a crashed process
+	staleAfter = time.Minute
+)
+
+// pollInterval is how often paused and waiting jobs check whether they can go on
+var pollInterval = 2 * time.Second
+
+// Status is the status file of a job
+type Status struct {
+	Id        string    `json:"id"`
+	Name      string    `json:"name"`
+	Pid       int       `json:"pid"`
+	Priority  int       `json:"priority"`
+	State     State     `json:"state"`
+	Total     int       `json:"total"`
+	Done      int       `json:"done"`
+	Current   string    `json:"current,omitempty"`
+	StartedAt time.Time `json:"startedAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Job is a job of this process. Its methods are no-ops on a nil job, so a batch can run without one.
+type Job struct {
+	dir      string
+	mutex    sync.Mutex // guards the status and its file
+	status   Status
+	finished bool
+	stop     chan struct{}
+}
+
+// DefaultDir is the jobs folder in the cache folder of the user
+func DefaultDir() (string, error) {
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		return "", tracerr.Wrap(err)
+	}
+
+	return filepath.Join(cache, "fh5dl", "jobs"), nil
+}
+
+// Start registers a job of this process in dir
+func Start(dir string, name string, priority int, total int) (*Job, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+
+	id := make([]byte, 3)
+	if _, err := rand.Read(id); err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+
+	now := time.Now()
+	job := &Job{
+		dir: dir,
+		status: Status{
+			Id:        hex.EncodeToString(id),
+			Name:      name,
+			Pid:       os.Getpid(),
+			Priority:  priority,
+			State:     StateRunning,
+			Total:     total,
+			StartedAt: now,
+			UpdatedAt: now,
+		},
+		stop: make(chan struct{}),
+	}
+	if err := job.write(); err != nil {
+		return nil, err
+	}
+
+	go func() {
+		ticker := time.NewTicker(heartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-job.stop:
+				return
+			case <-ticker.C:
+				job.write()
+			}
+		}
+	}()
+
+	return job, nil
+}
+
+// Id identifies the job for the requests of other terminals
+func (j *Job) Id() string {
+	if j == nil {
+		return ""
+	}
+
+	return j.status.Id
+}
+
+// Progress records that done of the books are finished and current is being downloaded
+func (j *Job) Progress(done int, current string) {
+	if j == nil {
+		return
+	}
+
+	j.mutex.Lock()
+	j.status.Done = done
+	j.status.Current = current
+	j.mutex.Unlock()
+	j.write()
+}
+
+// Finish removes the job once it is done
+func (j *Job) Finish() {
+	if j == nil {
+		return
+	}
+
+	close(j.stop)
+
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	j.finished = true
+	os.Remove(statusPath(j.dir, j.status.Id))
+	os.Remove(controlPath(j.dir, j.status.Id))
+}
+
+// Turn waits until the job may start its next book: while it is paused, and while a job with a higher priority is
+// running. It returns false if the job was cancelled or ctx is done, in which case no more books should be started.
+func (j *Job) Turn(ctx context.Context, logf func(format string, args ...interface{})) bool {
+	if j == nil {
+		return ctx.Err() == nil
+	}
+
+	for {
+		state := StateRunning
+		switch readRequest(j.dir, j.status.Id) {
+		case RequestCancel:
+			logf("Job %s was cancelled", j.status.Id)
+			return false
+		case RequestPause:
+			state = StatePaused
+		default:
+			if other := j.higherPriorityJob(); other != nil {
+				state = StateWaiting
+				if j.state() != StateWaiting {
+					logf("Waiting for job %s (%s) with priority %d to finish", other.Id, other.Name, other.Priority)
+				}
+			}
+		}
+
+		if state == StatePaused && j.state() != StatePaused {
+			logf("Job %s is paused, resume it with: fh5dl jobs resume %s", j.status.Id, j.status.Id)
+		}
+		j.setState(state)
+		if state == StateRunning {
+			return true
+		}
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// higherPriorityJob returns a running or waiting job with a higher priority than this one, if there is one
+func (j *Job) higherPriorityJob() *Status {
+	others, err := List(j.dir)
+	if err != nil {
+		return nil
+	}
+
+	for _, other := range others {
+		if other.Id != j.status.Id && other.Priority > j.status.Priority && other.State != StatePaused {
+			return &other
+		}
+	}
+
+	return nil
+}
+
+func (j *Job) state() State {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return j.status.State
+}
+
+func (j *Job) setState(state State) {
+	j.mutex.Lock()
+	changed := j.status.State != state
+	j.status.State = state
+	j.mutex.Unlock()
+
+	if changed {
+		j.write()
+	}
+}
+
+// write saves the status of the job, replacing the file at once so that readers never see half of it
+func (j *Job) write() error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	if j.finished {
+		return nil
+	}
+
+	j.status.UpdatedAt = time.Now()
+	data, err := json.Marshal(j.status)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	path := statusPath(j.dir, j.status.Id)
+	if err := os.WriteFile(path+".tmp", data, 0644); err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	return tracerr.Wrap(os.Rename(path+".tmp", path))
+}
+
+// List returns the running jobs in dir, highest priority first. Jobs left over by crashed processes are removed.
+func List(dir string) ([]Status, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+
+	statuses := make([]Status, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+
+		var status Status
+		if err := json.Unmarshal(data, &status); err != nil {
+			continue
+		}
+
+		if time.Since(status.UpdatedAt) > staleAfter {
+			os.Remove(path)
+			os.Remove(controlPath(dir, status.Id))
+			continue
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	sort.SliceStable(statuses, func(i, k int) bool {
+		if statuses[i].Priority != statuses[k].Priority {
+			return statuses[i].Priority > statuses[k].Priority
+		}
+		return statuses[i].StartedAt.Before(statuses[k].StartedAt)
+	})
+
+	return statuses, nil
+}
+
+// Control asks the job with the given ID to pause, resume or cancel. The job acts on it before its next book.
+func Control(dir string, id string, request Request) error {
+	if _, err := os.Stat(statusPath(dir, id)); err != nil {
+		return fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+
+	switch request {
+	case RequestResume:
+		if err := os.Remove(controlPath(dir, id)); err != nil && !os.IsNotExist(err) {
+			return tracerr.Wrap(err)
+		}
+		return nil
+	case RequestPause, RequestCancel:
+		return tracerr.Wrap(os.WriteFile(controlPath(dir, id), []byte(request), 0644))
+	}
+
+	return fmt.Errorf("unknown request %q, expected pause, resume or cancel", request)
+}
+
+func readRequest(dir string, id string) Request {
+	data, err := os.ReadFile(controlPath(dir, id))
+	if err != nil {
+		return ""
+	}
+
+	return Request(strings.TrimSpace(string(data)))
+}
+
+func statusPath(dir string, id string) string {
+	return filepath.Join(dir, id+".json")
+}
+
+func controlPath(dir string, id string) string {
+	return filepath.Join(dir, id+".control")
+}
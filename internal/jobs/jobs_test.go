@@ -0,0 +1,105 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestJobLifecycle(t *testing.T) {
+	dir := t.TempDir()
+
+	job, err := Start(dir, "books.txt", 0, 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	job.Progress(3, "abcde/fghij")
+
+	statuses, err := List(dir)
+	if err != nil || len(statuses) != 1 {
+		t.Fatalf("expected the job to be listed, got %+v, %v", statuses, err)
+	}
+	if status := statuses[0]; status.Id != job.Id() || status.Done != 3 || status.Current != "abcde/fghij" || status.State != StateRunning {
+		t.Errorf("unexpected status %+v", status)
+	}
+
+	job.Finish()
+	if statuses, _ := List(dir); len(statuses) != 0 {
+		t.Errorf("expected no jobs once finished, got %+v", statuses)
+	}
+	if err := Control(dir, job.Id(), RequestPause); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected finished jobs not to be found, got %v", err)
+	}
+}
+
+func TestTurn(t *testing.T) {
+	defer func(original time.Duration) { pollInterval = original }(pollInterval)
+	pollInterval = 10 * time.Millisecond
+	logf := func(format string, args ...interface{}) {}
+
+	dir := t.TempDir()
+	job, _ := Start(dir, "books.txt", 0, 10)
+	defer job.Finish()
+
+	if !job.Turn(context.Background(), logf) {
+		t.Fatal("expected a job on its own to go on")
+	}
+
+	// paused jobs wait until resumed
+	Control(dir, job.Id(), RequestPause)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		if statuses, _ := List(dir); len(statuses) != 1 || statuses[0].State != StatePaused {
+			t.Errorf("expected the job to be paused, got %+v", statuses)
+		}
+		Control(dir, job.Id(), RequestResume)
+	}()
+	if !job.Turn(context.Background(), logf) {
+		t.Fatal("expected the job to go on once resumed")
+	}
+
+	// jobs with a higher priority go first
+	urgent, _ := Start(dir, "urgent.txt", 5, 1)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		urgent.Finish()
+	}()
+	start := time.Now()
+	if !job.Turn(context.Background(), logf) || time.Since(start) < 50*time.Millisecond {
+		t.Fatal("expected the job to wait for the one with a higher priority")
+	}
+
+	Control(dir, job.Id(), RequestCancel)
+	if job.Turn(context.Background(), logf) {
+		t.Error("expected a cancelled job to stop")
+	}
+}
+
+func TestListRemovesStaleJobs(t *testing.T) {
+	dir := t.TempDir()
+	job, _ := Start(dir, "books.txt", 0, 10)
+	defer job.Finish()
+
+	// a job of a crashed process stops updating its status
+	old := time.Now().Add(-2 * staleAfter)
+	os.WriteFile(statusPath(dir, "dead01"), []byte(`{"id":"dead01","updatedAt":"`+old.Format(time.RFC3339)+`"}`), 0644)
+
+	statuses, _ := List(dir)
+	if len(statuses) != 1 || statuses[0].Id != job.Id() {
+		t.Errorf("expected only the live job, got %+v", statuses)
+	}
+	if _, err := os.Stat(statusPath(dir, "dead01")); !os.IsNotExist(err) {
+		t.Error("expected the stale status file to be removed")
+	}
+}
+
+func TestNilJob(t *testing.T) {
+	var job *Job
+	job.Progress(1, "x")
+	job.Finish()
+	if job.Id() != "" || !job.Turn(context.Background(), nil) {
+		t.Error("expected a nil job to do nothing")
+	}
+}
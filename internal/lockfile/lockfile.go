@@ -0,0 +1,235 @@
+package lockfile
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ztrue/tracerr"
+)
+
+// ErrLocked is returned when the lock is held by another live process
+var ErrLocked = errors.New("locked by another process")
+
+// ErrNotOwner is returned when releasing a lock that was taken over by another process
+var ErrNotOwner = errors.New("lock is held by another process")
+
+// unreadableGrace is how long a lock that can't be read is taken to be held, as its owner may still be writing it on
+// a file system without hard links
+const unreadableGrace = 5 * time.Second
+
+// Lock is an exclusive lock file held by this process
+type Lock struct {
+	path  string
+	owner owner
+}
+
+// owner is stored inside the lock file to identify the process holding it. The token tells apart the locks of a
+// single process, which takes one per book of a batch.
+type owner struct {
+	Pid       int       `json:"pid"`
+	Host      string    `json:"host"`
+	Token     string    `json:"token,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Acquire creates the lock file at path. An existing lock is taken over when it is stale, meaning
+// the process that created it on this host is gone or it is older than staleAfter.
+func Acquire(path string, staleAfter time.Duration) (*Lock, error) {
+	for attempt := 0; attempt < 3; attempt++ {
+		lock, err := create(path)
+		if err == nil {
+			return lock, nil
+		}
+
+		if !os.IsExist(err) {
+			return nil, tracerr.Wrap(err)
+		}
+
+		if err := checkStale(path, staleAfter); err != nil {
+			return nil, err
+		}
+
+		if err := takeOver(path, staleAfter); err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrLocked, path)
+}
+
+// checkStale returns ErrLocked if the lock at path is held by a live process, and nil if it is stale or gone
+func checkStale(path string, staleAfter time.Duration) error {
+	current, modTime, err := readOwner(path)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	if current == nil && !modTime.IsZero() && time.Since(modTime) < unreadableGrace {
+		return fmt.Errorf("%w: %s is being created by another process", ErrLocked, path)
+	}
+
+	if current != nil && !current.isStale(staleAfter) {
+		return fmt.Errorf("%w: %s is held by pid %d on %s since %s",
+			ErrLocked, path, current.Pid, current.Host, current.CreatedAt.Format(time.RFC1123))
+	}
+
+	return nil
+}
+
+// takeOver removes a stale lock so that it can be created again. Processes taking over the same lock take turns
+// through a second lock file, and the lock is checked again once it is their turn, so one that another process
+// has just taken over is never removed.
+func takeOver(path string, staleAfter time.Duration) error {
+	guard := path + ".takeover"
+	if err := createExclusive(guard, nil); err != nil {
+		if !os.IsExist(err) {
+			return tracerr.Wrap(err)
+		}
+
+		// a takeover only takes a moment, so an old one was left behind by a crashed process
+		if info, statErr := os.Stat(guard); statErr == nil && time.Since(info.ModTime()) > unreadableGrace {
+			os.Remove(guard)
+			return nil
+		}
+
+		return fmt.Errorf("%w: %s is being taken over by another process", ErrLocked, path)
+	}
+	defer os.Remove(guard)
+
+	if err := checkStale(path, staleAfter); err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return tracerr.Wrap(err)
+	}
+
+	return nil
+}
+
+// create writes the owner into a temporary file next to the lock and links it into place, so that other processes
+// never see a lock without its owner. The link fails like an exclusive create when the lock exists. File systems
+// without hard links fall back to creating the lock file directly.
+func create(path string) (*Lock, error) {
+	token := make([]byte, 8)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+
+	host, _ := os.Hostname()
+	lock := &Lock{path: path, owner: owner{Pid: os.Getpid(), Host: host, Token: hex.EncodeToString(token), CreatedAt: time.Now()}}
+	data, err := json.Marshal(lock.owner)
+	if err != nil {
+		return nil, err
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(temp.Name())
+
+	_, writeErr := temp.Write(data)
+	closeErr := temp.Close()
+	if err := errors.Join(writeErr, closeErr); err != nil {
+		return nil, err
+	}
+
+	err = os.Link(temp.Name(), path)
+	if err != nil && !os.IsExist(err) {
+		err = createExclusive(path, data)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return lock, nil
+}
+
+// createExclusive creates the lock file with the owner in it, failing when it exists
+func createExclusive(path string, data []byte) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	_, writeErr := file.Write(data)
+	closeErr := file.Close()
+	if err := errors.Join(writeErr, closeErr); err != nil {
+		os.Remove(path)
+		return err
+	}
+
+	return nil
+}
+
+// Release removes the lock file, unless it was taken over by another process in the meantime
+func (l *Lock) Release() error {
+	current, _, err := readOwner(l.path)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	if current == nil {
+		return nil
+	}
+
+	if current.Pid != l.owner.Pid || current.Host != l.owner.Host || current.Token != l.owner.Token {
+		return fmt.Errorf("%w: %s now belongs to pid %d on %s", ErrNotOwner, l.path, current.Pid, current.Host)
+	}
+
+	if err := os.Remove(l.path); err != nil && !os.IsNotExist(err) {
+		return tracerr.Wrap(err)
+	}
+
+	return nil
+}
+
+// readOwner reads the owner of a lock file and when the file was last written, returning a nil owner if the file
+// is gone, empty or unreadable garbage
+func readOwner(path string) (*owner, time.Time, error) {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil, time.Time{}, nil
+	}
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, time.Time{}, nil
+	}
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	var o owner
+	if err := json.Unmarshal(data, &o); err != nil {
+		// a lock being written without a hard link, or a half-written one of a crashed process
+		return nil, info.ModTime(), nil
+	}
+
+	return &o, info.ModTime(), nil
+}
+
+// isStale reports whether the lock can safely be taken over
+func (o *owner) isStale(staleAfter time.Duration) bool {
+	if staleAfter > 0 && time.Since(o.CreatedAt) > staleAfter {
+		return true
+	}
+
+	// we can only check whether the process is alive if it ran on this machine
+	host, _ := os.Hostname()
+	if o.Host != host {
+		return false
+	}
+
+	return !processAlive(o.Pid)
+}
@@ -0,0 +1,160 @@
+package lockfile
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAcquireTwice(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "book.pdf.lock")
+
+	lock, err := Acquire(path, time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := Acquire(path, time.Hour); !errors.Is(err, ErrLocked) {
+		t.Fatalf("expected ErrLocked, got %v", err)
+	}
+
+	if err := lock.Release(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	lock, err = Acquire(path, time.Hour)
+	if err != nil {
+		t.Fatalf("expected the lock to be free after release, got %v", err)
+	}
+	lock.Release()
+}
+
+func TestAcquireStale(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "book.pdf.lock")
+
+	// an old lock left behind by a crashed process
+	data, _ := json.Marshal(owner{Pid: os.Getpid(), Host: "elsewhere", CreatedAt: time.Now().Add(-48 * time.Hour)})
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	lock, err := Acquire(path, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("expected the stale lock to be taken over, got %v", err)
+	}
+	lock.Release()
+}
+
+func TestAcquireUnreadable(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "book.pdf.lock")
+
+	// a lock another process is still writing
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := Acquire(path, time.Hour); !errors.Is(err, ErrLocked) {
+		t.Fatalf("expected a lock being written to be held, got %v", err)
+	}
+
+	// a half-written lock of a crashed process
+	old := time.Now().Add(-time.Minute)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lock, err := Acquire(path, time.Hour)
+	if err != nil {
+		t.Fatalf("expected the unreadable lock to be taken over, got %v", err)
+	}
+
+	if o, _, err := readOwner(path); err != nil || o == nil || o.Pid != os.Getpid() {
+		t.Errorf("expected the lock to name this process, got %+v (%v)", o, err)
+	}
+	lock.Release()
+
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("expected no files to be left behind, got %v", entries)
+	}
+}
+
+func TestAcquireStaleConcurrently(t *testing.T) {
+	for round := 0; round < 20; round++ {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "book.pdf.lock")
+
+		data, _ := json.Marshal(owner{Pid: os.Getpid(), Host: "elsewhere", CreatedAt: time.Now().Add(-48 * time.Hour)})
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		// processes that all find the same stale lock and take it over at once
+		locks := make([]*Lock, 8)
+		errs := make([]error, len(locks))
+		var wg sync.WaitGroup
+		for i := range locks {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				locks[i], errs[i] = Acquire(path, 24*time.Hour)
+			}()
+		}
+		wg.Wait()
+
+		var held *Lock
+		for i, lock := range locks {
+			if errs[i] != nil {
+				if !errors.Is(errs[i], ErrLocked) {
+					t.Fatalf("expected ErrLocked, got %v", errs[i])
+				}
+				continue
+			}
+			if held != nil {
+				t.Fatalf("expected a single process to take over the lock in round %d", round)
+			}
+			held = lock
+		}
+		if held == nil {
+			t.Fatalf("expected a process to take over the lock in round %d", round)
+		}
+
+		if o, _, err := readOwner(path); err != nil || o == nil || o.Token != held.owner.Token {
+			t.Fatalf("expected the lock to name its holder, got %+v (%v)", o, err)
+		}
+		if err := held.Release(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+			t.Errorf("expected no files to be left behind, got %v", entries)
+		}
+	}
+}
+
+func TestReleaseTakenOver(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "book.pdf.lock")
+
+	lock, err := Acquire(path, time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// the lock was found stale and taken over by another process
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	other, err := Acquire(path, time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := lock.Release(); !errors.Is(err, ErrNotOwner) {
+		t.Fatalf("expected ErrNotOwner, got %v", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("expected the lock of the other process to be kept, got %v", err)
+	}
+	other.Release()
+}
@@ -0,0 +1,23 @@
+//go:build windows
+
+package lockfile
+
+import (
+	"os"
+)
+
+// processAlive reports whether a process with the given pid exists
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+
+	// on windows FindProcess opens a handle and fails if the process doesn't exist
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	process.Release()
+
+	return true
+}
@@ -0,0 +1,336 @@
+package progress
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/schollz/progressbar/v3"
+)
+
+// rendering modes
+const (
+	ModeBar   = "bar"   // progress bars for interactive terminals
+	ModePlain = "plain" // periodic plain-text lines for log files, cron and CI
+	ModeJSON  = "json"  // one JSON event per line for other programs
+)
+
+// plainInterval is how often plain-text progress lines are printed
+const plainInterval = 10 * time.Second
+
+// Level is the severity of a log message
+type Level string
+
+const (
+	LevelInfo  Level = "info"
+	LevelWarn  Level = "warn"
+	LevelError Level = "error"
+)
+
+// Reporter is the single place downloads, captures and PDF generation report their progress to
+type Reporter interface {
+	// Start begins a phase (such as "download" or "capture") with the given number of steps
+	Start(phase string, description string, total int) Task
+	// Logf prints a message without disturbing the progress display
+	Logf(level Level, format string, args ...interface{})
+}
+
+// Task tracks the progress of a single phase
+type Task interface {
+	Add(num int)
+	Finish()
+}
+
+// Options configures how a reporter renders its output
+type Options struct {
+	Out   io.Writer // progress and info messages
+	Err   io.Writer // warnings and errors, except in JSON mode where everything goes to Out
+	Color bool      // use color codes in progress bars
+}
+
+// New creates a reporter for the given mode
+func New(mode string, options Options) (Reporter, error) {
+	switch mode {
+	case ModeBar:
+		return &barReporter{options: options}, nil
+	case ModePlain:
+		return &plainReporter{options: options}, nil
+	case ModeJSON:
+		encoder := json.NewEncoder(options.Out)
+		return &eventReporter{handle: func(event Event) { encoder.Encode(event) }}, nil
+	}
+
+	return nil, fmt.Errorf("invalid progress mode %q, expected %s, %s or %s", mode, ModeBar, ModePlain, ModeJSON)
+}
+
+// logLine formats a log message the way the bar and plain renderers print it
+func logLine(level Level, format string, args ...interface{}) string {
+	message := fmt.Sprintf(format, args...)
+
+	switch level {
+	case LevelWarn:
+		return "WARNING: " + message
+	case LevelError:
+		return "ERROR: " + message
+	}
+
+	return message
+}
+
+// writerFor picks the output of a log message
+func (o Options) writerFor(level Level) io.Writer {
+	if level != LevelInfo && o.Err != nil {
+		return o.Err
+	}
+
+	return o.Out
+}
+
+// barReporter renders each phase as a progress bar, printing log messages above the active bar
+type barReporter struct {
+	mutex   sync.Mutex
+	options Options
+	active  *progressbar.ProgressBar
+}
+
+type barTask struct {
+	reporter *barReporter
+	bar      *progressbar.ProgressBar
+}
+
+func (r *barReporter) theme() progressbar.Theme {
+	if !r.options.Color {
+		return progressbar.Theme{Saucer: "=", SaucerHead: ">", SaucerPadding: " ", BarStart: "[", BarEnd: "]"}
+	}
+
+	return progressbar.Theme{
+		Saucer:        "[green]=[reset]",
+		SaucerHead:    "[green]>[reset]",
+		SaucerPadding: " ",
+		BarStart:      "[",
+		BarEnd:        "]",
+	}
+}
+
+func (r *barReporter) Start(phase string, description string, total int) Task {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	out := r.options.Out
+	bar := progressbar.NewOptions(total,
+		progressbar.OptionSetWriter(out),
+		progressbar.OptionSetDescription(description),
+		progressbar.OptionEnableColorCodes(r.options.Color),
+		progressbar.OptionSetTheme(r.theme()),
+		progressbar.OptionShowCount(),
+		progressbar.OptionShowIts(),
+		progressbar.OptionSetElapsedTime(true),
+		progressbar.OptionSetPredictTime(true),
+		progressbar.OptionFullWidth(),
+		progressbar.OptionThrottle(65*time.Millisecond),
+		progressbar.OptionOnCompletion(func() {
+			fmt.Fprintln(out)
+		}),
+	)
+	r.active = bar
+
+	return &barTask{reporter: r, bar: bar}
+}
+
+func (r *barReporter) Logf(level Level, format string, args ...interface{}) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	// move the bar out of the way, print the message and draw the bar again below it
+	if r.active != nil && !r.active.IsFinished() {
+		r.active.Clear()
+		fmt.Fprintln(r.options.writerFor(level), logLine(level, format, args...))
+		r.active.RenderBlank()
+		return
+	}
+
+	fmt.Fprintln(r.options.writerFor(level), logLine(level, format, args...))
+}
+
+func (t *barTask) Add(num int) {
+	t.reporter.mutex.Lock()
+	defer t.reporter.mutex.Unlock()
+
+	t.bar.Add(num)
+}
+
+func (t *barTask) Finish() {
+	t.reporter.mutex.Lock()
+	defer t.reporter.mutex.Unlock()
+
+	if !t.bar.IsFinished() {
+		t.bar.Finish()
+	}
+	if t.reporter.active == t.bar {
+		t.reporter.active = nil
+	}
+}
+
+// plainReporter prints a progress line for each phase every few seconds
+type plainReporter struct {
+	mutex   sync.Mutex
+	options Options
+}
+
+type plainTask struct {
+	reporter    *plainReporter
+	description string
+	total       int
+	current     int
+	startTime   time.Time
+	lastPrint   time.Time
+	finished    bool
+}
+
+func (r *plainReporter) Start(phase string, description string, total int) Task {
+	now := time.Now()
+	return &plainTask{reporter: r, description: description, total: total, startTime: now, lastPrint: now}
+}
+
+func (r *plainReporter) Logf(level Level, format string, args ...interface{}) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	fmt.Fprintln(r.options.writerFor(level), logLine(level, format, args...))
+}
+
+func (t *plainTask) Add(num int) {
+	t.reporter.mutex.Lock()
+	defer t.reporter.mutex.Unlock()
+
+	t.current += num
+	if t.current >= t.total || time.Since(t.lastPrint) >= plainInterval {
+		t.print()
+	}
+}
+
+func (t *plainTask) Finish() {
+	t.reporter.mutex.Lock()
+	defer t.reporter.mutex.Unlock()
+
+	if !t.finished && t.current < t.total {
+		t.print()
+	}
+	t.finished = true
+}
+
+func (t *plainTask) print() {
+	t.lastPrint = time.Now()
+	if t.current >= t.total {
+		t.finished = true
+	}
+
+	percent := 100.0
+	if t.total > 0 {
+		percent = float64(t.current) / float64(t.total) * 100
+	}
+
+	line := fmt.Sprintf("%s: %d/%d (%.0f%%)", t.description, t.current, t.total, percent)
+
+	elapsed := time.Since(t.startTime)
+	if rate := float64(t.current) / elapsed.Seconds(); t.current > 0 && rate > 0 {
+		eta := time.Duration(float64(t.total-t.current)/rate) * time.Second
+		line += fmt.Sprintf(", %.1f/s, elapsed %s, ETA %s", rate, elapsed.Round(time.Second), eta.Round(time.Second))
+	}
+
+	fmt.Fprintln(t.reporter.options.Out, line)
+}
+
+// Event is a single progress event, written as a line of JSON in JSON mode
+type Event struct {
+	Time        time.Time `json:"time"`
+	Type        string    `json:"type"` // start, progress, finish or log
+	Phase       string    `json:"phase,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Current     int       `json:"current,omitempty"`
+	Total       int       `json:"total,omitempty"`
+	Level       Level     `json:"level,omitempty"`
+	Message     string    `json:"message,omitempty"`
+}
+
+// Func receives the progress events of a reporter created with NewFunc
+type Func func(event Event)
+
+// NewFunc creates a reporter that passes every event to fn instead of printing it, for programs embedding the
+// downloader. Events are delivered one at a time, in order, so fn doesn't need to be safe for concurrent use,
+// but it should return quickly as downloads wait for it.
+func NewFunc(fn Func) Reporter {
+	return &eventReporter{handle: fn}
+}
+
+// NewChannel creates a reporter that sends every event to the channel. The channel is never closed, and
+// downloads block while it is full.
+func NewChannel(events chan<- Event) Reporter {
+	return NewFunc(func(event Event) { events <- event })
+}
+
+// eventReporter hands every event to a handler, one at a time
+type eventReporter struct {
+	mutex  sync.Mutex
+	handle Func
+}
+
+type eventTask struct {
+	reporter *eventReporter
+	event    Event
+	finished bool
+}
+
+func (r *eventReporter) emit(event Event) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.emitLocked(event)
+}
+
+// emitLocked hands an event to the handler while the mutex is held, so the events of a task are delivered in the
+// order its counts changed
+func (r *eventReporter) emitLocked(event Event) {
+	event.Time = time.Now()
+	r.handle(event)
+}
+
+func (r *eventReporter) Start(phase string, description string, total int) Task {
+	task := &eventTask{reporter: r, event: Event{Phase: phase, Description: description, Total: total}}
+
+	event := task.event
+	event.Type = "start"
+	r.emit(event)
+
+	return task
+}
+
+func (r *eventReporter) Logf(level Level, format string, args ...interface{}) {
+	r.emit(Event{Type: "log", Level: level, Message: fmt.Sprintf(format, args...)})
+}
+
+func (t *eventTask) Add(num int) {
+	t.reporter.mutex.Lock()
+	defer t.reporter.mutex.Unlock()
+
+	t.event.Current += num
+	event := t.event
+	event.Type = "progress"
+	t.reporter.emitLocked(event)
+}
+
+func (t *eventTask) Finish() {
+	t.reporter.mutex.Lock()
+	defer t.reporter.mutex.Unlock()
+
+	if t.finished {
+		return
+	}
+	t.finished = true
+
+	event := t.event
+	event.Type = "finish"
+	t.reporter.emitLocked(event)
+}
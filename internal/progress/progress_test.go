@@ -0,0 +1,130 @@
+package progress
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestJSONEvents(t *testing.T) {
+	var out bytes.Buffer
+	reporter, err := New(ModeJSON, Options{Out: &out})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	task := reporter.Start("download", "Downloading images", 2)
+	task.Add(1)
+	reporter.Logf(LevelWarn, "page %d is missing", 3)
+	task.Add(1)
+	task.Finish()
+	task.Finish()
+
+	types := make([]string, 0)
+	scanner := bufio.NewScanner(&out)
+	for scanner.Scan() {
+		var event Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("invalid event %q: %v", scanner.Text(), err)
+		}
+		types = append(types, event.Type)
+
+		if event.Type == "log" && (event.Level != LevelWarn || event.Message != "page 3 is missing") {
+			t.Errorf("unexpected log event %+v", event)
+		}
+		if event.Type == "finish" && (event.Phase != "download" || event.Current != 2 || event.Total != 2) {
+			t.Errorf("unexpected finish event %+v", event)
+		}
+	}
+
+	expected := "start,progress,log,progress,finish"
+	if got := strings.Join(types, ","); got != expected {
+		t.Errorf("expected events %s, got %s", expected, got)
+	}
+}
+
+func TestChannelEvents(t *testing.T) {
+	events := make(chan Event, 10)
+	reporter := NewChannel(events)
+
+	task := reporter.Start("capture", "Capturing pages", 1)
+	task.Add(1)
+	task.Finish()
+	reporter.Logf(LevelInfo, "done")
+
+	expected := []string{"start", "progress", "finish", "log"}
+	for _, eventType := range expected {
+		event := <-events
+		if event.Type != eventType || event.Time.IsZero() {
+			t.Errorf("expected a %s event, got %+v", eventType, event)
+		}
+	}
+
+	if len(events) != 0 {
+		t.Errorf("expected no more events, got %d", len(events))
+	}
+}
+
+func TestConcurrentAddOrder(t *testing.T) {
+	counts := make([]int, 0)
+	reporter := NewFunc(func(event Event) {
+		if event.Type == "progress" {
+			counts = append(counts, event.Current)
+		}
+	})
+
+	task := reporter.Start("download", "Downloading images", 800)
+	var wg sync.WaitGroup
+	for worker := 0; worker < 8; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				task.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	task.Finish()
+
+	if len(counts) != 800 {
+		t.Fatalf("expected 800 progress events, got %d", len(counts))
+	}
+	for i, count := range counts {
+		if count != i+1 {
+			t.Fatalf("expected progress events in increasing order, got %d after %d", count, counts[max(i-1, 0)])
+		}
+	}
+}
+
+func TestPlainLines(t *testing.T) {
+	var out, errOut bytes.Buffer
+	reporter, err := New(ModePlain, Options{Out: &out, Err: &errOut})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	task := reporter.Start("capture", "Capturing pages", 2)
+	task.Add(1)
+	reporter.Logf(LevelError, "page %d failed", 2)
+	task.Add(1)
+	task.Finish()
+
+	// intermediate progress is throttled, only the final line is printed right away
+	if got := out.String(); !strings.HasPrefix(got, "Capturing pages: 2/2 (100%)") || strings.Count(got, "\n") != 1 {
+		t.Errorf("unexpected progress output %q", got)
+	}
+
+	if got := errOut.String(); got != "ERROR: page 2 failed\n" {
+		t.Errorf("unexpected error output %q", got)
+	}
+}
+
+func TestInvalidMode(t *testing.T) {
+	if _, err := New("fancy", Options{}); err == nil {
+		t.Error("expected an error for an unknown mode")
+	}
+}
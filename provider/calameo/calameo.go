@@ -0,0 +1,138 @@
+// Package calameo is the provider for publications hosted on calameo.com
+package calameo
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	book "github.com/ygunayer/fh5dl/book"
+	"github.com/ygunayer/fh5dl/provider"
+)
+
+// maxPages bounds the search for the last page of a publication
+const maxPages = 4096
+
+// assetsUrl is where the pages of a publication are, by the key of its assets
+const assetsUrl = "https://p.calameoassets.com/%s/"
+
+var (
+	// codeRegex finds the code of a publication in its /read/ or /books/ URL
+	codeRegex = regexp.MustCompile(`^/(?:read|books)/(\w+)`)
+	// assetKeyRegex finds the key of the assets of a publication in the cover or page images the reader refers to
+	assetKeyRegex = regexp.MustCompile(`calameoassets\.com(?:\\?/)([\w-]+)(?:\\?/)`)
+)
+
+func init() {
+	provider.Register(&Provider{})
+}
+
+// Provider downloads publications from calameo.com
+type Provider struct{}
+
+func (p *Provider) Name() string {
+	return "calameo"
+}
+
+// Matches accepts URLs on calameo.com and its subdomains
+func (p *Provider) Matches(idOrUrl string) bool {
+	u, err := url.Parse(idOrUrl)
+	if err != nil || u.Host == "" {
+		return false
+	}
+
+	host := strings.ToLower(u.Hostname())
+	return host == "calameo.com" || strings.HasSuffix(host, ".calameo.com")
+}
+
+// Resolve finds the assets of the publication through its reader page, and counts its pages by probing them
+func (p *Provider) Resolve(ctx context.Context, idOrUrl string) (*book.Book, error) {
+	u, err := url.Parse(idOrUrl)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid calameo URL: %s", idOrUrl)
+	}
+	match := codeRegex.FindStringSubmatch(u.Path)
+	if match == nil {
+		return nil, fmt.Errorf("invalid calameo URL: %s, expected a /read/ or /books/ URL", idOrUrl)
+	}
+	readerUrl := fmt.Sprintf("https://www.calameo.com/read/%s", match[1])
+
+	body, err := book.FetchText(ctx, readerUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	keyMatch := assetKeyRegex.FindStringSubmatch(body)
+	if keyMatch == nil {
+		return nil, fmt.Errorf("%w in %s: no page assets in the reader", book.ErrConfigParse, readerUrl)
+	}
+	assets := fmt.Sprintf(assetsUrl, keyMatch[1])
+
+	if err := checkJpegPages(ctx, assets); err != nil {
+		return nil, err
+	}
+
+	count, err := provider.CountPages(maxPages, func(pageNumber int) (bool, error) {
+		return book.Exists(ctx, jpegPageUrl(assets, pageNumber))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	pages := make([]book.Page, 0, count)
+	for pageNumber := 1; pageNumber <= count; pageNumber++ {
+		pages = append(pages, book.Page{Number: pageNumber, ImageUrls: []string{jpegPageUrl(assets, pageNumber)}})
+	}
+
+	return &book.Book{
+		Url:   readerUrl,
+		Id:    "calameo/" + match[1],
+		Title: strings.TrimSuffix(book.HtmlTitle(body), " - Calameo"),
+		Pages: pages,
+	}, nil
+}
+
+// jpegPageUrl is the JPEG rendering of a page, the largest of the raster ones
+func jpegPageUrl(assets string, pageNumber int) string {
+	return fmt.Sprintf("%sp%d.jpg", assets, pageNumber)
+}
+
+// svgPageUrl is the vector rendering of a page, which some publications only have
+func svgPageUrl(assets string, pageNumber int) string {
+	return fmt.Sprintf("%sp%d.svgz", assets, pageNumber)
+}
+
+// checkJpegPages makes sure the publication has JPEG pages, as PDFs are made of images and SVG pages can't be
+// turned into one
+func checkJpegPages(ctx context.Context, assets string) error {
+	jpeg, err := book.Exists(ctx, jpegPageUrl(assets, 1))
+	if err != nil || jpeg {
+		return err
+	}
+
+	svg, err := book.Exists(ctx, svgPageUrl(assets, 1))
+	if err != nil {
+		return err
+	}
+	if svg {
+		return fmt.Errorf("the publication only has SVG pages, which can't be downloaded yet")
+	}
+
+	return fmt.Errorf("%w: no pages at %s", book.ErrBookNotFound, assets)
+}
+
+// Capabilities of the viewer, which can be opened on a single page
+func (p *Provider) Capabilities() provider.Capabilities {
+	return provider.Capabilities{InteractiveCapture: true}
+}
+
+func (p *Provider) Images(b *book.Book) []book.PageImage {
+	return b.FindAllImages()
+}
+
+// PageUrl points the reader to a page using the page query parameter
+func (p *Provider) PageUrl(b *book.Book, pageNumber int) string {
+	return fmt.Sprintf("%s?page=%d", b.Url, pageNumber)
+}
@@ -0,0 +1,79 @@
+package calameo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	book "github.com/ygunayer/fh5dl/book"
+)
+
+func TestMatches(t *testing.T) {
+	p := &Provider{}
+
+	if !p.Matches("https://www.calameo.com/read/0001234567890abcdef12") {
+		t.Errorf("expected calameo.com to match")
+	}
+	for _, idOrUrl := range []string{"abcde/fghij", "https://online.fliphtml5.com/abcde/fghij/", "https://notcalameo.com/read/1"} {
+		if p.Matches(idOrUrl) {
+			t.Errorf("expected %s not to match", idOrUrl)
+		}
+	}
+}
+
+// readerTransport serves a reader page and the given number of pages in the given format
+type readerTransport struct {
+	pages     int
+	extension string
+}
+
+func (r readerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	status, body := http.StatusNotFound, ""
+	if req.URL.String() == "https://www.calameo.com/read/0001234567890abcdef12" {
+		status = http.StatusOK
+		body = `<title>Annual Report 2024 - Calameo</title><meta property="og:image" content="https://i.calameoassets.com/240101-abcdef/large.jpg">`
+	}
+	for pageNumber := 1; pageNumber <= r.pages; pageNumber++ {
+		if req.URL.String() == fmt.Sprintf("https://p.calameoassets.com/240101-abcdef/p%d.%s", pageNumber, r.extension) {
+			status = http.StatusOK
+		}
+	}
+
+	return &http.Response{
+		StatusCode: status,
+		Status:     http.StatusText(status),
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Header:     make(http.Header),
+		Request:    req,
+	}, nil
+}
+
+func TestResolve(t *testing.T) {
+	defer func(original http.RoundTripper) { book.Transport = original }(book.Transport)
+	book.Transport = readerTransport{pages: 12, extension: "jpg"}
+
+	b, err := (&Provider{}).Resolve(context.Background(), "https://en.calameo.com/books/0001234567890abcdef12")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if b.Id != "calameo/0001234567890abcdef12" || b.Title != "Annual Report 2024" || len(b.Pages) != 12 {
+		t.Fatalf("unexpected book %+v", b)
+	}
+	if b.Pages[11].ImageUrls[0] != "https://p.calameoassets.com/240101-abcdef/p12.jpg" {
+		t.Errorf("unexpected page image %s", b.Pages[11].ImageUrls[0])
+	}
+
+	book.Transport = readerTransport{pages: 12, extension: "svgz"}
+	if _, err := (&Provider{}).Resolve(context.Background(), "https://www.calameo.com/read/0001234567890abcdef12"); err == nil || !strings.Contains(err.Error(), "SVG") {
+		t.Errorf("expected SVG only publications to be refused, got %v", err)
+	}
+
+	book.Transport = readerTransport{}
+	if _, err := (&Provider{}).Resolve(context.Background(), "https://www.calameo.com/read/0001234567890abcdef12"); !errors.Is(err, book.ErrBookNotFound) {
+		t.Errorf("expected a publication without pages to be not found, got %v", err)
+	}
+}
@@ -0,0 +1,56 @@
+// Package fliphtml5 is the provider for books hosted on fliphtml5.com
+package fliphtml5
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	book "github.com/ygunayer/fh5dl/book"
+	"github.com/ygunayer/fh5dl/provider"
+)
+
+func init() {
+	provider.Register(&Provider{})
+}
+
+// Provider downloads books from fliphtml5.com
+type Provider struct{}
+
+func (p *Provider) Name() string {
+	return "fliphtml5"
+}
+
+// Matches accepts book IDs such as "abcde/fghij" and URLs on fliphtml5.com
+func (p *Provider) Matches(idOrUrl string) bool {
+	if _, err := book.ParseId(idOrUrl); err != nil {
+		return false
+	}
+
+	u, err := url.Parse(idOrUrl)
+	if err != nil || u.Host == "" {
+		return true
+	}
+
+	host := strings.ToLower(u.Hostname())
+	return host == "fliphtml5.com" || strings.HasSuffix(host, ".fliphtml5.com")
+}
+
+func (p *Provider) Resolve(ctx context.Context, idOrUrl string) (*book.Book, error) {
+	return book.Get(ctx, idOrUrl)
+}
+
+// Capabilities of FlipHTML5, which every other part of fh5dl was written for
+func (p *Provider) Capabilities() provider.Capabilities {
+	return provider.Capabilities{InteractiveCapture: true, TableOfContents: true, Layers: true, Thumbnails: true}
+}
+
+func (p *Provider) Images(b *book.Book) []book.PageImage {
+	return b.FindAllImages()
+}
+
+// PageUrl points the viewer to a page using the #p= fragment
+func (p *Provider) PageUrl(b *book.Book, pageNumber int) string {
+	return fmt.Sprintf("%s#p=%d", b.Url, pageNumber)
+}
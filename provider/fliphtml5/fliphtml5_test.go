@@ -0,0 +1,19 @@
+package fliphtml5
+
+import "testing"
+
+func TestMatches(t *testing.T) {
+	p := &Provider{}
+
+	for _, idOrUrl := range []string{"abcde/fghij", "https://online.fliphtml5.com/abcde/fghij/", "https://fliphtml5.com/abcde/fghij"} {
+		if !p.Matches(idOrUrl) {
+			t.Errorf("expected %s to match", idOrUrl)
+		}
+	}
+
+	for _, idOrUrl := range []string{"https://portal.example.com/abcde/fghij", "not a book"} {
+		if p.Matches(idOrUrl) {
+			t.Errorf("expected %s not to match", idOrUrl)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+// Package flippingbook is the provider for publications made with FlippingBook, hosted on flippingbook.com or on
+// the publisher's own site
+package flippingbook
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	book "github.com/ygunayer/fh5dl/book"
+	"github.com/ygunayer/fh5dl/provider"
+)
+
+// maxPages bounds the search for the last page of a publication
+const maxPages = 4096
+
+// pageTemplates are where publications keep their page images, relative to the publication, largest first. The
+// first one page 1 is found at is used for every page.
+var pageTemplates = []string{
+	"files/assets/common/page-html5-substrates/page%04d_4.jpg",
+	"files/assets/common/page-html5-substrates/page%04d_3.jpg",
+	"files/assets/common/page-html5-substrates/page%04d_2.jpg",
+	"files/assets/common/page-html5-substrates/page%04d_1.jpg",
+	"files/assets/common/page-substrates/page%04d.jpg",
+}
+
+// onlineRegex matches publications hosted on online.flippingbook.com, whose pages are further path segments
+var onlineRegex = regexp.MustCompile(`^/view/(\d+)/`)
+
+func init() {
+	provider.Register(&Provider{})
+}
+
+// Provider downloads FlippingBook publications. Those on the publisher's own domain are used with --provider
+// flippingbook, as they can't be told apart by their URL.
+type Provider struct{}
+
+func (p *Provider) Name() string {
+	return "flippingbook"
+}
+
+// Matches accepts URLs on flippingbook.com and its subdomains
+func (p *Provider) Matches(idOrUrl string) bool {
+	u, err := url.Parse(idOrUrl)
+	if err != nil || u.Host == "" {
+		return false
+	}
+
+	host := strings.ToLower(u.Hostname())
+	return host == "flippingbook.com" || strings.HasSuffix(host, ".flippingbook.com")
+}
+
+// publicationUrl returns the root folder of the publication the URL points into, along with an ID for it
+func publicationUrl(idOrUrl string) (*url.URL, string, error) {
+	u, err := url.Parse(idOrUrl)
+	if err != nil || u.Host == "" {
+		return nil, "", fmt.Errorf("invalid FlippingBook URL: %s", idOrUrl)
+	}
+	u.Fragment = ""
+	u.RawQuery = ""
+
+	if match := onlineRegex.FindStringSubmatch(u.Path); match != nil {
+		u.Path = match[0]
+		return u, "flippingbook/" + match[1], nil
+	}
+
+	u.Path = strings.TrimSuffix(u.Path, "index.html")
+	if !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+	}
+
+	return u, "flippingbook/" + strings.Trim(u.Hostname()+strings.ReplaceAll(u.Path, "/", "_"), "_"), nil
+}
+
+// Resolve finds the page images of the publication by probing where FlippingBook keeps them
+func (p *Provider) Resolve(ctx context.Context, idOrUrl string) (*book.Book, error) {
+	root, id, err := publicationUrl(idOrUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := book.FetchText(ctx, root.String())
+	if err != nil {
+		return nil, err
+	}
+
+	template, err := findPageTemplate(ctx, root)
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := provider.CountPages(maxPages, func(pageNumber int) (bool, error) {
+		return book.Exists(ctx, pageImageUrl(root, template, pageNumber))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	pages := make([]book.Page, 0, count)
+	for pageNumber := 1; pageNumber <= count; pageNumber++ {
+		pages = append(pages, book.Page{Number: pageNumber, ImageUrls: []string{pageImageUrl(root, template, pageNumber)}})
+	}
+
+	return &book.Book{
+		Url:   root.String(),
+		Id:    id,
+		Title: book.HtmlTitle(body),
+		Pages: pages,
+	}, nil
+}
+
+func pageImageUrl(root *url.URL, template string, pageNumber int) string {
+	return root.JoinPath(fmt.Sprintf(template, pageNumber)).String()
+}
+
+// findPageTemplate returns the first of pageTemplates the publication has page 1 at
+func findPageTemplate(ctx context.Context, root *url.URL) (string, error) {
+	for _, template := range pageTemplates {
+		exists, err := book.Exists(ctx, pageImageUrl(root, template, 1))
+		if err != nil {
+			return "", err
+		}
+		if exists {
+			return template, nil
+		}
+	}
+
+	return "", fmt.Errorf("%w in %s: no page images where FlippingBook keeps them", book.ErrConfigParse, root)
+}
+
+// Capabilities of the viewer, which can be opened on a single page
+func (p *Provider) Capabilities() provider.Capabilities {
+	return provider.Capabilities{InteractiveCapture: true}
+}
+
+func (p *Provider) Images(b *book.Book) []book.PageImage {
+	return b.FindAllImages()
+}
+
+// PageUrl points the viewer to a page, which FlippingBook has as a path segment
+func (p *Provider) PageUrl(b *book.Book, pageNumber int) string {
+	return fmt.Sprintf("%s%d/", b.Url, pageNumber)
+}
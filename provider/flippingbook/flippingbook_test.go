@@ -0,0 +1,86 @@
+package flippingbook
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	book "github.com/ygunayer/fh5dl/book"
+)
+
+func TestMatches(t *testing.T) {
+	p := &Provider{}
+
+	if !p.Matches("https://online.flippingbook.com/view/123456789/") {
+		t.Errorf("expected online.flippingbook.com to match")
+	}
+	for _, idOrUrl := range []string{"abcde/fghij", "https://online.fliphtml5.com/abcde/fghij/", "https://catalog.example.com/2024/"} {
+		if p.Matches(idOrUrl) {
+			t.Errorf("expected %s not to match", idOrUrl)
+		}
+	}
+}
+
+func TestPublicationUrl(t *testing.T) {
+	cases := []struct {
+		url  string
+		root string
+		id   string
+	}{
+		{"https://online.flippingbook.com/view/123456789/12/", "https://online.flippingbook.com/view/123456789/", "flippingbook/123456789"},
+		{"https://catalog.example.com/2024/index.html#12", "https://catalog.example.com/2024/", "flippingbook/catalog.example.com_2024"},
+		{"https://catalog.example.com/2024", "https://catalog.example.com/2024/", "flippingbook/catalog.example.com_2024"},
+	}
+
+	for _, c := range cases {
+		root, id, err := publicationUrl(c.url)
+		if err != nil {
+			t.Fatalf("unexpected error for %s: %v", c.url, err)
+		}
+		if root.String() != c.root || id != c.id {
+			t.Errorf("expected %s (%s) for %s, got %s (%s)", c.root, c.id, c.url, root, id)
+		}
+	}
+}
+
+// publicationTransport serves a publication with the given number of pages at the second largest size
+type publicationTransport int
+
+func (p publicationTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	status, body := http.StatusNotFound, ""
+	if req.URL.Path == "/view/123456789/" {
+		status, body = http.StatusOK, "<title>Spring Catalog</title>"
+	}
+	for pageNumber := 1; pageNumber <= int(p); pageNumber++ {
+		if req.URL.Path == "/view/123456789/"+fmt.Sprintf(pageTemplates[1], pageNumber) {
+			status = http.StatusOK
+		}
+	}
+
+	return &http.Response{
+		StatusCode: status,
+		Status:     http.StatusText(status),
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Header:     make(http.Header),
+		Request:    req,
+	}, nil
+}
+
+func TestResolve(t *testing.T) {
+	defer func(original http.RoundTripper) { book.Transport = original }(book.Transport)
+	book.Transport = publicationTransport(37)
+
+	b, err := (&Provider{}).Resolve(context.Background(), "https://online.flippingbook.com/view/123456789/5/")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if b.Title != "Spring Catalog" || len(b.Pages) != 37 {
+		t.Fatalf("unexpected book %+v", b)
+	}
+	if expected := "https://online.flippingbook.com/view/123456789/files/assets/common/page-html5-substrates/page0037_3.jpg"; b.Pages[36].ImageUrls[0] != expected {
+		t.Errorf("expected %s, got %s", expected, b.Pages[36].ImageUrls[0])
+	}
+}
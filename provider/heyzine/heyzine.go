@@ -0,0 +1,70 @@
+// Package heyzine is the provider for flipbooks hosted on heyzine.com
+package heyzine
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	book "github.com/ygunayer/fh5dl/book"
+	"github.com/ygunayer/fh5dl/provider"
+)
+
+// pageImageRegex finds the page images the viewer lists, such as .../pages/12.jpg or .../page-12.webp, also when
+// the slashes are escaped inside JSON
+var pageImageRegex = regexp.MustCompile(`https?:(?:\\?/){2}[^"'\s<>()]+?/pages?(?:\\?/|[_-])?(\d+)\.(?:jpe?g|png|webp)`)
+
+func init() {
+	provider.Register(&Provider{})
+}
+
+// Provider downloads flipbooks from heyzine.com, including the ones on custom subdomains of it
+type Provider struct{}
+
+func (p *Provider) Name() string {
+	return "heyzine"
+}
+
+// Matches accepts URLs on heyzine.com and its subdomains
+func (p *Provider) Matches(idOrUrl string) bool {
+	return provider.MatchesDomain(idOrUrl, "heyzine.com")
+}
+
+// Resolve reads the page images out of the viewer of the flipbook
+func (p *Provider) Resolve(ctx context.Context, idOrUrl string) (*book.Book, error) {
+	u, err := url.Parse(idOrUrl)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid heyzine URL: %s", idOrUrl)
+	}
+	u.Fragment = ""
+	u.RawQuery = ""
+
+	body, pages, err := provider.ScrapeViewer(ctx, u.String(), pageImageRegex, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &book.Book{
+		Url:   u.String(),
+		Id:    "heyzine/" + strings.TrimSuffix(path.Base(strings.TrimSuffix(u.Path, "/")), ".html"),
+		Title: book.HtmlTitle(body),
+		Pages: pages,
+	}, nil
+}
+
+// Capabilities of the viewer, which can be opened on a single page
+func (p *Provider) Capabilities() provider.Capabilities {
+	return provider.Capabilities{InteractiveCapture: true}
+}
+
+func (p *Provider) Images(b *book.Book) []book.PageImage {
+	return b.FindAllImages()
+}
+
+// PageUrl points the viewer to a page using the #page/ fragment
+func (p *Provider) PageUrl(b *book.Book, pageNumber int) string {
+	return fmt.Sprintf("%s#page/%d", b.Url, pageNumber)
+}
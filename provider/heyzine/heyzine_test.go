@@ -0,0 +1,62 @@
+package heyzine
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	book "github.com/ygunayer/fh5dl/book"
+	"github.com/ygunayer/fh5dl/provider"
+	"github.com/ygunayer/fh5dl/provider/providertest"
+)
+
+func TestMatches(t *testing.T) {
+	p := &Provider{}
+
+	for _, idOrUrl := range []string{"https://heyzine.com/flip-book/1a2b3c4d5e.html", "https://mydept.heyzine.com/catalog"} {
+		if !p.Matches(idOrUrl) {
+			t.Errorf("expected %s to match", idOrUrl)
+		}
+	}
+
+	for _, idOrUrl := range []string{"abcde/fghij", "https://online.fliphtml5.com/abcde/fghij/", "https://notheyzine.com/x"} {
+		if p.Matches(idOrUrl) {
+			t.Errorf("expected %s not to match", idOrUrl)
+		}
+	}
+}
+
+func TestParsePages(t *testing.T) {
+	body := `<img src="https://cdnc.heyzine.com/flip-book/cover/1a2b.jpg">
+<script>var pages = ["https:\/\/cdnc.heyzine.com\/files\/1a2b\/pages\/2.jpg", "https:\/\/cdnc.heyzine.com\/files\/1a2b\/pages\/1.jpg",
+"https:\/\/cdnc.heyzine.com\/files\/1a2b\/pages\/10.jpg", "https:\/\/cdnc.heyzine.com\/files\/1a2b\/pages\/2.jpg"];</script>`
+
+	pages := provider.ScrapePages(body, pageImageRegex, nil)
+	if len(pages) != 10 {
+		t.Fatalf("expected 10 pages, got %+v", pages)
+	}
+	if pages[0].ImageUrls[0] != "https://cdnc.heyzine.com/files/1a2b/pages/1.jpg" || pages[9].Number != 10 || !strings.HasSuffix(pages[9].ImageUrls[0], "/10.jpg") {
+		t.Errorf("expected the pages in order, got %+v", pages)
+	}
+	if missing := provider.MissingPages(pages); len(missing) != 7 || missing[0] != 3 {
+		t.Errorf("expected the pages without an image to be kept as missing, got %v", missing)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	defer func(original http.RoundTripper) { book.Transport = original }(book.Transport)
+	book.Transport = providertest.ViewerTransport(`<meta property="og:title" content="Course Catalog &amp; Guide">
+"https://cdnc.heyzine.com/files/1a2b/pages/1.jpg"`)
+
+	b, err := (&Provider{}).Resolve(context.Background(), "https://heyzine.com/flip-book/1a2b3c4d5e.html#page/3")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if b.Id != "heyzine/1a2b3c4d5e" || b.Title != "Course Catalog & Guide" || len(b.Pages) != 1 {
+		t.Errorf("unexpected book %+v", b)
+	}
+	if b.Url != "https://heyzine.com/flip-book/1a2b3c4d5e.html" {
+		t.Errorf("expected the fragment to be dropped, got %s", b.Url)
+	}
+}
@@ -0,0 +1,78 @@
+// Package joomag is the provider for magazines hosted on joomag.com
+package joomag
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	book "github.com/ygunayer/fh5dl/book"
+	"github.com/ygunayer/fh5dl/provider"
+)
+
+var (
+	// magazineIdRegex finds the numeric ID of a magazine, the last segment of its viewer URL
+	magazineIdRegex = regexp.MustCompile(`/(\d{6,})/?$`)
+	// pageImageRegex finds the page images in the magazine resources the viewer refers to, such as
+	// .../res_mag/0/12/345/6789/pages/page_3.jpg, also when the slashes are escaped inside JSON
+	pageImageRegex = regexp.MustCompile(`https?:(?:\\?/){2}[^"'\s<>()]*?joomag\.com(?:\\?/)res_mag(?:\\?/)[^"'\s<>()]+?(?:\\?/)(?:page|pg|p)?[_-]?(\d+)\.(?:jpe?g|png|webp)`)
+)
+
+func init() {
+	provider.Register(&Provider{})
+}
+
+// Provider downloads magazines from joomag.com
+type Provider struct{}
+
+func (p *Provider) Name() string {
+	return "joomag"
+}
+
+// Matches accepts URLs on joomag.com and its subdomains, such as viewer.joomag.com
+func (p *Provider) Matches(idOrUrl string) bool {
+	return provider.MatchesDomain(idOrUrl, "joomag.com")
+}
+
+// Resolve reads the page images out of the viewer of the magazine
+func (p *Provider) Resolve(ctx context.Context, idOrUrl string) (*book.Book, error) {
+	u, err := url.Parse(idOrUrl)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid joomag URL: %s", idOrUrl)
+	}
+	u.Fragment = ""
+	u.RawQuery = ""
+
+	match := magazineIdRegex.FindStringSubmatch(u.Path)
+	if match == nil {
+		return nil, fmt.Errorf("invalid joomag URL: %s, expected the viewer URL of a magazine", idOrUrl)
+	}
+
+	body, pages, err := provider.ScrapeViewer(ctx, u.String(), pageImageRegex, provider.SkipThumbnails)
+	if err != nil {
+		return nil, err
+	}
+
+	return &book.Book{
+		Url:   u.String(),
+		Id:    "joomag/" + match[1],
+		Title: strings.TrimSuffix(book.HtmlTitle(body), " - Joomag"),
+		Pages: pages,
+	}, nil
+}
+
+// Capabilities of the viewer, which can be opened on a single page
+func (p *Provider) Capabilities() provider.Capabilities {
+	return provider.Capabilities{InteractiveCapture: true}
+}
+
+func (p *Provider) Images(b *book.Book) []book.PageImage {
+	return b.FindAllImages()
+}
+
+// PageUrl points the viewer to a page using the page query parameter
+func (p *Provider) PageUrl(b *book.Book, pageNumber int) string {
+	return fmt.Sprintf("%s?page=%d", b.Url, pageNumber)
+}
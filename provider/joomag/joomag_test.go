@@ -0,0 +1,59 @@
+package joomag
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	book "github.com/ygunayer/fh5dl/book"
+	"github.com/ygunayer/fh5dl/provider"
+	"github.com/ygunayer/fh5dl/provider/providertest"
+)
+
+func TestMatches(t *testing.T) {
+	p := &Provider{}
+
+	for _, idOrUrl := range []string{"https://viewer.joomag.com/city-news-may-2024/0123456001715000000", "https://www.joomag.com/magazine/city-news/0123456001715000000"} {
+		if !p.Matches(idOrUrl) {
+			t.Errorf("expected %s to match", idOrUrl)
+		}
+	}
+
+	for _, idOrUrl := range []string{"abcde/fghij", "https://www.calameo.com/read/000123", "https://notjoomag.com/x"} {
+		if p.Matches(idOrUrl) {
+			t.Errorf("expected %s not to match", idOrUrl)
+		}
+	}
+}
+
+func TestParsePages(t *testing.T) {
+	body := `{"pages": [{"thumb": "https:\/\/s1.joomag.com\/res_mag\/0\/12\/345\/6789\/thumbs\/page_1.jpg",
+"image": "https:\/\/s1.joomag.com\/res_mag\/0\/12\/345\/6789\/pages\/page_1.jpg"},
+{"image": "https:\/\/s1.joomag.com\/res_mag\/0\/12\/345\/6789\/pages\/page_2.jpg"}]}`
+
+	pages := provider.ScrapePages(body, pageImageRegex, provider.SkipThumbnails)
+	if len(pages) != 2 {
+		t.Fatalf("expected 2 pages, got %+v", pages)
+	}
+	if pages[0].ImageUrls[0] != "https://s1.joomag.com/res_mag/0/12/345/6789/pages/page_1.jpg" {
+		t.Errorf("expected the full size image of the first page, got %+v", pages)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	defer func(original http.RoundTripper) { book.Transport = original }(book.Transport)
+	book.Transport = providertest.ViewerTransport(`<title>City News May 2024 - Joomag</title>
+"https://s1.joomag.com/res_mag/0/12/345/6789/pages/page_1.jpg"`)
+
+	b, err := (&Provider{}).Resolve(context.Background(), "https://viewer.joomag.com/city-news-may-2024/0123456001715000000?short")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if b.Id != "joomag/0123456001715000000" || b.Title != "City News May 2024" || len(b.Pages) != 1 {
+		t.Errorf("unexpected book %+v", b)
+	}
+
+	if _, err := (&Provider{}).Resolve(context.Background(), "https://www.joomag.com/en/"); err == nil {
+		t.Error("expected an error for a URL without a magazine")
+	}
+}
@@ -0,0 +1,35 @@
+package provider
+
+// CountPages finds the last page of a book that exists, for platforms whose page count can only be found by probing
+// the page images. It doubles the page number until a page is missing and then halves the gap, in a logarithmic
+// number of checks, and never counts more than maxPages.
+func CountPages(maxPages int, exists func(pageNumber int) (bool, error)) (int, error) {
+	last := 1
+	for last*2 <= maxPages {
+		ok, err := exists(last * 2)
+		if err != nil {
+			return 0, err
+		}
+		if !ok {
+			break
+		}
+		last *= 2
+	}
+
+	// the last page is in [last, missing)
+	missing := min(last*2, maxPages+1)
+	for missing-last > 1 {
+		middle := (last + missing) / 2
+		ok, err := exists(middle)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			last = middle
+		} else {
+			missing = middle
+		}
+	}
+
+	return last, nil
+}
@@ -0,0 +1,19 @@
+package provider
+
+import "testing"
+
+func TestCountPages(t *testing.T) {
+	for _, pages := range []int{1, 2, 7, 64, 100, 4096} {
+		checks := 0
+		count, err := CountPages(4096, func(pageNumber int) (bool, error) {
+			checks++
+			return pageNumber <= pages, nil
+		})
+		if err != nil || count != pages {
+			t.Errorf("expected %d pages, got %d (%v)", pages, count, err)
+		}
+		if checks > 26 {
+			t.Errorf("expected a logarithmic number of checks for %d pages, got %d", pages, checks)
+		}
+	}
+}
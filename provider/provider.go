@@ -0,0 +1,120 @@
+// Package provider lets fh5dl download from flipbook platforms other than FlipHTML5.
+//
+// A provider is a package that implements Provider and registers itself from an init function:
+//
+//	func init() {
+//		provider.Register(&myPortal{})
+//	}
+//
+// Providers are enabled by importing them for their side effects in cmd/providers.go, or in a
+// separate file of package main behind a build tag (e.g. //go:build provider_myportal) so that
+// private providers, such as ones for school portals, can be kept out of regular builds. This
+// package and the book package are public, so providers can also be kept in other modules.
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	book "github.com/ygunayer/fh5dl/book"
+)
+
+// Provider knows how to download books from a single flipbook platform
+type Provider interface {
+	// Name is a short identifier of the platform, such as "fliphtml5"
+	Name() string
+	// Matches reports whether the ID or URL belongs to this platform
+	Matches(idOrUrl string) bool
+	// Resolve fetches the information of a book, including the images of its pages
+	Resolve(ctx context.Context, idOrUrl string) (*book.Book, error)
+	// Images returns the images to download for a book, in the order they appear in the PDF
+	Images(b *book.Book) []book.PageImage
+	// PageUrl returns the URL of a single page in the platform's viewer, used for interactive captures
+	PageUrl(b *book.Book, pageNumber int) string
+}
+
+// Capabilities are the features of a platform beyond downloading the images of its pages, which decide the flags
+// that make sense for its books
+type Capabilities struct {
+	InteractiveCapture bool `json:"interactiveCapture"` // pages can be opened one by one in the viewer, for -i
+	TableOfContents    bool `json:"tableOfContents"`    // books can have a table of contents, for bookmarks and --toc-page
+	Layers             bool `json:"layers"`             // pages can have links and notes on top of their image
+	Thumbnails         bool `json:"thumbnails"`         // pages also come in a smaller size, for --thumbnail-fallback
+}
+
+// Describer is implemented by providers that support some of the Capabilities
+type Describer interface {
+	Capabilities() Capabilities
+}
+
+// CapabilitiesOf returns the capabilities of a provider, none if it doesn't describe them
+func CapabilitiesOf(p Provider) Capabilities {
+	if describer, ok := p.(Describer); ok {
+		return describer.Capabilities()
+	}
+
+	return Capabilities{}
+}
+
+var (
+	mutex     sync.RWMutex
+	providers = make([]Provider, 0)
+)
+
+// Register adds a provider to the registry. It panics if a provider with the same name is already registered.
+func Register(p Provider) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	for _, existing := range providers {
+		if existing.Name() == p.Name() {
+			panic(fmt.Sprintf("provider %s is already registered", p.Name()))
+		}
+	}
+
+	providers = append(providers, p)
+}
+
+// For returns the first registered provider that matches the ID or URL
+func For(idOrUrl string) (Provider, error) {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	for _, p := range providers {
+		if p.Matches(idOrUrl) {
+			return p, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no provider supports %s", idOrUrl)
+}
+
+// Get returns the provider with the given name
+func Get(name string) (Provider, bool) {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	for _, p := range providers {
+		if p.Name() == name {
+			return p, true
+		}
+	}
+
+	return nil, false
+}
+
+// Names returns the names of every registered provider in alphabetical order
+func Names() []string {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	names := make([]string, 0, len(providers))
+	for _, p := range providers {
+		names = append(names, p.Name())
+	}
+	sort.Strings(names)
+
+	return names
+}
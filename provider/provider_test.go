@@ -0,0 +1,73 @@
+package provider
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	book "github.com/ygunayer/fh5dl/book"
+)
+
+type fakeProvider struct {
+	name   string
+	prefix string
+}
+
+func (p *fakeProvider) Name() string                { return p.name }
+func (p *fakeProvider) Matches(idOrUrl string) bool { return strings.HasPrefix(idOrUrl, p.prefix) }
+func (p *fakeProvider) Resolve(ctx context.Context, idOrUrl string) (*book.Book, error) {
+	return &book.Book{Url: idOrUrl}, nil
+}
+func (p *fakeProvider) Images(b *book.Book) []book.PageImage        { return nil }
+func (p *fakeProvider) PageUrl(b *book.Book, pageNumber int) string { return b.Url }
+
+func TestRegistry(t *testing.T) {
+	Register(&fakeProvider{name: "portal", prefix: "https://portal.example.com/"})
+	Register(&fakeProvider{name: "catchall", prefix: "https://"})
+
+	p, err := For("https://portal.example.com/books/1")
+	if err != nil || p.Name() != "portal" {
+		t.Errorf("expected the portal provider, got %v (%v)", p, err)
+	}
+
+	p, err = For("https://other.example.com/books/1")
+	if err != nil || p.Name() != "catchall" {
+		t.Errorf("expected the first matching provider to be picked, got %v (%v)", p, err)
+	}
+
+	if _, err := For("ftp://portal.example.com"); err == nil {
+		t.Error("expected an error when no provider matches")
+	}
+
+	if _, ok := Get("portal"); !ok {
+		t.Error("expected to find the portal provider by name")
+	}
+
+	if names := strings.Join(Names(), ","); names != "catchall,portal" {
+		t.Errorf("unexpected names %s", names)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected registering a duplicate name to panic")
+		}
+	}()
+	Register(&fakeProvider{name: "portal"})
+}
+
+// describedProvider is a fakeProvider that describes its capabilities
+type describedProvider struct {
+	fakeProvider
+}
+
+func (p *describedProvider) Capabilities() Capabilities { return Capabilities{TableOfContents: true} }
+
+func TestCapabilitiesOf(t *testing.T) {
+	if capabilities := CapabilitiesOf(&fakeProvider{}); capabilities != (Capabilities{}) {
+		t.Errorf("expected no capabilities, got %+v", capabilities)
+	}
+
+	if capabilities := CapabilitiesOf(&describedProvider{}); !capabilities.TableOfContents || capabilities.InteractiveCapture {
+		t.Errorf("unexpected capabilities %+v", capabilities)
+	}
+}
@@ -0,0 +1,21 @@
+// Package providertest has what the tests of the providers share
+package providertest
+
+import (
+	"io"
+	"net/http"
+	"strings"
+)
+
+// ViewerTransport serves a fixed viewer page for every request, for the tests of providers that read the pages of a
+// book out of its viewer. Swap it in for book.Transport.
+type ViewerTransport string
+
+func (v ViewerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       io.NopCloser(strings.NewReader(string(v))),
+		Header:     make(http.Header),
+		Request:    req,
+	}, nil
+}
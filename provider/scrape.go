@@ -0,0 +1,110 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	book "github.com/ygunayer/fh5dl/book"
+)
+
+// MatchesDomain reports whether the URL is on the domain or one of its subdomains, for the Matches of platforms that
+// host every book on their own domain
+func MatchesDomain(idOrUrl string, domain string) bool {
+	u, err := url.Parse(idOrUrl)
+	if err != nil || u.Host == "" {
+		return false
+	}
+
+	host := strings.ToLower(u.Hostname())
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+// SkipThumbnails leaves the thumbnails of the pages out of ScrapePages, for viewers that list both
+func SkipThumbnails(imageUrl string) bool {
+	return strings.Contains(strings.ToLower(imageUrl), "thumb")
+}
+
+// ScrapeViewer fetches the viewer page of a book and collects its pages with ScrapePages. The body of the page is
+// returned along with them, for the metadata of the book such as its title. Pages the viewer lists no image for are
+// left for the download to report as missing, unless most of them are, which is taken for a pattern that doesn't
+// fit the viewer anymore.
+func ScrapeViewer(ctx context.Context, viewerUrl string, regex *regexp.Regexp, skip func(imageUrl string) bool) (string, []book.Page, error) {
+	body, err := book.FetchText(ctx, viewerUrl)
+	if err != nil {
+		return "", nil, err
+	}
+
+	pages := ScrapePages(body, regex, skip)
+	if len(pages) == 0 {
+		return "", nil, fmt.Errorf("%w in %s: no page images in the viewer", book.ErrConfigParse, viewerUrl)
+	}
+
+	if found := len(pages) - len(MissingPages(pages)); found*2 < len(pages) {
+		return "", nil, fmt.Errorf("%w in %s: the viewer has images for only %d of %d pages", book.ErrConfigParse, viewerUrl, found, len(pages))
+	}
+
+	return body, pages, nil
+}
+
+// MissingPages returns the numbers of the pages ScrapePages found no image for
+func MissingPages(pages []book.Page) []int {
+	missing := make([]int, 0)
+	for _, page := range pages {
+		if len(page.ImageUrls) == 0 {
+			missing = append(missing, page.Number)
+		}
+	}
+
+	return missing
+}
+
+// ScrapePages collects the pages of a book out of the page images a viewer refers to, for platforms that list them
+// in their viewer page. The first group of the regex is the number of the page, and the first image of every page is
+// kept unless skip rejects it. Escaped slashes, as inside JSON, are unescaped. Pages keep the number of their image,
+// or the one after it for viewers that count from zero, and pages without an image are kept empty, so that they are
+// reported as missing instead of shifting the pages after them.
+func ScrapePages(body string, regex *regexp.Regexp, skip func(imageUrl string) bool) []book.Page {
+	urls := make(map[int]string)
+	for _, match := range regex.FindAllStringSubmatch(body, -1) {
+		number, err := strconv.Atoi(match[1])
+		if err != nil {
+			continue
+		}
+
+		imageUrl := strings.ReplaceAll(match[0], `\/`, "/")
+		if skip != nil && skip(imageUrl) {
+			continue
+		}
+		if _, ok := urls[number]; !ok {
+			urls[number] = imageUrl
+		}
+	}
+	if len(urls) == 0 {
+		return []book.Page{}
+	}
+
+	// the viewer may count pages from zero, the book always counts from one
+	offset := 0
+	if _, ok := urls[0]; ok {
+		offset = 1
+	}
+
+	last := 0
+	for number := range urls {
+		last = max(last, number+offset)
+	}
+
+	pages := make([]book.Page, last)
+	for i := range pages {
+		pages[i].Number = i + 1
+	}
+	for number, imageUrl := range urls {
+		pages[number+offset-1].ImageUrls = []string{imageUrl}
+	}
+
+	return pages
+}
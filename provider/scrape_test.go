@@ -0,0 +1,81 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+
+	book "github.com/ygunayer/fh5dl/book"
+	"github.com/ygunayer/fh5dl/provider/providertest"
+)
+
+func TestScrapePages(t *testing.T) {
+	regex := regexp.MustCompile(`https?:(?:\\?/){2}[^"'\s]+?/(\d+)\.jpg`)
+	body := `["https:\/\/cdn.example.com\/thumbs\/1.jpg", "https:\/\/cdn.example.com\/pages\/1.jpg", "https://cdn.example.com/pages/0.jpg",
+"https://cdn.example.com/pages/0.jpg"]`
+
+	pages := ScrapePages(body, regex, func(imageUrl string) bool { return strings.Contains(imageUrl, "/thumbs/") })
+	if len(pages) != 2 {
+		t.Fatalf("expected 2 pages, got %+v", pages)
+	}
+	if pages[0].Number != 1 || pages[0].ImageUrls[0] != "https://cdn.example.com/pages/0.jpg" || pages[1].ImageUrls[0] != "https://cdn.example.com/pages/1.jpg" {
+		t.Errorf("unexpected pages %+v", pages)
+	}
+}
+
+func TestScrapePagesKeepsGaps(t *testing.T) {
+	regex := regexp.MustCompile(`https?://[^"'\s]+?/(\d+)\.jpg`)
+	body := `"https://cdn.example.com/pages/4.jpg" "https://cdn.example.com/pages/1.jpg" "https://cdn.example.com/pages/2.jpg"`
+
+	pages := ScrapePages(body, regex, nil)
+	if len(pages) != 4 || pages[3].Number != 4 || pages[3].ImageUrls[0] != "https://cdn.example.com/pages/4.jpg" {
+		t.Fatalf("expected the pages to keep their numbers, got %+v", pages)
+	}
+	if missing := MissingPages(pages); len(missing) != 1 || missing[0] != 3 {
+		t.Errorf("expected page 3 to be missing, got %v", missing)
+	}
+}
+
+func TestScrapeViewer(t *testing.T) {
+	defer func(original http.RoundTripper) { book.Transport = original }(book.Transport)
+	regex := regexp.MustCompile(`https?://[^"'\s]+?/(\d+)\.jpg`)
+
+	book.Transport = providertest.ViewerTransport(`<title>Catalog</title> "https://cdn.example.com/pages/1.jpg"`)
+	body, pages, err := ScrapeViewer(context.Background(), "https://example.com/catalog", regex, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if book.HtmlTitle(body) != "Catalog" || len(pages) != 1 {
+		t.Errorf("expected the body and the page of the viewer, got %q and %+v", body, pages)
+	}
+
+	book.Transport = providertest.ViewerTransport(`<title>Sign in</title>`)
+	if _, _, err := ScrapeViewer(context.Background(), "https://example.com/catalog", regex, nil); !errors.Is(err, book.ErrConfigParse) {
+		t.Errorf("expected a parse error for a viewer without pages, got %v", err)
+	}
+
+	// an image the pattern shouldn't have matched, such as a logo with a year in its name
+	book.Transport = providertest.ViewerTransport(`"https://cdn.example.com/pages/1.jpg" "https://cdn.example.com/logo/2024.jpg"`)
+	if _, _, err := ScrapeViewer(context.Background(), "https://example.com/catalog", regex, nil); !errors.Is(err, book.ErrConfigParse) {
+		t.Errorf("expected a parse error for a viewer with images for few of its pages, got %v", err)
+	}
+}
+
+func TestMatchesDomain(t *testing.T) {
+	cases := map[string]bool{
+		"https://joomag.com/magazine/1":        true,
+		"https://viewer.Joomag.com/magazine/1": true,
+		"https://notjoomag.com/x":              false,
+		"joomag.com/magazine/1":                false,
+		"abcde/fghij":                          false,
+	}
+
+	for idOrUrl, expected := range cases {
+		if actual := MatchesDomain(idOrUrl, "joomag.com"); actual != expected {
+			t.Errorf("expected %v for %s, got %v", expected, idOrUrl, actual)
+		}
+	}
+}
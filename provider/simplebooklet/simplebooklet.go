@@ -0,0 +1,78 @@
+// Package simplebooklet is the provider for booklets hosted on simplebooklet.com
+package simplebooklet
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	book "github.com/ygunayer/fh5dl/book"
+	"github.com/ygunayer/fh5dl/provider"
+)
+
+// pageImageRegex finds the page images in the user files the viewer refers to, such as
+// .../userFiles/a/b/c/key/pages/page_3.jpg, also when the slashes are escaped inside JSON
+var pageImageRegex = regexp.MustCompile(`https?:(?:\\?/){2}[^"'\s<>()]*?simplebooklet\.com(?:\\?/)userFiles(?:\\?/)[^"'\s<>()]+?(?:\\?/)(?:page|pg|p)?[_-]?(\d+)\.(?:jpe?g|png|webp)`)
+
+func init() {
+	provider.Register(&Provider{})
+}
+
+// Provider downloads booklets from simplebooklet.com
+type Provider struct{}
+
+func (p *Provider) Name() string {
+	return "simplebooklet"
+}
+
+// Matches accepts URLs on simplebooklet.com and its subdomains
+func (p *Provider) Matches(idOrUrl string) bool {
+	return provider.MatchesDomain(idOrUrl, "simplebooklet.com")
+}
+
+// Resolve reads the page images out of the viewer of the booklet
+func (p *Provider) Resolve(ctx context.Context, idOrUrl string) (*book.Book, error) {
+	u, err := url.Parse(idOrUrl)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid simplebooklet URL: %s", idOrUrl)
+	}
+	u.Fragment = ""
+
+	// booklets are either published under a name or only have the key of the publish page
+	id := u.Query().Get("wpKey")
+	if id == "" {
+		u.RawQuery = ""
+		id = strings.Trim(u.Path, "/")
+	}
+	if id == "" || strings.Contains(id, "/") {
+		return nil, fmt.Errorf("invalid simplebooklet URL: %s, expected the URL of a booklet", idOrUrl)
+	}
+
+	body, pages, err := provider.ScrapeViewer(ctx, u.String(), pageImageRegex, provider.SkipThumbnails)
+	if err != nil {
+		return nil, err
+	}
+
+	return &book.Book{
+		Url:   u.String(),
+		Id:    "simplebooklet/" + id,
+		Title: book.HtmlTitle(body),
+		Pages: pages,
+	}, nil
+}
+
+// Capabilities of the viewer, which can be opened on a single page
+func (p *Provider) Capabilities() provider.Capabilities {
+	return provider.Capabilities{InteractiveCapture: true}
+}
+
+func (p *Provider) Images(b *book.Book) []book.PageImage {
+	return b.FindAllImages()
+}
+
+// PageUrl points the viewer to a page using the #page= fragment
+func (p *Provider) PageUrl(b *book.Book, pageNumber int) string {
+	return fmt.Sprintf("%s#page=%d", b.Url, pageNumber)
+}
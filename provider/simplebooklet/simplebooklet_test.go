@@ -0,0 +1,60 @@
+package simplebooklet
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	book "github.com/ygunayer/fh5dl/book"
+	"github.com/ygunayer/fh5dl/provider"
+	"github.com/ygunayer/fh5dl/provider/providertest"
+)
+
+func TestMatches(t *testing.T) {
+	p := &Provider{}
+
+	for _, idOrUrl := range []string{"https://simplebooklet.com/springnewsletter", "https://www.simplebooklet.com/publish.php?wpKey=AbC123"} {
+		if !p.Matches(idOrUrl) {
+			t.Errorf("expected %s to match", idOrUrl)
+		}
+	}
+
+	for _, idOrUrl := range []string{"abcde/fghij", "https://heyzine.com/flip-book/1a2b.html", "https://notsimplebooklet.com/x"} {
+		if p.Matches(idOrUrl) {
+			t.Errorf("expected %s not to match", idOrUrl)
+		}
+	}
+}
+
+func TestParsePages(t *testing.T) {
+	body := `<img src="https://simplebooklet.com/userFiles/a/1/2/AbC123/thumbs/page_1.jpg">
+<script>var pages = ["https:\/\/simplebooklet.com\/userFiles\/a\/1\/2\/AbC123\/pages\/page_2.jpg",
+"https:\/\/simplebooklet.com\/userFiles\/a\/1\/2\/AbC123\/pages\/page_1.jpg"];</script>`
+
+	pages := provider.ScrapePages(body, pageImageRegex, provider.SkipThumbnails)
+	if len(pages) != 2 {
+		t.Fatalf("expected 2 pages, got %+v", pages)
+	}
+	if pages[0].ImageUrls[0] != "https://simplebooklet.com/userFiles/a/1/2/AbC123/pages/page_1.jpg" {
+		t.Errorf("expected the full size image of the first page, got %+v", pages)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	defer func(original http.RoundTripper) { book.Transport = original }(book.Transport)
+	book.Transport = providertest.ViewerTransport(`<title>Spring Newsletter</title>
+"https://simplebooklet.com/userFiles/a/1/2/AbC123/pages/page_1.jpg"`)
+
+	b, err := (&Provider{}).Resolve(context.Background(), "https://simplebooklet.com/springnewsletter#page=3")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if b.Id != "simplebooklet/springnewsletter" || b.Title != "Spring Newsletter" || len(b.Pages) != 1 {
+		t.Errorf("unexpected book %+v", b)
+	}
+
+	b, err = (&Provider{}).Resolve(context.Background(), "https://simplebooklet.com/publish.php?wpKey=AbC123")
+	if err != nil || b.Id != "simplebooklet/AbC123" {
+		t.Errorf("expected the key to be the ID, got %+v, %v", b, err)
+	}
+}
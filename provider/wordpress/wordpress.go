@@ -0,0 +1,161 @@
+// Package wordpress is the provider for flipbooks embedded into self-hosted sites with the DearFlip or Real3D
+// flipbook plugins of WordPress
+package wordpress
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+
+	book "github.com/ygunayer/fh5dl/book"
+	"github.com/ygunayer/fh5dl/provider"
+)
+
+// real3dAttributeRegex finds the options Real3D writes into the embed element as HTML escaped JSON
+var real3dAttributeRegex = regexp.MustCompile(`data-flipbook-options\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+
+func init() {
+	provider.Register(&Provider{})
+}
+
+// Provider downloads flipbooks embedded with the DearFlip or Real3D plugins. Those can be on any site, so it never
+// matches a URL and has to be picked with --provider wordpress.
+type Provider struct{}
+
+func (p *Provider) Name() string {
+	return "wordpress"
+}
+
+// Matches is always false, as the plugins can't be recognized from the URL of the page they're embedded into
+func (p *Provider) Matches(idOrUrl string) bool {
+	return false
+}
+
+// embed is the part of the options of an embed that is about its pages. DearFlip lists the page images in source,
+// or has the URL of a PDF there; Real3D lists them in pages and has the PDF in pdfUrl.
+type embed struct {
+	Source interface{} `json:"source"`
+	Pages  []struct {
+		Src string `json:"src"`
+	} `json:"pages"`
+	PdfUrl string `json:"pdfUrl"`
+}
+
+// imageUrls returns the page images of the embed in order
+func (e *embed) imageUrls() []string {
+	urls := make([]string, 0)
+	if sources, ok := e.Source.([]interface{}); ok {
+		for _, source := range sources {
+			if s, ok := source.(string); ok && s != "" {
+				urls = append(urls, s)
+			}
+		}
+	}
+	for _, page := range e.Pages {
+		if page.Src != "" {
+			urls = append(urls, page.Src)
+		}
+	}
+
+	return urls
+}
+
+// pdfUrl returns the PDF the embed is made from, if any
+func (e *embed) pdfUrl() string {
+	if source, ok := e.Source.(string); ok && source != "" {
+		return source
+	}
+
+	return e.PdfUrl
+}
+
+// parseEmbeds returns the options of every DearFlip and Real3D embed of the page
+func parseEmbeds(body string) []embed {
+	objects := book.AssignedObjects(body, "option_df_")
+	objects = append(objects, book.AssignedObjects(body, "real3dflipbook_")...)
+	for _, match := range real3dAttributeRegex.FindAllStringSubmatch(body, -1) {
+		objects = append(objects, html.UnescapeString(match[1]+match[2]))
+	}
+
+	embeds := make([]embed, 0, len(objects))
+	for _, object := range objects {
+		var e embed
+		if err := book.DecodeObject(object, &e); err == nil {
+			embeds = append(embeds, e)
+		}
+	}
+
+	return embeds
+}
+
+// Resolve reads the page images out of the first embed of the page that lists them
+func (p *Provider) Resolve(ctx context.Context, idOrUrl string) (*book.Book, error) {
+	u, err := url.Parse(idOrUrl)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid page URL: %s", idOrUrl)
+	}
+	u.Fragment = ""
+
+	body, err := book.FetchText(ctx, u.String())
+	if err != nil {
+		return nil, err
+	}
+
+	embeds := parseEmbeds(body)
+	if len(embeds) == 0 {
+		return nil, fmt.Errorf("%w in %s: no DearFlip or Real3D flipbook on the page", book.ErrConfigParse, u)
+	}
+
+	for _, e := range embeds {
+		imageUrls := e.imageUrls()
+		if len(imageUrls) == 0 {
+			continue
+		}
+
+		pages := make([]book.Page, 0, len(imageUrls))
+		for i, imageUrl := range imageUrls {
+			// the plugins often list images relative to the page
+			if resolved, err := u.Parse(imageUrl); err == nil {
+				imageUrl = resolved.String()
+			}
+			pages = append(pages, book.Page{Number: i + 1, ImageUrls: []string{imageUrl}})
+		}
+
+		return &book.Book{
+			Url:   u.String(),
+			Id:    "wordpress/" + bookId(u),
+			Title: book.HtmlTitle(body),
+			Pages: pages,
+		}, nil
+	}
+
+	// flipbooks made from a PDF render it in the browser, so there are no page images to download
+	for _, e := range embeds {
+		if pdfUrl := e.pdfUrl(); pdfUrl != "" {
+			if resolved, err := u.Parse(pdfUrl); err == nil {
+				pdfUrl = resolved.String()
+			}
+			return nil, fmt.Errorf("the flipbook on %s is made from a PDF, download it directly from %s", u, pdfUrl)
+		}
+	}
+
+	return nil, fmt.Errorf("%w in %s: the flipbook lists no page images", book.ErrConfigParse, u)
+}
+
+// bookId turns the host and path of the page into an ID, as the plugins don't expose one of their own
+func bookId(u *url.URL) string {
+	id := u.Hostname() + strings.TrimSuffix(u.Path, "/")
+	return strings.NewReplacer("/", "_", ".", "_").Replace(id)
+}
+
+func (p *Provider) Images(b *book.Book) []book.PageImage {
+	return b.FindAllImages()
+}
+
+// PageUrl returns the page the flipbook is embedded into, the plugins have no links to single pages
+func (p *Provider) PageUrl(b *book.Book, pageNumber int) string {
+	return b.Url
+}
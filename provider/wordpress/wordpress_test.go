@@ -0,0 +1,67 @@
+package wordpress
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	book "github.com/ygunayer/fh5dl/book"
+)
+
+func TestParseEmbeds(t *testing.T) {
+	body := `<script>var option_df_1234 = {"source":["https:\/\/example.com\/wp-content\/uploads\/p1.jpg","p2.jpg"],"height":"auto"};</script>
+<div class="real3dflipbook" data-flipbook-options="{&quot;pages&quot;:[{&quot;src&quot;:&quot;/uploads/r1.jpg&quot;}],&quot;pdfUrl&quot;:&quot;&quot;}"></div>
+<script>var real3dflipbook_7 = {pdfUrl: 'catalog.pdf',};</script>`
+
+	embeds := parseEmbeds(body)
+	if len(embeds) != 3 {
+		t.Fatalf("expected 3 embeds, got %+v", embeds)
+	}
+
+	if urls := embeds[0].imageUrls(); len(urls) != 2 || urls[0] != "https://example.com/wp-content/uploads/p1.jpg" {
+		t.Errorf("unexpected DearFlip images %v", urls)
+	}
+	// scripts come before the attributes of embed elements
+	if urls := embeds[2].imageUrls(); len(urls) != 1 || urls[0] != "/uploads/r1.jpg" {
+		t.Errorf("unexpected Real3D images %v", urls)
+	}
+	if embeds[1].pdfUrl() != "catalog.pdf" || len(embeds[1].imageUrls()) != 0 {
+		t.Errorf("unexpected Real3D PDF embed %+v", embeds[1])
+	}
+}
+
+// pageTransport serves a fixed page
+type pageTransport string
+
+func (p pageTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       io.NopCloser(strings.NewReader(string(p))),
+		Header:     make(http.Header),
+		Request:    req,
+	}, nil
+}
+
+func TestResolve(t *testing.T) {
+	defer func(original http.RoundTripper) { book.Transport = original }(book.Transport)
+	book.Transport = pageTransport(`<title>Spring Catalog</title>
+<script>var option_df_1234 = {source: ['pages/1.jpg', 'pages/2.jpg']};</script>`)
+
+	b, err := (&Provider{}).Resolve(context.Background(), "https://shop.example.com/catalog/#top")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if b.Id != "wordpress/shop_example_com_catalog" || b.Title != "Spring Catalog" || len(b.Pages) != 2 {
+		t.Errorf("unexpected book %+v", b)
+	}
+	if b.Pages[1].ImageUrls[0] != "https://shop.example.com/catalog/pages/2.jpg" {
+		t.Errorf("expected relative images to be resolved, got %v", b.Pages[1].ImageUrls)
+	}
+
+	book.Transport = pageTransport(`<script>var option_df_1 = {"source": "/uploads/catalog.pdf"};</script>`)
+	if _, err := (&Provider{}).Resolve(context.Background(), "https://shop.example.com/catalog/"); err == nil || !strings.Contains(err.Error(), "https://shop.example.com/uploads/catalog.pdf") {
+		t.Errorf("expected the PDF to be named, got %v", err)
+	}
+}